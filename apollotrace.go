@@ -0,0 +1,273 @@
+package graphql
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ApolloTrace is resolver-level timing information that servers include in
+// the extensions of responses, either in the Apollo Tracing format, under
+// "tracing", or as an Apollo federated trace, under "ftv1".
+// See WithApolloTrace.
+type ApolloTrace struct {
+	StartTime time.Time
+	EndTime   time.Time
+	Duration  time.Duration
+
+	// Parsing and Validation are the phases of parsing and validating the
+	// document. Federated traces don't record them, so they're zero then.
+	Parsing    TracePhase
+	Validation TracePhase
+
+	// Resolvers are the timings of the resolvers of the response fields.
+	Resolvers []ResolverTrace
+}
+
+// TracePhase is a phase of the server's handling of an operation.
+type TracePhase struct {
+	StartOffset time.Duration // Start of the phase, relative to the trace's StartTime.
+	Duration    time.Duration
+}
+
+// ResolverTrace is the timing of the resolver of a single response field.
+type ResolverTrace struct {
+	Path        []any  // Path of the field in the response, as in Error.Path.
+	ParentType  string // Type that the field belongs to.
+	FieldName   string // Name of the field in the schema, which may be aliased in Path.
+	ReturnType  string // Type of the field.
+	StartOffset time.Duration
+	Duration    time.Duration
+}
+
+// WithApolloTrace asks the server to include a trace of the operation in the
+// response and decodes it into t, so that latency can be attributed to
+// specific resolvers. It sends the Apollo-Federation-Include-Trace header,
+// which makes servers that support federated tracing include one. t is left
+// as is if the response doesn't include a valid trace.
+func WithApolloTrace(t *ApolloTrace) CallOption {
+	return func(cfg *callConfig) { cfg.apolloTrace = t }
+}
+
+// decodeApolloTrace decodes the trace in the response extensions ext
+// into t, if there's one. t is left as is if decoding fails.
+func decodeApolloTrace(ext map[string]json.RawMessage, t *ApolloTrace) error {
+	var trace ApolloTrace
+	if raw, ok := ext["tracing"]; ok {
+		if err := trace.unmarshalTracing(raw); err != nil {
+			return err
+		}
+	} else if raw, ok := ext["ftv1"]; ok {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return err
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return err
+		}
+		if err := trace.unmarshalFTV1(b); err != nil {
+			return err
+		}
+	} else {
+		return nil
+	}
+	*t = trace
+	return nil
+}
+
+// unmarshalTracing decodes a trace in the Apollo Tracing format.
+//
+// Specification: https://github.com/apollographql/apollo-tracing.
+func (t *ApolloTrace) unmarshalTracing(b []byte) error {
+	type phase struct {
+		StartOffset int64
+		Duration    int64
+	}
+	var v struct {
+		StartTime  time.Time
+		EndTime    time.Time
+		Duration   int64
+		Parsing    phase
+		Validation phase
+		Execution  struct {
+			Resolvers []struct {
+				Path        []any
+				ParentType  string
+				FieldName   string
+				ReturnType  string
+				StartOffset int64
+				Duration    int64
+			}
+		}
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*t = ApolloTrace{
+		StartTime:  v.StartTime,
+		EndTime:    v.EndTime,
+		Duration:   time.Duration(v.Duration),
+		Parsing:    TracePhase{time.Duration(v.Parsing.StartOffset), time.Duration(v.Parsing.Duration)},
+		Validation: TracePhase{time.Duration(v.Validation.StartOffset), time.Duration(v.Validation.Duration)},
+	}
+	for _, r := range v.Execution.Resolvers {
+		for i, p := range r.Path {
+			if f, ok := p.(float64); ok {
+				r.Path[i] = int(f)
+			}
+		}
+		t.Resolvers = append(t.Resolvers, ResolverTrace{
+			Path:        r.Path,
+			ParentType:  r.ParentType,
+			FieldName:   r.FieldName,
+			ReturnType:  r.ReturnType,
+			StartOffset: time.Duration(r.StartOffset),
+			Duration:    time.Duration(r.Duration),
+		})
+	}
+	return nil
+}
+
+// unmarshalFTV1 decodes an Apollo federated trace, which is a Trace message
+// of Apollo's reports.proto in the protocol buffers wire format.
+// Only the fields that ApolloTrace has are decoded.
+func (t *ApolloTrace) unmarshalFTV1(b []byte) error {
+	return protoFields(b, func(num int, v uint64, b []byte) error {
+		switch num {
+		case 3, 4: // end_time, start_time.
+			ts, err := protoTimestamp(b)
+			if err != nil {
+				return err
+			}
+			if num == 3 {
+				t.EndTime = ts
+			} else {
+				t.StartTime = ts
+			}
+		case 11: // duration_ns.
+			t.Duration = time.Duration(v)
+		case 14: // root.
+			return t.unmarshalFTV1Node(b, nil)
+		}
+		return nil
+	})
+}
+
+// unmarshalFTV1Node decodes a Trace.Node message, the node of the field with
+// path, adding resolver traces for it, if it's a field, and its children.
+func (t *ApolloTrace) unmarshalFTV1Node(b []byte, parent []any) error {
+	var (
+		r        ResolverTrace
+		id       any
+		start    uint64
+		end      uint64
+		children [][]byte
+	)
+	err := protoFields(b, func(num int, v uint64, b []byte) error {
+		switch num {
+		case 1: // response_name.
+			id = string(b)
+		case 2: // index.
+			id = int(v)
+		case 3: // type.
+			r.ReturnType = string(b)
+		case 8: // start_time.
+			start = v
+		case 9: // end_time.
+			end = v
+		case 12: // child.
+			children = append(children, b)
+		case 13: // parent_type.
+			r.ParentType = string(b)
+		case 14: // original_field_name.
+			r.FieldName = string(b)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	path := parent
+	if id != nil {
+		path = append(parent[:len(parent):len(parent)], id)
+	}
+	if name, ok := id.(string); ok {
+		if r.FieldName == "" {
+			r.FieldName = name
+		}
+		r.Path = path
+		r.StartOffset = time.Duration(start)
+		r.Duration = time.Duration(end - start)
+		t.Resolvers = append(t.Resolvers, r)
+	}
+	for _, c := range children {
+		if err := t.unmarshalFTV1Node(c, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// protoTimestamp decodes a google.protobuf.Timestamp message.
+func protoTimestamp(b []byte) (time.Time, error) {
+	var secs, nanos int64
+	err := protoFields(b, func(num int, v uint64, b []byte) error {
+		switch num {
+		case 1:
+			secs = int64(v)
+		case 2:
+			nanos = int64(v)
+		}
+		return nil
+	})
+	return time.Unix(secs, nanos).UTC(), err
+}
+
+// protoFields calls f for each field of the protocol buffers message b,
+// with its number, and either its value if it's a varint or fixed-size
+// field, or its bytes if it's a length-delimited field.
+func protoFields(b []byte, f func(num int, v uint64, b []byte) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errors.New("invalid protocol buffers field key")
+		}
+		b = b[n:]
+		var v uint64
+		var field []byte
+		switch key & 7 {
+		case 0: // Varint.
+			v, n = binary.Uvarint(b)
+			if n <= 0 {
+				return errors.New("invalid protocol buffers varint")
+			}
+		case 1: // 64-bit.
+			if len(b) < 8 {
+				return errors.New("truncated protocol buffers field")
+			}
+			v, n = binary.LittleEndian.Uint64(b), 8
+		case 2: // Length-delimited.
+			l, m := binary.Uvarint(b)
+			if m <= 0 || uint64(len(b)-m) < l {
+				return errors.New("truncated protocol buffers field")
+			}
+			field, n = b[m:m+int(l)], m+int(l)
+		case 5: // 32-bit.
+			if len(b) < 4 {
+				return errors.New("truncated protocol buffers field")
+			}
+			v, n = uint64(binary.LittleEndian.Uint32(b)), 4
+		default:
+			return fmt.Errorf("unsupported protocol buffers wire type %d", key&7)
+		}
+		b = b[n:]
+		if err := f(int(key>>3), v, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/isihu/graphql"
+)
+
+func TestWithApolloTrace_tracing(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{
+			"data": {"user": {"name": "Gopher"}},
+			"extensions": {"tracing": {
+				"version": 1,
+				"startTime": "2026-10-15T10:00:00.000Z",
+				"endTime": "2026-10-15T10:00:00.005Z",
+				"duration": 5000000,
+				"parsing": {"startOffset": 10000, "duration": 20000},
+				"validation": {"startOffset": 30000, "duration": 40000},
+				"execution": {"resolvers": [
+					{"path": ["user"], "parentType": "Query", "fieldName": "user", "returnType": "User", "startOffset": 100000, "duration": 4000000},
+					{"path": ["user", "name"], "parentType": "User", "fieldName": "name", "returnType": "String", "startOffset": 4200000, "duration": 1000}
+				]}
+			}}
+		}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct{ User struct{ Name graphql.String } }
+	var trace graphql.ApolloTrace
+	err := client.Query(context.Background(), &q, nil, graphql.WithApolloTrace(&trace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := trace.Duration, 5*time.Millisecond; got != want {
+		t.Errorf("got duration: %v, want: %v", got, want)
+	}
+	if got, want := trace.Validation, (graphql.TracePhase{StartOffset: 30 * time.Microsecond, Duration: 40 * time.Microsecond}); got != want {
+		t.Errorf("got validation: %+v, want: %+v", got, want)
+	}
+	if got, want := fmt.Sprint(trace.Resolvers), "[{[user] Query user User 100µs 4ms} {[user name] User name String 4.2ms 1µs}]"; got != want {
+		t.Errorf("got resolvers: %s, want: %s", got, want)
+	}
+}
+
+// protoField encodes a protocol buffers field with number num,
+// whose value is either a uint64 or a []byte.
+func protoField(num int, v any) []byte {
+	switch v := v.(type) {
+	case uint64:
+		b := binary.AppendUvarint(nil, uint64(num)<<3)
+		return binary.AppendUvarint(b, v)
+	case []byte:
+		b := binary.AppendUvarint(nil, uint64(num)<<3|2)
+		b = binary.AppendUvarint(b, uint64(len(v)))
+		return append(b, v...)
+	}
+	panic("unsupported value")
+}
+
+func concat(bs ...[]byte) []byte {
+	var b []byte
+	for _, x := range bs {
+		b = append(b, x...)
+	}
+	return b
+}
+
+func TestWithApolloTrace_ftv1(t *testing.T) {
+	name := concat(
+		protoField(1, []byte("name")),
+		protoField(3, []byte("String")),
+		protoField(13, []byte("User")),
+		protoField(8, uint64(4200000)),
+		protoField(9, uint64(4201000)),
+	)
+	user := concat(
+		protoField(1, []byte("me")),
+		protoField(14, []byte("user")),
+		protoField(3, []byte("User")),
+		protoField(13, []byte("Query")),
+		protoField(8, uint64(100000)),
+		protoField(9, uint64(4100000)),
+		protoField(12, name),
+	)
+	trace := concat(
+		protoField(4, protoField(1, uint64(1791972000))),
+		protoField(11, uint64(5000000)),
+		protoField(14, protoField(12, user)),
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.Header.Get("Apollo-Federation-Include-Trace"), "ftv1"; got != want {
+			t.Errorf("got Apollo-Federation-Include-Trace header: %q, want: %q", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"me": {"name": "Gopher"}}, "extensions": {"ftv1": "`+base64.StdEncoding.EncodeToString(trace)+`"}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Me struct{ Name graphql.String } `graphql:"me: user"`
+	}
+	var got graphql.ApolloTrace
+	err := client.Query(context.Background(), &q, nil, graphql.WithApolloTrace(&got))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := got.StartTime, time.Unix(1791972000, 0).UTC(); !got.Equal(want) {
+		t.Errorf("got start time: %v, want: %v", got, want)
+	}
+	if got, want := got.Duration, 5*time.Millisecond; got != want {
+		t.Errorf("got duration: %v, want: %v", got, want)
+	}
+	if got, want := fmt.Sprint(got.Resolvers), "[{[me] Query user User 100µs 4ms} {[me name] User name String 4.2ms 1µs}]"; got != want {
+		t.Errorf("got resolvers: %s, want: %s", got, want)
+	}
+}
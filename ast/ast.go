@@ -0,0 +1,200 @@
+// Package ast provides a parser and printer for GraphQL executable documents,
+// along with the syntax tree they operate on, and a parser for schemas.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-Language.
+package ast
+
+// Document is a GraphQL document.
+type Document struct {
+	Definitions []Definition
+}
+
+// Operations returns the operation definitions in d, in order.
+func (d *Document) Operations() []*OperationDefinition {
+	var ops []*OperationDefinition
+	for _, def := range d.Definitions {
+		if op, ok := def.(*OperationDefinition); ok {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// Fragment returns the fragment definition named name,
+// or nil if d doesn't have one.
+func (d *Document) Fragment(name string) *FragmentDefinition {
+	for _, def := range d.Definitions {
+		if f, ok := def.(*FragmentDefinition); ok && f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Definition is a top-level definition in a Document.
+// It's one of *OperationDefinition or *FragmentDefinition.
+type Definition interface {
+	definition()
+}
+
+// OperationDefinition is a query, mutation or subscription operation.
+type OperationDefinition struct {
+	Operation           string // "query", "mutation" or "subscription".
+	Name                string // Empty for anonymous operations.
+	VariableDefinitions []*VariableDefinition
+	Directives          []*Directive
+	SelectionSet        SelectionSet
+}
+
+// FragmentDefinition is a named fragment.
+type FragmentDefinition struct {
+	Name          string
+	TypeCondition string
+	Directives    []*Directive
+	SelectionSet  SelectionSet
+}
+
+func (*OperationDefinition) definition() {}
+func (*FragmentDefinition) definition()  {}
+
+// VariableDefinition is a variable defined by an operation.
+type VariableDefinition struct {
+	Variable     string // Variable name, without the leading "$".
+	Type         *Type
+	DefaultValue Value // Nil if there's no default value.
+	Directives   []*Directive
+}
+
+// Type is a reference to an input type. Exactly one of
+// NamedType and Elem is set, for named and list types respectively.
+type Type struct {
+	NamedType string // E.g., "Int".
+	Elem      *Type  // Element type of a list type.
+	NonNull   bool
+}
+
+// SelectionSet is a list of selections.
+type SelectionSet []Selection
+
+// Selection is an entry in a SelectionSet.
+// It's one of *Field, *FragmentSpread or *InlineFragment.
+type Selection interface {
+	selection()
+}
+
+// Field is a field selection.
+type Field struct {
+	Alias        string // Empty if the field isn't aliased.
+	Name         string
+	Arguments    []*Argument
+	Directives   []*Directive
+	SelectionSet SelectionSet // Empty for leaf fields.
+}
+
+// ResponseKey returns the key under which f appears in the response,
+// which is its alias if it has one, or its name otherwise.
+func (f *Field) ResponseKey() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// FragmentSpread is a spread of a named fragment, e.g., "...UserFields".
+type FragmentSpread struct {
+	Name       string
+	Directives []*Directive
+}
+
+// InlineFragment is an inline fragment, e.g., "... on User{login}".
+type InlineFragment struct {
+	TypeCondition string // Empty if there's no type condition.
+	Directives    []*Directive
+	SelectionSet  SelectionSet
+}
+
+func (*Field) selection()          {}
+func (*FragmentSpread) selection() {}
+func (*InlineFragment) selection() {}
+
+// Argument is a named argument of a field or directive.
+type Argument struct {
+	Name  string
+	Value Value
+}
+
+// Directive is a directive applied to a definition or selection, e.g., "@include(if:$x)".
+type Directive struct {
+	Name      string
+	Arguments []*Argument
+}
+
+// Value is an input value.
+// It's one of *Variable, *IntValue, *FloatValue, *StringValue, *BooleanValue,
+// *NullValue, *EnumValue, *ListValue or *ObjectValue.
+type Value interface {
+	value()
+}
+
+type (
+	// Variable is a reference to a variable, e.g., "$id".
+	Variable struct {
+		Name string // Variable name, without the leading "$".
+	}
+
+	// IntValue is an integer literal, kept as written.
+	IntValue struct {
+		Raw string
+	}
+
+	// FloatValue is a floating point literal, kept as written.
+	FloatValue struct {
+		Raw string
+	}
+
+	// StringValue is a string literal, with escape sequences
+	// and block string indentation already processed.
+	StringValue struct {
+		Value string
+		Block bool // Whether it was written as a block string.
+	}
+
+	// BooleanValue is a true or false literal.
+	BooleanValue struct {
+		Value bool
+	}
+
+	// NullValue is the null literal.
+	NullValue struct{}
+
+	// EnumValue is an enum literal, e.g., "OPEN".
+	EnumValue struct {
+		Name string
+	}
+
+	// ListValue is a list literal.
+	ListValue struct {
+		Values []Value
+	}
+
+	// ObjectValue is an input object literal.
+	ObjectValue struct {
+		Fields []*ObjectField
+	}
+)
+
+// ObjectField is a field of an ObjectValue.
+type ObjectField struct {
+	Name  string
+	Value Value
+}
+
+func (*Variable) value()     {}
+func (*IntValue) value()     {}
+func (*FloatValue) value()   {}
+func (*StringValue) value()  {}
+func (*BooleanValue) value() {}
+func (*NullValue) value()    {}
+func (*EnumValue) value()    {}
+func (*ListValue) value()    {}
+func (*ObjectValue) value()  {}
@@ -0,0 +1,285 @@
+package ast
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+)
+
+// Equivalent reports whether the GraphQL documents a and b are semantically
+// the same, as their canonical forms are. See Canonical.
+func Equivalent(a, b string) (bool, error) {
+	da, err := Parse(a)
+	if err != nil {
+		return false, err
+	}
+	db, err := Parse(b)
+	if err != nil {
+		return false, err
+	}
+	return Canonical(da).String() == Canonical(db).String(), nil
+}
+
+// Canonical returns a copy of d in a canonical form, which is the same for
+// documents that differ only in:
+//
+//   - formatting;
+//   - the order of definitions, selections, arguments and input object fields;
+//   - selections that merge, such as a field selected twice with different
+//     subselections, or inline fragments with the same type condition;
+//   - the names of variables, which are renamed $v1, $v2, etc.,
+//     in the order they're first used.
+//
+// It's for comparing documents, e.g., in tests or when migrating queries;
+// the order of selections may matter to the code decoding responses.
+func Canonical(d *Document) *Document {
+	c := new(Document)
+	for _, def := range d.Definitions {
+		switch def := def.(type) {
+		case *OperationDefinition:
+			op := *def
+			op.VariableDefinitions = slices.Clone(def.VariableDefinitions)
+			op.Directives = canonicalDirectives(def.Directives)
+			op.SelectionSet = canonicalSelectionSet(def.SelectionSet)
+			c.Definitions = append(c.Definitions, &op)
+		case *FragmentDefinition:
+			f := *def
+			f.Directives = canonicalDirectives(def.Directives)
+			f.SelectionSet = canonicalSelectionSet(def.SelectionSet)
+			c.Definitions = append(c.Definitions, &f)
+		}
+	}
+	slices.SortStableFunc(c.Definitions, func(a, b Definition) int {
+		return cmp.Compare(definitionKey(a), definitionKey(b))
+	})
+	renameVariables(c)
+	return c
+}
+
+// definitionKey returns the key by which canonical definitions are sorted,
+// operations first.
+func definitionKey(def Definition) string {
+	switch def := def.(type) {
+	case *OperationDefinition:
+		return "0" + def.Name
+	case *FragmentDefinition:
+		return "1" + def.Name
+	}
+	return ""
+}
+
+// canonicalSelectionSet returns a sorted copy of set, with the selections
+// that merge merged.
+func canonicalSelectionSet(set SelectionSet) SelectionSet {
+	if len(set) == 0 {
+		return nil
+	}
+	var out SelectionSet
+	merged := make(map[string]Selection) // By mergeKey.
+	for _, sel := range set {
+		key := mergeKey(sel)
+		switch sel := sel.(type) {
+		case *Field:
+			if prev, ok := merged[key].(*Field); ok {
+				prev.SelectionSet = append(prev.SelectionSet, sel.SelectionSet...)
+				continue
+			}
+			f := *sel
+			f.Arguments = canonicalArguments(sel.Arguments)
+			f.Directives = canonicalDirectives(sel.Directives)
+			f.SelectionSet = slices.Clone(sel.SelectionSet)
+			merged[key] = &f
+			out = append(out, &f)
+		case *InlineFragment:
+			if prev, ok := merged[key].(*InlineFragment); ok {
+				prev.SelectionSet = append(prev.SelectionSet, sel.SelectionSet...)
+				continue
+			}
+			f := *sel
+			f.Directives = canonicalDirectives(sel.Directives)
+			f.SelectionSet = slices.Clone(sel.SelectionSet)
+			merged[key] = &f
+			out = append(out, &f)
+		case *FragmentSpread:
+			if _, ok := merged[key]; ok {
+				continue
+			}
+			f := *sel
+			f.Directives = canonicalDirectives(sel.Directives)
+			merged[key] = &f
+			out = append(out, &f)
+		}
+	}
+	for _, sel := range out {
+		switch sel := sel.(type) {
+		case *Field:
+			sel.SelectionSet = canonicalSelectionSet(sel.SelectionSet)
+		case *InlineFragment:
+			sel.SelectionSet = canonicalSelectionSet(sel.SelectionSet)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b Selection) int {
+		return cmp.Compare(selectionKey(a), selectionKey(b))
+	})
+	return out
+}
+
+// mergeKey returns the key of selections that merge into one, which is
+// the selection without its selection set.
+func mergeKey(sel Selection) string {
+	var p printer
+	switch sel := sel.(type) {
+	case *Field:
+		p.selection(&Field{Alias: sel.ResponseKey(), Name: sel.Name, Arguments: canonicalArguments(sel.Arguments), Directives: sel.Directives})
+	case *InlineFragment:
+		p.selection(&InlineFragment{TypeCondition: sel.TypeCondition, Directives: sel.Directives})
+	case *FragmentSpread:
+		p.selection(sel)
+	}
+	return p.String()
+}
+
+// selectionKey returns the key by which canonical selections are sorted,
+// which doesn't depend on the names of variables.
+func selectionKey(sel Selection) string {
+	p := printer{anonymous: true}
+	p.selection(sel)
+	return p.String()
+}
+
+func canonicalArguments(args []*Argument) []*Argument {
+	if len(args) == 0 {
+		return nil
+	}
+	out := make([]*Argument, len(args))
+	for i, arg := range args {
+		out[i] = &Argument{Name: arg.Name, Value: canonicalValue(arg.Value)}
+	}
+	slices.SortStableFunc(out, func(a, b *Argument) int { return cmp.Compare(a.Name, b.Name) })
+	return out
+}
+
+// canonicalDirectives returns a copy of dirs with their arguments sorted.
+// The order of directives is kept, since it may be significant.
+func canonicalDirectives(dirs []*Directive) []*Directive {
+	if len(dirs) == 0 {
+		return nil
+	}
+	out := make([]*Directive, len(dirs))
+	for i, d := range dirs {
+		out[i] = &Directive{Name: d.Name, Arguments: canonicalArguments(d.Arguments)}
+	}
+	return out
+}
+
+func canonicalValue(v Value) Value {
+	switch v := v.(type) {
+	case *Variable:
+		return &Variable{Name: v.Name}
+	case *ListValue:
+		list := &ListValue{Values: make([]Value, len(v.Values))}
+		for i, elem := range v.Values {
+			list.Values[i] = canonicalValue(elem)
+		}
+		return list
+	case *ObjectValue:
+		obj := &ObjectValue{Fields: make([]*ObjectField, len(v.Fields))}
+		for i, f := range v.Fields {
+			obj.Fields[i] = &ObjectField{Name: f.Name, Value: canonicalValue(f.Value)}
+		}
+		slices.SortStableFunc(obj.Fields, func(a, b *ObjectField) int { return cmp.Compare(a.Name, b.Name) })
+		return obj
+	}
+	return v
+}
+
+// renameVariables renames the variables of the canonical document d $v1,
+// $v2, etc., in the order they're first used, following fragment spreads,
+// and sorts the variable definitions of its operations by their new names.
+// Unused variables come last, in the order they're defined.
+func renameVariables(d *Document) {
+	order := make(map[string]int) // Of old names.
+	use := func(name string) {
+		if _, ok := order[name]; !ok {
+			order[name] = len(order) + 1
+		}
+	}
+	visited := make(map[string]bool)
+	var spread func(s *FragmentSpread)
+	spread = func(s *FragmentSpread) {
+		if f := d.Fragment(s.Name); f != nil && !visited[s.Name] {
+			visited[s.Name] = true
+			walkVariables(f.Directives, f.SelectionSet, func(v *Variable) { use(v.Name) }, spread)
+		}
+	}
+	ops := d.Operations()
+	for _, op := range ops {
+		walkVariables(op.Directives, op.SelectionSet, func(v *Variable) { use(v.Name) }, spread)
+	}
+	for _, op := range ops {
+		for _, v := range op.VariableDefinitions {
+			use(v.Variable)
+		}
+	}
+
+	name := func(old string) string { return "v" + strconv.Itoa(order[old]) }
+	rename := func(v *Variable) { v.Name = name(v.Name) }
+	for _, def := range d.Definitions {
+		switch def := def.(type) {
+		case *OperationDefinition:
+			slices.SortStableFunc(def.VariableDefinitions, func(a, b *VariableDefinition) int {
+				return cmp.Compare(order[a.Variable], order[b.Variable])
+			})
+			for i, v := range def.VariableDefinitions {
+				c := *v
+				c.Variable = name(v.Variable)
+				def.VariableDefinitions[i] = &c
+			}
+			walkVariables(def.Directives, def.SelectionSet, rename, nil)
+		case *FragmentDefinition:
+			walkVariables(def.Directives, def.SelectionSet, rename, nil)
+		}
+	}
+}
+
+// walkVariables calls f with the variables used in dirs and set, in order,
+// and spread, if non-nil, with the fragment spreads in set where they are.
+func walkVariables(dirs []*Directive, set SelectionSet, f func(v *Variable), spread func(s *FragmentSpread)) {
+	var value func(v Value)
+	value = func(v Value) {
+		switch v := v.(type) {
+		case *Variable:
+			f(v)
+		case *ListValue:
+			for _, elem := range v.Values {
+				value(elem)
+			}
+		case *ObjectValue:
+			for _, field := range v.Fields {
+				value(field.Value)
+			}
+		}
+	}
+	arguments := func(args []*Argument) {
+		for _, arg := range args {
+			value(arg.Value)
+		}
+	}
+	for _, d := range dirs {
+		arguments(d.Arguments)
+	}
+	for _, sel := range set {
+		switch sel := sel.(type) {
+		case *Field:
+			arguments(sel.Arguments)
+			walkVariables(sel.Directives, sel.SelectionSet, f, spread)
+		case *InlineFragment:
+			walkVariables(sel.Directives, sel.SelectionSet, f, spread)
+		case *FragmentSpread:
+			walkVariables(sel.Directives, nil, f, spread)
+			if spread != nil {
+				spread(sel)
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/isihu/graphql/ast"
+)
+
+func TestEquivalent(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{
+			a:    `{viewer{login,id}}`,
+			b:    "query {\n  viewer {\n    id\n    login\n  }\n}",
+			want: true,
+		},
+		{
+			a:    `query($owner:String!$name:String!){repository(owner:$owner,name:$name){name}}`,
+			b:    `query($n:String!,$o:String!){repository(name:$n,owner:$o){name}}`,
+			want: true,
+		},
+		{
+			// The types of the variables are swapped.
+			a:    `query($a:Int$b:String){user(id:$a,login:$b){name}}`,
+			b:    `query($a:String$b:Int){user(id:$a,login:$b){name}}`,
+			want: false,
+		},
+		{
+			a:    `{viewer{login},viewer{id},... on Query{rateLimit{cost}},... on Query{rateLimit{remaining}}}`,
+			b:    `{rateLimit @skip(if:false){remaining,cost},viewer{id,login}}`,
+			want: false, // The directive isn't ignored.
+		},
+		{
+			a:    `{viewer{login},viewer{id},...on Query{rateLimit{cost}},...on Query{rateLimit{remaining}}}`,
+			b:    `{...on Query{rateLimit{remaining,cost}},viewer{id,login}}`,
+			want: true,
+		},
+		{
+			a:    `{a:user(id:1){name},b:user(id:2){name}}`,
+			b:    `{b:user(id:1){name},a:user(id:2){name}}`,
+			want: false,
+		},
+		{
+			a:    `query Q($f:Filter){search(filter:{states:[OPEN],query:$f}){...F}} fragment F on Result{id,title}`,
+			b:    `fragment F on Result{title,id} query Q($x:Filter){search(filter:{query:$x,states:[OPEN]}){...F}}`,
+			want: true,
+		},
+		{
+			a:    `query Q{viewer{login}}`,
+			b:    `query R{viewer{login}}`,
+			want: false,
+		},
+	}
+	for _, tc := range tests {
+		got, err := ast.Equivalent(tc.a, tc.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("Equivalent(%q, %q): got %v, want: %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	doc, err := ast.Parse(`query($unused:Int,$b:ID!,$a:ID!){y:node(id:$b){id},x:node(id:$a){id},node(id:$a){...on User{login}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ast.Canonical(doc).String()
+	want := `query($v1:ID!$v2:ID!$v3:Int){node(id:$v1){...on User{login}},x:node(id:$v1){id},y:node(id:$v2){id}}`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,343 @@
+package ast
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tokenKind is the kind of a lexical token.
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+	tokenBlockString
+)
+
+// token is a lexical token.
+type token struct {
+	kind  tokenKind
+	value string // Punctuator, name, raw number, or processed string value.
+	line  int    // 1-based.
+	col   int    // 1-based, in runes.
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "<EOF>"
+	case tokenString, tokenBlockString:
+		return strconv.Quote(t.value)
+	default:
+		return fmt.Sprintf("%q", t.value)
+	}
+}
+
+// SyntaxError is an error in the syntax of a GraphQL document.
+type SyntaxError struct {
+	Message string
+	Line    int // 1-based line of the error.
+	Column  int // 1-based column of the error.
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("graphql: syntax error at %d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// lexer splits a GraphQL document into tokens.
+type lexer struct {
+	src  string
+	pos  int // Byte offset into src.
+	line int
+	col  int
+}
+
+func newLexer(src string) *lexer {
+	src = strings.TrimPrefix(src, "\uFEFF") // Byte order mark is ignored.
+	return &lexer{src: src, line: 1, col: 1}
+}
+
+func (l *lexer) errorf(line, col int, format string, args ...any) error {
+	return &SyntaxError{Message: fmt.Sprintf(format, args...), Line: line, Column: col}
+}
+
+// advance consumes n bytes of src, which must not span a line terminator.
+func (l *lexer) advance(n int) {
+	l.col += utf8.RuneCountInString(l.src[l.pos : l.pos+n])
+	l.pos += n
+}
+
+// newline consumes a line terminator of n bytes.
+func (l *lexer) newline(n int) {
+	l.pos += n
+	l.line++
+	l.col = 1
+}
+
+// skipIgnored skips whitespace, line terminators, commas and comments.
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; c {
+		case ' ', '\t', ',':
+			l.advance(1)
+		case '\n':
+			l.newline(1)
+		case '\r':
+			if strings.HasPrefix(l.src[l.pos:], "\r\n") {
+				l.newline(2)
+			} else {
+				l.newline(1)
+			}
+		case '#':
+			end := strings.IndexAny(l.src[l.pos:], "\r\n")
+			if end == -1 {
+				end = len(l.src) - l.pos
+			}
+			l.advance(end)
+		default:
+			return
+		}
+	}
+}
+
+// next returns the next token.
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+	line, col := l.line, l.col
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, line: line, col: col}, nil
+	}
+	c := l.src[l.pos]
+	switch {
+	case strings.IndexByte("!$&():=@[]{}|", c) != -1:
+		l.advance(1)
+		return token{kind: tokenPunct, value: string(c), line: line, col: col}, nil
+	case c == '.':
+		if !strings.HasPrefix(l.src[l.pos:], "...") {
+			return token{}, l.errorf(line, col, "unexpected %q", c)
+		}
+		l.advance(3)
+		return token{kind: tokenPunct, value: "...", line: line, col: col}, nil
+	case isNameStart(c):
+		end := l.pos + 1
+		for end < len(l.src) && isNameContinue(l.src[end]) {
+			end++
+		}
+		name := l.src[l.pos:end]
+		l.advance(end - l.pos)
+		return token{kind: tokenName, value: name, line: line, col: col}, nil
+	case c == '-' || isDigit(c):
+		return l.number(line, col)
+	case c == '"':
+		if strings.HasPrefix(l.src[l.pos:], `"""`) {
+			return l.blockString(line, col)
+		}
+		return l.string(line, col)
+	default:
+		r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+		return token{}, l.errorf(line, col, "unexpected character %q", r)
+	}
+}
+
+// number lexes an IntValue or FloatValue.
+func (l *lexer) number(line, col int) (token, error) {
+	i := l.pos
+	if l.src[i] == '-' {
+		i++
+	}
+	digits := func() int {
+		start := i
+		for i < len(l.src) && isDigit(l.src[i]) {
+			i++
+		}
+		return i - start
+	}
+	start := i
+	if n := digits(); n == 0 {
+		return token{}, l.errorf(line, col, "invalid number, expected digit")
+	} else if n > 1 && l.src[start] == '0' {
+		return token{}, l.errorf(line, col, "invalid number, unexpected digit after 0")
+	}
+	kind := tokenInt
+	if i < len(l.src) && l.src[i] == '.' {
+		kind = tokenFloat
+		i++
+		if digits() == 0 {
+			return token{}, l.errorf(line, col, "invalid number, expected digit after '.'")
+		}
+	}
+	if i < len(l.src) && (l.src[i] == 'e' || l.src[i] == 'E') {
+		kind = tokenFloat
+		i++
+		if i < len(l.src) && (l.src[i] == '+' || l.src[i] == '-') {
+			i++
+		}
+		if digits() == 0 {
+			return token{}, l.errorf(line, col, "invalid number, expected digit in exponent")
+		}
+	}
+	if i < len(l.src) && (l.src[i] == '.' || isNameStart(l.src[i])) {
+		return token{}, l.errorf(line, col, "invalid number, unexpected %q", l.src[i])
+	}
+	raw := l.src[l.pos:i]
+	l.advance(i - l.pos)
+	return token{kind: kind, value: raw, line: line, col: col}, nil
+}
+
+// string lexes a quoted StringValue, processing its escape sequences.
+func (l *lexer) string(line, col int) (token, error) {
+	var sb strings.Builder
+	i := l.pos + 1
+	for {
+		if i >= len(l.src) || l.src[i] == '\n' || l.src[i] == '\r' {
+			return token{}, l.errorf(line, col, "unterminated string")
+		}
+		switch c := l.src[i]; c {
+		case '"':
+			l.advance(i + 1 - l.pos)
+			return token{kind: tokenString, value: sb.String(), line: line, col: col}, nil
+		case '\\':
+			if i+1 >= len(l.src) {
+				return token{}, l.errorf(line, col, "unterminated string")
+			}
+			switch e := l.src[i+1]; e {
+			case '"', '\\', '/':
+				sb.WriteByte(e)
+			case 'b':
+				sb.WriteByte('\b')
+			case 'f':
+				sb.WriteByte('\f')
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'u':
+				r, n, ok := unescapeUnicode(l.src[i+2:])
+				if !ok {
+					return token{}, l.errorf(line, col, "invalid unicode escape sequence in string")
+				}
+				sb.WriteRune(r)
+				i += n
+			default:
+				return token{}, l.errorf(line, col, "invalid escape sequence \\%c in string", e)
+			}
+			i += 2
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+}
+
+// unescapeUnicode decodes the hex digits following "\u" at the start of s,
+// either as XXXX (including surrogate pairs) or as {X...}.
+// It returns the rune and the number of bytes of s consumed.
+func unescapeUnicode(s string) (r rune, n int, ok bool) {
+	if strings.HasPrefix(s, "{") {
+		end := strings.IndexByte(s, '}')
+		if end < 2 {
+			return 0, 0, false
+		}
+		v, err := strconv.ParseUint(s[1:end], 16, 32)
+		if err != nil || v > utf8.MaxRune {
+			return 0, 0, false
+		}
+		return rune(v), end + 1, true
+	}
+	if len(s) < 4 {
+		return 0, 0, false
+	}
+	v, err := strconv.ParseUint(s[:4], 16, 16)
+	if err != nil {
+		return 0, 0, false
+	}
+	r = rune(v)
+	if r >= 0xD800 && r < 0xDC00 && len(s) >= 10 && s[4:6] == `\u` {
+		// Leading surrogate, followed by a trailing one.
+		lo, err := strconv.ParseUint(s[6:10], 16, 16)
+		if err == nil && lo >= 0xDC00 && lo < 0xE000 {
+			return (r-0xD800)<<10 + (rune(lo) - 0xDC00) + 0x10000, 10, true
+		}
+	}
+	return r, 4, true
+}
+
+// blockString lexes a block StringValue, removing its common indentation.
+func (l *lexer) blockString(line, col int) (token, error) {
+	var raw strings.Builder
+	l.advance(3)
+	for {
+		if l.pos >= len(l.src) {
+			return token{}, l.errorf(line, col, "unterminated block string")
+		}
+		switch {
+		case strings.HasPrefix(l.src[l.pos:], `"""`):
+			l.advance(3)
+			return token{kind: tokenBlockString, value: blockStringValue(raw.String()), line: line, col: col}, nil
+		case strings.HasPrefix(l.src[l.pos:], `\"""`):
+			raw.WriteString(`"""`)
+			l.advance(4)
+		case strings.HasPrefix(l.src[l.pos:], "\r\n"):
+			raw.WriteByte('\n')
+			l.newline(2)
+		case l.src[l.pos] == '\n' || l.src[l.pos] == '\r':
+			raw.WriteByte('\n')
+			l.newline(1)
+		default:
+			raw.WriteByte(l.src[l.pos])
+			l.pos++
+			if utf8.RuneStart(l.src[l.pos-1]) {
+				l.col++
+			}
+		}
+	}
+}
+
+// blockStringValue implements the BlockStringValue algorithm of the specification,
+// given raw with line terminators normalized to "\n".
+func blockStringValue(raw string) string {
+	lines := strings.Split(raw, "\n")
+	common := -1
+	for _, line := range lines[1:] {
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < len(line) && (common == -1 || indent < common) {
+			common = indent
+		}
+	}
+	if common > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= common {
+				lines[i] = lines[i][common:]
+			} else {
+				lines[i] = ""
+			}
+		}
+	}
+	for len(lines) > 0 && strings.Trim(lines[0], " \t") == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.Trim(lines[len(lines)-1], " \t") == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isNameContinue(c byte) bool {
+	return isNameStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,487 @@
+package ast
+
+// Parse parses src as a GraphQL executable document.
+// The returned error, if any, is a *SyntaxError.
+func Parse(src string) (*Document, error) {
+	p := &parser{lex: newLexer(src)}
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+	return p.document()
+}
+
+// parser is a recursive descent parser for GraphQL documents.
+// It keeps a single token of lookahead.
+type parser struct {
+	lex *lexer
+	tok token // Current token.
+}
+
+// advance moves to the next token.
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return p.lex.errorf(p.tok.line, p.tok.col, format, args...)
+}
+
+// peek reports whether the current token is the punctuator punct.
+func (p *parser) peek(punct string) bool {
+	return p.tok.kind == tokenPunct && p.tok.value == punct
+}
+
+// peekName reports whether the current token is the name name.
+func (p *parser) peekName(name string) bool {
+	return p.tok.kind == tokenName && p.tok.value == name
+}
+
+// skip consumes the current token if it's the punctuator punct,
+// and reports whether it did.
+func (p *parser) skip(punct string) (bool, error) {
+	if !p.peek(punct) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+// expect consumes the current token, which must be the punctuator punct.
+func (p *parser) expect(punct string) error {
+	if !p.peek(punct) {
+		return p.errorf("expected %q, found %v", punct, p.tok)
+	}
+	return p.advance()
+}
+
+// name consumes the current token, which must be a name, and returns it.
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", p.errorf("expected name, found %v", p.tok)
+	}
+	name := p.tok.value
+	return name, p.advance()
+}
+
+func (p *parser) document() (*Document, error) {
+	doc := new(Document)
+	for p.tok.kind != tokenEOF {
+		def, err := p.definition()
+		if err != nil {
+			return nil, err
+		}
+		doc.Definitions = append(doc.Definitions, def)
+	}
+	if len(doc.Definitions) == 0 {
+		return nil, p.errorf("expected definition, found %v", p.tok)
+	}
+	return doc, nil
+}
+
+func (p *parser) definition() (Definition, error) {
+	switch {
+	case p.peek("{"):
+		set, err := p.selectionSet()
+		if err != nil {
+			return nil, err
+		}
+		return &OperationDefinition{Operation: "query", SelectionSet: set}, nil
+	case p.peekName("query"), p.peekName("mutation"), p.peekName("subscription"):
+		return p.operationDefinition()
+	case p.peekName("fragment"):
+		return p.fragmentDefinition()
+	default:
+		return nil, p.errorf("expected definition, found %v", p.tok)
+	}
+}
+
+func (p *parser) operationDefinition() (*OperationDefinition, error) {
+	op := &OperationDefinition{Operation: p.tok.value}
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokenName {
+		op.Name = p.tok.value
+		err = p.advance()
+		if err != nil {
+			return nil, err
+		}
+	}
+	op.VariableDefinitions, err = p.variableDefinitions()
+	if err != nil {
+		return nil, err
+	}
+	op.Directives, err = p.directives(false)
+	if err != nil {
+		return nil, err
+	}
+	op.SelectionSet, err = p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func (p *parser) fragmentDefinition() (*FragmentDefinition, error) {
+	err := p.advance() // "fragment".
+	if err != nil {
+		return nil, err
+	}
+	f := new(FragmentDefinition)
+	if p.peekName("on") {
+		return nil, p.errorf("unexpected %v, expected fragment name", p.tok)
+	}
+	f.Name, err = p.name()
+	if err != nil {
+		return nil, err
+	}
+	if !p.peekName("on") {
+		return nil, p.errorf(`expected "on", found %v`, p.tok)
+	}
+	err = p.advance()
+	if err != nil {
+		return nil, err
+	}
+	f.TypeCondition, err = p.name()
+	if err != nil {
+		return nil, err
+	}
+	f.Directives, err = p.directives(false)
+	if err != nil {
+		return nil, err
+	}
+	f.SelectionSet, err = p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (p *parser) variableDefinitions() ([]*VariableDefinition, error) {
+	if ok, err := p.skip("("); !ok || err != nil {
+		return nil, err
+	}
+	var defs []*VariableDefinition
+	for {
+		if p.peek(")") && len(defs) == 0 {
+			return nil, p.errorf("expected variable definition, found %v", p.tok)
+		}
+		if ok, err := p.skip(")"); ok || err != nil {
+			return defs, err
+		}
+		def, err := p.variableDefinition()
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+}
+
+func (p *parser) variableDefinition() (*VariableDefinition, error) {
+	err := p.expect("$")
+	if err != nil {
+		return nil, err
+	}
+	def := new(VariableDefinition)
+	def.Variable, err = p.name()
+	if err != nil {
+		return nil, err
+	}
+	err = p.expect(":")
+	if err != nil {
+		return nil, err
+	}
+	def.Type, err = p.typeRef()
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip("="); err != nil {
+		return nil, err
+	} else if ok {
+		def.DefaultValue, err = p.value(true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	def.Directives, err = p.directives(true)
+	if err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+func (p *parser) typeRef() (*Type, error) {
+	t := new(Type)
+	if ok, err := p.skip("["); err != nil {
+		return nil, err
+	} else if ok {
+		t.Elem, err = p.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		err = p.expect("]")
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		t.NamedType, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+	}
+	ok, err := p.skip("!")
+	if err != nil {
+		return nil, err
+	}
+	t.NonNull = ok
+	return t, nil
+}
+
+func (p *parser) selectionSet() (SelectionSet, error) {
+	err := p.expect("{")
+	if err != nil {
+		return nil, err
+	}
+	var set SelectionSet
+	for {
+		if p.peek("}") && len(set) == 0 {
+			return nil, p.errorf("expected selection, found %v", p.tok)
+		}
+		if ok, err := p.skip("}"); ok || err != nil {
+			return set, err
+		}
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, sel)
+	}
+}
+
+func (p *parser) selection() (Selection, error) {
+	if ok, err := p.skip("..."); err != nil {
+		return nil, err
+	} else if ok {
+		return p.fragment()
+	}
+	return p.field()
+}
+
+func (p *parser) field() (*Field, error) {
+	f := new(Field)
+	var err error
+	f.Name, err = p.name()
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		f.Alias = f.Name
+		f.Name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+	}
+	f.Arguments, err = p.arguments(false)
+	if err != nil {
+		return nil, err
+	}
+	f.Directives, err = p.directives(false)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		f.SelectionSet, err = p.selectionSet()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// fragment parses a fragment spread or inline fragment, after the "...".
+func (p *parser) fragment() (Selection, error) {
+	if p.tok.kind == tokenName && !p.peekName("on") {
+		spread := new(FragmentSpread)
+		var err error
+		spread.Name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+		spread.Directives, err = p.directives(false)
+		if err != nil {
+			return nil, err
+		}
+		return spread, nil
+	}
+	inline := new(InlineFragment)
+	if p.peekName("on") {
+		err := p.advance()
+		if err != nil {
+			return nil, err
+		}
+		inline.TypeCondition, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	inline.Directives, err = p.directives(false)
+	if err != nil {
+		return nil, err
+	}
+	inline.SelectionSet, err = p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return inline, nil
+}
+
+// arguments parses an optional argument list.
+// If isConst is true, variables aren't allowed in values.
+func (p *parser) arguments(isConst bool) ([]*Argument, error) {
+	if ok, err := p.skip("("); !ok || err != nil {
+		return nil, err
+	}
+	var args []*Argument
+	for {
+		if p.peek(")") && len(args) == 0 {
+			return nil, p.errorf("expected argument, found %v", p.tok)
+		}
+		if ok, err := p.skip(")"); ok || err != nil {
+			return args, err
+		}
+		arg := new(Argument)
+		var err error
+		arg.Name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+		err = p.expect(":")
+		if err != nil {
+			return nil, err
+		}
+		arg.Value, err = p.value(isConst)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+}
+
+// directives parses an optional list of directives.
+// If isConst is true, variables aren't allowed in argument values.
+func (p *parser) directives(isConst bool) ([]*Directive, error) {
+	var dirs []*Directive
+	for p.peek("@") {
+		err := p.advance()
+		if err != nil {
+			return nil, err
+		}
+		d := new(Directive)
+		d.Name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+		d.Arguments, err = p.arguments(isConst)
+		if err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, d)
+	}
+	return dirs, nil
+}
+
+// value parses an input value.
+// If isConst is true, variables aren't allowed.
+func (p *parser) value(isConst bool) (Value, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokenInt:
+		return &IntValue{Raw: tok.value}, p.advance()
+	case tokenFloat:
+		return &FloatValue{Raw: tok.value}, p.advance()
+	case tokenString:
+		return &StringValue{Value: tok.value}, p.advance()
+	case tokenBlockString:
+		return &StringValue{Value: tok.value, Block: true}, p.advance()
+	case tokenName:
+		var v Value
+		switch tok.value {
+		case "true":
+			v = &BooleanValue{Value: true}
+		case "false":
+			v = &BooleanValue{Value: false}
+		case "null":
+			v = &NullValue{}
+		default:
+			v = &EnumValue{Name: tok.value}
+		}
+		return v, p.advance()
+	case tokenPunct:
+		switch tok.value {
+		case "$":
+			if isConst {
+				return nil, p.errorf("unexpected variable in constant value")
+			}
+			err := p.advance()
+			if err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			return &Variable{Name: name}, nil
+		case "[":
+			err := p.advance()
+			if err != nil {
+				return nil, err
+			}
+			list := &ListValue{}
+			for {
+				if ok, err := p.skip("]"); ok || err != nil {
+					return list, err
+				}
+				v, err := p.value(isConst)
+				if err != nil {
+					return nil, err
+				}
+				list.Values = append(list.Values, v)
+			}
+		case "{":
+			err := p.advance()
+			if err != nil {
+				return nil, err
+			}
+			obj := &ObjectValue{}
+			for {
+				if ok, err := p.skip("}"); ok || err != nil {
+					return obj, err
+				}
+				f := new(ObjectField)
+				f.Name, err = p.name()
+				if err != nil {
+					return nil, err
+				}
+				err = p.expect(":")
+				if err != nil {
+					return nil, err
+				}
+				f.Value, err = p.value(isConst)
+				if err != nil {
+					return nil, err
+				}
+				obj.Fields = append(obj.Fields, f)
+			}
+		}
+	}
+	return nil, p.errorf("expected value, found %v", tok)
+}
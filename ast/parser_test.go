@@ -0,0 +1,102 @@
+package ast_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/isihu/graphql/ast"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // Printed document.
+	}{
+		{
+			in:   `{viewer{login,createdAt,id,databaseId},rateLimit{cost,limit,remaining,resetAt}}`,
+			want: `{viewer{login,createdAt,id,databaseId},rateLimit{cost,limit,remaining,resetAt}}`,
+		},
+		{
+			in:   `query($issueNumber:Int!$repositoryName:String!$repositoryOwner:String!){repository(owner: $repositoryOwner, name: $repositoryName){issue(number: $issueNumber){body}}}`,
+			want: `query($issueNumber:Int!$repositoryName:String!$repositoryOwner:String!){repository(owner:$repositoryOwner,name:$repositoryName){issue(number:$issueNumber){body}}}`,
+		},
+		{
+			in:   `mutation($input:AddReactionInput!){addReaction(input:$input){subject{reactionGroups{users{totalCount}}}}}`,
+			want: `mutation($input:AddReactionInput!){addReaction(input:$input){subject{reactionGroups{users{totalCount}}}}}`,
+		},
+		{
+			in:   `{actor{login,avatarUrl,url},createdAt,... on IssueComment{body},currentTitle}`,
+			want: `{actor{login,avatarUrl,url},createdAt,...on IssueComment{body},currentTitle}`,
+		},
+		{
+			in: `
+				# A query with everything.
+				query Everything($ids: [ID!]! = ["a", "b"], $first: Int = 10 @deprecated) @live {
+					n1: node(id: "MDEyOklzc3VlQ29tbWVudDE2OTQwNzk0Ng==") {
+						...NodeFields @include(if: true)
+						... @skip(if: false) { id }
+					}
+					search(query: """
+						multi
+						  line
+					""", filter: {state: OPEN, score: -1.5e3, tags: [], owner: null}) { count }
+				}
+
+				fragment NodeFields on Node { id, __typename }
+			`,
+			want: `query Everything($ids:[ID!]!=["a","b"]$first:Int=10@deprecated)@live{n1:node(id:"MDEyOklzc3VlQ29tbWVudDE2OTQwNzk0Ng=="){...NodeFields@include(if:true),...@skip(if:false){id}},search(query:"multi\n  line",filter:{state:OPEN,score:-1.5e3,tags:[],owner:null}){count}}` +
+				`fragment NodeFields on Node{id,__typename}`,
+		},
+		{
+			in:   `subscription OnEvent { event(filter: "tab\tquote\"unicodeé\u{1F600}") { id } }`,
+			want: `subscription OnEvent{event(filter:"tab\tquote\"unicodeé😀"){id}}`,
+		},
+	}
+	for _, tc := range tests {
+		doc, err := ast.Parse(tc.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.in, err)
+			continue
+		}
+		if got := doc.String(); got != tc.want {
+			t.Errorf("\ngot:  %s\nwant: %s", got, tc.want)
+		}
+		// Printed documents should parse back to the same tree.
+		again, err := ast.Parse(doc.String())
+		if err != nil {
+			t.Errorf("Parse(%q): %v", doc.String(), err)
+			continue
+		}
+		if !reflect.DeepEqual(again, doc) && !strings.Contains(tc.in, `"""`) {
+			t.Errorf("document %q doesn't round trip", tc.in)
+		}
+	}
+}
+
+func TestParse_error(t *testing.T) {
+	tests := []struct {
+		in   string
+		want ast.SyntaxError
+	}{
+		{in: ``, want: ast.SyntaxError{Message: "expected definition, found <EOF>", Line: 1, Column: 1}},
+		{in: `{user{name}`, want: ast.SyntaxError{Message: `expected name, found <EOF>`, Line: 1, Column: 12}},
+		{in: "{\n  user(id: $id) {}\n}", want: ast.SyntaxError{Message: `expected selection, found "}"`, Line: 2, Column: 18}},
+		{in: `{user(id: 01){name}}`, want: ast.SyntaxError{Message: "invalid number, unexpected digit after 0", Line: 1, Column: 11}},
+		{in: `{user(name: "unterminated){name}}`, want: ast.SyntaxError{Message: "unterminated string", Line: 1, Column: 13}},
+		{in: `query($a: Int = $b){a}`, want: ast.SyntaxError{Message: "unexpected variable in constant value", Line: 1, Column: 17}},
+		{in: `fragment on on User{a}`, want: ast.SyntaxError{Message: `unexpected "on", expected fragment name`, Line: 1, Column: 10}},
+	}
+	for _, tc := range tests {
+		_, err := ast.Parse(tc.in)
+		var se *ast.SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("Parse(%q): got error %v, want *ast.SyntaxError", tc.in, err)
+			continue
+		}
+		if *se != tc.want {
+			t.Errorf("Parse(%q):\ngot:  %+v\nwant: %+v", tc.in, *se, tc.want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// String returns d printed as a minified GraphQL document,
+// in the same style as documents derived from Go types.
+//
+// E.g., "query($id:ID!){node(id:$id){id,...on User{login}}}".
+func (d *Document) String() string {
+	var p printer
+	for _, def := range d.Definitions {
+		p.definition(def)
+	}
+	return p.String()
+}
+
+// Indent returns d printed as a GraphQL document with every selection
+// on its own line, indented by indent per level, such as for golden files.
+// indent must not be empty.
+//
+// E.g., "query($id:ID!) {\n\tnode(id:$id) {\n\t\tid\n\t}\n}" for "\t".
+func (d *Document) Indent(indent string) string {
+	p := printer{indent: indent}
+	for i, def := range d.Definitions {
+		if i != 0 {
+			p.WriteString("\n\n")
+		}
+		p.definition(def)
+	}
+	return p.String()
+}
+
+// printer prints syntax trees as minified GraphQL,
+// or with selections indented, if indent isn't empty.
+type printer struct {
+	strings.Builder
+	indent    string
+	depth     int  // Nesting level of selection sets, when indenting.
+	anonymous bool // Whether to print variables without their names, as "$".
+}
+
+func (p *printer) definition(def Definition) {
+	switch def := def.(type) {
+	case *OperationDefinition:
+		if def.Operation != "query" || def.Name != "" || len(def.VariableDefinitions) > 0 || len(def.Directives) > 0 {
+			p.WriteString(def.Operation)
+			if def.Name != "" {
+				p.WriteString(" ")
+				p.WriteString(def.Name)
+			}
+		}
+		if len(def.VariableDefinitions) > 0 {
+			p.WriteString("(")
+			for _, v := range def.VariableDefinitions {
+				p.variableDefinition(v)
+			}
+			p.WriteString(")")
+		}
+		p.directives(def.Directives)
+		p.selectionSet(def.SelectionSet)
+	case *FragmentDefinition:
+		p.WriteString("fragment ")
+		p.WriteString(def.Name)
+		p.WriteString(" on ")
+		p.WriteString(def.TypeCondition)
+		p.directives(def.Directives)
+		p.selectionSet(def.SelectionSet)
+	default:
+		panic(fmt.Errorf("ast: unexpected definition type %T", def))
+	}
+}
+
+func (p *printer) variableDefinition(v *VariableDefinition) {
+	// Don't insert a comma here, same as queryArguments in package graphql.
+	p.WriteString("$")
+	p.WriteString(v.Variable)
+	p.WriteString(":")
+	p.typeRef(v.Type)
+	if v.DefaultValue != nil {
+		p.WriteString("=")
+		p.value(v.DefaultValue)
+	}
+	p.directives(v.Directives)
+}
+
+func (p *printer) typeRef(t *Type) {
+	if t.Elem != nil {
+		p.WriteString("[")
+		p.typeRef(t.Elem)
+		p.WriteString("]")
+	} else {
+		p.WriteString(t.NamedType)
+	}
+	if t.NonNull {
+		p.WriteString("!")
+	}
+}
+
+func (p *printer) selectionSet(set SelectionSet) {
+	if len(set) == 0 {
+		return
+	}
+	if p.indent != "" {
+		p.indentedSelectionSet(set)
+		return
+	}
+	p.WriteString("{")
+	for i, sel := range set {
+		if i != 0 {
+			p.WriteString(",")
+		}
+		p.selection(sel)
+	}
+	p.WriteString("}")
+}
+
+func (p *printer) indentedSelectionSet(set SelectionSet) {
+	if p.Len() != 0 && !strings.HasSuffix(p.String(), "\n") {
+		p.WriteString(" ")
+	}
+	p.WriteString("{\n")
+	p.depth++
+	for _, sel := range set {
+		p.WriteString(strings.Repeat(p.indent, p.depth))
+		p.selection(sel)
+		p.WriteString("\n")
+	}
+	p.depth--
+	p.WriteString(strings.Repeat(p.indent, p.depth))
+	p.WriteString("}")
+}
+
+func (p *printer) selection(sel Selection) {
+	switch sel := sel.(type) {
+	case *Field:
+		if sel.Alias != "" {
+			p.WriteString(sel.Alias)
+			p.WriteString(":")
+		}
+		p.WriteString(sel.Name)
+		p.arguments(sel.Arguments)
+		p.directives(sel.Directives)
+		p.selectionSet(sel.SelectionSet)
+	case *FragmentSpread:
+		p.WriteString("...")
+		p.WriteString(sel.Name)
+		p.directives(sel.Directives)
+	case *InlineFragment:
+		p.WriteString("...")
+		if sel.TypeCondition != "" {
+			p.WriteString("on ")
+			p.WriteString(sel.TypeCondition)
+		}
+		p.directives(sel.Directives)
+		p.selectionSet(sel.SelectionSet)
+	default:
+		panic(fmt.Errorf("ast: unexpected selection type %T", sel))
+	}
+}
+
+func (p *printer) arguments(args []*Argument) {
+	if len(args) == 0 {
+		return
+	}
+	p.WriteString("(")
+	for i, arg := range args {
+		if i != 0 {
+			p.WriteString(",")
+		}
+		p.WriteString(arg.Name)
+		p.WriteString(":")
+		p.value(arg.Value)
+	}
+	p.WriteString(")")
+}
+
+func (p *printer) directives(dirs []*Directive) {
+	for _, d := range dirs {
+		p.WriteString("@")
+		p.WriteString(d.Name)
+		p.arguments(d.Arguments)
+	}
+}
+
+func (p *printer) value(v Value) {
+	switch v := v.(type) {
+	case *Variable:
+		p.WriteString("$")
+		if !p.anonymous {
+			p.WriteString(v.Name)
+		}
+	case *IntValue:
+		p.WriteString(v.Raw)
+	case *FloatValue:
+		p.WriteString(v.Raw)
+	case *StringValue:
+		p.WriteString(quote(v.Value))
+	case *BooleanValue:
+		if v.Value {
+			p.WriteString("true")
+		} else {
+			p.WriteString("false")
+		}
+	case *NullValue:
+		p.WriteString("null")
+	case *EnumValue:
+		p.WriteString(v.Name)
+	case *ListValue:
+		p.WriteString("[")
+		for i, elem := range v.Values {
+			if i != 0 {
+				p.WriteString(",")
+			}
+			p.value(elem)
+		}
+		p.WriteString("]")
+	case *ObjectValue:
+		p.WriteString("{")
+		for i, f := range v.Fields {
+			if i != 0 {
+				p.WriteString(",")
+			}
+			p.WriteString(f.Name)
+			p.WriteString(":")
+			p.value(f.Value)
+		}
+		p.WriteString("}")
+	default:
+		panic(fmt.Errorf("ast: unexpected value type %T", v))
+	}
+}
+
+// quote returns s as a GraphQL string literal.
+func quote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&sb, `\u%04x`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
@@ -0,0 +1,45 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/isihu/graphql/ast"
+)
+
+func TestDocument_Indent(t *testing.T) {
+	doc, err := ast.Parse(`query($id:ID!){node(id:$id){id,...on User{login}}}fragment F on User{name}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `query($id:ID!) {
+	node(id:$id) {
+		id
+		...on User {
+			login
+		}
+	}
+}
+
+fragment F on User {
+	name
+}`
+	if got := doc.Indent("\t"); got != want {
+		t.Errorf("\ngot:\n%s\nwant:\n%s", got, want)
+	}
+	again, err := ast.Parse(doc.Indent("\t"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := again.String(), doc.String(); got != want {
+		t.Errorf("indented document doesn't round trip:\ngot:  %s\nwant: %s", got, want)
+	}
+
+	// An anonymous query is printed with its selection set only.
+	doc, err = ast.Parse(`{viewer{login}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := doc.Indent("  "), "{\n  viewer {\n    login\n  }\n}"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
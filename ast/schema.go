@@ -0,0 +1,646 @@
+package ast
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Schema is a GraphQL schema, defined by a type system document in the
+// GraphQL schema definition language (SDL), or by the result of an
+// introspection query.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-Type-System.
+type Schema struct {
+	// Names of the root operation types. Mutation and Subscription
+	// are empty if the schema doesn't support those operations.
+	Query, Mutation, Subscription string
+
+	// Types are the named types of the schema, by name,
+	// including the built-in scalars.
+	Types map[string]*TypeDefinition
+}
+
+// TypeKind is the kind of a named type.
+type TypeKind string
+
+// Kinds of named types, as in introspection results.
+const (
+	Scalar      TypeKind = "SCALAR"
+	Object      TypeKind = "OBJECT"
+	Interface   TypeKind = "INTERFACE"
+	Union       TypeKind = "UNION"
+	Enum        TypeKind = "ENUM"
+	InputObject TypeKind = "INPUT_OBJECT"
+)
+
+// TypeDefinition is a named type of a Schema.
+type TypeDefinition struct {
+	Kind        TypeKind
+	Name        string
+	Description string
+
+	Interfaces  []string           // Interfaces an object or interface implements.
+	Fields      []*FieldDefinition // Fields of an object or interface.
+	Members     []string           // Member types of a union.
+	EnumValues  []string           // Values of an enum.
+	InputFields []*InputValue      // Fields of an input object.
+}
+
+// Field returns the field of t named name, or nil if it doesn't have one.
+func (t *TypeDefinition) Field(name string) *FieldDefinition {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// FieldDefinition is a field of an object or interface type.
+type FieldDefinition struct {
+	Name        string
+	Description string
+	Arguments   []*InputValue
+	Type        *Type
+	Deprecated  bool
+}
+
+// InputValue is an argument of a field, or a field of an input object.
+type InputValue struct {
+	Name         string
+	Description  string
+	Type         *Type
+	DefaultValue Value // Nil if there's no default value.
+}
+
+// Type returns the named type name, or nil if s doesn't have one.
+func (s *Schema) Type(name string) *TypeDefinition {
+	return s.Types[name]
+}
+
+// RootType returns the root type of operations of kind operation, which is
+// "query", "mutation" or "subscription", or nil if s doesn't support them.
+func (s *Schema) RootType(operation string) *TypeDefinition {
+	var name string
+	switch operation {
+	case "query":
+		name = s.Query
+	case "mutation":
+		name = s.Mutation
+	case "subscription":
+		name = s.Subscription
+	}
+	if name == "" {
+		return nil
+	}
+	return s.Types[name]
+}
+
+// PossibleTypes returns the names of the object types that the named type
+// name may be at run time, sorted: the members of a union, the objects
+// implementing an interface, or the type itself for an object.
+func (s *Schema) PossibleTypes(name string) []string {
+	t := s.Types[name]
+	if t == nil {
+		return nil
+	}
+	switch t.Kind {
+	case Object:
+		return []string{name}
+	case Union:
+		return slices.Sorted(slices.Values(t.Members))
+	case Interface:
+		var names []string
+		for _, o := range s.Types {
+			if o.Kind == Object && slices.Contains(o.Interfaces, name) {
+				names = append(names, o.Name)
+			}
+		}
+		slices.Sort(names)
+		return names
+	}
+	return nil
+}
+
+// builtinScalars are the scalar types every schema has.
+var builtinScalars = []string{"Int", "Float", "String", "Boolean", "ID"}
+
+func newSchema() *Schema {
+	s := &Schema{Types: make(map[string]*TypeDefinition)}
+	for _, name := range builtinScalars {
+		s.Types[name] = &TypeDefinition{Kind: Scalar, Name: name}
+	}
+	return s
+}
+
+// ParseSchema parses src as a GraphQL type system document, such as a
+// schema.graphql file. Directive definitions and applied directives, other
+// than @deprecated on fields, are ignored. Type extensions are merged into
+// the types they extend. Without a schema definition, the root operation
+// types are those named Query, Mutation and Subscription.
+// Syntax errors are *SyntaxError.
+func ParseSchema(src string) (*Schema, error) {
+	p := &parser{lex: newLexer(src)}
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+	s := newSchema()
+	var extensions []*TypeDefinition
+	hasSchemaDef := false
+	for p.tok.kind != tokenEOF {
+		description, err := p.description()
+		if err != nil {
+			return nil, err
+		}
+		extend := p.peekName("extend")
+		if extend {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		}
+		if p.peekName("schema") {
+			if err := p.schemaDefinition(s); err != nil {
+				return nil, err
+			}
+			hasSchemaDef = hasSchemaDef || !extend
+			continue
+		}
+		if p.peekName("directive") {
+			if err := p.directiveDefinition(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		line, col := p.tok.line, p.tok.col
+		t, err := p.typeDefinition()
+		if err != nil {
+			return nil, err
+		}
+		t.Description = description
+		if extend {
+			extensions = append(extensions, t)
+			continue
+		}
+		if s.Types[t.Name] != nil && !slices.Contains(builtinScalars, t.Name) {
+			return nil, p.lex.errorf(line, col, "type %s is defined more than once", t.Name)
+		}
+		s.Types[t.Name] = t
+	}
+	for _, ext := range extensions {
+		t := s.Types[ext.Name]
+		if t == nil {
+			return nil, fmt.Errorf("graphql: extension of undefined type %s", ext.Name)
+		}
+		if t.Kind != ext.Kind {
+			return nil, fmt.Errorf("graphql: extension of %s type %s as %s", t.Kind, t.Name, ext.Kind)
+		}
+		t.Interfaces = append(t.Interfaces, ext.Interfaces...)
+		t.Fields = append(t.Fields, ext.Fields...)
+		t.Members = append(t.Members, ext.Members...)
+		t.EnumValues = append(t.EnumValues, ext.EnumValues...)
+		t.InputFields = append(t.InputFields, ext.InputFields...)
+	}
+	if !hasSchemaDef {
+		for op, name := range map[*string]string{&s.Query: "Query", &s.Mutation: "Mutation", &s.Subscription: "Subscription"} {
+			if t := s.Types[name]; *op == "" && t != nil && t.Kind == Object {
+				*op = name
+			}
+		}
+	}
+	if s.Query == "" {
+		return nil, errors.New("graphql: schema has no query type")
+	}
+	return s, nil
+}
+
+// description parses an optional description.
+func (p *parser) description() (string, error) {
+	if p.tok.kind != tokenString && p.tok.kind != tokenBlockString {
+		return "", nil
+	}
+	s := p.tok.value
+	return s, p.advance()
+}
+
+func (p *parser) schemaDefinition(s *Schema) error {
+	err := p.advance() // "schema".
+	if err != nil {
+		return err
+	}
+	if _, err := p.directives(true); err != nil {
+		return err
+	}
+	if ok, err := p.skip("{"); !ok || err != nil {
+		return err // A schema extension may only have directives.
+	}
+	for {
+		if ok, err := p.skip("}"); ok || err != nil {
+			return err
+		}
+		op := p.tok
+		if _, err := p.name(); err != nil {
+			return err
+		}
+		if err := p.expect(":"); err != nil {
+			return err
+		}
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		switch op.value {
+		case "query":
+			s.Query = name
+		case "mutation":
+			s.Mutation = name
+		case "subscription":
+			s.Subscription = name
+		default:
+			return p.lex.errorf(op.line, op.col, "unknown operation type %v", op)
+		}
+	}
+}
+
+// directiveDefinition parses and discards a directive definition.
+func (p *parser) directiveDefinition() error {
+	err := p.advance() // "directive".
+	if err != nil {
+		return err
+	}
+	if err := p.expect("@"); err != nil {
+		return err
+	}
+	if _, err := p.name(); err != nil {
+		return err
+	}
+	if _, err := p.inputValues("(", ")"); err != nil {
+		return err
+	}
+	if p.peekName("repeatable") {
+		if err := p.advance(); err != nil {
+			return err
+		}
+	}
+	if !p.peekName("on") {
+		return p.errorf(`expected "on", found %v`, p.tok)
+	}
+	if err := p.advance(); err != nil {
+		return err
+	}
+	_, err = p.nameList("|")
+	return err
+}
+
+func (p *parser) typeDefinition() (*TypeDefinition, error) {
+	kinds := map[string]TypeKind{
+		"scalar":    Scalar,
+		"type":      Object,
+		"interface": Interface,
+		"union":     Union,
+		"enum":      Enum,
+		"input":     InputObject,
+	}
+	kind, ok := kinds[p.tok.value]
+	if p.tok.kind != tokenName || !ok {
+		return nil, p.errorf("expected type definition, found %v", p.tok)
+	}
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+	t := &TypeDefinition{Kind: kind}
+	t.Name, err = p.name()
+	if err != nil {
+		return nil, err
+	}
+	if (kind == Object || kind == Interface) && p.peekName("implements") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		t.Interfaces, err = p.nameList("&")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.directives(true); err != nil {
+		return nil, err
+	}
+	switch kind {
+	case Object, Interface:
+		t.Fields, err = p.fieldDefinitions()
+	case Union:
+		if ok, err := p.skip("="); !ok || err != nil {
+			return t, err
+		}
+		t.Members, err = p.nameList("|")
+	case Enum:
+		t.EnumValues, err = p.enumValues()
+	case InputObject:
+		t.InputFields, err = p.inputValues("{", "}")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// nameList parses a list of names separated by sep,
+// which may also precede the first one.
+func (p *parser) nameList(sep string) ([]string, error) {
+	if _, err := p.skip(sep); err != nil {
+		return nil, err
+	}
+	var names []string
+	for {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		if ok, err := p.skip(sep); !ok || err != nil {
+			return names, err
+		}
+	}
+}
+
+// fieldDefinitions parses an optional list of field definitions.
+func (p *parser) fieldDefinitions() ([]*FieldDefinition, error) {
+	if ok, err := p.skip("{"); !ok || err != nil {
+		return nil, err
+	}
+	var fields []*FieldDefinition
+	for {
+		if ok, err := p.skip("}"); ok || err != nil {
+			return fields, err
+		}
+		f := new(FieldDefinition)
+		var err error
+		f.Description, err = p.description()
+		if err != nil {
+			return nil, err
+		}
+		f.Name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+		f.Arguments, err = p.inputValues("(", ")")
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		f.Type, err = p.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		dirs, err := p.directives(true)
+		if err != nil {
+			return nil, err
+		}
+		f.Deprecated = slices.ContainsFunc(dirs, func(d *Directive) bool { return d.Name == "deprecated" })
+		fields = append(fields, f)
+	}
+}
+
+// inputValues parses an optional list of argument or input field
+// definitions, enclosed in open and close.
+func (p *parser) inputValues(open, close string) ([]*InputValue, error) {
+	if ok, err := p.skip(open); !ok || err != nil {
+		return nil, err
+	}
+	var values []*InputValue
+	for {
+		if ok, err := p.skip(close); ok || err != nil {
+			return values, err
+		}
+		v := new(InputValue)
+		var err error
+		v.Description, err = p.description()
+		if err != nil {
+			return nil, err
+		}
+		v.Name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		v.Type, err = p.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		if ok, err := p.skip("="); err != nil {
+			return nil, err
+		} else if ok {
+			v.DefaultValue, err = p.value(true)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if _, err := p.directives(true); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+}
+
+// enumValues parses an optional list of enum value definitions.
+func (p *parser) enumValues() ([]string, error) {
+	if ok, err := p.skip("{"); !ok || err != nil {
+		return nil, err
+	}
+	var values []string
+	for {
+		if ok, err := p.skip("}"); ok || err != nil {
+			return values, err
+		}
+		if _, err := p.description(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.directives(true); err != nil {
+			return nil, err
+		}
+		values = append(values, name)
+	}
+}
+
+// SchemaFromIntrospection returns the schema described by the JSON result
+// of an introspection query, such as the standard one of GraphQL tools.
+// It accepts the whole response, its data, or the __schema object.
+func SchemaFromIntrospection(result []byte) (*Schema, error) {
+	type typeRef struct {
+		Kind   string   `json:"kind"`
+		Name   string   `json:"name"`
+		OfType *typeRef `json:"ofType"`
+	}
+	type inputValue struct {
+		Name         string   `json:"name"`
+		Description  string   `json:"description"`
+		Type         *typeRef `json:"type"`
+		DefaultValue *string  `json:"defaultValue"`
+	}
+	type named struct {
+		Name string `json:"name"`
+	}
+	type introspection struct {
+		QueryType        *named `json:"queryType"`
+		MutationType     *named `json:"mutationType"`
+		SubscriptionType *named `json:"subscriptionType"`
+		Types            []struct {
+			Kind        TypeKind `json:"kind"`
+			Name        string   `json:"name"`
+			Description string   `json:"description"`
+			Fields      []struct {
+				Name              string       `json:"name"`
+				Description       string       `json:"description"`
+				Args              []inputValue `json:"args"`
+				Type              *typeRef     `json:"type"`
+				IsDeprecated      bool         `json:"isDeprecated"`
+				DeprecationReason *string      `json:"deprecationReason"`
+			} `json:"fields"`
+			InputFields   []inputValue `json:"inputFields"`
+			Interfaces    []named      `json:"interfaces"`
+			PossibleTypes []named      `json:"possibleTypes"`
+			EnumValues    []named      `json:"enumValues"`
+		} `json:"types"`
+	}
+	var in struct {
+		Data *struct {
+			Schema *introspection `json:"__schema"`
+		} `json:"data"`
+		Schema *introspection `json:"__schema"`
+	}
+	if err := json.Unmarshal(result, &in); err != nil {
+		return nil, fmt.Errorf("graphql: introspection result: %v", err)
+	}
+	is := in.Schema
+	if in.Data != nil {
+		is = in.Data.Schema
+	}
+	if is == nil {
+		var schema introspection
+		if err := json.Unmarshal(result, &schema); err != nil || schema.QueryType == nil {
+			return nil, errors.New("graphql: introspection result has no __schema")
+		}
+		is = &schema
+	}
+	if is.QueryType == nil {
+		return nil, errors.New("graphql: introspection result has no query type")
+	}
+
+	var convertType func(r *typeRef) (*Type, error)
+	convertType = func(r *typeRef) (*Type, error) {
+		if r == nil {
+			return nil, errors.New("graphql: introspection result has a missing type reference")
+		}
+		switch r.Kind {
+		case "NON_NULL":
+			t, err := convertType(r.OfType)
+			if err != nil {
+				return nil, err
+			}
+			t.NonNull = true
+			return t, nil
+		case "LIST":
+			elem, err := convertType(r.OfType)
+			if err != nil {
+				return nil, err
+			}
+			return &Type{Elem: elem}, nil
+		}
+		return &Type{NamedType: r.Name}, nil
+	}
+	convertValues := func(in []inputValue) ([]*InputValue, error) {
+		var out []*InputValue
+		for _, v := range in {
+			t, err := convertType(v.Type)
+			if err != nil {
+				return nil, err
+			}
+			iv := &InputValue{Name: v.Name, Description: v.Description, Type: t}
+			if v.DefaultValue != nil {
+				iv.DefaultValue, err = ParseValue(*v.DefaultValue)
+				if err != nil {
+					return nil, err
+				}
+			}
+			out = append(out, iv)
+		}
+		return out, nil
+	}
+
+	s := newSchema()
+	s.Query = is.QueryType.Name
+	if is.MutationType != nil {
+		s.Mutation = is.MutationType.Name
+	}
+	if is.SubscriptionType != nil {
+		s.Subscription = is.SubscriptionType.Name
+	}
+	for _, it := range is.Types {
+		t := &TypeDefinition{Kind: it.Kind, Name: it.Name, Description: it.Description}
+		for _, f := range it.Fields {
+			ft, err := convertType(f.Type)
+			if err != nil {
+				return nil, err
+			}
+			args, err := convertValues(f.Args)
+			if err != nil {
+				return nil, err
+			}
+			t.Fields = append(t.Fields, &FieldDefinition{
+				Name:        f.Name,
+				Description: f.Description,
+				Arguments:   args,
+				Type:        ft,
+				Deprecated:  f.IsDeprecated,
+			})
+		}
+		for _, i := range it.Interfaces {
+			t.Interfaces = append(t.Interfaces, i.Name)
+		}
+		if it.Kind == Union {
+			for _, m := range it.PossibleTypes {
+				t.Members = append(t.Members, m.Name)
+			}
+		}
+		for _, v := range it.EnumValues {
+			t.EnumValues = append(t.EnumValues, v.Name)
+		}
+		var err error
+		t.InputFields, err = convertValues(it.InputFields)
+		if err != nil {
+			return nil, err
+		}
+		s.Types[t.Name] = t
+	}
+	return s, nil
+}
+
+// ParseValue parses src as a constant GraphQL input value, such as the
+// default value of an argument in an introspection result.
+// The returned error, if any, is a *SyntaxError.
+func ParseValue(src string) (Value, error) {
+	p := &parser{lex: newLexer(src)}
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+	v, err := p.value(true)
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokenEOF {
+		return nil, p.errorf("unexpected %v after value", p.tok)
+	}
+	return v, nil
+}
@@ -0,0 +1,187 @@
+package ast_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/isihu/graphql/ast"
+)
+
+const testSchema = `
+"""
+The query root.
+"""
+type Query {
+	"Looks up a node by ID."
+	node(id: ID!): Node
+	search(query: String!, first: Int = 10): [SearchResult!]!
+}
+
+interface Node {
+	id: ID!
+}
+
+type User implements Node & Actor {
+	id: ID!
+	login: String!
+	name: String @deprecated(reason: "Use login.")
+}
+
+type Bot implements Actor {
+	login: String!
+}
+
+interface Actor {
+	login: String!
+}
+
+union SearchResult = | User | Repository
+
+type Repository implements Node {
+	id: ID!
+	state: State!
+}
+
+enum State {
+	OPEN
+	"Not open."
+	CLOSED @deprecated
+}
+
+input Filter {
+	states: [State!] = [OPEN]
+}
+
+scalar DateTime @specifiedBy(url: "https://tools.ietf.org/html/rfc3339")
+
+directive @auth(role: String) repeatable on FIELD_DEFINITION | OBJECT
+
+extend type Repository {
+	createdAt: DateTime!
+}
+
+extend union SearchResult = Bot
+`
+
+func TestParseSchema(t *testing.T) {
+	s, err := ast.ParseSchema(testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := [3]string{s.Query, s.Mutation, s.Subscription}, [3]string{"Query", "", ""}; got != want {
+		t.Errorf("got root types: %q, want: %q", got, want)
+	}
+	query := s.Type("Query")
+	if got, want := query.Description, "The query root."; got != want {
+		t.Errorf("got description: %q, want: %q", got, want)
+	}
+	search := query.Field("search")
+	if got, want := search.Type, (&ast.Type{Elem: &ast.Type{NamedType: "SearchResult", NonNull: true}, NonNull: true}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got type: %+v, want: %+v", got, want)
+	}
+	if got, want := search.Arguments[1].DefaultValue, (&ast.IntValue{Raw: "10"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got default value: %+v, want: %+v", got, want)
+	}
+	if !s.Type("User").Field("name").Deprecated {
+		t.Error("got User.name not deprecated, want deprecated")
+	}
+	if got, want := s.Type("Repository").Field("createdAt").Type.NamedType, "DateTime"; got != want {
+		t.Errorf("got extended field type: %q, want: %q", got, want)
+	}
+	if got, want := s.Type("State").EnumValues, []string{"OPEN", "CLOSED"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got enum values: %q, want: %q", got, want)
+	}
+	if got, want := s.Type("Filter").InputFields[0].Name, "states"; got != want {
+		t.Errorf("got input field: %q, want: %q", got, want)
+	}
+	for name, want := range map[string][]string{
+		"Node":         {"Repository", "User"},
+		"Actor":        {"Bot", "User"},
+		"SearchResult": {"Bot", "Repository", "User"},
+		"User":         {"User"},
+		"State":        nil,
+	} {
+		if got := s.PossibleTypes(name); !reflect.DeepEqual(got, want) {
+			t.Errorf("got possible types of %s: %q, want: %q", name, got, want)
+		}
+	}
+	if s.Type("String") == nil {
+		t.Error("got no String type, want built-in scalar")
+	}
+}
+
+func TestParseSchema_schemaDefinition(t *testing.T) {
+	s, err := ast.ParseSchema(`schema { query: Root mutation: Mutations } type Root { a: Int } type Mutations { b: Int } type Subscription { c: Int }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := [3]string{s.Query, s.Mutation, s.Subscription}, [3]string{"Root", "Mutations", ""}; got != want {
+		t.Errorf("got root types: %q, want: %q", got, want)
+	}
+}
+
+func TestParseSchema_error(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: `type Query { a: Int } type Query { b: Int }`, want: "graphql: syntax error at 1:23: type Query is defined more than once"},
+		{in: `type Query { a Int }`, want: `graphql: syntax error at 1:16: expected ":", found "Int"`},
+		{in: `query { a }`, want: `graphql: syntax error at 1:1: expected type definition, found "query"`},
+		{in: `type User { a: Int }`, want: "graphql: schema has no query type"},
+		{in: `type Query { a: Int } extend type User { b: Int }`, want: "graphql: extension of undefined type User"},
+	}
+	for _, tc := range tests {
+		_, err := ast.ParseSchema(tc.in)
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("ParseSchema(%q): got error: %v, want: %v", tc.in, err, tc.want)
+		}
+	}
+	var se *ast.SyntaxError
+	if _, err := ast.ParseSchema(tests[1].in); !errors.As(err, &se) {
+		t.Errorf("got error type: %T, want: *ast.SyntaxError", err)
+	}
+}
+
+func TestSchemaFromIntrospection(t *testing.T) {
+	result := `{"data": {"__schema": {
+		"queryType": {"name": "Query"},
+		"mutationType": null,
+		"subscriptionType": null,
+		"types": [
+			{"kind": "OBJECT", "name": "Query", "fields": [
+				{"name": "users", "args": [{"name": "first", "type": {"kind": "SCALAR", "name": "Int"}, "defaultValue": "10"}],
+				 "type": {"kind": "NON_NULL", "ofType": {"kind": "LIST", "ofType": {"kind": "OBJECT", "name": "User"}}}}
+			], "interfaces": []},
+			{"kind": "OBJECT", "name": "User", "fields": [
+				{"name": "id", "args": [], "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "ID"}}},
+				{"name": "name", "args": [], "type": {"kind": "SCALAR", "name": "String"}, "isDeprecated": true}
+			], "interfaces": [{"name": "Node"}]},
+			{"kind": "INTERFACE", "name": "Node", "fields": [
+				{"name": "id", "args": [], "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "ID"}}}
+			], "possibleTypes": [{"name": "User"}]}
+		]
+	}}}`
+	s, err := ast.SchemaFromIntrospection([]byte(result))
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := s.Type("Query").Field("users")
+	if got, want := users.Type, (&ast.Type{Elem: &ast.Type{NamedType: "User"}, NonNull: true}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got type: %+v, want: %+v", got, want)
+	}
+	if got, want := users.Arguments[0].DefaultValue, (&ast.IntValue{Raw: "10"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got default value: %+v, want: %+v", got, want)
+	}
+	if !s.Type("User").Field("name").Deprecated {
+		t.Error("got User.name not deprecated, want deprecated")
+	}
+	if got, want := s.PossibleTypes("Node"), []string{"User"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got possible types: %q, want: %q", got, want)
+	}
+
+	if _, err := ast.SchemaFromIntrospection([]byte(`{"data": {}}`)); err == nil {
+		t.Error("got no error for a result without __schema, want one")
+	}
+}
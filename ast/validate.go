@@ -0,0 +1,354 @@
+package ast
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ValidationError is an error in a document that makes it invalid against
+// a schema, as reported by Validate.
+type ValidationError struct {
+	Operation string // Name of the operation, or its kind if it's anonymous.
+	Path      string // Path of the selection, e.g., "repository.issues", or "" for the operation.
+	Message   string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("graphql: %s: %s", e.Operation, e.Message)
+	}
+	return fmt.Sprintf("graphql: %s: %s: %s", e.Operation, e.Path, e.Message)
+}
+
+// Validate validates the operations of doc against s, and returns the errors
+// that make them invalid, such as selections of fields that don't exist,
+// missing required arguments, and variables of the wrong types. It's for
+// catching operations that a schema change breaks. The errors are
+// *ValidationError. Not all validation rules of the specification are
+// checked, only those that depend on the schema, and those about variables.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-Validation.
+func Validate(s *Schema, doc *Document) []error {
+	v := &validator{schema: s, doc: doc}
+	for _, op := range doc.Operations() {
+		v.operation(op)
+	}
+	return v.errs
+}
+
+// validator is the state of validating a document.
+type validator struct {
+	schema *Schema
+	doc    *Document
+	errs   []error
+
+	// State of the operation being validated.
+	op        string
+	variables map[string]*Type // Defined variables.
+	used      map[string]bool  // Used variables.
+	spreading []string         // Fragments being spread, to detect cycles.
+}
+
+func (v *validator) errorf(path, format string, args ...any) {
+	v.errs = append(v.errs, &ValidationError{Operation: v.op, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) operation(op *OperationDefinition) {
+	v.op = op.Name
+	if v.op == "" {
+		v.op = op.Operation
+	}
+	v.variables = make(map[string]*Type)
+	v.used = make(map[string]bool)
+	root := v.schema.RootType(op.Operation)
+	if root == nil {
+		v.errorf("", "schema doesn't support %s operations", op.Operation)
+		return
+	}
+	for _, def := range op.VariableDefinitions {
+		if _, ok := v.variables[def.Variable]; ok {
+			v.errorf("", "variable $%s is defined more than once", def.Variable)
+		}
+		v.variables[def.Variable] = def.Type
+		t := v.schema.Type(namedType(def.Type))
+		if t == nil {
+			v.errorf("", "variable $%s has unknown type %s", def.Variable, namedType(def.Type))
+		} else if t.Kind != Scalar && t.Kind != Enum && t.Kind != InputObject {
+			v.errorf("", "variable $%s has type %s, which isn't an input type", def.Variable, t.Name)
+		} else if def.DefaultValue != nil {
+			v.value("", "default value of $"+def.Variable, def.DefaultValue, def.Type)
+		}
+	}
+	v.directives("", op.Directives)
+	v.selectionSet("", root, op.SelectionSet)
+	for _, def := range op.VariableDefinitions {
+		if !v.used[def.Variable] {
+			v.errorf("", "variable $%s is never used", def.Variable)
+		}
+	}
+}
+
+// namedType returns the name of the named type of t, or of its elements.
+func namedType(t *Type) string {
+	for t.Elem != nil {
+		t = t.Elem
+	}
+	return t.NamedType
+}
+
+// isComposite reports whether t is an object, interface or union type.
+func isComposite(t *TypeDefinition) bool {
+	return t.Kind == Object || t.Kind == Interface || t.Kind == Union
+}
+
+// joinPath returns the path of the selection key in the selection at path.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// selectionSet validates set, selected on a value of type t at path.
+func (v *validator) selectionSet(path string, t *TypeDefinition, set SelectionSet) {
+	for _, sel := range set {
+		switch sel := sel.(type) {
+		case *Field:
+			v.field(path, t, sel)
+		case *InlineFragment:
+			v.directives(path, sel.Directives)
+			ct := t
+			if sel.TypeCondition != "" {
+				ct = v.typeCondition(path, t, sel.TypeCondition)
+			}
+			if ct != nil {
+				v.selectionSet(path, ct, sel.SelectionSet)
+			}
+		case *FragmentSpread:
+			v.directives(path, sel.Directives)
+			frag := v.doc.Fragment(sel.Name)
+			if frag == nil {
+				v.errorf(path, "unknown fragment %s", sel.Name)
+				continue
+			}
+			if slices.Contains(v.spreading, sel.Name) {
+				v.errorf(path, "fragment %s spreads itself", sel.Name)
+				continue
+			}
+			ct := v.typeCondition(path, t, frag.TypeCondition)
+			if ct == nil {
+				continue
+			}
+			v.spreading = append(v.spreading, sel.Name)
+			v.directives(path, frag.Directives)
+			v.selectionSet(path, ct, frag.SelectionSet)
+			v.spreading = v.spreading[:len(v.spreading)-1]
+		}
+	}
+}
+
+// typeCondition returns the type named cond of a fragment spread on
+// a value of type t at path, or nil if the fragment can't apply to it.
+func (v *validator) typeCondition(path string, t *TypeDefinition, cond string) *TypeDefinition {
+	ct := v.schema.Type(cond)
+	if ct == nil {
+		v.errorf(path, "fragment on unknown type %s", cond)
+		return nil
+	}
+	if !isComposite(ct) {
+		v.errorf(path, "fragment on %s, which isn't an object, interface or union type", cond)
+		return nil
+	}
+	possible := v.schema.PossibleTypes(t.Name)
+	if !slices.ContainsFunc(v.schema.PossibleTypes(cond), func(name string) bool { return slices.Contains(possible, name) }) {
+		v.errorf(path, "fragment on %s can never apply to %s", cond, t.Name)
+		return nil
+	}
+	return ct
+}
+
+// field validates the field f selected on a value of type t at path.
+func (v *validator) field(path string, t *TypeDefinition, f *Field) {
+	fieldPath := joinPath(path, f.ResponseKey())
+	v.directives(fieldPath, f.Directives)
+	switch f.Name {
+	case "__typename":
+		if len(f.SelectionSet) > 0 {
+			v.errorf(fieldPath, "field __typename of type String! can't have a selection set")
+		}
+		return
+	case "__schema", "__type":
+		if t.Name == v.schema.Query {
+			return // Introspection isn't validated.
+		}
+	}
+	def := t.Field(f.Name)
+	if def == nil {
+		v.errorf(fieldPath, "field %s isn't defined on type %s", f.Name, t.Name)
+		return
+	}
+	for _, arg := range f.Arguments {
+		i := slices.IndexFunc(def.Arguments, func(a *InputValue) bool { return a.Name == arg.Name })
+		if i == -1 {
+			v.errorf(fieldPath, "unknown argument %s of field %s.%s", arg.Name, t.Name, f.Name)
+			continue
+		}
+		v.value(fieldPath, "argument "+arg.Name, arg.Value, def.Arguments[i].Type)
+	}
+	for _, a := range def.Arguments {
+		if a.Type.NonNull && a.DefaultValue == nil &&
+			!slices.ContainsFunc(f.Arguments, func(arg *Argument) bool { return arg.Name == a.Name }) {
+			v.errorf(fieldPath, "missing required argument %s of field %s.%s", a.Name, t.Name, f.Name)
+		}
+	}
+	ft := v.schema.Type(namedType(def.Type))
+	switch {
+	case ft == nil:
+		v.errorf(fieldPath, "field %s.%s has unknown type %s", t.Name, f.Name, namedType(def.Type))
+	case isComposite(ft) && len(f.SelectionSet) == 0:
+		v.errorf(fieldPath, "field %s of type %s must have a selection set", f.Name, ft.Name)
+	case !isComposite(ft) && len(f.SelectionSet) > 0:
+		v.errorf(fieldPath, "field %s of type %s can't have a selection set", f.Name, ft.Name)
+	case isComposite(ft):
+		v.selectionSet(fieldPath, ft, f.SelectionSet)
+	}
+}
+
+// directives validates the arguments of the @skip and @include directives
+// in dirs at path. Other directives aren't known.
+func (v *validator) directives(path string, dirs []*Directive) {
+	for _, d := range dirs {
+		for _, arg := range d.Arguments {
+			if (d.Name == "skip" || d.Name == "include") && arg.Name == "if" {
+				v.value(path, "argument if of @"+d.Name, arg.Value, &Type{NamedType: "Boolean", NonNull: true})
+			} else {
+				v.value(path, "", arg.Value, nil)
+			}
+		}
+	}
+}
+
+// value validates the input value val of type t, described by what,
+// at path. If t is nil, only the variables in it are validated.
+func (v *validator) value(path, what string, val Value, t *Type) {
+	if vr, ok := val.(*Variable); ok {
+		v.used[vr.Name] = true
+		vt, ok := v.variables[vr.Name]
+		if !ok {
+			v.errorf(path, "variable $%s isn't defined", vr.Name)
+		} else if t != nil && !compatible(vt, t) {
+			v.errorf(path, "variable $%s of type %s can't be used as %s of type %s", vr.Name, typeString(vt), what, typeString(t))
+		}
+		return
+	}
+	if t == nil {
+		switch val := val.(type) {
+		case *ListValue:
+			for _, elem := range val.Values {
+				v.value(path, what, elem, nil)
+			}
+		case *ObjectValue:
+			for _, f := range val.Fields {
+				v.value(path, what, f.Value, nil)
+			}
+		}
+		return
+	}
+	if _, ok := val.(*NullValue); ok {
+		if t.NonNull {
+			v.errorf(path, "%s of type %s can't be null", what, typeString(t))
+		}
+		return
+	}
+	if t.Elem != nil {
+		if list, ok := val.(*ListValue); ok {
+			for _, elem := range list.Values {
+				v.value(path, what, elem, t.Elem)
+			}
+		} else {
+			v.value(path, what, val, t.Elem) // A single value is coerced to a list.
+		}
+		return
+	}
+	nt := v.schema.Type(t.NamedType)
+	if nt == nil {
+		return // Reported where the type is referenced.
+	}
+	invalid := func() {
+		var p printer
+		p.value(val)
+		v.errorf(path, "%s of type %s can't be %s", what, typeString(t), p.String())
+	}
+	switch nt.Kind {
+	case Enum:
+		if e, ok := val.(*EnumValue); !ok || !slices.Contains(nt.EnumValues, e.Name) {
+			invalid()
+		}
+	case InputObject:
+		obj, ok := val.(*ObjectValue)
+		if !ok {
+			invalid()
+			return
+		}
+		for _, f := range obj.Fields {
+			i := slices.IndexFunc(nt.InputFields, func(iv *InputValue) bool { return iv.Name == f.Name })
+			if i == -1 {
+				v.errorf(path, "%s has unknown field %s of type %s", what, f.Name, nt.Name)
+				continue
+			}
+			v.value(path, what+"."+f.Name, f.Value, nt.InputFields[i].Type)
+		}
+		for _, iv := range nt.InputFields {
+			if iv.Type.NonNull && iv.DefaultValue == nil &&
+				!slices.ContainsFunc(obj.Fields, func(f *ObjectField) bool { return f.Name == iv.Name }) {
+				v.errorf(path, "%s is missing required field %s of type %s", what, iv.Name, nt.Name)
+			}
+		}
+	case Scalar:
+		var ok bool
+		switch val.(type) {
+		case *IntValue:
+			ok = nt.Name == "Int" || nt.Name == "Float" || nt.Name == "ID"
+		case *FloatValue:
+			ok = nt.Name == "Float"
+		case *StringValue:
+			ok = nt.Name == "String" || nt.Name == "ID"
+		case *BooleanValue:
+			ok = nt.Name == "Boolean"
+		}
+		if !ok && !slices.Contains(builtinScalars, nt.Name) {
+			ok = true // Custom scalars may have any input values.
+			v.value(path, what, val, nil)
+		}
+		if !ok {
+			invalid()
+		}
+	default:
+		invalid()
+	}
+}
+
+// compatible reports whether a variable of type vt can be used where
+// a value of type t is expected.
+func compatible(vt, t *Type) bool {
+	if t.NonNull {
+		if !vt.NonNull {
+			return false
+		}
+		return compatible(&Type{NamedType: vt.NamedType, Elem: vt.Elem}, &Type{NamedType: t.NamedType, Elem: t.Elem})
+	}
+	if vt.NonNull {
+		return compatible(&Type{NamedType: vt.NamedType, Elem: vt.Elem}, t)
+	}
+	if t.Elem != nil {
+		return vt.Elem != nil && compatible(vt.Elem, t.Elem)
+	}
+	return vt.Elem == nil && vt.NamedType == t.NamedType
+}
+
+// typeString returns t as written in GraphQL, e.g., "[ID!]!".
+func typeString(t *Type) string {
+	var p printer
+	p.typeRef(t)
+	return p.String()
+}
@@ -0,0 +1,84 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/isihu/graphql/ast"
+)
+
+func TestValidate(t *testing.T) {
+	s, err := ast.ParseSchema(testSchema + `
+extend type Query {
+	repositories(filter: Filter, states: [State!], first: Int!): [Repository!]!
+	user(login: String!): User
+}
+extend input Filter {
+	owner: String!
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{
+			in: `query($id: ID!, $first: Int!, $states: [State!]) {
+				node(id: $id) { id, __typename, ... on User { login }, ...RepositoryFields }
+				repositories(first: $first, states: $states, filter: {owner: "isihu", states: [OPEN]}) { state }
+				search(query: "go") { ... on Actor { login } }
+				__schema { types { name } }
+			}
+			fragment RepositoryFields on Repository { createdAt }`,
+		},
+		{
+			in:   `mutation { a }`,
+			want: []string{"graphql: mutation: schema doesn't support mutation operations"},
+		},
+		{
+			in: `query Q($id: ID, $unused: Int, $obj: Repository) {
+				node(id: $id) { id, nickname, ... on State { a } }
+				user { login { length } }
+				repositories(first: "10", states: CLOSED, filter: {owner: null, color: RED}, after: $cursor)
+				search(query: "go") { ... on Bot { id } }
+			}`,
+			want: []string{
+				"graphql: Q: variable $obj has type Repository, which isn't an input type",
+				"graphql: Q: node: variable $id of type ID can't be used as argument id of type ID!",
+				"graphql: Q: node.nickname: field nickname isn't defined on type Node",
+				"graphql: Q: node: fragment on State, which isn't an object, interface or union type",
+				"graphql: Q: user: missing required argument login of field Query.user",
+				"graphql: Q: user.login: field login of type String can't have a selection set",
+				`graphql: Q: repositories: argument first of type Int! can't be "10"`,
+				"graphql: Q: repositories: argument filter.owner of type String! can't be null",
+				"graphql: Q: repositories: argument filter has unknown field color of type Filter",
+				"graphql: Q: repositories: unknown argument after of field Query.repositories",
+				"graphql: Q: repositories: field repositories of type Repository must have a selection set",
+				"graphql: Q: search.id: field id isn't defined on type Bot",
+				"graphql: Q: variable $unused is never used",
+				"graphql: Q: variable $obj is never used",
+			},
+		},
+	}
+	for _, tc := range tests {
+		doc, err := ast.Parse(tc.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		errs := ast.Validate(s, doc)
+		var got []string
+		for _, err := range errs {
+			got = append(got, err.Error())
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("got %d errors, want: %d:\n%q", len(got), len(tc.want), got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("got error %d: %s, want: %s", i, got[i], tc.want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// AuditRecord describes a mutation sent by the client, for audit logs.
+type AuditRecord struct {
+	Operation Operation
+	Caller    string        // Identity of the caller, as returned by AuditOptions.Caller.
+	Time      time.Time     // When the mutation started.
+	Duration  time.Duration // How long the mutation took, including retries.
+
+	// Variables is a snapshot of the mutation's variables,
+	// redacted as configured by AuditOptions.Redact.
+	Variables map[string]any
+
+	Status AuditStatus
+	Err    error // Error the mutation failed with, or nil if it succeeded.
+}
+
+// AuditStatus is the result status of an audited mutation.
+type AuditStatus int
+
+const (
+	// AuditSucceeded is the status of a mutation that succeeded.
+	AuditSucceeded AuditStatus = iota
+
+	// AuditPartial is the status of a mutation whose response had both
+	// data and errors, which means that some of its writes may have happened.
+	AuditPartial
+
+	// AuditFailed is the status of a mutation that failed.
+	AuditFailed
+)
+
+func (s AuditStatus) String() string {
+	switch s {
+	case AuditSucceeded:
+		return "succeeded"
+	case AuditPartial:
+		return "partial"
+	case AuditFailed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+// AuditOptions configures what WithMutationAudit records.
+type AuditOptions struct {
+	// Caller, if non-nil, returns the identity of the caller of a mutation
+	// from its context, such as the authenticated user or service.
+	Caller func(ctx context.Context) string
+
+	// Redact, if non-nil, returns the value to record for the variable named
+	// name with value. If it's nil, the values of all variables are
+	// replaced by "[REDACTED]".
+	Redact func(name string, value any) any
+}
+
+// WithMutationAudit makes the client call audit with a record of every
+// mutation sent by Mutate and Do, after the mutation completes, whether it
+// succeeded or not. Queries and subscriptions aren't audited.
+func WithMutationAudit(audit func(ctx context.Context, r AuditRecord), opts AuditOptions) ClientOption {
+	return func(c *Client) {
+		c.audit = audit
+		c.auditOptions = opts
+	}
+}
+
+// auditMutation calls the client's audit function with a record of the mutation
+// with the GraphQL document query and variables, which started at start,
+// took d, and failed with err, if non-nil.
+func (c *Client) auditMutation(ctx context.Context, query string, variables map[string]any, start time.Time, d time.Duration, err error) {
+	r := AuditRecord{
+		Operation: newOperation(query),
+		Time:      start,
+		Duration:  d,
+		Err:       err,
+	}
+	if c.auditOptions.Caller != nil {
+		r.Caller = c.auditOptions.Caller(ctx)
+	}
+	if len(variables) > 0 {
+		r.Variables = make(map[string]any, len(variables))
+		for name, value := range variables {
+			if c.auditOptions.Redact != nil {
+				value = c.auditOptions.Redact(name, value)
+			} else {
+				value = "[REDACTED]"
+			}
+			r.Variables[name] = value
+		}
+	}
+	var partial *PartialDataError
+	switch {
+	case err == nil:
+		r.Status = AuditSucceeded
+	case errors.As(err, &partial):
+		r.Status = AuditPartial
+	default:
+		r.Status = AuditFailed
+	}
+	c.audit(ctx, r)
+}
@@ -0,0 +1,65 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+type callerKey struct{}
+
+func TestWithMutationAudit(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"addStar": {"starrable": {"id": "1"}}}, "errors": [{"message": "notification failed"}]}`)
+	})
+	var got []graphql.AuditRecord
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithMutationAudit(func(ctx context.Context, r graphql.AuditRecord) { got = append(got, r) }, graphql.AuditOptions{
+			Caller: func(ctx context.Context) string { return ctx.Value(callerKey{}).(string) },
+			Redact: func(name string, value any) any {
+				if name == "token" {
+					return "[REDACTED]"
+				}
+				return value
+			},
+		}))
+	ctx := context.WithValue(context.Background(), callerKey{}, "alice")
+
+	var m struct {
+		AddStar struct {
+			Starrable struct{ ID graphql.ID }
+		} `graphql:"addStar(id:$id,token:$token)"`
+	}
+	err := client.Mutate(ctx, &m, map[string]any{"id": graphql.ID("1"), "token": graphql.String("s3cr3t")})
+	if err == nil {
+		t.Fatal("got error: nil, want: non-nil")
+	}
+	var q struct {
+		Viewer struct{ Login graphql.String }
+	}
+	client.Query(ctx, &q, nil)
+
+	if len(got) != 1 {
+		t.Fatalf("got %d audit records, want: 1", len(got))
+	}
+	r := got[0]
+	if got, want := r.Operation.Type, "mutation"; got != want {
+		t.Errorf("got operation type: %q, want: %q", got, want)
+	}
+	if got, want := r.Caller, "alice"; got != want {
+		t.Errorf("got caller: %q, want: %q", got, want)
+	}
+	if got, want := mustMarshal(r.Variables), `{"id":"1","token":"[REDACTED]"}`; got != want {
+		t.Errorf("got variables: %s, want: %s", got, want)
+	}
+	if got, want := r.Status, graphql.AuditPartial; got != want {
+		t.Errorf("got status: %v, want: %v", got, want)
+	}
+	if r.Err != err {
+		t.Errorf("got error: %v, want: %v", r.Err, err)
+	}
+}
@@ -0,0 +1,82 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// WithAuthRefresh makes the client call refresh when the server rejects
+// a request as unauthenticated, either with a 401 Unauthorized status code
+// or with a GraphQL error whose extensions code is "UNAUTHENTICATED",
+// and then transparently replay the request once.
+//
+// refresh returns headers with new credentials, such as Authorization,
+// which are set on the replayed request and on all later requests.
+// It may also return no headers, if it refreshes credentials that are
+// applied elsewhere, such as by the HTTP client's transport.
+// Operations rejected at the same time share a single call to refresh.
+func WithAuthRefresh(refresh func(ctx context.Context) (http.Header, error)) ClientOption {
+	return func(c *Client) { c.refreshAuth = refresh }
+}
+
+// authGeneration returns the generation of the credentials that
+// requests are sent with, which refreshAuthHeader increments.
+func (c *Client) authGeneration() uint64 {
+	c.authMu.RLock()
+	defer c.authMu.RUnlock()
+	return c.authGen
+}
+
+// refreshAuthHeader calls c.refreshAuth and stores the headers it returns,
+// unless the credentials were refreshed since generation gen, which a
+// rejected request was sent with. Concurrent calls for the same generation
+// wait for the first one, rather than each calling c.refreshAuth.
+func (c *Client) refreshAuthHeader(ctx context.Context, gen uint64) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	if c.authGeneration() != gen {
+		return nil
+	}
+	header, err := c.refreshAuth(ctx)
+	if err != nil {
+		return fmt.Errorf("refreshing credentials: %w", err)
+	}
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	c.authGen++
+	if len(header) == 0 {
+		return nil
+	}
+	if c.authHeader == nil {
+		c.authHeader = make(http.Header)
+	}
+	for k, vs := range header {
+		c.authHeader[k] = vs
+	}
+	return nil
+}
+
+// unauthenticated reports whether the outcome of sending
+// a request indicates that its credentials were rejected.
+func unauthenticated(out *response, err error) bool {
+	for err != nil {
+		if se, ok := err.(*HTTPError); ok {
+			return se.StatusCode == http.StatusUnauthorized
+		}
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return false
+		}
+		err = u.Unwrap()
+	}
+	if out == nil {
+		return false
+	}
+	for _, e := range out.Errors {
+		if e.Code() == "UNAUTHENTICATED" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+func TestClient_Query_authRefresh(t *testing.T) {
+	for _, unauthorized := range []func(w http.ResponseWriter){
+		func(w http.ResponseWriter) {
+			http.Error(w, "token expired", http.StatusUnauthorized)
+		},
+		func(w http.ResponseWriter) {
+			w.Header().Set("Content-Type", "application/json")
+			mustWrite(w, `{"errors": [{"message": "token expired", "extensions": {"code": "UNAUTHENTICATED"}}]}`)
+		},
+	} {
+		var calls int
+		mux := http.NewServeMux()
+		mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+			calls++
+			if req.Header.Get("Authorization") != "Bearer fresh" {
+				unauthorized(w)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+		})
+		var refreshes int
+		client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+			graphql.WithHeader("Authorization", "Bearer stale"),
+			graphql.WithAuthRefresh(func(ctx context.Context) (http.Header, error) {
+				refreshes++
+				return http.Header{"Authorization": {"Bearer fresh"}}, nil
+			}),
+		)
+
+		for range 2 {
+			var q struct {
+				Viewer struct {
+					Login string
+				}
+			}
+			err := client.Query(context.Background(), &q, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := q.Viewer.Login, "gopher"; got != want {
+				t.Errorf("got q.Viewer.Login: %q, want: %q", got, want)
+			}
+		}
+		// The first query is replayed after refreshing,
+		// and the second one uses the fresh credentials right away.
+		if got, want := calls, 3; got != want {
+			t.Errorf("got calls: %v, want: %v", got, want)
+		}
+		if got, want := refreshes, 1; got != want {
+			t.Errorf("got refreshes: %v, want: %v", got, want)
+		}
+	}
+}
+
+// Test that operations rejected concurrently share a single refresh.
+func TestClient_Query_authRefreshConcurrent(t *testing.T) {
+	const n = 8
+	var rejected sync.WaitGroup
+	rejected.Add(n)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer fresh" {
+			// Reject all operations before any refreshes.
+			rejected.Done()
+			rejected.Wait()
+			http.Error(w, "token expired", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+	var refreshes atomic.Int32
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithAuthRefresh(func(ctx context.Context) (http.Header, error) {
+			refreshes.Add(1)
+			return http.Header{"Authorization": {"Bearer fresh"}}, nil
+		}),
+	)
+
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var q struct {
+				Viewer struct {
+					Login string
+				}
+			}
+			if err := client.Query(context.Background(), &q, nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got, want := refreshes.Load(), int32(1); got != want {
+		t.Errorf("got refreshes: %v, want: %v", got, want)
+	}
+}
+
+// Test that a request is only replayed once.
+func TestClient_Query_authRefreshReplaysOnce(t *testing.T) {
+	var calls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		http.Error(w, "nope", http.StatusUnauthorized)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithAuthRefresh(func(ctx context.Context) (http.Header, error) { return nil, nil }),
+	)
+
+	var q struct {
+		Viewer struct {
+			Login string
+		}
+	}
+	err := client.Query(context.Background(), &q, nil)
+	if err == nil {
+		t.Fatal("got error: nil, want: non-nil")
+	}
+	if got, want := calls, 2; got != want {
+		t.Errorf("got calls: %v, want: %v", got, want)
+	}
+}
@@ -0,0 +1,212 @@
+package graphql
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// LoadBalancing is a policy for choosing among the IP addresses
+// that the GraphQL server host name resolves to.
+type LoadBalancing int
+
+const (
+	// RoundRobin cycles through the addresses in turn.
+	RoundRobin LoadBalancing = iota
+
+	// LeastPending chooses the address with the fewest open connections.
+	LeastPending
+)
+
+// WithDNSLoadBalancing makes the client resolve the host name of the GraphQL
+// server to all of its IP addresses, and distribute new connections across
+// them according to policy. An address that fails to connect is marked
+// unhealthy and avoided for a while. Resolved addresses are refreshed
+// periodically. It's meant for headless-service deployments that have no
+// external load balancer.
+//
+// Load is distributed per connection, and idle connections are reused for
+// later requests. Limit MaxConnsPerHost or disable keep-alives on the
+// transport for a finer-grained distribution of requests.
+//
+// Like other options that configure the transport, it requires the
+// HTTP client's transport to be nil or an *http.Transport.
+func WithDNSLoadBalancing(policy LoadBalancing) ClientOption {
+	return func(c *Client) { c.balancer = newBalancer(policy) }
+}
+
+// balancer dials connections to one of the IP addresses of a host,
+// chosen according to a load balancing policy.
+type balancer struct {
+	policy   LoadBalancing
+	dial     func(ctx context.Context, network, addr string) (net.Conn, error)
+	lookup   func(ctx context.Context, host string) ([]net.IPAddr, error)
+	ttl      time.Duration // How long resolved addresses are used for.
+	cooldown time.Duration // How long an address stays unhealthy for.
+	now      func() time.Time
+
+	mu    sync.Mutex
+	hosts map[string]*balancedHost // Keyed by host name.
+}
+
+// balancedHost is the state of a single host name.
+type balancedHost struct {
+	resolved  time.Time
+	endpoints []*endpoint
+	next      int // Index of next endpoint for RoundRobin.
+}
+
+// endpoint is the state of a single IP address of a host.
+type endpoint struct {
+	ip             string
+	open           int       // Number of open connections.
+	unhealthyUntil time.Time // Zero if healthy.
+}
+
+func newBalancer(policy LoadBalancing) *balancer {
+	return &balancer{
+		policy:   policy,
+		dial:     (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+		lookup:   net.DefaultResolver.LookupIPAddr,
+		ttl:      30 * time.Second,
+		cooldown: 30 * time.Second,
+		now:      time.Now,
+		hosts:    make(map[string]*balancedHost),
+	}
+}
+
+// DialContext dials addr, a host and port, by trying the IP addresses
+// of host in the order chosen by the load balancing policy.
+func (b *balancer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return b.dial(ctx, network, addr)
+	}
+	endpoints, err := b.endpoints(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var firstErr error
+	for _, e := range endpoints {
+		conn, err := b.dial(ctx, network, net.JoinHostPort(e.ip, port))
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			b.mu.Lock()
+			e.unhealthyUntil = b.now().Add(b.cooldown)
+			b.mu.Unlock()
+			continue
+		}
+		b.mu.Lock()
+		e.open++
+		e.unhealthyUntil = time.Time{}
+		b.mu.Unlock()
+		return &balancedConn{Conn: conn, b: b, e: e}, nil
+	}
+	return nil, firstErr
+}
+
+// endpoints returns the endpoints of host in the order they should be tried.
+// Healthy endpoints come first, ordered by the load balancing policy.
+func (b *balancer) endpoints(ctx context.Context, host string) ([]*endpoint, error) {
+	b.mu.Lock()
+	h, ok := b.hosts[host]
+	stale := !ok || b.now().Sub(h.resolved) > b.ttl
+	b.mu.Unlock()
+	if stale {
+		addrs, err := b.lookup(ctx, host)
+		if err != nil && !ok {
+			return nil, err
+		}
+		if err == nil {
+			b.refresh(host, addrs)
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h = b.hosts[host]
+	n := len(h.endpoints)
+	if n == 0 {
+		return nil, &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+	}
+	order := make([]*endpoint, 0, n)
+	switch b.policy {
+	case LeastPending:
+		order = append(order, h.endpoints...)
+		// Stable insertion sort by open connections; n is small.
+		for i := 1; i < n; i++ {
+			for j := i; j > 0 && order[j].open < order[j-1].open; j-- {
+				order[j], order[j-1] = order[j-1], order[j]
+			}
+		}
+	default:
+		for i := range n {
+			order = append(order, h.endpoints[(h.next+i)%n])
+		}
+		h.next = (h.next + 1) % n
+	}
+	// Move unhealthy endpoints to the back, keeping them as a last resort.
+	now := b.now()
+	healthy, unhealthy := order[:0:0], []*endpoint(nil)
+	for _, e := range order {
+		if now.Before(e.unhealthyUntil) {
+			unhealthy = append(unhealthy, e)
+		} else {
+			healthy = append(healthy, e)
+		}
+	}
+	return append(healthy, unhealthy...), nil
+}
+
+// refresh replaces the endpoints of host with addrs,
+// keeping the state of addresses that remain.
+func (b *balancer) refresh(host string, addrs []net.IPAddr) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h, ok := b.hosts[host]
+	if !ok {
+		h = new(balancedHost)
+		b.hosts[host] = h
+	}
+	old := make(map[string]*endpoint, len(h.endpoints))
+	for _, e := range h.endpoints {
+		old[e.ip] = e
+	}
+	h.endpoints = h.endpoints[:0]
+	for _, a := range addrs {
+		ip := a.String()
+		if e, ok := old[ip]; ok {
+			h.endpoints = append(h.endpoints, e)
+		} else {
+			h.endpoints = append(h.endpoints, &endpoint{ip: ip})
+		}
+	}
+	h.resolved = b.now()
+	if h.next >= len(h.endpoints) {
+		h.next = 0
+	}
+}
+
+// balancedConn is a connection to an endpoint,
+// which is tracked as open until it's closed.
+type balancedConn struct {
+	net.Conn
+	b    *balancer
+	e    *endpoint
+	once sync.Once
+}
+
+func (c *balancedConn) Close() error {
+	c.once.Do(func() {
+		c.b.mu.Lock()
+		c.e.open--
+		c.b.mu.Unlock()
+	})
+	return c.Conn.Close()
+}
@@ -0,0 +1,99 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"testing"
+)
+
+func TestBalancer_roundRobin(t *testing.T) {
+	b := newTestBalancer(RoundRobin, nil)
+	var got []string
+	for range 4 {
+		conn, err := b.DialContext(context.Background(), "tcp", "graphql.internal:443")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, conn.RemoteAddr().String())
+	}
+	want := []string{"10.0.0.1:443", "10.0.0.2:443", "10.0.0.3:443", "10.0.0.1:443"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestBalancer_leastPending(t *testing.T) {
+	b := newTestBalancer(LeastPending, nil)
+	dial := func() net.Conn {
+		conn, err := b.DialContext(context.Background(), "tcp", "graphql.internal:443")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn
+	}
+	c1, c2, c3 := dial(), dial(), dial()
+	if got := []string{c1.RemoteAddr().String(), c2.RemoteAddr().String(), c3.RemoteAddr().String()}; !slices.Equal(got, []string{"10.0.0.1:443", "10.0.0.2:443", "10.0.0.3:443"}) {
+		t.Errorf("got %v, want each address once", got)
+	}
+	c2.Close()
+	if got, want := dial().RemoteAddr().String(), "10.0.0.2:443"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestBalancer_unhealthy(t *testing.T) {
+	var dialed []string
+	b := newTestBalancer(RoundRobin, func(addr string) error {
+		dialed = append(dialed, addr)
+		if addr == "10.0.0.1:443" {
+			return fmt.Errorf("connection refused")
+		}
+		return nil
+	})
+	for range 3 {
+		_, err := b.DialContext(context.Background(), "tcp", "graphql.internal:443")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The first dial fails over to the next address, and the failed one
+	// is then skipped until it's healthy again.
+	want := []string{"10.0.0.1:443", "10.0.0.2:443", "10.0.0.2:443", "10.0.0.3:443"}
+	if !slices.Equal(dialed, want) {
+		t.Errorf("got dialed %v, want %v", dialed, want)
+	}
+}
+
+// newTestBalancer returns a balancer for a host that resolves
+// to 3 addresses, whose dials fail if fail returns an error.
+func newTestBalancer(policy LoadBalancing, fail func(addr string) error) *balancer {
+	b := newBalancer(policy)
+	b.lookup = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		return []net.IPAddr{{IP: net.ParseIP("10.0.0.1")}, {IP: net.ParseIP("10.0.0.2")}, {IP: net.ParseIP("10.0.0.3")}}, nil
+	}
+	b.dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if fail != nil {
+			if err := fail(addr); err != nil {
+				return nil, err
+			}
+		}
+		return fakeConn{addr: addr}, nil
+	}
+	return b
+}
+
+// fakeConn is a net.Conn that only knows its remote address.
+type fakeConn struct {
+	net.Conn
+	addr string
+}
+
+func (c fakeConn) RemoteAddr() net.Addr { return fakeAddr(c.addr) }
+func (c fakeConn) Close() error         { return nil }
+
+type fakeAddr string
+
+func (a fakeAddr) Network() string { return "tcp" }
+func (a fakeAddr) String() string  { return string(a) }
@@ -0,0 +1,280 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithBatching makes the client coalesce queries sent within window of each
+// other to the same URL into a single HTTP request, whose body is a JSON array
+// of the requests, and whose response is a JSON array of their responses,
+// in order. It reduces round trips in code that fans out into many small
+// queries, dataloader-style, at the cost of delaying each query by up to
+// window. A batch is sent as soon as it has maxSize queries, if maxSize is
+// positive. The server must support batched requests, as Apollo Server
+// does with allowBatchedHttpRequests.
+//
+// Only queries are batched, since the server may run the operations of a
+// batch in parallel. Queries sent as persisted queries, with streamed
+// bodies, or with per-operation headers, such as those of Apollo traces,
+// fingerprints, payload captures, tracing and deadlines, are sent on their
+// own, so no queries are batched with WithTracer. A query that has no other
+// queries to share a batch with is sent on its own too. The statistics of a
+// batched query count its own part of the batch's bodies, and the timings
+// of the batch's HTTP request.
+//
+// The HTTP request of a batch is sent with a context that carries none of
+// the values of the contexts of its queries, since they may differ, e.g.,
+// in the tenant or credentials that a transport reads from them. To batch
+// queries whose contexts carry such values, use WithBatchKey.
+func WithBatching(window time.Duration, maxSize int) ClientOption {
+	return func(c *Client) {
+		c.batcher = &batcher{
+			window:  window,
+			maxSize: maxSize,
+			pending: make(map[string]*batch),
+		}
+	}
+}
+
+// WithBatchKey makes the client set by WithBatching batch only queries
+// whose contexts have the same key, as returned by key, such as the ID
+// of a tenant or user. The HTTP request of a batch is sent with the values
+// of the context of its first query, which the other queries must agree on
+// by having the same key.
+func WithBatchKey(key func(ctx context.Context) string) ClientOption {
+	return func(c *Client) { c.batchKey = key }
+}
+
+// batcher coalesces queries into batches.
+type batcher struct {
+	window  time.Duration
+	maxSize int
+
+	mu      sync.Mutex
+	pending map[string]*batch // Batches being collected, by URL and batch key.
+}
+
+// batch is a batch of queries to a URL.
+type batch struct {
+	url   string
+	ctx   context.Context // Context whose values the batch's request is sent with.
+	calls []*batchedCall
+	timer *time.Timer
+}
+
+// batchedCall is a query in a batch.
+type batchedCall struct {
+	ctx  context.Context
+	in   request
+	done chan struct{} // Closed when the call has a result.
+
+	alone bool // Whether the call had no batch to share, and must be sent on its own.
+	out   *response
+	err   error
+
+	// Statistics of the call's part of the batch, added to the call's
+	// callStats by its goroutine, which may have stopped waiting.
+	sent, received    int64
+	roundTrip, decode time.Duration
+	timings           Timings
+	cacheStatus       string
+}
+
+// batchable reports whether in, to be sent with ctx and cfg, can be batched.
+func (c *Client) batchable(ctx context.Context, cfg *callConfig, in request) bool {
+	if c.batcher == nil || c.persistedQueries || c.tracer != nil || namedOperationType(in.Query, in.OperationName) != "query" {
+		return false
+	}
+	if _, ok := ctx.Deadline(); ok && c.deadlineHeader != "" {
+		return false
+	}
+	return !cfg.streamBody && cfg.apolloTrace == nil && cfg.fingerprint == "" && cfg.capture == nil
+}
+
+// sendBatched sends in to url as part of a batch, and returns its response.
+func (c *Client) sendBatched(ctx context.Context, cfg *callConfig, url string, in request) (*response, error) {
+	call := &batchedCall{ctx: ctx, in: in, done: make(chan struct{})}
+	key := url
+	values := context.Background()
+	if c.batchKey != nil {
+		key += "\x00" + c.batchKey(ctx)
+		values = context.WithoutCancel(ctx)
+	}
+	b := c.batcher
+	b.mu.Lock()
+	p := b.pending[key]
+	if p == nil {
+		p = &batch{url: url, ctx: values}
+		b.pending[key] = p
+		p.timer = time.AfterFunc(b.window, func() { c.flushBatch(key, p) })
+	}
+	p.calls = append(p.calls, call)
+	if b.maxSize > 0 && len(p.calls) >= b.maxSize && p.timer.Stop() {
+		delete(b.pending, key)
+		go c.flushBatch(key, p)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, &NetworkError{Err: ctx.Err()}
+	}
+	if call.alone {
+		return c.send(ctx, cfg, http.MethodPost, url, in)
+	}
+	cfg.stats.requestBytes.Add(call.sent)
+	cfg.stats.responseBytes.Add(call.received)
+	cfg.stats.roundTrip += call.roundTrip
+	cfg.stats.decode += call.decode
+	cfg.stats.conn.set(call.timings)
+	cfg.stats.cacheStatus = call.cacheStatus
+	return call.out, call.err
+}
+
+// flushBatch removes p from the pending batches under key, and sends it.
+func (c *Client) flushBatch(key string, p *batch) {
+	b := c.batcher
+	b.mu.Lock()
+	if b.pending[key] == p {
+		delete(b.pending, key)
+	}
+	calls := p.calls
+	b.mu.Unlock()
+	if len(calls) == 1 {
+		calls[0].alone = true
+		close(calls[0].done)
+		return
+	}
+
+	// Send the batch until every call in it is canceled.
+	ctx, cancel := context.WithCancel(p.ctx)
+	defer cancel()
+	var live atomic.Int32
+	live.Store(int32(len(calls)))
+	for _, call := range calls {
+		stop := context.AfterFunc(call.ctx, func() {
+			if live.Add(-1) == 0 {
+				cancel()
+			}
+		})
+		defer stop()
+	}
+	start := time.Now()
+	outs, status, err := c.sendBatch(ctx, p.url, calls)
+	for i, call := range calls {
+		if err != nil {
+			call.err = err
+		} else {
+			call.out = outs[i]
+		}
+		if c.logger != nil {
+			c.logRequest(call.ctx, call.in, time.Since(start), status, call.err)
+		}
+		close(call.done)
+	}
+}
+
+// sendBatch sends the requests of calls to url as a single HTTP request,
+// and returns their responses, and the status code of the HTTP response, if any.
+// It records the statistics of every call's part of the batch in the call.
+func (c *Client) sendBatch(ctx context.Context, url string, calls []*batchedCall) ([]*response, int, error) {
+	buf := getBuffer()
+	defer buf.release()
+	buf.WriteByte('[')
+	for i, call := range calls {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		n := buf.Len()
+		if err := buf.enc.Encode(call.in); err != nil {
+			return nil, 0, err
+		}
+		call.sent = int64(buf.Len() - n)
+	}
+	buf.WriteByte(']')
+	var conn connTimings
+	ctx = conn.trace(ctx)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, buf.body())
+	if err != nil {
+		return nil, 0, err
+	}
+	req.ContentLength = int64(buf.Len())
+	req.GetBody = func() (io.ReadCloser, error) { return buf.body(), nil }
+	req.Header.Set("Content-Type", "application/json")
+	c.setHeaders(req.Header)
+	if c.dump != nil {
+		c.dumpRequest(req)
+	}
+	roundTripStart := time.Now()
+	resp, err := c.httpClient.Do(req)
+	for _, call := range calls {
+		call.roundTrip = time.Since(roundTripStart)
+		call.timings = conn.timings()
+	}
+	if err != nil {
+		return nil, 0, &NetworkError{Err: err}
+	}
+	defer resp.Body.Close()
+	for _, call := range calls {
+		call.cacheStatus = cacheStatus(resp.Header)
+	}
+	if c.dump != nil {
+		c.dumpResponse(resp)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, newHTTPError(resp, resp.Body)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, &NetworkError{Err: err}
+	}
+	if c.verifyResponse != nil {
+		if err := c.verifyResponse(resp.Header, body); err != nil {
+			return nil, resp.StatusCode, err
+		}
+	}
+	decodeError := func(err error) *DecodeError {
+		var prefix prefixWriter
+		prefix.Write(body)
+		return &DecodeError{
+			Err:         err,
+			Status:      resp.Status,
+			ContentType: resp.Header.Get("Content-Type"),
+			Body:        prefix.b,
+			truncated:   prefix.truncated,
+		}
+	}
+	var raws []json.RawMessage
+	if err := json.Unmarshal(body, &raws); err != nil {
+		return nil, resp.StatusCode, decodeError(err)
+	}
+	if len(raws) != len(calls) {
+		return nil, resp.StatusCode, decodeError(errBatchMismatch)
+	}
+	outs := make([]*response, len(raws))
+	for i, raw := range raws {
+		decodeStart := time.Now()
+		out, err := c.decodeResponse(raw)
+		calls[i].received = int64(len(raw))
+		calls[i].decode = time.Since(decodeStart)
+		if err != nil {
+			return nil, resp.StatusCode, decodeError(err)
+		}
+		out.header = resp.Header
+		c.prepareErrors(out.Errors)
+		outs[i] = out
+	}
+	return outs, resp.StatusCode, nil
+}
+
+// errBatchMismatch is returned when the response to a batch doesn't have
+// a response for every request.
+var errBatchMismatch = errors.New("graphql: batched response doesn't match the batch")
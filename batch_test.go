@@ -0,0 +1,253 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/isihu/graphql"
+)
+
+// batchHandler answers batched and single requests for the name of a user,
+// recording the number of requests in each HTTP request.
+type batchHandler struct {
+	mu    sync.Mutex
+	sizes []int
+}
+
+func (h *batchHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body := mustRead(req.Body)
+	type request struct{ Variables struct{ ID string } }
+	var ins []request
+	batched := strings.HasPrefix(body, "[")
+	if batched {
+		mustUnmarshal(body, &ins)
+	} else {
+		var in request
+		mustUnmarshal(body, &in)
+		ins = append(ins, in)
+	}
+	h.mu.Lock()
+	h.sizes = append(h.sizes, len(ins))
+	h.mu.Unlock()
+	outs := make([]string, len(ins))
+	for i, in := range ins {
+		outs[i] = fmt.Sprintf(`{"data": {"user": {"name": "user %s"}}}`, in.Variables.ID)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if batched {
+		mustWrite(w, "["+strings.Join(outs, ",")+"]")
+	} else {
+		mustWrite(w, outs[0])
+	}
+}
+
+func queryUsers(t *testing.T, client *graphql.Client, ids ...string) {
+	t.Helper()
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var q struct {
+				User struct{ Name graphql.String } `graphql:"user(id:$id)"`
+			}
+			err := client.Query(context.Background(), &q, map[string]any{"id": graphql.ID(id)})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if got, want := q.User.Name, graphql.String("user "+id); got != want {
+				t.Errorf("got name: %q, want: %q", got, want)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestWithBatching(t *testing.T) {
+	var h batchHandler
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: &h}},
+		graphql.WithBatching(50*time.Millisecond, 0))
+	queryUsers(t, client, "1", "2", "3")
+	if got, want := h.sizes, []int{3}; !slices.Equal(got, want) {
+		t.Errorf("got requests of sizes: %v, want: %v", got, want)
+	}
+
+	// A query without others to share a batch with is sent on its own.
+	h.sizes = nil
+	queryUsers(t, client, "4")
+	if got, want := h.sizes, []int{1}; !slices.Equal(got, want) {
+		t.Errorf("got requests of sizes: %v, want: %v", got, want)
+	}
+}
+
+func TestWithBatching_maxSize(t *testing.T) {
+	var h batchHandler
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: &h}},
+		graphql.WithBatching(time.Hour, 2))
+	queryUsers(t, client, "1", "2")
+	if got, want := h.sizes, []int{2}; !slices.Equal(got, want) {
+		t.Errorf("got requests of sizes: %v, want: %v", got, want)
+	}
+}
+
+func TestWithBatching_mismatch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `[{"data": {"user": {"name": "user 1"}}}]`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithBatching(time.Hour, 2))
+	var wg sync.WaitGroup
+	for _, id := range []string{"1", "2"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var q struct {
+				User struct{ Name graphql.String } `graphql:"user(id:$id)"`
+			}
+			err := client.Query(context.Background(), &q, map[string]any{"id": graphql.ID(id)})
+			var de *graphql.DecodeError
+			if !errors.As(err, &de) {
+				t.Errorf("got error: %v, want a *graphql.DecodeError", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestWithBatching_deadlineHeader(t *testing.T) {
+	var h batchHandler
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: &h}},
+		graphql.WithBatching(50*time.Millisecond, 0), graphql.WithDeadlineHeader("Request-Timeout", graphql.TimeoutMillis))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, id := range []string{"1", "2"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var q struct {
+				User struct{ Name graphql.String } `graphql:"user(id:$id)"`
+			}
+			if err := client.Query(ctx, &q, map[string]any{"id": graphql.ID(id)}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	// Queries with deadlines are sent on their own, with their own headers.
+	if got, want := h.sizes, []int{1, 1}; !slices.Equal(got, want) {
+		t.Errorf("got requests of sizes: %v, want: %v", got, want)
+	}
+}
+
+func TestWithBatching_stats(t *testing.T) {
+	var h batchHandler
+	var mu sync.Mutex
+	var stats []graphql.Stats
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: &h}},
+		graphql.WithBatching(time.Hour, 2),
+		graphql.WithStatsHandler(func(ctx context.Context, s graphql.Stats) {
+			mu.Lock()
+			stats = append(stats, s)
+			mu.Unlock()
+		}))
+	queryUsers(t, client, "1", "2")
+	if got, want := len(stats), 2; got != want {
+		t.Fatalf("got %d stats, want: %d", got, want)
+	}
+	for _, s := range stats {
+		if s.BytesSent == 0 {
+			t.Error("got no bytes sent")
+		}
+		if got, want := s.BytesReceived, int64(len(`{"data": {"user": {"name": "user 1"}}}`)); got != want {
+			t.Errorf("got bytes received: %d, want: %d", got, want)
+		}
+	}
+}
+
+func TestWithBatching_canceled(t *testing.T) {
+	var h batchHandler
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: &h}},
+		graphql.WithBatching(time.Hour, 0))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var q struct {
+		User struct{ Name graphql.String } `graphql:"user(id:$id)"`
+	}
+	err := client.Query(ctx, &q, map[string]any{"id": graphql.ID("1")})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error: %v, want: %v", err, context.Canceled)
+	}
+	if got, want := graphql.Classify(err), graphql.KindNetwork; got != want {
+		t.Errorf("got kind: %v, want: %v", got, want)
+	}
+}
+
+func TestWithBatchKey(t *testing.T) {
+	var h batchHandler
+	var mu sync.Mutex
+	var tenants []any // Tenants of the contexts of HTTP requests, by batch size.
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		tenants = append(tenants, req.Context().Value(tenantKey{}))
+		mu.Unlock()
+		h.ServeHTTP(w, req)
+	})
+	queryTenants := func(client *graphql.Client, tenants ...string) {
+		t.Helper()
+		var wg sync.WaitGroup
+		for i, tenant := range tenants {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ctx := context.WithValue(context.Background(), tenantKey{}, tenant)
+				var q struct {
+					User struct{ Name graphql.String } `graphql:"user(id:$id)"`
+				}
+				if err := client.Query(ctx, &q, map[string]any{"id": graphql.ID(fmt.Sprint(i))}); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	// Without a batch key, batches are sent without the values of their queries' contexts.
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}},
+		graphql.WithBatching(time.Hour, 2))
+	queryTenants(client, "a", "b")
+	if got, want := h.sizes, []int{2}; !slices.Equal(got, want) {
+		t.Errorf("got requests of sizes: %v, want: %v", got, want)
+	}
+	if got, want := tenants, []any{nil}; !slices.Equal(got, want) {
+		t.Errorf("got request tenants: %v, want: %v", got, want)
+	}
+
+	// Queries of different tenants aren't batched together.
+	h.sizes, tenants = nil, nil
+	client = graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}},
+		graphql.WithBatching(20*time.Millisecond, 2),
+		graphql.WithBatchKey(func(ctx context.Context) string { return ctx.Value(tenantKey{}).(string) }))
+	queryTenants(client, "a", "b")
+	if got, want := h.sizes, []int{1, 1}; !slices.Equal(got, want) {
+		t.Errorf("got requests of sizes: %v, want: %v", got, want)
+	}
+	slices.SortFunc(tenants, func(a, b any) int { return strings.Compare(a.(string), b.(string)) })
+	if got, want := tenants, []any{"a", "b"}; !slices.Equal(got, want) {
+		t.Errorf("got request tenants: %v, want: %v", got, want)
+	}
+	queryTenants(client, "a", "a")
+	if got, want := h.sizes, []int{1, 1, 2}; !slices.Equal(got, want) {
+		t.Errorf("got requests of sizes: %v, want: %v", got, want)
+	}
+}
@@ -0,0 +1,74 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+)
+
+// OverflowPolicy is what a subscription does with a new event when its buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until the subscriber receives an event. It's the default.
+	// Over a shared WebSocket connection, the results of the subscription then
+	// queue in memory, rather than holding up the connection's other subscriptions,
+	// and the subscription stops with ErrSubscriptionOverflow if the queue stays
+	// full. Over Server-Sent Events, the stream isn't read meanwhile.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropOldest discards the oldest buffered event to make room.
+	OverflowDropOldest
+
+	// OverflowError stops the subscription with ErrSubscriptionOverflow.
+	OverflowError
+)
+
+// ErrSubscriptionOverflow is the error that stops a subscription whose buffer
+// overflows under OverflowError, or whose queue on a shared WebSocket connection
+// overflows. Such subscriptions aren't reconnected by default.
+var ErrSubscriptionOverflow = errors.New("graphql: subscription buffer overflowed")
+
+// WithSubscriptionBuffer sets how many events each subscription buffers for
+// a subscriber that's slow to receive them, and what it does when its buffer
+// is full. The default is no buffer and OverflowBlock. The size is at least one
+// with OverflowDropOldest and OverflowError.
+func WithSubscriptionBuffer(size int, policy OverflowPolicy) ClientOption {
+	if policy != OverflowBlock {
+		size = max(size, 1)
+	}
+	return func(c *Client) {
+		c.subscriptionBuffer = max(size, 0)
+		c.subscriptionOverflow = policy
+	}
+}
+
+// send sends ev to the subscriber according to s.overflow.
+func (s *Subscription) send(ctx context.Context, ev Event) error {
+	switch s.overflow {
+	case OverflowDropOldest:
+		for {
+			select {
+			case s.events <- ev:
+				return nil
+			default:
+			}
+			select {
+			case <-s.events:
+			default:
+			}
+		}
+	case OverflowError:
+		select {
+		case s.events <- ev:
+			return nil
+		default:
+			return ErrSubscriptionOverflow
+		}
+	}
+	select {
+	case s.events <- ev:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
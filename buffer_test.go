@@ -0,0 +1,64 @@
+package graphql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+// subscribeUnread starts a subscription with a server that sends three results
+// and completes, and returns it once it has stopped, without receiving events.
+func subscribeUnread(t *testing.T, policy graphql.OverflowPolicy) *graphql.Subscription {
+	t.Helper()
+	srv := transportWSServer(t, func(payload map[string]any, send func(typ, payload string)) {
+		send("next", `{"data":{"tick":1}}`)
+		send("next", `{"data":{"tick":2}}`)
+		send("next", `{"data":{"tick":3}}`)
+		send("complete", "")
+	}, nil)
+	t.Cleanup(srv.Close)
+	stopped := make(chan struct{})
+	client := graphql.NewClient(srv.URL, nil,
+		graphql.WithSubscriptionBuffer(1, policy),
+		graphql.WithSubscriptionHooks(graphql.SubscriptionHooks{
+			Completed: func(context.Context, string, error) { close(stopped) },
+		}))
+
+	sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-stopped
+	return sub
+}
+
+func TestClient_Subscribe_overflowDropOldest(t *testing.T) {
+	sub := subscribeUnread(t, graphql.OverflowDropOldest)
+
+	var got []string
+	for ev := range sub.Events() {
+		got = append(got, string(ev.Data))
+	}
+	if len(got) != 1 || got[0] != `{"tick":3}` {
+		t.Errorf("got events: %q, want: %q", got, []string{`{"tick":3}`})
+	}
+	if err := sub.Err(); err != nil {
+		t.Errorf("got error: %v, want: nil", err)
+	}
+}
+
+func TestClient_Subscribe_overflowError(t *testing.T) {
+	sub := subscribeUnread(t, graphql.OverflowError)
+
+	var got []string
+	for ev := range sub.Events() {
+		got = append(got, string(ev.Data))
+	}
+	if len(got) != 1 || got[0] != `{"tick":1}` {
+		t.Errorf("got events: %q, want: %q", got, []string{`{"tick":1}`})
+	}
+	if got, want := sub.Err(), graphql.ErrSubscriptionOverflow; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
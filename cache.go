@@ -0,0 +1,280 @@
+package graphql
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/isihu/graphql/ast"
+)
+
+// Cache is a store of cached query results, which lets clients in several
+// instances of a service share results through a store such as Redis,
+// groupcache or BigCache. Values are opaque to the store. Errors are
+// treated as cache misses by the client, so a failing store makes
+// queries go to the server rather than fail.
+type Cache interface {
+	// Get returns the value stored under key, and whether there's one.
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+
+	// Set stores value under key for ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// Delete removes the value stored under key, if any.
+	Delete(ctx context.Context, key string) error
+}
+
+// WithCache makes the client cache the data of successful queries sent by
+// Query and Do in memory for ttl, and answer repeats of them from the cache
+// instead of sending them. Queries are cached by their normalized document
+// and variables, so documents differing only in whitespace share entries,
+// unless WithCacheKeyFunc says otherwise.
+// Responses with errors aren't cached, unless WithNegativeCache says
+// otherwise, and neither are mutations and subscriptions.
+//
+// Per-call options WithCacheTTL, WithoutCache and WithCacheRefresh override
+// the TTL, bypass the cache and refresh the cached result, respectively.
+//
+// The cache isn't bounded in size, other than by the expiry of results,
+// which are removed as others are stored. To bound it, use WithCacheStore
+// with a MemoryCache that has limits.
+func WithCache(ttl time.Duration) ClientOption {
+	return WithCacheStore(NewMemoryCache(0, 0), ttl)
+}
+
+// WithCacheStore is like WithCache, but caches results in store.
+func WithCacheStore(store Cache, ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		c.cache = store
+		c.cacheTTL = ttl
+	}
+}
+
+// WithStaleWhileRevalidate makes the client answer queries whose cached
+// results expired at most maxStale ago with the stale results right away,
+// while refreshing them in the background, instead of waiting for the
+// server. Results older than that are refreshed before answering.
+// It applies to the cache set by WithCache or WithCacheStore.
+func WithStaleWhileRevalidate(maxStale time.Duration) ClientOption {
+	return func(c *Client) { c.maxStale = maxStale }
+}
+
+// WithNegativeCache makes the client cache the results of queries that
+// failed with errors that will recur if the queries are sent again, and
+// answer repeats of them with the same errors, so that retries of such
+// queries, e.g., by many users loading a page about a deleted item, don't
+// all reach the server. Results are cached for ttl, which is typically
+// short, if every error they have has one of codes. Without codes,
+// errors with the codes NOT_FOUND, GRAPHQL_PARSE_FAILED and
+// GRAPHQL_VALIDATION_FAILED are cached. It applies to the cache set by
+// WithCache or WithCacheStore.
+func WithNegativeCache(ttl time.Duration, codes ...string) ClientOption {
+	if len(codes) == 0 {
+		codes = []string{"NOT_FOUND", "GRAPHQL_PARSE_FAILED", "GRAPHQL_VALIDATION_FAILED"}
+	}
+	return func(c *Client) {
+		c.negativeTTL = ttl
+		c.negativeCodes = make(map[string]bool, len(codes))
+		for _, code := range codes {
+			c.negativeCodes[code] = true
+		}
+	}
+}
+
+// cacheFailure caches errs, the errors of a query, along with its data,
+// if any, under key, if they're errors that WithNegativeCache caches.
+// Failures are cached as a JSON array of the data and errors, which
+// successful results, being objects, aren't mistaken for.
+func (c *Client) cacheFailure(ctx context.Context, key string, data json.RawMessage, errs error) {
+	e, ok := errs.(Errors)
+	if !ok || c.negativeTTL <= 0 {
+		return
+	}
+	for _, err := range e {
+		if !c.negativeCodes[err.Code()] {
+			return
+		}
+	}
+	v, err := json.Marshal([]any{data, e})
+	if err != nil {
+		return
+	}
+	c.cache.Set(ctx, key, encodeCacheValue(v, time.Now().Add(c.negativeTTL)), c.negativeTTL)
+}
+
+// decodeCached decodes the cached data v into res, or, if v is a cached
+// failure, decodes its data and returns its errors as a response would.
+func (c *Client) decodeCached(cfg *callConfig, v []byte, res any, merge bool) error {
+	if len(v) == 0 || v[0] != '[' {
+		return c.decodeData(cfg, v, res, merge)
+	}
+	// Decoding into raw messages keeps a null data element, which decoding
+	// into a pointer would drop.
+	var failure []json.RawMessage
+	if err := json.Unmarshal(v, &failure); err != nil {
+		return &DecodeError{Err: err}
+	}
+	if len(failure) != 2 {
+		return &DecodeError{Err: errors.New("malformed cached failure")}
+	}
+	data := failure[0]
+	var cached Errors
+	if err := json.Unmarshal(failure[1], &cached); err != nil {
+		return &DecodeError{Err: err}
+	}
+	hasData := len(data) > 0 && string(data) != "null"
+	c.prepareErrors(cached)
+	errs := cached.withoutCodes(cfg.ignoredCodes)
+	if len(errs) > 0 && cfg.errorPolicy == ErrorPolicyNone {
+		return errs
+	}
+	if hasData {
+		if err := c.decodeData(cfg, data, res, merge); err != nil {
+			return err
+		}
+	}
+	if len(errs) > 0 && cfg.errorPolicy != ErrorPolicyIgnore {
+		if hasData {
+			return &PartialDataError{Errors: errs}
+		}
+		return errs
+	}
+	return nil
+}
+
+// revalidate refreshes the cached result of the query with variables,
+// sent with cfg, in the background, unless it's already being refreshed.
+func (c *Client) revalidate(ctx context.Context, cfg *callConfig, key, query string, variables map[string]any) {
+	if _, refreshing := c.revalidating.LoadOrStore(key, true); refreshing {
+		return
+	}
+	refresh := &callConfig{
+		url:          cfg.url,
+		extensions:   cfg.extensions,
+		cacheTTL:     cfg.cacheTTL,
+		ignoredCodes: cfg.ignoredCodes,
+		errorPolicy:  cfg.errorPolicy,
+		refreshCache: true,
+	}
+	go func() {
+		defer c.revalidating.Delete(key)
+		// The refresh outlives the operation that triggered it.
+		c.do(context.WithoutCancel(ctx), refresh, query, nil, false, variables)
+	}()
+}
+
+// encodeCacheValue returns the value to cache for data, fresh until expires.
+// Values outlive their freshness by the client's maximum staleness.
+func encodeCacheValue(data []byte, expires time.Time) []byte {
+	v := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint64(v, uint64(expires.UnixNano()))
+	return append(v, data...)
+}
+
+// decodeCacheValue returns the data in the cached value v, and when it expires.
+func decodeCacheValue(v []byte) (data []byte, expires time.Time, ok bool) {
+	if len(v) < 8 {
+		return nil, time.Time{}, false
+	}
+	return v[8:], time.Unix(0, int64(binary.BigEndian.Uint64(v))), true
+}
+
+// InvalidateCache removes the result of the query derived from q with
+// variables, as sent by Query with opts, from the client's cache, and the
+// results of its root fields from the normalized cache. Only the options
+// that results are cached by, WithURL and WithExtensions, matter.
+func (c *Client) InvalidateCache(ctx context.Context, q any, variables map[string]any, opts ...CallOption) error {
+	cfg := newCallConfig(opts)
+	query := constructQuery(q, variables)
+	if len(c.rewriters) > 0 {
+		// Results are cached by the documents sent, as rewritten.
+		var err error
+		query, err = c.rewrite(query)
+		if err != nil {
+			return err
+		}
+	}
+	if c.entities != nil {
+		if doc, err := ast.Parse(query); err == nil {
+			c.entities.invalidate(doc, variables)
+		}
+	}
+	if c.cache == nil {
+		return nil
+	}
+	key := c.cacheKeyOf(ctx, cfg, query, variables)
+	if key == "" {
+		return nil
+	}
+	return c.cache.Delete(ctx, key)
+}
+
+// WithCacheTTL makes the result of a query be cached for ttl,
+// instead of the TTL set by WithCache.
+func WithCacheTTL(ttl time.Duration) CallOption {
+	return func(cfg *callConfig) { cfg.cacheTTL = ttl }
+}
+
+// WithoutCache makes a query bypass the client's caches, set by WithCache,
+// WithNormalizedCache and WithConditionalRequests: it's sent to the server
+// even if it's cached, and its result isn't cached.
+func WithoutCache() CallOption {
+	return func(cfg *callConfig) { cfg.noCache = true }
+}
+
+// WithCacheRefresh makes a query be sent to the server even if it's cached,
+// and its result replace the cached one, e.g., after a change the cache
+// doesn't know about.
+func WithCacheRefresh() CallOption {
+	return func(cfg *callConfig) { cfg.refreshCache = true }
+}
+
+// CacheKeyFunc returns the key under which the result of the query with the
+// GraphQL document query and variables, sent with ctx, is cached.
+// If it returns "" or an error, the result isn't cached. The client scopes
+// the key to the URL the query is sent to, its operation name and its
+// request extensions, so results from one endpoint aren't served for another.
+type CacheKeyFunc func(ctx context.Context, query string, variables map[string]any) (string, error)
+
+// WithCacheKeyFunc makes the client derive cache keys with key instead
+// of DefaultCacheKey, e.g., to include a tenant ID from the context, or to
+// leave out volatile variables. Wrapping DefaultCacheKey keeps the
+// normalization of documents.
+func WithCacheKeyFunc(key CacheKeyFunc) ClientOption {
+	return func(c *Client) { c.cacheKey = key }
+}
+
+// DefaultCacheKey is the CacheKeyFunc used by default. It returns the
+// hex-encoded SHA-256 hash of the normalized document and the variables,
+// so documents differing only in whitespace share keys.
+// Documents that don't parse are hashed as is.
+func DefaultCacheKey(ctx context.Context, query string, variables map[string]any) (string, error) {
+	if doc, err := ast.Parse(query); err == nil {
+		query = doc.String()
+	}
+	vars, err := json.Marshal(variables)
+	if err != nil {
+		return "", err
+	}
+	return documentHash(query + "\x00" + string(vars)), nil
+}
+
+// cacheKeyOf returns the cache key of the query with variables sent with ctx
+// and cfg, or "" if its result isn't cached.
+func (c *Client) cacheKeyOf(ctx context.Context, cfg *callConfig, query string, variables map[string]any) string {
+	key := DefaultCacheKey
+	if c.cacheKey != nil {
+		key = c.cacheKey
+	}
+	k, err := key(ctx, query, variables)
+	if err != nil || k == "" {
+		return ""
+	}
+	ext, err := json.Marshal(cfg.extensions)
+	if err != nil {
+		return ""
+	}
+	return documentHash(c.endpoint(cfg, "query") + "\x00" + cfg.operationName + "\x00" + string(ext) + "\x00" + k)
+}
@@ -0,0 +1,479 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/isihu/graphql"
+	"github.com/isihu/graphql/ast"
+)
+
+func TestWithCache(t *testing.T) {
+	var calls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"flag": {"enabled": true}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithCache(time.Hour))
+	query := func(doc string, variables map[string]any, opts ...graphql.CallOption) {
+		t.Helper()
+		var res struct {
+			Flag struct{ Enabled graphql.Boolean }
+		}
+		err := client.Do(context.Background(), doc, &res, false, variables, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.Flag.Enabled {
+			t.Errorf("got flag disabled, want enabled")
+		}
+	}
+
+	query(`query($name:String!){flag(name:$name){enabled}}`, map[string]any{"name": "dark-mode"})
+	query("query ($name: String!) {\n  flag(name: $name) { enabled }\n}", map[string]any{"name": "dark-mode"})
+	if got, want := calls, 1; got != want {
+		t.Errorf("got %d requests after a repeated query, want: %d", got, want)
+	}
+	query(`query($name:String!){flag(name:$name){enabled}}`, map[string]any{"name": "beta"})
+	if got, want := calls, 2; got != want {
+		t.Errorf("got %d requests after a query with other variables, want: %d", got, want)
+	}
+	query(`query($name:String!){flag(name:$name){enabled}}`, map[string]any{"name": "beta"}, graphql.WithoutCache())
+	if got, want := calls, 3; got != want {
+		t.Errorf("got %d requests after a query bypassing the cache, want: %d", got, want)
+	}
+	query(`{flag(name:"short"){enabled}}`, nil, graphql.WithCacheTTL(time.Nanosecond))
+	time.Sleep(time.Millisecond)
+	query(`{flag(name:"short"){enabled}}`, nil)
+	if got, want := calls, 5; got != want {
+		t.Errorf("got %d requests after an expired query, want: %d", got, want)
+	}
+}
+
+func TestWithCache_errors(t *testing.T) {
+	var calls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"flag": null}, "errors": [{"message": "flag service unavailable"}]}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithCache(time.Hour))
+
+	for range 2 {
+		var res struct {
+			Flag *struct{ Enabled graphql.Boolean }
+		}
+		client.Do(context.Background(), `{flag(name:"dark-mode"){enabled}}`, &res, false, nil)
+	}
+	if got, want := calls, 2; got != want {
+		t.Errorf("got %d requests, want: %d", got, want)
+	}
+}
+
+// mapCache is a graphql.Cache that records the TTLs it's given,
+// and fails if err is set.
+type mapCache struct {
+	values map[string][]byte
+	ttls   []time.Duration
+	err    error
+}
+
+func (m *mapCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	v, ok := m.values[key]
+	return v, ok, m.err
+}
+
+func (m *mapCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.values[key] = value
+	m.ttls = append(m.ttls, ttl)
+	return m.err
+}
+
+func (m *mapCache) Delete(ctx context.Context, key string) error {
+	delete(m.values, key)
+	return m.err
+}
+
+func TestWithCacheStore(t *testing.T) {
+	var calls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"flag": {"enabled": true}}}`)
+	})
+	store := &mapCache{values: make(map[string][]byte)}
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithCacheStore(store, time.Minute))
+	var q struct {
+		Flag struct{ Enabled graphql.Boolean } `graphql:"flag(name:$name)"`
+	}
+	variables := map[string]any{"name": graphql.String("dark-mode")}
+	query := func() {
+		t.Helper()
+		if err := client.Query(context.Background(), &q, variables); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	query()
+	query()
+	if got, want := calls, 1; got != want {
+		t.Errorf("got %d requests, want: %d", got, want)
+	}
+	if got, want := store.ttls, []time.Duration{time.Minute}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("got TTLs: %v, want: %v", got, want)
+	}
+	if err := client.InvalidateCache(context.Background(), &q, variables); err != nil {
+		t.Fatal(err)
+	}
+	query()
+	if got, want := calls, 2; got != want {
+		t.Errorf("got %d requests after invalidating the cache, want: %d", got, want)
+	}
+
+	// A failing store doesn't fail queries.
+	store.err = errors.New("connection refused")
+	query()
+	if got, want := calls, 3; got != want {
+		t.Errorf("got %d requests with a failing store, want: %d", got, want)
+	}
+}
+
+func TestWithStaleWhileRevalidate(t *testing.T) {
+	var calls atomic.Int32
+	refreshed := make(chan struct{}, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, fmt.Sprintf(`{"data": {"config": {"version": %d}}}`, n))
+		if n == 2 {
+			refreshed <- struct{}{}
+		}
+	})
+	var statuses []string
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithCache(time.Millisecond),
+		graphql.WithStaleWhileRevalidate(time.Hour),
+		graphql.WithStatsHandler(func(ctx context.Context, s graphql.Stats) { statuses = append(statuses, s.CacheStatus) }))
+	query := func() int {
+		t.Helper()
+		var q struct {
+			Config struct{ Version int }
+		}
+		if err := client.Query(context.Background(), &q, nil); err != nil {
+			t.Fatal(err)
+		}
+		return q.Config.Version
+	}
+
+	if got, want := query(), 1; got != want {
+		t.Errorf("got version: %v, want: %v", got, want)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if got, want := query(), 1; got != want {
+		t.Errorf("got stale version: %v, want: %v", got, want)
+	}
+	<-refreshed
+	// The refreshed result is cached shortly after the response is written.
+	got := query()
+	for i := 0; got == 1 && i < 100; i++ {
+		time.Sleep(time.Millisecond)
+		got = query()
+	}
+	if want := 2; got != want {
+		t.Errorf("got refreshed version: %v, want: %v", got, want)
+	}
+	if got, want := statuses[:2], []string{"", "STALE"}; got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got cache statuses: %q, want: %q", got, want)
+	}
+}
+
+func TestWithStaleWhileRevalidate_callOptions(t *testing.T) {
+	var calls atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, fmt.Sprintf(`{"data": {"config": {"version": %d}}, "errors": [{"message": "deprecated", "extensions": {"code": "DEPRECATED"}}]}`, n))
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithCache(time.Millisecond),
+		graphql.WithStaleWhileRevalidate(time.Hour))
+	query := func() int {
+		t.Helper()
+		var q struct {
+			Config struct{ Version int }
+		}
+		if err := client.Query(context.Background(), &q, nil, graphql.WithIgnoredErrorCodes("DEPRECATED")); err != nil {
+			t.Fatal(err)
+		}
+		return q.Config.Version
+	}
+
+	query()
+	time.Sleep(5 * time.Millisecond)
+	// Refreshes ignore the same errors as the queries that trigger them,
+	// so their results are cached.
+	got := query()
+	for i := 0; got == 1 && i < 100; i++ {
+		time.Sleep(time.Millisecond)
+		got = query()
+	}
+	if got == 1 {
+		t.Error("got the stale version, want a refreshed one")
+	}
+}
+
+func TestClient_InvalidateCache_rewriter(t *testing.T) {
+	var calls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"flag": {"enabled": true}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithCache(time.Hour),
+		graphql.WithDocumentRewriter(func(doc *ast.Document) error {
+			doc.Operations()[0].Name = "Flag"
+			return nil
+		}))
+	var q struct {
+		Flag struct{ Enabled graphql.Boolean }
+	}
+	for range 2 {
+		if err := client.Query(context.Background(), &q, nil); err != nil {
+			t.Fatal(err)
+		}
+		// Results are invalidated by the documents sent, as rewritten.
+		if err := client.InvalidateCache(context.Background(), &q, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := calls, 2; got != want {
+		t.Errorf("got %d requests, want: %d", got, want)
+	}
+}
+
+func TestWithCache_endpoints(t *testing.T) {
+	var calls int
+	mux := http.NewServeMux()
+	for _, path := range []string{"/graphql", "/canary"} {
+		mux.HandleFunc(path, func(w http.ResponseWriter, req *http.Request) {
+			calls++
+			var in struct{ Extensions map[string]any }
+			mustUnmarshal(mustRead(req.Body), &in)
+			w.Header().Set("Content-Type", "application/json")
+			mustWrite(w, fmt.Sprintf(`{"data": {"version": "%s%v"}}`, path, in.Extensions["locale"]))
+		})
+	}
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithCache(time.Hour))
+	query := func(opts ...graphql.CallOption) string {
+		t.Helper()
+		var q struct{ Version string }
+		if err := client.Query(context.Background(), &q, nil, opts...); err != nil {
+			t.Fatal(err)
+		}
+		return q.Version
+	}
+
+	// Results are cached by the endpoint and extensions they're from.
+	for range 2 {
+		if got, want := query(), "/graphql<nil>"; got != want {
+			t.Errorf("got version: %q, want: %q", got, want)
+		}
+		if got, want := query(graphql.WithURL("/canary")), "/canary<nil>"; got != want {
+			t.Errorf("got canary version: %q, want: %q", got, want)
+		}
+		if got, want := query(graphql.WithExtensions(map[string]any{"locale": "fr"})), "/graphqlfr"; got != want {
+			t.Errorf("got version with extensions: %q, want: %q", got, want)
+		}
+	}
+	if got, want := calls, 3; got != want {
+		t.Errorf("got %d requests, want: %d", got, want)
+	}
+	if err := client.InvalidateCache(context.Background(), &struct{ Version string }{}, nil, graphql.WithURL("/canary")); err != nil {
+		t.Fatal(err)
+	}
+	query()
+	query(graphql.WithURL("/canary"))
+	if got, want := calls, 4; got != want {
+		t.Errorf("got %d requests after invalidating the canary's result, want: %d", got, want)
+	}
+}
+
+type tenantKey struct{}
+
+func TestWithCacheKeyFunc(t *testing.T) {
+	var calls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"flag": {"enabled": true}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithCache(time.Hour),
+		graphql.WithCacheKeyFunc(func(ctx context.Context, query string, variables map[string]any) (string, error) {
+			tenant, _ := ctx.Value(tenantKey{}).(string)
+			if tenant == "" {
+				return "", nil
+			}
+			// Leave out the volatile request ID.
+			vars := maps.Clone(variables)
+			delete(vars, "requestID")
+			key, err := graphql.DefaultCacheKey(ctx, query, vars)
+			return tenant + ":" + key, err
+		}))
+	query := func(tenant, requestID string) {
+		t.Helper()
+		ctx := context.WithValue(context.Background(), tenantKey{}, tenant)
+		var q struct {
+			Flag struct{ Enabled graphql.Boolean } `graphql:"flag(name:\"dark-mode\",requestID:$requestID)"`
+		}
+		if err := client.Query(ctx, &q, map[string]any{"requestID": graphql.String(requestID)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	query("acme", "1")
+	query("acme", "2")
+	if got, want := calls, 1; got != want {
+		t.Errorf("got %d requests for one tenant, want: %d", got, want)
+	}
+	query("globex", "3")
+	if got, want := calls, 2; got != want {
+		t.Errorf("got %d requests for two tenants, want: %d", got, want)
+	}
+	query("", "4")
+	query("", "5")
+	if got, want := calls, 4; got != want {
+		t.Errorf("got %d requests after uncached queries, want: %d", got, want)
+	}
+}
+
+func TestWithCacheRefresh(t *testing.T) {
+	var calls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, fmt.Sprintf(`{"data": {"config": {"version": %d}}}`, calls))
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithCache(time.Hour))
+	query := func(opts ...graphql.CallOption) int {
+		t.Helper()
+		var q struct {
+			Config struct{ Version int }
+		}
+		if err := client.Query(context.Background(), &q, nil, opts...); err != nil {
+			t.Fatal(err)
+		}
+		return q.Config.Version
+	}
+
+	for _, tc := range []struct {
+		opts []graphql.CallOption
+		want int
+	}{
+		{want: 1},
+		{want: 1},
+		{opts: []graphql.CallOption{graphql.WithCacheRefresh()}, want: 2},
+		{want: 2},
+		{opts: []graphql.CallOption{graphql.WithoutCache()}, want: 3},
+		{want: 2},
+	} {
+		if got := query(tc.opts...); got != tc.want {
+			t.Errorf("got version: %v, want: %v", got, tc.want)
+		}
+	}
+}
+
+func TestWithNegativeCache(t *testing.T) {
+	var calls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		var in struct{ Variables map[string]string }
+		mustUnmarshal(mustRead(req.Body), &in)
+		w.Header().Set("Content-Type", "application/json")
+		switch in.Variables["id"] {
+		case "invalid":
+			mustWrite(w, `{"errors": [{"message": "bad", "extensions": {"code": "GRAPHQL_VALIDATION_FAILED"}}]}`)
+		case "deleted":
+			mustWrite(w, `{"data": {"user": null}, "errors": [{"message": "user not found", "path": ["user"], "extensions": {"code": "NOT_FOUND"}}]}`)
+		default:
+			mustWrite(w, `{"errors": [{"message": "service unavailable", "extensions": {"code": "INTERNAL_SERVER_ERROR"}}]}`)
+		}
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithCache(time.Hour), graphql.WithNegativeCache(time.Minute))
+	const doc = `query($id:ID!){user(id:$id){login}}`
+	query := func(id string, opts ...graphql.CallOption) error {
+		var res struct {
+			User *struct{ Login graphql.String }
+		}
+		return client.Do(context.Background(), doc, &res, false, map[string]any{"id": id}, opts...)
+	}
+
+	for range 2 {
+		err := query("deleted")
+		var partial *graphql.PartialDataError
+		if !errors.As(err, &partial) {
+			t.Fatalf("got error: %v, want a *graphql.PartialDataError", err)
+		}
+		if !errors.Is(err, graphql.ErrNotFound) {
+			t.Errorf("got error: %v, want graphql.ErrNotFound", err)
+		}
+		if got, want := partial.Errors[0].Path, []any{"user"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got path: %v, want: %v", got, want)
+		}
+	}
+	if got, want := calls, 1; got != want {
+		t.Errorf("got %d requests after a repeated NOT_FOUND query, want: %d", got, want)
+	}
+	if err := query("deleted", graphql.WithIgnoredErrorCodes("NOT_FOUND")); err != nil {
+		t.Errorf("got error with NOT_FOUND ignored: %v", err)
+	}
+
+	// Failures without data are cached too.
+	for range 2 {
+		err := query("invalid")
+		var errs graphql.Errors
+		if !errors.As(err, &errs) || !graphql.HasErrorCode(err, "GRAPHQL_VALIDATION_FAILED") {
+			t.Errorf("got error: %v, want GRAPHQL_VALIDATION_FAILED", err)
+		}
+		if got, want := graphql.Classify(err), graphql.KindGraphQL; got != want {
+			t.Errorf("got kind: %v, want: %v", got, want)
+		}
+	}
+	if got, want := calls, 2; got != want {
+		t.Errorf("got %d requests after a repeated invalid query, want: %d", got, want)
+	}
+
+	// Other errors may be transient, so they aren't cached.
+	for range 2 {
+		if err := query("1"); !graphql.HasErrorCode(err, "INTERNAL_SERVER_ERROR") {
+			t.Errorf("got error: %v, want INTERNAL_SERVER_ERROR", err)
+		}
+	}
+	if got, want := calls, 4; got != want {
+		t.Errorf("got %d requests after a repeated failing query, want: %d", got, want)
+	}
+}
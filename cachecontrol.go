@@ -0,0 +1,83 @@
+package graphql
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithServerCacheHints makes the cache set by WithCache or WithCacheStore
+// keep results for as long as the server says they're fresh, instead of
+// its single TTL. Servers give hints in the Cache-Control header of
+// responses, such as "max-age=60, private", and in the "cacheControl"
+// response extension of Apollo Server, whose shortest max-age applies.
+// Results that are hinted as not cacheable, with no-store or a max-age
+// of 0, aren't cached. Results without hints are cached for the TTL.
+// WithCacheTTL still takes precedence over hints.
+//
+// If shared is true, the cache is shared between users, e.g., in a store
+// used by a multi-tenant service, and results hinted as private to a user
+// aren't cached.
+func WithServerCacheHints(shared bool) ClientOption {
+	return func(c *Client) {
+		c.cacheHints = true
+		c.sharedCache = shared
+	}
+}
+
+// cachePolicy is the caching policy of a response, as hinted by the server.
+type cachePolicy struct {
+	maxAge    time.Duration // How long the response is fresh, if hasMaxAge.
+	hasMaxAge bool
+	private   bool // Whether the response is private to a user.
+}
+
+// maxAgeHint adds a hint that a response is fresh for maxAge to p.
+func (p *cachePolicy) maxAgeHint(maxAge time.Duration) {
+	if !p.hasMaxAge || maxAge < p.maxAge {
+		p.maxAge = maxAge
+	}
+	p.hasMaxAge = true
+}
+
+// cachePolicy returns the caching policy hinted by the server in out.
+func (out *response) cachePolicy() cachePolicy {
+	p := parseCacheControl(out.header)
+	var ext struct {
+		Hints []struct {
+			MaxAge int    `json:"maxAge"`
+			Scope  string `json:"scope"`
+		} `json:"hints"`
+	}
+	if raw, ok := out.Extensions["cacheControl"]; ok && json.Unmarshal(raw, &ext) == nil {
+		for _, h := range ext.Hints {
+			p.maxAgeHint(time.Duration(h.MaxAge) * time.Second)
+			p.private = p.private || strings.EqualFold(h.Scope, "PRIVATE")
+		}
+	}
+	return p
+}
+
+// parseCacheControl returns the caching policy given by the Cache-Control header in h.
+func parseCacheControl(h http.Header) cachePolicy {
+	var p cachePolicy
+	for _, v := range h.Values("Cache-Control") {
+		for _, directive := range strings.Split(v, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			switch strings.ToLower(name) {
+			case "no-store", "no-cache":
+				p.maxAgeHint(0)
+			case "private":
+				p.private = true
+			case "max-age":
+				seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+				if err == nil && seconds >= 0 {
+					p.maxAgeHint(time.Duration(seconds) * time.Second)
+				}
+			}
+		}
+	}
+	return p
+}
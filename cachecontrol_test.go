@@ -0,0 +1,73 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/isihu/graphql"
+)
+
+func TestWithServerCacheHints(t *testing.T) {
+	tests := []struct {
+		name         string
+		cacheControl string
+		extensions   string
+		shared       bool
+		want         []time.Duration // TTLs of the cached results.
+	}{
+		{name: "no hints", want: []time.Duration{time.Minute}},
+		{name: "header", cacheControl: "public, max-age=300", want: []time.Duration{300 * time.Second}},
+		{
+			name:       "extension",
+			extensions: `{"cacheControl": {"version": 1, "hints": [{"path": ["config"], "maxAge": 120}, {"path": ["config", "theme"], "maxAge": 30}]}}`,
+			want:       []time.Duration{30 * time.Second},
+		},
+		{
+			name:         "header and extension",
+			cacheControl: "max-age=10",
+			extensions:   `{"cacheControl": {"version": 1, "hints": [{"path": ["config"], "maxAge": 120}]}}`,
+			want:         []time.Duration{10 * time.Second},
+		},
+		{name: "no-store", cacheControl: "no-store"},
+		{name: "max-age=0", cacheControl: "max-age=0"},
+		{name: "private", cacheControl: "max-age=60, private", want: []time.Duration{time.Minute}},
+		{name: "private in shared cache", cacheControl: "max-age=60, private", shared: true},
+		{
+			name:       "private extension in shared cache",
+			extensions: `{"cacheControl": {"version": 1, "hints": [{"path": ["config"], "maxAge": 60, "scope": "PRIVATE"}]}}`,
+			shared:     true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if tc.cacheControl != "" {
+					w.Header().Set("Cache-Control", tc.cacheControl)
+				}
+				ext := ""
+				if tc.extensions != "" {
+					ext = `, "extensions": ` + tc.extensions
+				}
+				mustWrite(w, `{"data": {"config": {"theme": "dark"}}`+ext+`}`)
+			})
+			store := &mapCache{values: make(map[string][]byte)}
+			client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+				graphql.WithCacheStore(store, time.Minute),
+				graphql.WithServerCacheHints(tc.shared))
+
+			var q struct {
+				Config struct{ Theme graphql.String }
+			}
+			if err := client.Query(context.Background(), &q, nil); err != nil {
+				t.Fatal(err)
+			}
+			if got := store.ttls; len(got) != len(tc.want) || len(got) == 1 && got[0] != tc.want[0] {
+				t.Errorf("got TTLs: %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/rand/v2"
+	"time"
+)
+
+// Capture is the raw payload of an operation captured by WithPayloadCapture.
+// If the operation was retried, it holds the last attempt.
+type Capture struct {
+	Operation Operation
+	Time      time.Time     // When the operation started.
+	Duration  time.Duration // How long the operation took, including retries.
+
+	Request  []byte // JSON body of the request.
+	Status   int    // Status code of the response, or 0 if there was none.
+	Response []byte // Body of the response, as far as it was read.
+
+	// Err is the error the operation failed with, or nil if it succeeded.
+	Err error
+}
+
+// WithPayloadCapture makes the client capture the request and response
+// payloads of a random sample of the operations sent by Query, Mutate and Do,
+// and pass them to sink after the operations complete, for offline analysis.
+// rate is the fraction of operations captured, between 0 and 1;
+// e.g., 0.01 captures 1% of them. Operations that aren't sampled
+// don't pay for capturing.
+//
+// Captured payloads aren't redacted, so they may contain credentials
+// and personal data in variables and results.
+func WithPayloadCapture(rate float64, sink func(ctx context.Context, c Capture)) ClientOption {
+	return func(c *Client) {
+		c.captureRate = rate
+		c.captureSink = sink
+	}
+}
+
+// sampleCapture reports whether to capture the payload of an operation.
+func (c *Client) sampleCapture() bool {
+	return c.captureSink != nil && c.captureRate > 0 && rand.Float64() < c.captureRate
+}
+
+// payloadCapture is the payload of an operation's last attempt,
+// captured while sending it.
+type payloadCapture struct {
+	request  []byte
+	status   int
+	response bytes.Buffer
+}
+
+// start resets p for a new attempt sending in.
+func (p *payloadCapture) start(in request) {
+	p.request, _ = json.Marshal(in)
+	p.status = 0
+	p.response.Reset()
+}
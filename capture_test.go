@@ -0,0 +1,59 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+func TestWithPayloadCapture(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+	for _, tc := range []struct {
+		rate float64
+		want int
+	}{
+		{rate: 1, want: 3},
+		{rate: 0, want: 0},
+	} {
+		var got []graphql.Capture
+		client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+			graphql.WithPayloadCapture(tc.rate, func(ctx context.Context, c graphql.Capture) { got = append(got, c) }))
+
+		for range 3 {
+			var res struct{ User struct{ Name graphql.String } }
+			err := client.Do(context.Background(), "query GetUser($login:String!){user(login:$login){name}}", &res, false,
+				map[string]any{"login": "gopher"})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if len(got) != tc.want {
+			t.Fatalf("rate %v: got %d captures, want: %d", tc.rate, len(got), tc.want)
+		}
+		if tc.want == 0 {
+			continue
+		}
+		c := got[0]
+		if got, want := c.Operation.Name, "GetUser"; got != want {
+			t.Errorf("got operation name: %q, want: %q", got, want)
+		}
+		if got, want := string(c.Request), `{"query":"query GetUser($login:String!){user(login:$login){name}}","variables":{"login":"gopher"}}`; got != want {
+			t.Errorf("got request:\n%s\nwant:\n%s", got, want)
+		}
+		if got, want := c.Status, http.StatusOK; got != want {
+			t.Errorf("got status: %v, want: %v", got, want)
+		}
+		if got, want := string(c.Response), `{"data": {"user": {"name": "Gopher"}}}`; got != want {
+			t.Errorf("got response: %s, want: %s", got, want)
+		}
+		if c.Err != nil {
+			t.Errorf("got error: %v, want: nil", c.Err)
+		}
+	}
+}
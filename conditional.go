@@ -0,0 +1,104 @@
+package graphql
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// WithConditionalRequests makes the client keep the responses to GET requests
+// that have an ETag or Last-Modified header, and revalidate them on repeats
+// by sending If-None-Match or If-Modified-Since. A 304 Not Modified response
+// is treated as a cache hit, and answered with the kept response.
+// Only GET requests, such as those of persisted queries sent with
+// WithPersistedQueries(true), are conditional, since gateways
+// implement HTTP validation only for them. Responses with errors
+// aren't kept. At most maxResponses responses are kept, and the least
+// recently used are dropped to make room for others; zero means no limit.
+func WithConditionalRequests(maxResponses int) ClientOption {
+	return func(c *Client) {
+		c.validated = &validatedResponses{
+			responses:    make(map[string]*list.Element),
+			lru:          list.New(),
+			maxResponses: maxResponses,
+		}
+	}
+}
+
+// validatedResponses are responses kept for conditional requests, by URL.
+type validatedResponses struct {
+	mu           sync.Mutex
+	responses    map[string]*list.Element // Of lru, holding *validatedResponse.
+	lru          *list.List               // Responses, most recently used first.
+	maxResponses int
+}
+
+// validatedResponse is a response kept for conditional requests.
+type validatedResponse struct {
+	url          string
+	etag         string
+	lastModified string
+	body         []byte
+}
+
+func (v *validatedResponses) get(url string) *validatedResponse {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	el, ok := v.responses[url]
+	if !ok {
+		return nil
+	}
+	v.lru.MoveToFront(el)
+	return el.Value.(*validatedResponse)
+}
+
+func (v *validatedResponses) set(url string, r *validatedResponse) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if el, ok := v.responses[url]; ok {
+		v.lru.Remove(el)
+	}
+	r.url = url
+	v.responses[url] = v.lru.PushFront(r)
+	for v.maxResponses > 0 && v.lru.Len() > v.maxResponses {
+		old := v.lru.Remove(v.lru.Back()).(*validatedResponse)
+		delete(v.responses, old.url)
+	}
+}
+
+// setConditionalHeaders sets the headers in h that make a request
+// conditional on r having changed.
+func (r *validatedResponse) setConditionalHeaders(h http.Header) {
+	if r.etag != "" {
+		h.Set("If-None-Match", r.etag)
+	}
+	if r.lastModified != "" {
+		h.Set("If-Modified-Since", r.lastModified)
+	}
+}
+
+// validatorRecorder records the body of a response with validators,
+// to keep it if the response turns out to be cacheable.
+type validatorRecorder struct {
+	r   validatedResponse
+	buf bytes.Buffer
+}
+
+// newValidatorRecorder returns a recorder for resp, and body teeing into it,
+// or nil and body if resp has no validators.
+func newValidatorRecorder(resp *http.Response, body io.Reader) (*validatorRecorder, io.Reader) {
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return nil, body
+	}
+	rec := &validatorRecorder{r: validatedResponse{etag: etag, lastModified: lastModified}}
+	return rec, io.TeeReader(body, &rec.buf)
+}
+
+// response returns the recorded response.
+func (rec *validatorRecorder) response() *validatedResponse {
+	rec.r.body = rec.buf.Bytes()
+	return &rec.r
+}
@@ -0,0 +1,80 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+func TestWithConditionalRequests(t *testing.T) {
+	var statuses []int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			t.Errorf("got method: %v, want: GET", req.Method)
+		}
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			statuses = append(statuses, http.StatusNotModified)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		statuses = append(statuses, http.StatusOK)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"v1"`)
+		mustWrite(w, `{"data": {"config": {"theme": "dark"}}}`)
+	})
+	var cacheStatuses []string
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithPersistedQueries(true),
+		graphql.WithConditionalRequests(100),
+		graphql.WithStatsHandler(func(ctx context.Context, s graphql.Stats) { cacheStatuses = append(cacheStatuses, s.CacheStatus) }))
+
+	for range 2 {
+		var q struct {
+			Config struct{ Theme graphql.String }
+		}
+		err := client.Query(context.Background(), &q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := q.Config.Theme, graphql.String("dark"); got != want {
+			t.Errorf("got theme: %q, want: %q", got, want)
+		}
+	}
+	if got, want := statuses, []int{http.StatusOK, http.StatusNotModified}; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got statuses: %v, want: %v", got, want)
+	}
+	if got, want := cacheStatuses[1], "HIT"; got != want {
+		t.Errorf("got cache status of the revalidated query: %q, want: %q", got, want)
+	}
+}
+
+func TestWithConditionalRequests_maxResponses(t *testing.T) {
+	var conditional []bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		conditional = append(conditional, req.Header.Get("If-None-Match") != "")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"v1"`)
+		mustWrite(w, `{"data": {"setting": {"value": "1"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithPersistedQueries(true),
+		graphql.WithConditionalRequests(1))
+
+	// The response to the second query makes room by dropping the first's.
+	for _, name := range []string{"theme", "locale", "theme"} {
+		var q struct {
+			Setting struct{ Value graphql.String } `graphql:"setting(name:$name)"`
+		}
+		if err := client.Query(context.Background(), &q, map[string]any{"name": graphql.String(name)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := conditional, []bool{false, false, false}; !slices.Equal(got, want) {
+		t.Errorf("got conditional requests: %v, want: %v", got, want)
+	}
+}
@@ -0,0 +1,63 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/isihu/graphql/internal/websocket"
+)
+
+// WithSubscriptionInitPayload sets the payload of the connection_init message
+// that initializes WebSocket subscription connections, which servers commonly
+// read credentials from, since browsers can't set headers on WebSocket requests.
+// payload is encoded as JSON. Over AbsinthePhoenix, it's the parameters
+// of joining the control channel.
+func WithSubscriptionInitPayload(payload any) ClientOption {
+	return WithSubscriptionInitPayloadFunc(func(context.Context) (any, error) { return payload, nil })
+}
+
+// WithSubscriptionInitPayloadFunc is like WithSubscriptionInitPayload,
+// but calls payload for every new connection, such as to get a fresh token.
+// ctx is the context of the subscription that establishes the connection.
+func WithSubscriptionInitPayloadFunc(payload func(ctx context.Context) (any, error)) ClientOption {
+	return func(c *Client) { c.subscriptionInit = payload }
+}
+
+// ConnectionRejectedError is returned when the server rejects the initialization
+// of a WebSocket subscription connection, such as because of missing or invalid
+// credentials. Subscriptions that fail with it aren't reconnected by default.
+type ConnectionRejectedError struct {
+	// Code is the WebSocket close code that the server closed the connection with,
+	// 4401 Unauthorized or 4403 Forbidden, or zero if it rejected it by a message.
+	Code int
+
+	// Reason is the close reason, if any.
+	Reason string
+
+	// Payload is the payload of the rejecting message, if any.
+	Payload json.RawMessage
+}
+
+func (e *ConnectionRejectedError) Error() string {
+	switch {
+	case e.Code != 0 && e.Reason != "":
+		return fmt.Sprintf("graphql: connection rejected with close code %d: %s", e.Code, e.Reason)
+	case e.Code != 0:
+		return fmt.Sprintf("graphql: connection rejected with close code %d", e.Code)
+	case len(e.Payload) > 0:
+		return fmt.Sprintf("graphql: connection rejected: %s", e.Payload)
+	}
+	return "graphql: connection rejected"
+}
+
+// rejection returns a *ConnectionRejectedError if err is the server closing
+// the connection with the close code for an unauthorized or forbidden
+// connection, as graphql-transport-ws servers do, and err otherwise.
+func rejection(err error) error {
+	ce, ok := err.(*websocket.CloseError)
+	if !ok || ce.Code != 4401 && ce.Code != 4403 {
+		return err
+	}
+	return &ConnectionRejectedError{Code: ce.Code, Reason: ce.Reason}
+}
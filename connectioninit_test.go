@@ -0,0 +1,64 @@
+package graphql_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/isihu/graphql"
+	"github.com/isihu/graphql/internal/websocket"
+)
+
+func TestClient_Subscribe_initPayloadRejected(t *testing.T) {
+	payloads := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := websocket.Upgrade(w, req, []string{"graphql-transport-ws"}, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var msg struct{ Payload map[string]any }
+		mustUnmarshal(string(data), &msg)
+		payloads <- mustMarshal(msg.Payload)
+		conn.CloseWithCode(4403, "Forbidden")
+		conn.ReadMessage()
+	}))
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil, graphql.WithSubscriptionInitPayloadFunc(func(ctx context.Context) (any, error) {
+		return map[string]string{"token": "t0k3n"}, nil
+	}))
+
+	_, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if got, want := <-payloads, `{"token":"t0k3n"}`; got != want {
+		t.Errorf("got connection_init payload: %s, want: %s", got, want)
+	}
+	re, ok := err.(*graphql.ConnectionRejectedError)
+	if !ok {
+		t.Fatalf("got error: %v, want a *graphql.ConnectionRejectedError", err)
+	}
+	if got, want := re.Code, 4403; got != want {
+		t.Errorf("got close code: %v, want: %v", got, want)
+	}
+	if got, want := err.Error(), "graphql: connection rejected with close code 4403: Forbidden"; got != want {
+		t.Errorf("got error: %q, want: %q", got, want)
+	}
+}
+
+func TestClient_Subscribe_initPayloadError(t *testing.T) {
+	client := graphql.NewClient("http://example.invalid", nil, graphql.WithSubscriptionInitPayloadFunc(func(ctx context.Context) (any, error) {
+		return nil, fmt.Errorf("no token")
+	}))
+
+	_, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if got, want := err, "building connection_init payload: no token"; got == nil || got.Error() != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
@@ -0,0 +1,53 @@
+package graphql
+
+import (
+	"strconv"
+	"time"
+)
+
+// WithDeadlineHeader makes the client translate the deadline of the context
+// of every request, if it has one, into the header named header, so that
+// downstream GraphQL gateways can budget their own work accordingly.
+// format formats the time remaining until the deadline as the header value;
+// TimeoutMillis and GRPCTimeout are common choices. Nil format means
+// TimeoutMillis.
+func WithDeadlineHeader(header string, format func(time.Duration) string) ClientOption {
+	if format == nil {
+		format = TimeoutMillis
+	}
+	return func(c *Client) {
+		c.deadlineHeader = header
+		c.formatDeadline = format
+	}
+}
+
+// TimeoutMillis formats d as a whole number of milliseconds, e.g., "1500",
+// as used by headers such as X-Timeout-Ms. Negative durations format as "0".
+func TimeoutMillis(d time.Duration) string {
+	return strconv.FormatInt(max(d, 0).Milliseconds(), 10)
+}
+
+// GRPCTimeout formats d in the format of the grpc-timeout header, e.g., "1500m".
+// It uses the finest unit that fits the value in at most 8 digits.
+// Negative durations format as "0n".
+//
+// Specification: https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-HTTP2.md.
+func GRPCTimeout(d time.Duration) string {
+	d = max(d, 0)
+	const maxValue = 99999999
+	for _, u := range [...]struct {
+		unit time.Duration
+		name string
+	}{
+		{time.Nanosecond, "n"},
+		{time.Microsecond, "u"},
+		{time.Millisecond, "m"},
+		{time.Second, "S"},
+		{time.Minute, "M"},
+	} {
+		if v := d / u.unit; v <= maxValue {
+			return strconv.FormatInt(int64(v), 10) + u.name
+		}
+	}
+	return strconv.FormatInt(min(int64(d/time.Hour), maxValue), 10) + "H"
+}
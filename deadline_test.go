@@ -0,0 +1,71 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/isihu/graphql"
+)
+
+func TestClient_Query_deadlineHeader(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		ms, err := strconv.Atoi(req.Header.Get("X-Timeout-Ms"))
+		if err != nil {
+			t.Errorf("got invalid X-Timeout-Ms header: %v", err)
+		}
+		if ms <= 0 || ms > 5000 {
+			t.Errorf("got X-Timeout-Ms: %v, want in (0, 5000]", ms)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+	// Nil format means TimeoutMillis.
+	for _, format := range []func(time.Duration) string{graphql.TimeoutMillis, nil} {
+		client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+			graphql.WithDeadlineHeader("X-Timeout-Ms", format))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		var q struct {
+			User struct {
+				Name string
+			}
+		}
+		err := client.Query(ctx, &q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGRPCTimeout(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{in: -time.Second, want: "0n"},
+		{in: 1500 * time.Nanosecond, want: "1500n"},
+		{in: 1500 * time.Millisecond, want: "1500000u"},
+		{in: 30 * time.Second, want: "30000000u"},
+		{in: 2 * time.Hour, want: "7200000m"},
+		{in: 5000 * time.Hour, want: "18000000S"},
+	}
+	for _, tc := range tests {
+		if got := graphql.GRPCTimeout(tc.in); got != tc.want {
+			t.Errorf("GRPCTimeout(%v): got %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestTimeoutMillis(t *testing.T) {
+	if got, want := graphql.TimeoutMillis(1500*time.Millisecond), "1500"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := graphql.TimeoutMillis(-time.Second), "0"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
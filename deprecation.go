@@ -0,0 +1,91 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Deprecation is a deprecation notice that the server included in a response,
+// such as for a deprecated field that the operation selected.
+type Deprecation struct {
+	Message string
+
+	// Details holds the other members of the notice, if it came from
+	// the response extensions as an object, such as the deprecated
+	// field's path or its removal date.
+	Details map[string]any
+}
+
+// WithDeprecationHandler makes the client call handle for every deprecation
+// notice in the responses to operations, so that usage of deprecated fields
+// can be tracked down before they're removed. query is the operation's document.
+//
+// Notices are read from the "deprecations" and "warnings" members of the
+// response extensions, whose entries are either messages or objects with
+// a "message" member, and from Warning headers with the 299 code.
+func WithDeprecationHandler(handle func(ctx context.Context, query string, d Deprecation)) ClientOption {
+	return func(c *Client) { c.handleDeprecation = handle }
+}
+
+// deprecations returns the deprecation notices in out.
+func (out *response) deprecations() []Deprecation {
+	var ds []Deprecation
+	for _, text := range warnings(out.header.Values("Warning")) {
+		ds = append(ds, Deprecation{Message: text})
+	}
+	for _, key := range []string{"deprecations", "warnings"} {
+		var entries []json.RawMessage
+		if json.Unmarshal(out.Extensions[key], &entries) != nil {
+			continue
+		}
+		for _, e := range entries {
+			var d Deprecation
+			if json.Unmarshal(e, &d.Message) != nil {
+				if json.Unmarshal(e, &d.Details) != nil {
+					continue
+				}
+				d.Message, _ = d.Details["message"].(string)
+				delete(d.Details, "message")
+				if len(d.Details) == 0 {
+					d.Details = nil
+				}
+			}
+			ds = append(ds, d)
+		}
+	}
+	return ds
+}
+
+// warnings returns the texts of the warnings with the 299 (persistent
+// warning) code in the given Warning header values.
+//
+// Specification: https://www.rfc-editor.org/rfc/rfc7234#section-5.5.
+func warnings(values []string) []string {
+	var texts []string
+	for _, v := range values {
+		for {
+			v = strings.TrimLeft(v, " ,")
+			code, rest, ok := strings.Cut(v, " ")
+			if !ok {
+				break
+			}
+			_, rest, _ = strings.Cut(rest, " ") // Skip the agent.
+			text, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				break
+			}
+			rest = strings.TrimLeft(rest[len(text):], " ")
+			if date, err := strconv.QuotedPrefix(rest); err == nil {
+				rest = rest[len(date):]
+			}
+			if code == "299" {
+				text, _ = strconv.Unquote(text)
+				texts = append(texts, text)
+			}
+			v = rest
+		}
+	}
+	return texts
+}
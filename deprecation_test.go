@@ -0,0 +1,51 @@
+package graphql_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+func TestWithDeprecationHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Warning", `299 api.example.com "The login field is deprecated" "Sat, 01 Aug 2026 00:00:00 GMT", 199 - "Miscellaneous"`)
+		w.Header().Add("Warning", `299 - "Use viewer instead of me"`)
+		mustWrite(w, `{
+			"data": {"user": {"name": "Gopher"}},
+			"extensions": {
+				"deprecations": [{"message": "User.name is deprecated", "path": ["user", "name"]}],
+				"warnings": ["Rate limit is lowering soon"]
+			}
+		}`)
+	})
+	var got []string
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithDeprecationHandler(func(ctx context.Context, query string, d graphql.Deprecation) {
+			got = append(got, fmt.Sprintf("%s: %s %v", query, d.Message, d.Details))
+		}))
+
+	var q struct {
+		User struct {
+			Name graphql.String
+		}
+	}
+	err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`{user{name}}: The login field is deprecated map[]`,
+		`{user{name}}: Use viewer instead of me map[]`,
+		`{user{name}}: User.name is deprecated map[path:[user name]]`,
+		`{user{name}}: Rate limit is lowering soon map[]`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got deprecations:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
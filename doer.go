@@ -0,0 +1,22 @@
+package graphql
+
+import "context"
+
+// Doer sends GraphQL operations, as Client does. Code that sends operations
+// can depend on a Doer rather than a *Client, so that its tests can inject
+// a fake implementation, without a server or an http.RoundTripper.
+type Doer interface {
+	// Query executes a single GraphQL query request, as Client.Query does.
+	Query(ctx context.Context, q any, variables map[string]any, opts ...CallOption) error
+
+	// Mutate executes a single GraphQL mutation request, as Client.Mutate does.
+	Mutate(ctx context.Context, m any, variables map[string]any, opts ...CallOption) error
+
+	// Do executes a single GraphQL operation, as Client.Do does.
+	Do(ctx context.Context, query string, res any, merge bool, variables map[string]any, opts ...CallOption) error
+
+	// Subscribe starts a GraphQL subscription, as Client.Subscribe does.
+	Subscribe(ctx context.Context, query string, variables map[string]any, opts ...CallOption) (*Subscription, error)
+}
+
+var _ Doer = (*Client)(nil)
@@ -0,0 +1,43 @@
+package graphql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+// fakeDoer answers queries by calling query, and fails other operations.
+type fakeDoer struct {
+	graphql.Doer
+	query func(q any, variables map[string]any) error
+}
+
+func (d fakeDoer) Query(_ context.Context, q any, variables map[string]any, _ ...graphql.CallOption) error {
+	return d.query(q, variables)
+}
+
+// viewerLogin is code under test that depends on a Doer.
+func viewerLogin(ctx context.Context, d graphql.Doer) (string, error) {
+	var q struct {
+		Viewer struct{ Login string }
+	}
+	err := d.Query(ctx, &q, nil)
+	return q.Viewer.Login, err
+}
+
+func TestDoer(t *testing.T) {
+	d := fakeDoer{query: func(q any, _ map[string]any) error {
+		q.(*struct {
+			Viewer struct{ Login string }
+		}).Viewer.Login = "gopher"
+		return nil
+	}}
+	login, err := viewerLogin(context.Background(), d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := login, "gopher"; got != want {
+		t.Errorf("got login: %q, want: %q", got, want)
+	}
+}
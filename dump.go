@@ -0,0 +1,68 @@
+package graphql
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+)
+
+// WithDebugDump makes the client dump every HTTP request it sends for an
+// operation, and the response to it, to w, in the wire format of
+// httputil.DumpRequestOut and httputil.DumpResponse. It's meant for
+// debugging, as it reads every response into memory.
+//
+// The values of the Authorization, Proxy-Authorization, Cookie and
+// Set-Cookie headers, and of the headers named in redact, are replaced
+// by "[REDACTED]". Streamed request bodies aren't dumped.
+func WithDebugDump(w io.Writer, redact ...string) ClientOption {
+	return func(c *Client) {
+		c.dump = w
+		c.dumpRedact = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+		c.dumpRedact = append(c.dumpRedact, redact...)
+	}
+}
+
+// redactHeader returns a copy of h with the values of c.dumpRedact redacted.
+func (c *Client) redactHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, key := range c.dumpRedact {
+		if vs := h.Values(key); len(vs) > 0 {
+			h.Set(key, "[REDACTED]")
+		}
+	}
+	return h
+}
+
+// dumpRequest dumps req to c.dump, leaving req as is.
+func (c *Client) dumpRequest(req *http.Request) {
+	r := req.Clone(req.Context())
+	r.Header = c.redactHeader(req.Header)
+	body := r.GetBody != nil
+	if body {
+		r.Body, _ = r.GetBody()
+	}
+	b, err := httputil.DumpRequestOut(r, body)
+	c.writeDump(b, err)
+}
+
+// dumpResponse dumps resp to c.dump, replacing its body
+// with an equivalent one that was read into memory.
+func (c *Client) dumpResponse(resp *http.Response) {
+	r := *resp
+	r.Header = c.redactHeader(resp.Header)
+	b, err := httputil.DumpResponse(&r, true)
+	resp.Body = r.Body
+	c.writeDump(b, err)
+}
+
+func (c *Client) writeDump(b []byte, err error) {
+	c.dumpMu.Lock()
+	defer c.dumpMu.Unlock()
+	if err != nil {
+		fmt.Fprintf(c.dump, "graphql: dumping failed: %v\n\n", err)
+		return
+	}
+	c.dump.Write(b)
+	io.WriteString(c.dump, "\n\n")
+}
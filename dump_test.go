@@ -0,0 +1,55 @@
+package graphql_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+func TestWithDebugDump(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.Header.Get("Authorization"), "Bearer s3cr3t"; got != want {
+			t.Errorf("got Authorization header: %q, want: %q", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Session", "abc")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	var buf bytes.Buffer
+	client := graphql.NewClient(srv.URL+"/graphql", nil,
+		graphql.WithHeader("Authorization", "Bearer s3cr3t"),
+		graphql.WithDebugDump(&buf, "X-Session"))
+
+	var q struct{ User struct{ Name graphql.String } }
+	err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.User.Name, graphql.String("Gopher"); got != want {
+		t.Errorf("got name: %q, want: %q", got, want)
+	}
+	dump := buf.String()
+	for _, want := range []string{
+		"POST /graphql HTTP/1.1\r\n",
+		"Authorization: [REDACTED]\r\n",
+		`{"query":"{user{name}}"}`,
+		"HTTP/1.1 200 OK\r\n",
+		"X-Session: [REDACTED]\r\n",
+		`{"data": {"user": {"name": "Gopher"}}}`,
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("got dump:\n%s\nwant it to contain: %q", dump, want)
+		}
+	}
+	if strings.Contains(dump, "s3cr3t") {
+		t.Errorf("got dump:\n%s\nwant it not to contain the Authorization header", dump)
+	}
+}
@@ -0,0 +1,573 @@
+package graphql
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isihu/graphql/ast"
+)
+
+// WithNormalizedCache makes the client keep an Apollo-style normalized cache
+// of the results of queries and mutations sent by Query, Mutate and Do.
+// Objects with an "id" field are stored once, as entities keyed by their
+// __typename and ID, and the results of different operations selecting
+// fields of the same entity are merged into it. Queries whose fields
+// are all in the cache, even if different operations fetched them,
+// are answered from it instead of being sent.
+//
+// To identify entities, the client adds __typename to the selection sets
+// of the documents it sends. Responses with errors aren't cached.
+// Fragments on abstract types are matched to cached entities by
+// whether the entities have all of their fields, since the client
+// doesn't know the schema. WithoutCache bypasses the normalized cache.
+//
+// Entities, and the results of root query fields, expire ttl after they're
+// last written, or after the TTL set by WithCacheTTL. Zero ttl means they
+// don't expire. At most maxEntities entities are kept, and the least
+// recently used are evicted to make room for others; zero means no limit.
+// InvalidateEntity removes an entity, and InvalidateCache removes the
+// results of the root fields of a query.
+func WithNormalizedCache(ttl time.Duration, maxEntities int) ClientOption {
+	return func(c *Client) { c.entities = newEntityStore(ttl, maxEntities) }
+}
+
+// InvalidateEntity removes the entity of type typename with ID id from the
+// normalized cache set by WithNormalizedCache, e.g., after it's deleted or
+// changed elsewhere. Queries selecting fields of it are sent again.
+func (c *Client) InvalidateEntity(typename, id string) {
+	if c.entities == nil {
+		return
+	}
+	s := c.entities
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entities[typename+":"+id]; ok {
+		s.remove(el)
+	}
+}
+
+// entityRef is a reference to the entity with a key, such as "User:1",
+// stored in place of the entity in the records referring to it.
+type entityRef string
+
+// entityStore is the store of a normalized cache. Records map the storage
+// keys of fields, which include their arguments, to values, which are
+// JSON values as decoded with json.Decoder.UseNumber, with nested
+// entities replaced by entityRefs.
+type entityStore struct {
+	ttl         time.Duration
+	maxEntities int
+
+	mu           sync.Mutex
+	entities     map[string]*list.Element // Of lru, holding *entityRecord, by key.
+	lru          *list.List               // Entities, most recently used first.
+	query        map[string]any           // Root query fields.
+	queryExpires map[string]time.Time     // When root query fields expire, if they do.
+	swept        time.Time                // When expired entities were last removed.
+}
+
+// entityRecord is an entity in an entityStore.
+type entityRecord struct {
+	key     string
+	fields  map[string]any
+	expires time.Time // Zero means never.
+}
+
+func newEntityStore(ttl time.Duration, maxEntities int) *entityStore {
+	return &entityStore{
+		ttl:          ttl,
+		maxEntities:  maxEntities,
+		entities:     make(map[string]*list.Element),
+		lru:          list.New(),
+		query:        make(map[string]any),
+		queryExpires: make(map[string]time.Time),
+		swept:        time.Now(),
+	}
+}
+
+// entity returns the fields of the entity with key, unless it's missing or
+// expired, and marks it as recently used.
+func (s *entityStore) entity(key string) (map[string]any, bool) {
+	el, ok := s.entities[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entityRecord)
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		s.remove(el)
+		return nil, false
+	}
+	s.lru.MoveToFront(el)
+	return e.fields, true
+}
+
+// writeEntity returns the fields of the entity with key to write to,
+// adding it if it's missing, and makes it expire at expires.
+func (s *entityStore) writeEntity(key string, expires time.Time) map[string]any {
+	el, ok := s.entities[key]
+	if !ok {
+		el = s.lru.PushFront(&entityRecord{key: key, fields: make(map[string]any)})
+		s.entities[key] = el
+	}
+	e := el.Value.(*entityRecord)
+	e.expires = expires
+	s.lru.MoveToFront(el)
+	return e.fields
+}
+
+// remove removes the entity el from s.
+func (s *entityStore) remove(el *list.Element) {
+	e := s.lru.Remove(el).(*entityRecord)
+	delete(s.entities, e.key)
+}
+
+// expire removes the expired root query fields of s, and, at most once
+// per TTL, its expired entities, which aren't removed otherwise unless
+// they're read or evicted.
+func (s *entityStore) expire() {
+	now := time.Now()
+	for key, expires := range s.queryExpires {
+		if now.After(expires) {
+			delete(s.query, key)
+			delete(s.queryExpires, key)
+		}
+	}
+	if s.ttl <= 0 || now.Sub(s.swept) < s.ttl {
+		return
+	}
+	s.swept = now
+	for el := s.lru.Front(); el != nil; {
+		next := el.Next()
+		if e := el.Value.(*entityRecord); !e.expires.IsZero() && now.After(e.expires) {
+			s.remove(el)
+		}
+		el = next
+	}
+}
+
+// evict removes the least recently used entities of s, until it holds
+// no more than its limit.
+func (s *entityStore) evict() {
+	for s.maxEntities > 0 && s.lru.Len() > s.maxEntities {
+		s.remove(s.lru.Back())
+	}
+}
+
+// invalidate removes the results of the root fields selected by the query
+// doc with variables from s.
+func (s *entityStore) invalidate(doc *ast.Document, variables map[string]any) {
+	ops := doc.Operations()
+	if len(ops) != 1 || ops[0].Operation != "query" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w := entityWalker{s: s, doc: doc, variables: variables}
+	w.fieldKeys(ops[0].SelectionSet, func(key string) {
+		delete(s.query, key)
+		delete(s.queryExpires, key)
+	})
+}
+
+// errEntityMismatch is returned when a response doesn't match its document.
+var errEntityMismatch = errors.New("graphql: response doesn't match the document")
+
+// read returns the data of the query doc with variables from s,
+// and whether s has all of it.
+func (s *entityStore) read(doc *ast.Document, variables map[string]any) (json.RawMessage, bool) {
+	ops := doc.Operations()
+	if len(ops) != 1 || ops[0].Operation != "query" {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	w := entityWalker{s: s, doc: doc, variables: variables}
+	out := make(map[string]any)
+	if !w.read(ops[0].SelectionSet, s.query, out) {
+		return nil, false
+	}
+	data, err := json.Marshal(out)
+	return data, err == nil
+}
+
+// normalize writes data, the data of the operation sent with variables, to s,
+// to expire after ttl, or s's TTL if it's zero, and returns the data as
+// selected by doc, the document sent was derived from by addTypenames.
+// Root mutation fields aren't kept.
+func (s *entityStore) normalize(sent, doc *ast.Document, variables map[string]any, data json.RawMessage, ttl time.Duration) (json.RawMessage, error) {
+	ops, sentOps := doc.Operations(), sent.Operations()
+	if len(ops) != 1 || len(sentOps) != 1 {
+		return nil, errEntityMismatch
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var obj map[string]any
+	if err := dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+	if ttl == 0 {
+		ttl = s.ttl
+	}
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	root := s.query
+	var written func(key string)
+	if ops[0].Operation == "query" {
+		written = func(key string) {
+			if expires.IsZero() {
+				delete(s.queryExpires, key)
+			} else {
+				s.queryExpires[key] = expires
+			}
+		}
+	} else {
+		root = make(map[string]any)
+	}
+	w := entityWalker{s: s, doc: sent, variables: variables, expires: expires}
+	w.write(sentOps[0].SelectionSet, obj, root, written)
+	w.doc = doc
+	out := make(map[string]any)
+	ok := w.read(ops[0].SelectionSet, root, out)
+	// Evict only after reading the data back, which may need every entity written.
+	s.evict()
+	if !ok {
+		return nil, errEntityMismatch
+	}
+	return json.Marshal(out)
+}
+
+// entityWalker walks the selection sets of an operation in doc with
+// variables, writing results to or reading them from s.
+type entityWalker struct {
+	s         *entityStore
+	doc       *ast.Document
+	variables map[string]any
+	expires   time.Time // When entities written expire. Zero means never.
+}
+
+// write writes the fields of obj selected by set to record, and calls
+// written, if non-nil, with the storage key of every field written.
+func (w entityWalker) write(set ast.SelectionSet, obj, record map[string]any, written func(key string)) {
+	for _, sel := range set {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			if !w.included(sel.Directives) {
+				continue
+			}
+			v, ok := obj[sel.ResponseKey()]
+			if !ok {
+				// The field is in a fragment that didn't apply.
+				continue
+			}
+			key := w.storageKey(sel)
+			record[key] = w.writeValue(sel.SelectionSet, v, record[key])
+			if written != nil {
+				written(key)
+			}
+		case *ast.InlineFragment:
+			if w.included(sel.Directives) {
+				w.write(sel.SelectionSet, obj, record, written)
+			}
+		case *ast.FragmentSpread:
+			if f := w.doc.Fragment(sel.Name); f != nil && w.included(sel.Directives) {
+				w.write(f.SelectionSet, obj, record, written)
+			}
+		}
+	}
+}
+
+// fieldKeys calls f with the storage key of every field selected by set,
+// but not by the selection sets of its fields.
+func (w entityWalker) fieldKeys(set ast.SelectionSet, f func(key string)) {
+	for _, sel := range set {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			f(w.storageKey(sel))
+		case *ast.InlineFragment:
+			w.fieldKeys(sel.SelectionSet, f)
+		case *ast.FragmentSpread:
+			if frag := w.doc.Fragment(sel.Name); frag != nil {
+				w.fieldKeys(frag.SelectionSet, f)
+			}
+		}
+	}
+}
+
+// writeValue returns the value to store for v, the value of a field with
+// the selection set set, whose stored value was old.
+func (w entityWalker) writeValue(set ast.SelectionSet, v, old any) any {
+	if len(set) == 0 {
+		return v
+	}
+	switch v := v.(type) {
+	case []any:
+		olds, _ := old.([]any)
+		values := make([]any, len(v))
+		for i, e := range v {
+			var old any
+			if i < len(olds) {
+				old = olds[i]
+			}
+			values[i] = w.writeValue(set, e, old)
+		}
+		return values
+	case map[string]any:
+		if key := entityKey(v); key != "" {
+			w.write(set, v, w.s.writeEntity(key, w.expires), nil)
+			return entityRef(key)
+		}
+		// Objects without IDs are stored inline in their parents,
+		// merged with what was there before.
+		record, ok := old.(map[string]any)
+		if !ok {
+			record = make(map[string]any)
+		}
+		w.write(set, v, record, nil)
+		return record
+	}
+	return v
+}
+
+// read reads the fields selected by set from record into out,
+// and reports whether record has all of them.
+func (w entityWalker) read(set ast.SelectionSet, record, out map[string]any) bool {
+	for _, sel := range set {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			if !w.included(sel.Directives) {
+				continue
+			}
+			v, ok := record[w.storageKey(sel)]
+			if !ok {
+				return false
+			}
+			v, ok = w.readValue(sel.SelectionSet, v, out[sel.ResponseKey()])
+			if !ok {
+				return false
+			}
+			out[sel.ResponseKey()] = v
+		case *ast.InlineFragment:
+			if w.included(sel.Directives) && !w.readFragment(sel.TypeCondition, sel.SelectionSet, record, out) {
+				return false
+			}
+		case *ast.FragmentSpread:
+			f := w.doc.Fragment(sel.Name)
+			if f == nil {
+				return false
+			}
+			if w.included(sel.Directives) && !w.readFragment(f.TypeCondition, f.SelectionSet, record, out) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// readFragment reads the fields of a fragment on the type typeCondition
+// with the selection set set from record into out, and reports whether
+// it could tell whether the fragment applies.
+func (w entityWalker) readFragment(typeCondition string, set ast.SelectionSet, record, out map[string]any) bool {
+	typename, _ := record["__typename"].(string)
+	if typeCondition == "" || typeCondition == typename {
+		return w.read(set, record, out)
+	}
+	// The type condition may be an interface or union that the record's type
+	// belongs to. Without a schema, assume it is if the record has every field.
+	fields := make(map[string]any)
+	if w.read(set, record, fields) {
+		mergeInto(out, fields)
+	}
+	return true
+}
+
+// readValue returns the value selected by set of a field stored as v,
+// merged into out, the value already read for the field, if any.
+func (w entityWalker) readValue(set ast.SelectionSet, v, out any) (any, bool) {
+	if len(set) == 0 {
+		return v, true
+	}
+	switch v := v.(type) {
+	case []any:
+		outs, _ := out.([]any)
+		values := make([]any, len(v))
+		for i, e := range v {
+			var out any
+			if i < len(outs) {
+				out = outs[i]
+			}
+			var ok bool
+			values[i], ok = w.readValue(set, e, out)
+			if !ok {
+				return nil, false
+			}
+		}
+		return values, true
+	case entityRef:
+		record, ok := w.s.entity(string(v))
+		if !ok {
+			return nil, false
+		}
+		return w.readValue(set, record, out)
+	case map[string]any:
+		obj, ok := out.(map[string]any)
+		if !ok {
+			obj = make(map[string]any)
+		}
+		return obj, w.read(set, v, obj)
+	}
+	return v, true
+}
+
+// mergeInto merges the object src into dst.
+func mergeInto(dst, src map[string]any) {
+	for k, v := range src {
+		d, dok := dst[k].(map[string]any)
+		s, sok := v.(map[string]any)
+		if dok && sok {
+			mergeInto(d, s)
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// storageKey returns the key a field is stored under, which is its name
+// followed by its arguments, if any, in JSON.
+func (w entityWalker) storageKey(f *ast.Field) string {
+	if len(f.Arguments) == 0 {
+		return f.Name
+	}
+	args := make(map[string]any, len(f.Arguments))
+	for _, arg := range f.Arguments {
+		args[arg.Name] = w.value(arg.Value)
+	}
+	b, err := json.Marshal(args)
+	if err != nil {
+		return f.Name + "(?)"
+	}
+	return f.Name + "(" + string(b) + ")"
+}
+
+// value returns the value of the input value v.
+func (w entityWalker) value(v ast.Value) any {
+	switch v := v.(type) {
+	case *ast.Variable:
+		return w.variables[v.Name]
+	case *ast.IntValue:
+		return json.Number(v.Raw)
+	case *ast.FloatValue:
+		return json.Number(v.Raw)
+	case *ast.StringValue:
+		return v.Value
+	case *ast.BooleanValue:
+		return v.Value
+	case *ast.EnumValue:
+		return v.Name
+	case *ast.ListValue:
+		values := make([]any, len(v.Values))
+		for i, e := range v.Values {
+			values[i] = w.value(e)
+		}
+		return values
+	case *ast.ObjectValue:
+		fields := make(map[string]any, len(v.Fields))
+		for _, f := range v.Fields {
+			fields[f.Name] = w.value(f.Value)
+		}
+		return fields
+	}
+	return nil
+}
+
+// included reports whether a selection with directives is included,
+// as decided by @skip and @include.
+func (w entityWalker) included(directives []*ast.Directive) bool {
+	for _, d := range directives {
+		if d.Name != "skip" && d.Name != "include" {
+			continue
+		}
+		for _, arg := range d.Arguments {
+			if arg.Name != "if" {
+				continue
+			}
+			cond, _ := w.value(arg.Value).(bool)
+			if cond == (d.Name == "skip") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// entityKey returns the key of the entity obj, which is its __typename
+// and ID, or "" if obj doesn't have both.
+func entityKey(obj map[string]any) string {
+	typename, _ := obj["__typename"].(string)
+	var id string
+	switch v := obj["id"].(type) {
+	case string:
+		id = v
+	case json.Number:
+		id = string(v)
+	}
+	if typename == "" || id == "" {
+		return ""
+	}
+	return typename + ":" + id
+}
+
+// addTypenames adds a __typename field to the selection sets in doc that
+// don't have one, except those of operations, so that entities can be
+// identified in the response.
+func addTypenames(doc *ast.Document) {
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.OperationDefinition:
+			for _, sel := range def.SelectionSet {
+				addSelectionTypenames(sel)
+			}
+		case *ast.FragmentDefinition:
+			def.SelectionSet = withTypename(def.SelectionSet)
+		}
+	}
+}
+
+func addSelectionTypenames(sel ast.Selection) {
+	switch sel := sel.(type) {
+	case *ast.Field:
+		if len(sel.SelectionSet) > 0 && !strings.HasPrefix(sel.Name, "__") {
+			sel.SelectionSet = withTypename(sel.SelectionSet)
+		}
+	case *ast.InlineFragment:
+		for _, sel := range sel.SelectionSet {
+			addSelectionTypenames(sel)
+		}
+	}
+}
+
+// withTypename returns set with a __typename field, and with __typename
+// fields added to the selection sets in it.
+func withTypename(set ast.SelectionSet) ast.SelectionSet {
+	has := false
+	for _, sel := range set {
+		if f, ok := sel.(*ast.Field); ok && f.Name == "__typename" && f.Alias == "" {
+			has = true
+		}
+		addSelectionTypenames(sel)
+	}
+	if !has {
+		set = append(set, &ast.Field{Name: "__typename"})
+	}
+	return set
+}
@@ -0,0 +1,202 @@
+package graphql_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/isihu/graphql"
+)
+
+func TestWithNormalizedCache(t *testing.T) {
+	// Responses by the documents the client is expected to send.
+	responses := map[string]string{
+		`{viewer{id,login,__typename}}`:                                                   `{"data": {"viewer": {"id": "1", "login": "gopher", "__typename": "User"}}}`,
+		`query($id:ID!){node(id:$id){id,...on User{name},__typename}}`:                    `{"data": {"node": {"id": "1", "name": "Gopher", "__typename": "User"}}}`,
+		`mutation{updateUser(id:"1",name:"Ferris"){user{id,name,__typename},__typename}}`: `{"data": {"updateUser": {"user": {"id": "1", "name": "Ferris", "__typename": "User"}, "__typename": "UpdateUserPayload"}}}`,
+	}
+	var sent []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		var in struct{ Query string }
+		mustUnmarshal(mustRead(req.Body), &in)
+		sent = append(sent, in.Query)
+		resp, ok := responses[in.Query]
+		if !ok {
+			t.Errorf("got unexpected query: %s", in.Query)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, resp)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithNormalizedCache(time.Minute, 0))
+	ctx := context.Background()
+
+	var viewer struct {
+		Viewer struct {
+			ID    graphql.ID
+			Login graphql.String
+		}
+	}
+	if err := client.Query(ctx, &viewer, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := viewer.Viewer.Login, graphql.String("gopher"); got != want {
+		t.Errorf("got login: %q, want: %q", got, want)
+	}
+	var node struct {
+		Node struct {
+			ID   graphql.ID
+			User struct{ Name graphql.String } `graphql:"... on User"`
+		} `graphql:"node(id:$id)"`
+	}
+	if err := client.Query(ctx, &node, map[string]any{"id": graphql.ID("1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both fields of the viewer were fetched by different queries.
+	var profile struct {
+		Viewer struct {
+			Login graphql.String
+			Name  graphql.String
+		}
+	}
+	if err := client.Query(ctx, &profile, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := profile.Viewer.Name, graphql.String("Gopher"); got != want {
+		t.Errorf("got name: %q, want: %q", got, want)
+	}
+	if got, want := len(sent), 2; got != want {
+		t.Errorf("got %d requests, want: %d", got, want)
+	}
+
+	// Mutation results update cached entities.
+	var m struct {
+		UpdateUser struct {
+			User struct {
+				ID   graphql.ID
+				Name graphql.String
+			}
+		} `graphql:"updateUser(id:\"1\",name:\"Ferris\")"`
+	}
+	if err := client.Mutate(ctx, &m, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Query(ctx, &profile, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := profile.Viewer.Name, graphql.String("Ferris"); got != want {
+		t.Errorf("got name after mutation: %q, want: %q", got, want)
+	}
+	if got, want := len(sent), 3; got != want {
+		t.Errorf("got %d requests, want: %d", got, want)
+	}
+}
+
+func TestWithNormalizedCache_errors(t *testing.T) {
+	var calls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"id": "1", "login": "gopher", "__typename": "User"}}, "errors": [{"message": "rate limit warning"}]}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithNormalizedCache(time.Minute, 0))
+
+	for range 2 {
+		var q struct {
+			Viewer struct {
+				ID    graphql.ID
+				Login graphql.String
+			}
+		}
+		err := client.Query(context.Background(), &q, nil)
+		if _, ok := err.(*graphql.PartialDataError); !ok {
+			t.Errorf("got error: %v, want a *graphql.PartialDataError", err)
+		}
+		if got, want := q.Viewer.Login, graphql.String("gopher"); got != want {
+			t.Errorf("got login: %q, want: %q", got, want)
+		}
+	}
+	if got, want := calls, 2; got != want {
+		t.Errorf("got %d requests, want: %d", got, want)
+	}
+}
+
+func TestWithNormalizedCache_bounds(t *testing.T) {
+	var calls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		var in struct{ Variables struct{ ID string } }
+		mustUnmarshal(mustRead(req.Body), &in)
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, fmt.Sprintf(`{"data": {"user": {"id": %q, "login": "user %s", "__typename": "User"}}}`, in.Variables.ID, in.Variables.ID))
+	})
+	newClient := func(ttl time.Duration, maxEntities int) *graphql.Client {
+		calls = 0
+		return graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+			graphql.WithNormalizedCache(ttl, maxEntities))
+	}
+	type query struct {
+		User struct {
+			ID    graphql.ID
+			Login graphql.String
+		} `graphql:"user(id:$id)"`
+	}
+	queryUser := func(client *graphql.Client, id string) {
+		t.Helper()
+		var q query
+		if err := client.Query(context.Background(), &q, map[string]any{"id": graphql.ID(id)}); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := q.User.Login, graphql.String("user "+id); got != want {
+			t.Errorf("got login: %q, want: %q", got, want)
+		}
+	}
+
+	t.Run("ttl", func(t *testing.T) {
+		client := newClient(time.Millisecond, 0)
+		queryUser(client, "1")
+		time.Sleep(2 * time.Millisecond)
+		queryUser(client, "1")
+		if got, want := calls, 2; got != want {
+			t.Errorf("got %d requests after the cache expired, want: %d", got, want)
+		}
+	})
+	t.Run("maxEntities", func(t *testing.T) {
+		client := newClient(time.Minute, 1)
+		queryUser(client, "1")
+		queryUser(client, "2") // Evicts user 1.
+		queryUser(client, "2")
+		queryUser(client, "1")
+		if got, want := calls, 3; got != want {
+			t.Errorf("got %d requests, want: %d", got, want)
+		}
+	})
+	t.Run("InvalidateEntity", func(t *testing.T) {
+		client := newClient(time.Minute, 0)
+		queryUser(client, "1")
+		client.InvalidateEntity("User", "1")
+		queryUser(client, "1")
+		if got, want := calls, 2; got != want {
+			t.Errorf("got %d requests after invalidating the entity, want: %d", got, want)
+		}
+	})
+	t.Run("InvalidateCache", func(t *testing.T) {
+		client := newClient(time.Minute, 0)
+		queryUser(client, "1")
+		variables := map[string]any{"id": graphql.ID("1")}
+		if err := client.InvalidateCache(context.Background(), &query{}, variables); err != nil {
+			t.Fatal(err)
+		}
+		queryUser(client, "1")
+		if got, want := calls, 2; got != want {
+			t.Errorf("got %d requests after invalidating the query, want: %d", got, want)
+		}
+	})
+}
@@ -0,0 +1,636 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/isihu/graphql/internal/jsonutil"
+)
+
+// Errors represents the "errors" array in a response from a GraphQL server.
+// If returned via error interface, the slice is expected to contain at least 1 element.
+// Use errors.As to retrieve it from errors returned by the client.
+//
+// Specification: https://spec.graphql.org/October2021/#sec-Errors.
+type Errors []Error
+
+// Error implements error interface. It returns the messages
+// of all errors, separated by newlines, as errors.Join does.
+func (e Errors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the individual errors, so that errors.As and errors.Is
+// consider every error the server returned, not just the first.
+// errors.As with a *Error target sets it to the first error.
+func (e Errors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Error is a single error in the "errors" array of a response.
+type Error struct {
+	Message   string     `json:"message"`
+	Locations []Location `json:"locations,omitempty"`
+
+	// Path is the path of the response field that experienced the error.
+	// Its elements are field names of type string and list indices of type int.
+	Path []any `json:"path,omitempty"`
+
+	// Extensions holds additional, server-specific information about the error.
+	Extensions map[string]any `json:"extensions,omitempty"`
+
+	detailed bool // Whether Error returns Detail rather than Message.
+}
+
+// Location is a location in the GraphQL document an error is associated with.
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Error implements error interface. It returns the message,
+// or Detail if the client was created with WithDetailedErrors.
+func (e Error) Error() string {
+	if e.detailed {
+		return e.Detail()
+	}
+	return e.Message
+}
+
+// Is reports whether target is the error for the class of e's code.
+func (e Error) Is(target error) bool {
+	return codeSentinels[e.Code()] == target
+}
+
+// Detail returns the message followed by the path and locations of the error,
+// if any, such as "resolver failed at repository.issues[2].author (3:15)".
+func (e Error) Detail() string {
+	var b strings.Builder
+	b.WriteString(e.Message)
+	for i, p := range e.Path {
+		switch {
+		case i == 0:
+			fmt.Fprintf(&b, " at %v", p)
+		case isIndex(p):
+			fmt.Fprintf(&b, "[%v]", p)
+		default:
+			fmt.Fprintf(&b, ".%v", p)
+		}
+	}
+	for i, l := range e.Locations {
+		if i == 0 {
+			b.WriteString(" (")
+		} else {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%d:%d", l.Line, l.Column)
+	}
+	if len(e.Locations) > 0 {
+		b.WriteString(")")
+	}
+	return b.String()
+}
+
+// isIndex reports whether the path element p is a list index.
+func isIndex(p any) bool {
+	_, ok := p.(int)
+	return ok
+}
+
+// debugExtensions are the members of error extensions that servers include
+// for debugging, such as stack traces. Apollo Server puts them in
+// "exception" (version 2) or "stacktrace" (version 3 and later), graphql-php
+// in "debugMessage" and "trace", and Hasura in "internal".
+var debugExtensions = []string{"exception", "stacktrace", "debugMessage", "trace", "internal"}
+
+// stripDebug removes debugExtensions from the extensions of e.
+func (e *Error) stripDebug() {
+	for _, key := range debugExtensions {
+		delete(e.Extensions, key)
+	}
+}
+
+// prepareErrors applies the client's error options, WithDetailedErrors and
+// WithDebugErrors, to errs, as decoded from the server's response.
+func (c *Client) prepareErrors(errs Errors) {
+	for i := range errs {
+		errs[i].detailed = c.detailedErrors
+		if !c.debugErrors {
+			errs[i].stripDebug()
+		}
+	}
+}
+
+// StackTrace returns the server-side stack trace of the error, if the server
+// included one in the error's extensions, as Apollo Server does in debug mode.
+// Stack traces are only kept if the client was created with WithDebugErrors.
+func (e Error) StackTrace() []string {
+	trace := e.Extensions["stacktrace"]
+	if exception, ok := e.Extensions["exception"].(map[string]any); ok && trace == nil {
+		trace = exception["stacktrace"]
+	}
+	lines, _ := trace.([]any)
+	var st []string
+	for _, l := range lines {
+		if l, ok := l.(string); ok {
+			st = append(st, l)
+		}
+	}
+	return st
+}
+
+// WithDebugErrors makes the client keep the debugging information, such as
+// stack traces, that servers in development mode include in the extensions
+// of GraphQL errors. By default, it's removed, so that it doesn't end up in
+// logs or reach end users if a server is misconfigured in production.
+// See Error.StackTrace.
+func WithDebugErrors() ClientOption {
+	return func(c *Client) { c.debugErrors = true }
+}
+
+// Severity is the severity of a GraphQL error.
+type Severity int
+
+const (
+	SeverityError   Severity = iota // The error failed the operation, at least in part. It's the default.
+	SeverityWarning                 // The operation succeeded, but something may need attention.
+	SeverityInfo                    // The error is merely informational.
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	}
+	return "error"
+}
+
+// Severity returns the severity of the error, which servers that distinguish
+// errors from warnings put in the "severity" or "level" entry of its
+// extensions. Errors without one have SeverityError.
+func (e Error) Severity() Severity {
+	level, ok := e.Extensions["severity"].(string)
+	if !ok {
+		level, _ = e.Extensions["level"].(string)
+	}
+	switch strings.ToLower(level) {
+	case "warning", "warn":
+		return SeverityWarning
+	case "info", "notice", "debug":
+		return SeverityInfo
+	}
+	return SeverityError
+}
+
+// splitWarnings splits e into the errors with SeverityError,
+// and the ones with lower severities.
+func (e Errors) splitWarnings() (errs, warnings Errors) {
+	for _, err := range e {
+		if err.Severity() == SeverityError {
+			errs = append(errs, err)
+		} else {
+			warnings = append(warnings, err)
+		}
+	}
+	return errs, warnings
+}
+
+// WithWarningHandler makes operations treat GraphQL errors with a severity
+// lower than SeverityError as warnings: rather than failing the operation,
+// they're passed to handle. query is the operation's document.
+func WithWarningHandler(handle func(ctx context.Context, query string, warning Error)) ClientOption {
+	return func(c *Client) { c.handleWarning = handle }
+}
+
+// WithDetailedErrors makes the messages of GraphQL errors returned by
+// operations include their paths and locations, as returned by Error.Detail,
+// which makes logged errors easier to trace back to the document.
+func WithDetailedErrors() ClientOption {
+	return func(c *Client) { c.detailedErrors = true }
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It decodes list indices in
+// the path as int rather than float64.
+func (e *Error) UnmarshalJSON(b []byte) error {
+	type plain Error
+	if err := json.Unmarshal(b, (*plain)(e)); err != nil {
+		return err
+	}
+	for i, p := range e.Path {
+		if f, ok := p.(float64); ok {
+			e.Path[i] = int(f)
+		}
+	}
+	return nil
+}
+
+// Code returns the machine-readable code of the error, which servers
+// such as Apollo, Hasura and Shopify put in the "code" entry of its
+// extensions, or "" if there isn't one.
+func (e Error) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+// PartialDataError is returned when a response has errors as well as data,
+// which happens when some fields failed to resolve but others didn't.
+// The result has been populated with the data, so fields that didn't fail
+// may still be used. Fields that failed are typically null; Errors reports
+// their paths.
+type PartialDataError struct {
+	Errors Errors
+}
+
+func (e *PartialDataError) Error() string {
+	return e.Errors.Error()
+}
+
+func (e *PartialDataError) Unwrap() error {
+	return e.Errors
+}
+
+// IsGraphQLError reports whether err is, or wraps, errors returned
+// by the GraphQL server in the "errors" array of a response,
+// as opposed to a failure to send the request or decode the response.
+func IsGraphQLError(err error) bool {
+	var errs Errors
+	return errors.As(err, &errs)
+}
+
+// ErrorCode returns the code of the first GraphQL error in err,
+// or "" if err has no GraphQL errors or the first one has no code.
+func ErrorCode(err error) string {
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) == 0 {
+		return ""
+	}
+	return errs[0].Code()
+}
+
+// HasErrorCode reports whether any of the GraphQL errors in err has code.
+func HasErrorCode(err error, code string) bool {
+	var errs Errors
+	errors.As(err, &errs)
+	for _, e := range errs {
+		if e.Code() == code {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorFields reports which fields of v, the result that an operation was
+// decoded into, the GraphQL errors in err apply to. Such fields are zero
+// because their resolvers failed, rather than because the server returned
+// no value for them. The result maps paths of fields, such as
+// "Repository.Issues[2].Author", to their errors. Errors without a path, or
+// whose path isn't selected by v, are left out.
+func ErrorFields(err error, v any) map[string]Error {
+	var errs Errors
+	if !errors.As(err, &errs) {
+		return nil
+	}
+	fields := make(map[string]Error)
+	for _, e := range errs {
+		for _, path := range jsonutil.FieldPaths(reflect.TypeOf(v), e.Path) {
+			fields[path] = e
+		}
+	}
+	return fields
+}
+
+// ErrorPolicy determines how an operation handles GraphQL errors
+// in its response.
+type ErrorPolicy int
+
+const (
+	// ErrorPolicyAll populates the result with the data of the response, if
+	// any, and returns the errors, as a *PartialDataError if there was data.
+	// It's the default.
+	ErrorPolicyAll ErrorPolicy = iota
+
+	// ErrorPolicyNone returns the errors without populating the result,
+	// so that partial data is never used.
+	ErrorPolicyNone
+
+	// ErrorPolicyIgnore populates the result with the data of the response,
+	// if any, and doesn't return the errors, even if there was no data.
+	ErrorPolicyIgnore
+)
+
+// WithErrorPolicy makes the operation handle GraphQL errors according to policy.
+func WithErrorPolicy(policy ErrorPolicy) CallOption {
+	return func(cfg *callConfig) { cfg.errorPolicy = policy }
+}
+
+// WithIgnoredErrorCodes drops GraphQL errors with any of codes from the
+// response before the operation's error policy is applied, such as for
+// errors that are expected and don't affect the caller.
+// If used more than once, the codes are combined.
+func WithIgnoredErrorCodes(codes ...string) CallOption {
+	return func(cfg *callConfig) {
+		if cfg.ignoredCodes == nil {
+			cfg.ignoredCodes = make(map[string]bool, len(codes))
+		}
+		for _, code := range codes {
+			cfg.ignoredCodes[code] = true
+		}
+	}
+}
+
+// withoutCodes returns the errors whose codes aren't in codes.
+func (e Errors) withoutCodes(codes map[string]bool) Errors {
+	if len(codes) == 0 {
+		return e
+	}
+	var errs Errors
+	for _, err := range e {
+		if !codes[err.Code()] {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// WithErrorDecoder makes the client decode the "errors" member of responses
+// with decode, rather than as Errors, for servers with nonstandard error
+// envelopes. decode receives the raw JSON of the member, if it's present and
+// non-null, and returns the error that operations return, such as a
+// domain-specific type. If it returns nil, the response is treated as having
+// no errors. WithIgnoredErrorCodes doesn't apply to such errors, and they
+// aren't wrapped in a *PartialDataError.
+func WithErrorDecoder(decode func(raw json.RawMessage) error) ClientOption {
+	return func(c *Client) { c.decodeErrors = decode }
+}
+
+// decodeResponse decodes a response from data. If c.decodeErrors is set,
+// it keeps the raw "errors" member of the response in rawErrors, and
+// decodes it as Errors on a best-effort basis, for the client's own use,
+// such as detecting rejected credentials.
+func (c *Client) decodeResponse(data []byte) (*response, error) {
+	if c.decodeErrors == nil {
+		var out response
+		err := json.Unmarshal(data, &out)
+		return &out, err
+	}
+	var raw struct {
+		response
+		Errors json.RawMessage `json:"errors"` // Shadows response.Errors.
+	}
+	err := json.Unmarshal(data, &raw)
+	out := &raw.response
+	if len(raw.Errors) > 0 && string(raw.Errors) != "null" {
+		out.rawErrors = raw.Errors
+		if json.Unmarshal(raw.Errors, &out.Errors) != nil {
+			out.Errors = nil
+		}
+	}
+	return out, err
+}
+
+// OperationError wraps the errors returned by operations when the client was
+// created with WithErrorContext. It identifies the failed operation, so that
+// a single logged error has enough information to reproduce it.
+type OperationError struct {
+	Type string // Operation type: "query", "mutation" or "subscription".
+	Name string // Operation name, or "" for an anonymous operation.
+	Hash string // Hex-encoded SHA-256 hash of the document.
+
+	// Variables is a snapshot of the operation's variables, with the values
+	// of variables that WithErrorContext didn't name replaced by "[REDACTED]".
+	Variables map[string]any
+
+	Err error
+}
+
+func (e *OperationError) Error() string {
+	name := e.Name
+	if name == "" {
+		name = "<anonymous>"
+	}
+	vars, _ := json.Marshal(e.Variables)
+	return fmt.Sprintf("%s %s (document sha256:%s, variables %s): %v", e.Type, name, e.Hash, vars, e.Err)
+}
+
+func (e *OperationError) Unwrap() error { return e.Err }
+
+// newOperationError returns an *OperationError for err, the error of an
+// operation with the GraphQL document query and variables.
+func newOperationError(query string, variables map[string]any, unredacted map[string]bool, err error) *OperationError {
+	var vars map[string]any
+	if len(variables) > 0 {
+		vars = make(map[string]any, len(variables))
+		for k, v := range variables {
+			if !unredacted[k] {
+				v = "[REDACTED]"
+			}
+			vars[k] = v
+		}
+	}
+	return &OperationError{
+		Type:      operationType(query),
+		Name:      operationName(query),
+		Hash:      documentHash(query),
+		Variables: vars,
+		Err:       err,
+	}
+}
+
+// WithErrorContext makes operations wrap their errors in an *OperationError,
+// which identifies the operation and includes a snapshot of its variables.
+// The values of variables other than those named in unredacted are redacted,
+// so that secrets and personal data don't end up in logs.
+func WithErrorContext(unredacted ...string) ClientOption {
+	return func(c *Client) {
+		c.errorContext = true
+		c.unredactedVariables = make(map[string]bool, len(unredacted))
+		for _, name := range unredacted {
+			c.unredactedVariables[name] = true
+		}
+	}
+}
+
+// NetworkError is returned when sending a request or receiving a response
+// fails, such as because the server couldn't be reached, the connection was
+// reset, or the context was done. Err is typically a *url.Error.
+type NetworkError struct {
+	Err error
+}
+
+func (e *NetworkError) Error() string { return e.Err.Error() }
+func (e *NetworkError) Unwrap() error { return e.Err }
+
+// DecodeError is returned when a response can't be decoded, either because
+// its body isn't a valid GraphQL response, or because its data doesn't fit
+// the result it's decoded into.
+type DecodeError struct {
+	Err error
+
+	// Status and ContentType are the status and Content-Type header of
+	// the response, and Body is a prefix of its body, of at most 512 bytes.
+	// They're set when the body isn't a valid GraphQL response, such as
+	// when a proxy responds with an HTML error page.
+	Status      string
+	ContentType string
+	Body        []byte
+
+	truncated bool // Whether Body is shorter than the body of the response.
+}
+
+func (e *DecodeError) Error() string {
+	if e.Status == "" {
+		return e.Err.Error()
+	}
+	body := fmt.Sprintf("%q", e.Body)
+	if e.truncated {
+		body += "..."
+	}
+	return fmt.Sprintf("decoding response with status %v and content type %q: %v; body: %s", e.Status, e.ContentType, e.Err, body)
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// decodeErrorBodyLimit is the maximum length of DecodeError.Body.
+const decodeErrorBodyLimit = 512
+
+// prefixWriter keeps the first decodeErrorBodyLimit bytes written to it.
+type prefixWriter struct {
+	b         []byte
+	truncated bool
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	n := min(len(p), decodeErrorBodyLimit-len(w.b))
+	w.b = append(w.b, p[:n]...)
+	if n < len(p) {
+		w.truncated = true
+	}
+	return len(p), nil
+}
+
+// HTTPError is returned when the server responds with a non-200 OK status code.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Header     http.Header
+
+	// Body is the body of the response, truncated to at most 64 KiB.
+	Body []byte
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("non-200 OK status code: %v body: %q", e.Status, e.Body)
+}
+
+// httpErrorBodyLimit is the maximum length of HTTPError.Body.
+const httpErrorBodyLimit = 64 << 10
+
+// newHTTPError returns an *HTTPError for resp, reading its body from body.
+func newHTTPError(resp *http.Response, body io.Reader) *HTTPError {
+	b, _ := io.ReadAll(io.LimitReader(body, httpErrorBodyLimit))
+	return &HTTPError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		Body:       b,
+	}
+}
+
+// Errors for common classes of failures, which operations' errors match with
+// errors.Is if the server responded with the corresponding status code, or
+// with a GraphQL error with the corresponding code in its extensions.
+var (
+	ErrUnauthorized = errors.New("graphql: unauthorized") // Status 401, or code UNAUTHENTICATED.
+	ErrForbidden    = errors.New("graphql: forbidden")    // Status 403, or code FORBIDDEN.
+	ErrNotFound     = errors.New("graphql: not found")    // Status 404, or code NOT_FOUND.
+	ErrRateLimited  = errors.New("graphql: rate limited") // Status 429, or code THROTTLED or RATE_LIMITED.
+)
+
+// statusSentinels and codeSentinels map status codes and error codes to
+// the errors they match.
+var (
+	statusSentinels = map[int]error{
+		http.StatusUnauthorized:    ErrUnauthorized,
+		http.StatusForbidden:       ErrForbidden,
+		http.StatusNotFound:        ErrNotFound,
+		http.StatusTooManyRequests: ErrRateLimited,
+	}
+	codeSentinels = map[string]error{
+		"UNAUTHENTICATED": ErrUnauthorized,
+		"FORBIDDEN":       ErrForbidden,
+		"NOT_FOUND":       ErrNotFound,
+		"THROTTLED":       ErrRateLimited,
+		"RATE_LIMITED":    ErrRateLimited,
+	}
+)
+
+// Is reports whether target is the error for the class of e's status code.
+func (e *HTTPError) Is(target error) bool {
+	return statusSentinels[e.StatusCode] == target
+}
+
+// ErrorKind classifies an error by the stage of an operation that failed.
+type ErrorKind int
+
+const (
+	KindOther      ErrorKind = iota // The error isn't classified, such as a failure to build the request.
+	KindNetwork                     // Sending the request or receiving the response failed. See NetworkError.
+	KindHTTPStatus                  // The server responded with a non-200 OK status code. See HTTPError.
+	KindDecode                      // The response couldn't be decoded. See DecodeError.
+	KindGraphQL                     // The server returned GraphQL errors. See Errors.
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindNetwork:
+		return "network"
+	case KindHTTPStatus:
+		return "http status"
+	case KindDecode:
+		return "decode"
+	case KindGraphQL:
+		return "graphql"
+	}
+	return "other"
+}
+
+// Classify returns the kind of err, which may wrap the error returned by
+// an operation. It returns KindOther for a nil err.
+func Classify(err error) ErrorKind {
+	var (
+		errs Errors
+		de   *DecodeError
+		se   *HTTPError
+		ne   *NetworkError
+	)
+	switch {
+	case errors.As(err, &errs):
+		return KindGraphQL
+	case errors.As(err, &de):
+		return KindDecode
+	case errors.As(err, &se):
+		return KindHTTPStatus
+	case errors.As(err, &ne):
+		return KindNetwork
+	}
+	return KindOther
+}
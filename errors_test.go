@@ -0,0 +1,488 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+func TestIsGraphQLError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/errors", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"errors": [{"message": "boom", "path": ["user"]}, {"message": "bang"}]}`)
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+
+	var q struct{ User struct{ Name graphql.String } }
+	for _, tc := range []struct {
+		url  string
+		want bool
+	}{
+		{url: "/errors", want: true},
+		{url: "/status", want: false},
+	} {
+		client := graphql.NewClient(tc.url, &http.Client{Transport: localRoundTripper{handler: mux}})
+		err := client.Query(context.Background(), &q, nil)
+		if err == nil {
+			t.Fatalf("%s: got error: nil, want: non-nil", tc.url)
+		}
+		if got := graphql.IsGraphQLError(err); got != tc.want {
+			t.Errorf("%s: got IsGraphQLError: %v, want: %v", tc.url, got, tc.want)
+		}
+		if got := graphql.IsGraphQLError(fmt.Errorf("wrapped: %w", err)); got != tc.want {
+			t.Errorf("%s: got IsGraphQLError of wrapped error: %v, want: %v", tc.url, got, tc.want)
+		}
+	}
+}
+
+func TestErrors_As(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", graphql.Errors{{Message: "boom", Path: []any{"user"}}, {Message: "bang"}})
+
+	var e graphql.Error
+	if !errors.As(err, &e) {
+		t.Fatalf("got errors.As: false, want: true")
+	}
+	if got, want := e.Message, "boom"; got != want {
+		t.Errorf("got message: %q, want: %q", got, want)
+	}
+	if graphql.IsGraphQLError(fmt.Errorf("no errors")) {
+		t.Error("got IsGraphQLError: true, want: false")
+	}
+}
+
+func TestErrors_Error(t *testing.T) {
+	err := graphql.Errors{{Message: "boom"}, {Message: "bang"}}
+	if got, want := err.Error(), "boom\nbang"; got != want {
+		t.Errorf("got error: %q, want: %q", got, want)
+	}
+	if got, want := len(err.Unwrap()), 2; got != want {
+		t.Errorf("got %d unwrapped errors, want: %d", got, want)
+	}
+	var e graphql.Error
+	if !errors.As(err, &e) || e.Message != "boom" {
+		t.Errorf("got errors.As: %v, want the first error", e)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/errors", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"errors": [{"message": "boom"}]}`)
+	})
+	mux.HandleFunc("/partial", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": null}, "errors": [{"message": "boom"}]}`)
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "boom", http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/html", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		mustWrite(w, `<html>boom</html>`)
+	})
+	mux.HandleFunc("/mismatch", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": 42}}}`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	closed := httptest.NewServer(mux)
+	closed.Close()
+
+	var q struct {
+		User *struct{ Name graphql.String }
+	}
+	for _, tc := range []struct {
+		url  string
+		want graphql.ErrorKind
+	}{
+		{url: srv.URL + "/errors", want: graphql.KindGraphQL},
+		{url: srv.URL + "/partial", want: graphql.KindGraphQL},
+		{url: srv.URL + "/status", want: graphql.KindHTTPStatus},
+		{url: srv.URL + "/html", want: graphql.KindDecode},
+		{url: srv.URL + "/mismatch", want: graphql.KindDecode},
+		{url: closed.URL, want: graphql.KindNetwork},
+	} {
+		client := graphql.NewClient(tc.url, nil)
+		err := client.Query(context.Background(), &q, nil)
+		if got := graphql.Classify(err); got != tc.want {
+			t.Errorf("%s: got kind: %v, want: %v (error: %v)", tc.url, got, tc.want, err)
+		}
+	}
+	if got, want := graphql.Classify(nil), graphql.KindOther; got != want {
+		t.Errorf("got kind of nil: %v, want: %v", got, want)
+	}
+}
+
+func TestClient_Query_decodeErrorBody(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/html", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		mustWrite(w, `<html>Bad Gateway</html>`)
+	})
+	mux.HandleFunc("/long", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		mustWrite(w, strings.Repeat("x", 1000))
+	})
+
+	var q struct{ User struct{ Name graphql.String } }
+	client := graphql.NewClient("/html", &http.Client{Transport: localRoundTripper{handler: mux}})
+	err := client.Query(context.Background(), &q, nil)
+	if got, want := fmt.Sprint(err), `decoding response with status 200 OK and content type "text/html": invalid character '<' looking for beginning of value; body: "<html>Bad Gateway</html>"`; got != want {
+		t.Errorf("got error: %s, want: %s", got, want)
+	}
+
+	client = graphql.NewClient("/long", &http.Client{Transport: localRoundTripper{handler: mux}})
+	err = client.Query(context.Background(), &q, nil)
+	var de *graphql.DecodeError
+	if !errors.As(err, &de) {
+		t.Fatalf("got error: %v, want a *graphql.DecodeError", err)
+	}
+	if got, want := string(de.Body), strings.Repeat("x", 512); got != want {
+		t.Errorf("got body: %q, want: %q", got, want)
+	}
+	if !strings.HasSuffix(err.Error(), `x"...`) {
+		t.Errorf("got error: %v, want a truncated body", err)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"errors": [
+			{"message": "throttled", "extensions": {"code": "THROTTLED"}},
+			{"message": "forbidden", "extensions": {"code": "FORBIDDEN"}},
+			{"message": "no code"}
+		]}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct{ User struct{ Name graphql.String } }
+	err := client.Query(context.Background(), &q, nil)
+	if got, want := graphql.ErrorCode(err), "THROTTLED"; got != want {
+		t.Errorf("got error code: %q, want: %q", got, want)
+	}
+	for code, want := range map[string]bool{"THROTTLED": true, "FORBIDDEN": true, "NOT_FOUND": false} {
+		if got := graphql.HasErrorCode(err, code); got != want {
+			t.Errorf("got HasErrorCode(%q): %v, want: %v", code, got, want)
+		}
+	}
+	if got := graphql.ErrorCode(fmt.Errorf("no errors")); got != "" {
+		t.Errorf("got error code: %q, want: empty", got)
+	}
+}
+
+func TestHTTPError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, strings.Repeat("x", 100<<10), http.StatusTooManyRequests)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct{ User struct{ Name graphql.String } }
+	err := client.Query(context.Background(), &q, nil)
+	var he *graphql.HTTPError
+	if !errors.As(err, &he) {
+		t.Fatalf("got error: %v, want a *graphql.HTTPError", err)
+	}
+	if got, want := he.StatusCode, http.StatusTooManyRequests; got != want {
+		t.Errorf("got status code: %v, want: %v", got, want)
+	}
+	if got, want := he.Header.Get("Retry-After"), "30"; got != want {
+		t.Errorf("got Retry-After header: %q, want: %q", got, want)
+	}
+	if got, want := len(he.Body), 64<<10; got != want {
+		t.Errorf("got body length: %v, want: %v", got, want)
+	}
+}
+
+func TestWithDetailedErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"errors": [
+			{"message": "resolver failed", "path": ["repository", "issues", 2, "author"], "locations": [{"line": 3, "column": 15}]},
+			{"message": "no details"}
+		]}`)
+	})
+
+	var q struct{ User struct{ Name graphql.String } }
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithDetailedErrors())
+	err := client.Query(context.Background(), &q, nil)
+	if got, want := fmt.Sprint(err), "resolver failed at repository.issues[2].author (3:15)\nno details"; got != want {
+		t.Errorf("got error: %q, want: %q", got, want)
+	}
+
+	client = graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+	err = client.Query(context.Background(), &q, nil)
+	if got, want := fmt.Sprint(err), "resolver failed\nno details"; got != want {
+		t.Errorf("got error without WithDetailedErrors: %q, want: %q", got, want)
+	}
+}
+
+func TestWithDebugErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"errors": [{"message": "resolver failed", "extensions": {
+			"code": "INTERNAL_SERVER_ERROR",
+			"stacktrace": ["Error: resolver failed", "    at resolve (schema.js:12:9)"]
+		}}]}`)
+	})
+
+	var q struct{ User struct{ Name graphql.String } }
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+	err := client.Query(context.Background(), &q, nil)
+	var errs graphql.Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("got error: %v, want graphql.Errors", err)
+	}
+	if got := errs[0].Extensions["stacktrace"]; got != nil {
+		t.Errorf("got stacktrace extension: %v, want: nil", got)
+	}
+	if got, want := errs[0].Code(), "INTERNAL_SERVER_ERROR"; got != want {
+		t.Errorf("got code: %q, want: %q", got, want)
+	}
+
+	client = graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithDebugErrors())
+	err = client.Query(context.Background(), &q, nil)
+	if !errors.As(err, &errs) {
+		t.Fatalf("got error: %v, want graphql.Errors", err)
+	}
+	if got, want := strings.Join(errs[0].StackTrace(), "\n"), "Error: resolver failed\n    at resolve (schema.js:12:9)"; got != want {
+		t.Errorf("got stack trace with WithDebugErrors: %q, want: %q", got, want)
+	}
+}
+
+func TestError_StackTrace(t *testing.T) {
+	tests := []struct {
+		name string
+		ext  string
+	}{
+		{name: "Apollo 2", ext: `{"exception": {"stacktrace": ["Error: boom", "    at f (a.js:1:1)"]}}`},
+		{name: "Apollo 3", ext: `{"stacktrace": ["Error: boom", "    at f (a.js:1:1)"]}`},
+	}
+	for _, tc := range tests {
+		var e graphql.Error
+		mustUnmarshal(`{"message": "boom", "extensions": `+tc.ext+`}`, &e)
+		if got, want := strings.Join(e.StackTrace(), "\n"), "Error: boom\n    at f (a.js:1:1)"; got != want {
+			t.Errorf("%s: got stack trace: %q, want: %q", tc.name, got, want)
+		}
+	}
+	if got := (graphql.Error{Message: "boom"}).StackTrace(); got != nil {
+		t.Errorf("got stack trace without extensions: %q, want: nil", got)
+	}
+}
+
+func TestErrorFields(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{
+			"data": {"node1": {"id": "1"}, "node2": null},
+			"errors": [
+				{"message": "Could not resolve to a node", "path": ["node2"]},
+				{"message": "Something else"}
+			]
+		}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Node1 *struct{ ID graphql.ID } `graphql:"node1: node(id: \"1\")"`
+		Node2 *struct{ ID graphql.ID } `graphql:"node2: node(id: \"2\")"`
+	}
+	err := client.Query(context.Background(), &q, nil)
+	fields := graphql.ErrorFields(err, &q)
+	if got, want := len(fields), 1; got != want {
+		t.Fatalf("got %d fields: %v, want: %d", got, fields, want)
+	}
+	if got, want := fields["Node2"].Message, "Could not resolve to a node"; got != want {
+		t.Errorf("got error of Node2: %q, want: %q", got, want)
+	}
+}
+
+func TestWithErrorPolicy(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{
+			"data": {"node1": {"id": "1"}, "node2": null},
+			"errors": [
+				{"message": "Could not resolve to a node", "path": ["node2"], "extensions": {"code": "NOT_FOUND"}}
+			]
+		}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	type query struct {
+		Node1 *struct{ ID graphql.ID } `graphql:"node1: node(id: \"1\")"`
+		Node2 *struct{ ID graphql.ID } `graphql:"node2: node(id: \"2\")"`
+	}
+	for _, tc := range []struct {
+		name     string
+		opts     []graphql.CallOption
+		wantErr  string
+		wantData bool
+	}{
+		{name: "default", wantErr: "Could not resolve to a node", wantData: true},
+		{name: "all", opts: []graphql.CallOption{graphql.WithErrorPolicy(graphql.ErrorPolicyAll)}, wantErr: "Could not resolve to a node", wantData: true},
+		{name: "none", opts: []graphql.CallOption{graphql.WithErrorPolicy(graphql.ErrorPolicyNone)}, wantErr: "Could not resolve to a node", wantData: false},
+		{name: "ignore", opts: []graphql.CallOption{graphql.WithErrorPolicy(graphql.ErrorPolicyIgnore)}, wantData: true},
+		{name: "ignored codes", opts: []graphql.CallOption{graphql.WithErrorPolicy(graphql.ErrorPolicyNone), graphql.WithIgnoredErrorCodes("NOT_FOUND")}, wantData: true},
+		{name: "other ignored codes", opts: []graphql.CallOption{graphql.WithIgnoredErrorCodes("FORBIDDEN")}, wantErr: "Could not resolve to a node", wantData: true},
+	} {
+		var q query
+		err := client.Query(context.Background(), &q, nil, tc.opts...)
+		if got := fmt.Sprint(err); tc.wantErr == "" && err != nil || tc.wantErr != "" && got != tc.wantErr {
+			t.Errorf("%s: got error: %v, want: %q", tc.name, err, tc.wantErr)
+		}
+		if got := q.Node1 != nil; got != tc.wantData {
+			t.Errorf("%s: got data: %v, want: %v", tc.name, got, tc.wantData)
+		}
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	})
+	mux.HandleFunc("/code", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"errors": [{"message": "boom"}, {"message": "Not allowed", "extensions": {"code": "FORBIDDEN"}}]}`)
+	})
+
+	var q struct{ User struct{ Name graphql.String } }
+	for _, tc := range []struct {
+		url  string
+		want error
+	}{
+		{url: "/status", want: graphql.ErrRateLimited},
+		{url: "/code", want: graphql.ErrForbidden},
+	} {
+		client := graphql.NewClient(tc.url, &http.Client{Transport: localRoundTripper{handler: mux}})
+		err := client.Query(context.Background(), &q, nil)
+		for _, sentinel := range []error{graphql.ErrUnauthorized, graphql.ErrForbidden, graphql.ErrNotFound, graphql.ErrRateLimited} {
+			if got, want := errors.Is(err, sentinel), sentinel == tc.want; got != want {
+				t.Errorf("%s: got errors.Is(err, %v): %v, want: %v", tc.url, sentinel, got, want)
+			}
+		}
+	}
+}
+
+// validationError is a domain-specific error decoded by WithErrorDecoder.
+type validationError struct {
+	Fields map[string][]string
+}
+
+func (e *validationError) Error() string { return fmt.Sprintf("invalid fields: %v", e.Fields) }
+
+func TestWithErrorDecoder(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": null, "errors": {"validation": {"login": ["is too short", "is taken"]}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithErrorDecoder(func(raw json.RawMessage) error {
+			var envelope struct {
+				Validation map[string][]string
+			}
+			if err := json.Unmarshal(raw, &envelope); err != nil {
+				return err
+			}
+			return &validationError{Fields: envelope.Validation}
+		}))
+
+	var m struct {
+		CreateUser struct{ ID graphql.ID } `graphql:"createUser(login: $login)"`
+	}
+	err := client.Mutate(context.Background(), &m, map[string]any{"login": graphql.String("x")})
+	var ve *validationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("got error: %v, want a *validationError", err)
+	}
+	if got, want := fmt.Sprint(ve.Fields["login"]), "[is too short is taken]"; got != want {
+		t.Errorf("got login errors: %s, want: %s", got, want)
+	}
+}
+
+func TestWithErrorContext(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"errors": [{"message": "Could not resolve to a User"}]}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithErrorContext("login"))
+
+	query := `query GetUser($login:String!$token:String!){user(login:$login, token:$token){name}}`
+	var res struct{ User struct{ Name graphql.String } }
+	err := client.Do(context.Background(), query, &res, false, map[string]any{"login": "gopher", "token": "s3cr3t"})
+	var oe *graphql.OperationError
+	if !errors.As(err, &oe) {
+		t.Fatalf("got error: %v, want a *graphql.OperationError", err)
+	}
+	if got, want := oe.Name, "GetUser"; got != want {
+		t.Errorf("got operation name: %q, want: %q", got, want)
+	}
+	if got, want := len(oe.Hash), 64; got != want {
+		t.Errorf("got hash length: %v, want: %v", got, want)
+	}
+	if got, want := err.Error(), `query GetUser (document sha256:`+oe.Hash+`, variables {"login":"gopher","token":"[REDACTED]"}): Could not resolve to a User`; got != want {
+		t.Errorf("got error: %s, want: %s", got, want)
+	}
+	if !graphql.IsGraphQLError(err) {
+		t.Error("got IsGraphQLError: false, want: true")
+	}
+}
+
+func TestWithWarningHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{
+			"data": {"user": {"name": "Gopher"}},
+			"errors": [
+				{"message": "Query cost is close to the limit", "extensions": {"severity": "WARNING"}},
+				{"message": "Served from cache", "extensions": {"level": "info"}}
+			]
+		}`)
+	})
+	var warnings []string
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithWarningHandler(func(ctx context.Context, query string, w graphql.Error) {
+			warnings = append(warnings, fmt.Sprintf("%v: %s", w.Severity(), w.Message))
+		}))
+
+	var q struct{ User struct{ Name graphql.String } }
+	err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatalf("got error: %v, want: nil", err)
+	}
+	if got, want := strings.Join(warnings, "\n"), "warning: Query cost is close to the limit\ninfo: Served from cache"; got != want {
+		t.Errorf("got warnings:\n%s\nwant:\n%s", got, want)
+	}
+	if got, want := q.User.Name, graphql.String("Gopher"); got != want {
+		t.Errorf("got name: %q, want: %q", got, want)
+	}
+
+	client = graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+	err = client.Query(context.Background(), &q, nil)
+	if !graphql.IsGraphQLError(err) {
+		t.Errorf("got error without WithWarningHandler: %v, want GraphQL errors", err)
+	}
+}
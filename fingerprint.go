@@ -0,0 +1,138 @@
+package graphql
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/isihu/graphql/ast"
+)
+
+// Fingerprint returns a stable hash of the shape of op's document, which
+// groups operations that differ only in literal values, aliases, whitespace,
+// or the order of fields, arguments and fragments. It's the hex-encoded
+// SHA-256 hash of the document normalized like Apollo's operation signatures:
+// string, number, list and object literals are replaced by empty values,
+// aliases are removed, and selections, arguments and definitions are sorted.
+// Documents that don't parse are hashed as is.
+func (op Operation) Fingerprint() string {
+	return fingerprint(op.Document)
+}
+
+// fingerprint returns the fingerprint of the GraphQL document query.
+func fingerprint(query string) string {
+	doc, err := ast.Parse(query)
+	if err != nil {
+		return documentHash(query)
+	}
+	normalize(doc)
+	return documentHash(doc.String())
+}
+
+// WithFingerprintHeader makes the client send the fingerprint of every
+// operation sent by Query, Mutate and Do in the HTTP header named name,
+// so that gateways and proxies can group traffic by operation shape.
+// See Operation.Fingerprint.
+func WithFingerprintHeader(name string) ClientOption {
+	return func(c *Client) { c.fingerprintHeader = name }
+}
+
+// normalize rewrites doc into the normalized form described by Operation.Fingerprint.
+func normalize(doc *ast.Document) {
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.OperationDefinition:
+			for _, v := range def.VariableDefinitions {
+				if v.DefaultValue != nil {
+					v.DefaultValue = stripLiteral(v.DefaultValue)
+				}
+				normalizeDirectives(v.Directives)
+			}
+			normalizeDirectives(def.Directives)
+			normalizeSelectionSet(def.SelectionSet)
+		case *ast.FragmentDefinition:
+			normalizeDirectives(def.Directives)
+			normalizeSelectionSet(def.SelectionSet)
+		}
+	}
+	// Operations come first, followed by fragments sorted by name.
+	slices.SortStableFunc(doc.Definitions, func(a, b ast.Definition) int {
+		fa, aok := a.(*ast.FragmentDefinition)
+		fb, bok := b.(*ast.FragmentDefinition)
+		switch {
+		case aok && bok:
+			return cmp.Compare(fa.Name, fb.Name)
+		case aok:
+			return 1
+		case bok:
+			return -1
+		}
+		return 0
+	})
+}
+
+func normalizeSelectionSet(set ast.SelectionSet) {
+	for _, sel := range set {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			sel.Alias = ""
+			normalizeArguments(sel.Arguments)
+			normalizeDirectives(sel.Directives)
+			normalizeSelectionSet(sel.SelectionSet)
+		case *ast.FragmentSpread:
+			normalizeDirectives(sel.Directives)
+		case *ast.InlineFragment:
+			normalizeDirectives(sel.Directives)
+			normalizeSelectionSet(sel.SelectionSet)
+		}
+	}
+	slices.SortStableFunc(set, func(a, b ast.Selection) int {
+		return cmp.Compare(selectionKey(a), selectionKey(b))
+	})
+}
+
+// selectionKey returns the key selections are sorted by:
+// fields by name, then fragment spreads by name, then inline fragments
+// by type condition.
+func selectionKey(sel ast.Selection) string {
+	switch sel := sel.(type) {
+	case *ast.Field:
+		return "0" + sel.Name
+	case *ast.FragmentSpread:
+		return "1" + sel.Name
+	case *ast.InlineFragment:
+		return "2" + sel.TypeCondition
+	}
+	return ""
+}
+
+func normalizeDirectives(dirs []*ast.Directive) {
+	for _, d := range dirs {
+		normalizeArguments(d.Arguments)
+	}
+}
+
+func normalizeArguments(args []*ast.Argument) {
+	for _, arg := range args {
+		arg.Value = stripLiteral(arg.Value)
+	}
+	slices.SortStableFunc(args, func(a, b *ast.Argument) int { return cmp.Compare(a.Name, b.Name) })
+}
+
+// stripLiteral returns v with its literal values replaced by empty ones.
+// Variables, booleans, enums and null are kept, since they're part
+// of the operation's shape rather than its data.
+func stripLiteral(v ast.Value) ast.Value {
+	switch v.(type) {
+	case *ast.IntValue:
+		return &ast.IntValue{Raw: "0"}
+	case *ast.FloatValue:
+		return &ast.FloatValue{Raw: "0"}
+	case *ast.StringValue:
+		return &ast.StringValue{}
+	case *ast.ListValue:
+		return &ast.ListValue{}
+	case *ast.ObjectValue:
+		return &ast.ObjectValue{}
+	}
+	return v
+}
@@ -0,0 +1,61 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+func TestOperation_Fingerprint(t *testing.T) {
+	fingerprint := func(doc string) string { return graphql.Operation{Document: doc}.Fingerprint() }
+	base := fingerprint(`query Search($n:Int!){search(query:"gopher",first:$n){nodes{id,name},total}}`)
+	for _, doc := range []string{
+		`query Search($n:Int!){search(query:"rustacean",first:$n){nodes{id,name},total}}`,
+		`query Search($n: Int!) {
+			search(first: $n, query: "gopher") {
+				total
+				nodes { name id }
+			}
+		}`,
+		`query Search($n:Int!){results:search(query:"",first:$n){nodes{id,name},total}}`,
+	} {
+		if got := fingerprint(doc); got != base {
+			t.Errorf("got fingerprint of %q: %v, want: %v", doc, got, base)
+		}
+	}
+	for _, doc := range []string{
+		`query Search($n:Int!){search(query:"gopher",first:$n){nodes{id},total}}`,
+		`query Find($n:Int!){search(query:"gopher",first:$n){nodes{id,name},total}}`,
+		`query Search($n:Int!){search(query:"gopher",first:$n,sort:NAME){nodes{id,name},total}}`,
+	} {
+		if got := fingerprint(doc); got == base {
+			t.Errorf("got fingerprint of %q equal to the base fingerprint, want a different one", doc)
+		}
+	}
+	if got, want := fingerprint(`{user(id:1){...A,...B}}fragment B on User{id}fragment A on User{name}`),
+		fingerprint(`fragment A on User{name}{user(id:2){...A,...B}}fragment B on User{id}`); got != want {
+		t.Errorf("got fingerprint with reordered fragments: %v, want: %v", got, want)
+	}
+}
+
+func TestWithFingerprintHeader(t *testing.T) {
+	const query = `query GetUser{user(login:"gopher"){name}}`
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.Header.Get("X-Operation-Fingerprint"), (graphql.Operation{Document: query}).Fingerprint(); got != want {
+			t.Errorf("got fingerprint header: %q, want: %q", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithFingerprintHeader("X-Operation-Fingerprint"))
+
+	var res struct{ User struct{ Name graphql.String } }
+	err := client.Do(context.Background(), query, &res, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
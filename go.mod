@@ -1,3 +1,3 @@
 module github.com/isihu/graphql
 
-go 1.19
+go 1.24
@@ -4,116 +4,604 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/isihu/graphql/ast"
 	"github.com/isihu/graphql/internal/jsonutil"
+	"github.com/isihu/graphql/internal/websocket"
 )
 
 // Client is a GraphQL client.
 type Client struct {
-	url        string       // GraphQL server URL.
-	httpClient *http.Client // Non-nil.
+	url        string            // GraphQL server URL.
+	urls       map[string]string // GraphQL server URLs by operation type, overriding url.
+	httpClient *http.Client      // Non-nil.
+
+	persistedQueries    bool // Whether Automatic Persisted Queries are enabled.
+	persistedQueriesGET bool // Whether hash-only queries are sent with GET.
+
+	persistedQueriesUnsupported atomic.Bool // Whether the server reported it doesn't support persisted queries.
+
+	header http.Header // Default headers sent with every request.
+
+	verifyResponse func(header http.Header, body []byte) error // Optional.
+	rewriters      []func(doc *ast.Document) error
+	retry          *RetryPolicy // Nil means operations aren't retried.
+
+	refreshAuth func(ctx context.Context) (http.Header, error) // Optional.
+	authMu      sync.RWMutex
+	authHeader  http.Header // Headers from the last refreshAuth call, guarded by authMu.
+	authGen     uint64      // Number of successful refreshAuth calls, guarded by authMu.
+	refreshMu   sync.Mutex  // Held while calling refreshAuth.
+
+	deadlineHeader string                     // Header for the context deadline. Empty means none.
+	formatDeadline func(time.Duration) string // Formats the time remaining until the deadline.
+
+	idempotencyHeader       string // Header for idempotency keys of mutations.
+	generateIdempotencyKeys bool   // Whether to generate missing idempotency keys.
+
+	detailedErrors      bool                            // Whether GraphQL error messages include their paths and locations.
+	debugErrors         bool                            // Whether debugging information in GraphQL errors is kept.
+	decodeErrors        func(raw json.RawMessage) error // Optional.
+	errorContext        bool                            // Whether operation errors are wrapped in *OperationError.
+	unredactedVariables map[string]bool                 // Variables whose values are kept in *OperationError.
+
+	handleDeprecation func(ctx context.Context, query string, d Deprecation) // Optional.
+	handleWarning     func(ctx context.Context, query string, warning Error) // Optional.
+	tracer            Tracer                                                 // Optional.
+	metrics           Metrics                                                // Optional.
+	handleStats       func(ctx context.Context, s Stats)                     // Optional.
+	fingerprintHeader string                                                 // Header for operation fingerprints. Empty means none.
+	captureRate       float64                                                // Fraction of operations whose payloads are captured.
+	captureSink       func(ctx context.Context, c Capture)                   // Optional.
+	profilerLabels    bool                                                   // Whether operations run with pprof labels.
+	logger            *slog.Logger                                           // Optional.
+	logOptions        LogOptions
+	slowThreshold     time.Duration // Zero means operations aren't reported as slow.
+	dump              io.Writer     // Optional. Guarded by dumpMu.
+	dumpMu            sync.Mutex
+	dumpRedact        []string                                 // Headers redacted from dumps.
+	audit             func(ctx context.Context, r AuditRecord) // Optional.
+	auditOptions      AuditOptions
+	cache             Cache               // Nil means results aren't cached.
+	cacheTTL          time.Duration       // How long results are cached by default.
+	maxStale          time.Duration       // How long expired results are served while being refreshed.
+	cacheKey          CacheKeyFunc        // Nil means DefaultCacheKey.
+	cacheHints        bool                // Whether server hints set how long results are cached.
+	sharedCache       bool                // Whether the cache is shared between users.
+	negativeTTL       time.Duration       // How long failures are cached. Zero means they aren't.
+	negativeCodes     map[string]bool     // Codes of the errors of failures that are cached.
+	revalidating      sync.Map            // Cache keys of results being refreshed in the background.
+	entities          *entityStore        // Nil means results aren't normalized and cached.
+	validated         *validatedResponses // Nil means requests aren't conditional.
+	batcher           *batcher            // Nil means queries aren't batched.
+	parallelDecoding  int                 // Minimum length of arrays decoded in parallel. Zero means none are.
+
+	batchKey func(ctx context.Context) string // Key of the queries a batch may have. Nil means any.
+
+	subscriptionProtocol    SubscriptionProtocol
+	subscriptionReconnect   *RetryPolicy // Nil means subscriptions don't reconnect.
+	subscriptionKeepAlive   KeepAlive
+	subscriptionHooks       SubscriptionHooks
+	subscriptionInit        func(ctx context.Context) (any, error)
+	subscriptionBuffer      int
+	subscriptionOverflow    OverflowPolicy
+	subscriptionCompression *websocket.Compression // Nil means no compression.
+	wsMu                    sync.Mutex
+	wsConns                 map[string]*wsConn // Shared subscription connections by URL, guarded by wsMu.
+	subsMu                  sync.Mutex
+	subs                    map[*Subscription]struct{} // Active subscriptions, guarded by subsMu.
+	closed                  bool                       // Whether Close was called, guarded by subsMu.
+
+	transportOpts []func(*http.Transport) // Applied to a copy of httpClient's transport.
+	balancer      *balancer               // Nil means no DNS-based load balancing.
+
+	err error // Configuration error, if any. Returned by every operation.
 }
 
 // NewClient creates a GraphQL client targeting the specified GraphQL server URL.
 // If httpClient is nil, then http.DefaultClient is used.
-func NewClient(url string, httpClient *http.Client) *Client {
+func NewClient(url string, httpClient *http.Client, opts ...ClientOption) *Client {
 	if httpClient == nil {
 		httpClient = http.DefaultClient
 	}
-	return &Client{
-		url:        url,
-		httpClient: httpClient,
+	c := &Client{
+		url:               url,
+		httpClient:        httpClient,
+		idempotencyHeader: "Idempotency-Key",
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.configureTransport()
+	return c
 }
 
 // Query executes a single GraphQL query request,
 // with a query derived from q, populating the response into it.
 // q should be a pointer to struct that corresponds to the GraphQL schema.
-func (c *Client) Query(ctx context.Context, q any, variables map[string]any) error {
+// If the response has both data and errors, q is populated with the data
+// and a *PartialDataError is returned.
+func (c *Client) Query(ctx context.Context, q any, variables map[string]any, opts ...CallOption) error {
 	query := constructQuery(q, variables)
-	return c.Do(ctx, query, q, false, variables)
+	return c.Do(ctx, query, q, false, variables, opts...)
 }
 
 // Mutate executes a single GraphQL mutation request,
 // with a mutation derived from m, populating the response into it.
 // m should be a pointer to struct that corresponds to the GraphQL schema.
-func (c *Client) Mutate(ctx context.Context, m any, variables map[string]any) error {
+func (c *Client) Mutate(ctx context.Context, m any, variables map[string]any, opts ...CallOption) error {
 	mutation := constructMutation(m, variables)
-	return c.Do(ctx, mutation, m, false, variables)
+	return c.Do(ctx, mutation, m, false, variables, opts...)
 }
 
 // do executes a single GraphQL operation.
-func (c *Client) Do(ctx context.Context, query string, res any, merge bool, variables map[string]any) error {
-	in := struct {
-		Query     string         `json:"query"`
-		Variables map[string]any `json:"variables,omitempty"`
-	}{
-		Query:     query,
-		Variables: variables,
-	}
-	var buf bytes.Buffer
-	err := json.NewEncoder(&buf).Encode(in)
-	if err != nil {
-		return err
+func (c *Client) Do(ctx context.Context, query string, res any, merge bool, variables map[string]any, opts ...CallOption) error {
+	if c.err != nil {
+		return c.err
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, &buf)
-	if err != nil {
-		return err
+	if len(c.rewriters) > 0 {
+		var err error
+		query, err = c.rewrite(query)
+		if err != nil {
+			return err
+		}
 	}
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return err
+	var span Span
+	if c.tracer != nil {
+		ctx, span = c.tracer.Start(ctx, newOperation(query))
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("non-200 OK status code: %v body: %q", resp.Status, body)
+	cfg := newCallConfig(opts)
+	if c.sampleCapture() {
+		cfg.capture = new(payloadCapture)
 	}
-	var out struct {
-		Data   *json.RawMessage
-		Errors errors
-		//Extensions any // Unused.
+	start := time.Now()
+	var err error
+	c.withProfilerLabels(ctx, query, func(ctx context.Context) {
+		err = c.do(ctx, cfg, query, res, merge, variables)
+	})
+	d := time.Since(start)
+	if err != nil && c.errorContext {
+		err = newOperationError(query, variables, c.unredactedVariables, err)
+	}
+	slow := c.slowThreshold > 0 && d >= c.slowThreshold
+	if slow && c.logger != nil {
+		c.logSlowOperation(ctx, query, variables, d, &cfg.stats)
+	}
+	if c.metrics != nil {
+		c.metrics.Observe(OperationMetrics{
+			Operation:     newOperation(query),
+			Duration:      d,
+			Attempts:      cfg.stats.attempts,
+			RequestBytes:  cfg.stats.requestBytes.Load(),
+			ResponseBytes: cfg.stats.responseBytes.Load(),
+			Timings:       cfg.stats.conn.timings(),
+			Slow:          slow,
+			Err:           err,
+		})
+	}
+	if c.handleStats != nil {
+		c.handleStats(ctx, cfg.stats.stats(newOperation(query), d, err))
+	}
+	if c.audit != nil && operationType(query) == "mutation" {
+		c.auditMutation(ctx, query, variables, start, d, err)
+	}
+	if cfg.capture != nil {
+		c.captureSink(ctx, Capture{
+			Operation: newOperation(query),
+			Time:      start,
+			Duration:  d,
+			Request:   cfg.capture.request,
+			Status:    cfg.capture.status,
+			Response:  cfg.capture.response.Bytes(),
+			Err:       err,
+		})
+	}
+	if span != nil {
+		span.End(err)
+	}
+	return err
+}
+
+// do sends the GraphQL document query and decodes the response into res.
+func (c *Client) do(ctx context.Context, cfg *callConfig, query string, res any, merge bool, variables map[string]any) error {
+	opType := namedOperationType(query, cfg.operationName)
+	if opType != "mutation" {
+		cfg.idempotencyKey = ""
+	} else if cfg.idempotencyKey == "" && c.generateIdempotencyKeys {
+		cfg.idempotencyKey = newIdempotencyKey()
+	}
+	url := c.endpoint(cfg, opType)
+	if c.fingerprintHeader != "" {
+		cfg.fingerprint = fingerprint(query)
+	}
+	var cacheKey string
+	if c.cache != nil && opType == "query" && !cfg.noCache {
+		cacheKey = c.cacheKeyOf(ctx, cfg, query, variables)
+	}
+	if cacheKey != "" {
+		v, ok, err := c.cache.Get(ctx, cacheKey)
+		if ok && err == nil && !cfg.refreshCache {
+			data, expires, ok := decodeCacheValue(v)
+			switch stale := time.Since(expires); {
+			case !ok:
+			case stale < 0:
+				cfg.stats.cacheStatus = "HIT"
+				return c.decodeCached(cfg, data, res, merge)
+			case stale <= c.maxStale:
+				cfg.stats.cacheStatus = "STALE"
+				c.revalidate(ctx, cfg, cacheKey, query, variables)
+				return c.decodeCached(cfg, data, res, merge)
+			}
+		}
+	}
+	in := request{
+		Query:         query,
+		OperationName: cfg.operationName,
+		Variables:     variables,
+		Extensions:    cfg.extensions,
+	}
+	var doc, sent *ast.Document // Documents as written and sent, for the normalized cache.
+	if c.entities != nil && (opType == "query" || opType == "mutation") && !cfg.noCache {
+		if d, err := ast.Parse(query); err == nil {
+			if data, ok := c.entities.read(d, variables); ok && !cfg.refreshCache {
+				cfg.stats.cacheStatus = "HIT"
+				return c.decodeData(cfg, data, res, merge)
+			}
+			// Parse the document again for a copy to add __typename fields to.
+			doc = d
+			sent, _ = ast.Parse(query)
+			addTypenames(sent)
+			in.Query = sent.String()
+		}
+	}
+	gen := c.authGeneration()
+	out, err := c.sendWithRetry(ctx, cfg, url, in)
+	if c.refreshAuth != nil && unauthenticated(out, err) {
+		// Refresh credentials and replay the request once.
+		err = c.refreshAuthHeader(ctx, gen)
+		if err != nil {
+			return err
+		}
+		out, err = c.sendWithRetry(ctx, cfg, url, in)
 	}
-	err = json.NewDecoder(resp.Body).Decode(&out)
 	if err != nil {
-		// TODO: Consider including response body in returned error, if deemed helpful.
 		return err
 	}
+	if cfg.responseHeader != nil {
+		*cfg.responseHeader = out.header
+	}
+	if cfg.apolloTrace != nil {
+		// A malformed trace doesn't fail the operation.
+		decodeApolloTrace(out.Extensions, cfg.apolloTrace)
+	}
+	if c.handleDeprecation != nil {
+		for _, d := range out.deprecations() {
+			c.handleDeprecation(ctx, query, d)
+		}
+	}
+	if c.handleWarning != nil {
+		var warnings Errors
+		out.Errors, warnings = out.Errors.splitWarnings()
+		for _, w := range warnings {
+			c.handleWarning(ctx, query, w)
+		}
+	}
+	var errs error
+	if out.rawErrors != nil {
+		errs = c.decodeErrors(out.rawErrors)
+	} else if e := out.Errors.withoutCodes(cfg.ignoredCodes); len(e) > 0 {
+		errs = e
+	}
+	var data json.RawMessage
+	if out.Data != nil {
+		data = *out.Data
+		if doc != nil {
+			// Leave out the added __typename fields, and cache entities
+			// only if the response is complete.
+			store := c.entities
+			if errs != nil {
+				store = newEntityStore(0, 0)
+			}
+			data, err = store.normalize(sent, doc, variables, data, cfg.cacheTTL)
+			if err != nil {
+				return &DecodeError{Err: err}
+			}
+		}
+	}
+	if errs != nil && cacheKey != "" {
+		c.cacheFailure(ctx, cacheKey, data, errs)
+	}
+	if errs != nil && cfg.errorPolicy == ErrorPolicyNone {
+		return errs
+	}
 	if out.Data != nil {
+		err = c.decodeData(cfg, data, res, merge)
+		if err != nil {
+			return err
+		}
+		if cacheKey != "" && errs == nil {
+			ttl := c.cacheTTL
+			if c.cacheHints {
+				p := out.cachePolicy()
+				if p.hasMaxAge {
+					ttl = p.maxAge
+				}
+				if p.private && c.sharedCache {
+					ttl = 0
+				}
+			}
+			if cfg.cacheTTL != 0 {
+				ttl = cfg.cacheTTL
+			}
+			if ttl > 0 {
+				c.cache.Set(ctx, cacheKey, encodeCacheValue(data, time.Now().Add(ttl)), ttl+c.maxStale)
+			}
+		}
+	}
+	if errs != nil && cfg.errorPolicy != ErrorPolicyIgnore {
+		if e, ok := errs.(Errors); ok && out.Data != nil {
+			return &PartialDataError{Errors: e}
+		}
+		return errs
+	}
+	return nil
+}
+
+// decodeData decodes the data of a response into res,
+// merging it into res's current value if merge is true.
+func (c *Client) decodeData(cfg *callConfig, data json.RawMessage, res any, merge bool) error {
+	if res == nil {
+		// The result is only being cached.
+		return nil
+	}
+	decodeStart := time.Now()
+	var err error
+	switch {
+	case c.parallelDecoding > 0:
+		p := jsonutil.Parallel{MinElements: c.parallelDecoding, Workers: runtime.GOMAXPROCS(0)}
 		if merge {
-			err = jsonutil.MergeUnmarshalGraphQL(*out.Data, res)
+			err = p.MergeUnmarshal(data, res)
 		} else {
-			err = jsonutil.UnmarshalGraphQL(*out.Data, res)
+			err = p.Unmarshal(data, res)
 		}
+	case merge:
+		err = jsonutil.MergeUnmarshalGraphQL(data, res)
+	default:
+		err = jsonutil.UnmarshalGraphQL(data, res)
+	}
+	cfg.stats.decode += time.Since(decodeStart)
+	if err != nil {
+		// TODO: Consider including response body in returned error, if deemed helpful.
+		return &DecodeError{Err: err}
+	}
+	return nil
+}
 
+// endpoint returns the URL to send an operation of type opType to.
+// The per-call URL takes precedence over the URL for the operation type,
+// which in turn takes precedence over the client's URL.
+func (c *Client) endpoint(cfg *callConfig, opType string) string {
+	if cfg.url != "" {
+		return cfg.url
+	}
+	if url, ok := c.urls[opType]; ok {
+		return url
+	}
+	return c.url
+}
+
+// rewrite parses query, passes it through c.rewriters in order,
+// and returns the resulting document.
+func (c *Client) rewrite(query string) (string, error) {
+	doc, err := ast.Parse(query)
+	if err != nil {
+		return "", err
+	}
+	for _, rewrite := range c.rewriters {
+		err := rewrite(doc)
 		if err != nil {
-			// TODO: Consider including response body in returned error, if deemed helpful.
-			return err
+			return "", err
 		}
 	}
-	if len(out.Errors) > 0 {
-		return out.Errors
+	return doc.String(), nil
+}
+
+// sendOnce makes a single attempt at sending in to url.
+func (c *Client) sendOnce(ctx context.Context, cfg *callConfig, url string, in request) (*response, error) {
+	cfg.stats.attempts++
+	if c.persistedQueries {
+		return c.doPersisted(ctx, cfg, url, in)
 	}
-	return nil
+	if c.batchable(ctx, cfg, in) {
+		return c.sendBatched(ctx, cfg, url, in)
+	}
+	return c.send(ctx, cfg, http.MethodPost, url, in)
+}
+
+// request is the body of a GraphQL request.
+type request struct {
+	Query         string         `json:"query,omitempty"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	Extensions    map[string]any `json:"extensions,omitempty"`
+
+	hashedQuery string // Document whose hash is sent in place of Query, if any.
+}
+
+// response is the body of a GraphQL response.
+type response struct {
+	Data       *json.RawMessage
+	Errors     Errors
+	Extensions map[string]json.RawMessage
+
+	header    http.Header     // Header of the HTTP response.
+	rawErrors json.RawMessage // The "errors" member, if non-null and decodeErrors is set.
 }
 
-// errors represents the "errors" array in a response from a GraphQL server.
-// If returned via error interface, the slice is expected to contain at least 1 element.
-//
-// Specification: https://spec.graphql.org/October2021/#sec-Errors.
-type errors []struct {
-	Message   string
-	Locations []struct {
-		Line   int
-		Column int
+// send sends in to url as a single HTTP request using the given method,
+// and decodes the response. Only GET and POST methods are supported.
+func (c *Client) send(ctx context.Context, cfg *callConfig, method, url string, in request) (out *response, err error) {
+	var status int // Status code of the response, if any.
+	if c.logger != nil {
+		start := time.Now()
+		defer func() { c.logRequest(ctx, in, time.Since(start), status, err) }()
+	}
+	ctx = cfg.stats.conn.trace(ctx)
+	if cfg.capture != nil {
+		cfg.capture.start(in)
+	}
+	var req *http.Request
+	switch method {
+	case http.MethodGet:
+		u, err := encodeGET(url, in)
+		if err != nil {
+			return nil, err
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		if cfg.streamBody {
+			var err error
+			req, err = newStreamingRequest(ctx, url, in, cfg.knownLength)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			buf := getBuffer()
+			defer buf.release()
+			err := buf.enc.Encode(in)
+			if err != nil {
+				return nil, err
+			}
+			req, err = http.NewRequestWithContext(ctx, http.MethodPost, url, buf.body())
+			if err != nil {
+				return nil, err
+			}
+			req.ContentLength = int64(buf.Len())
+			req.GetBody = func() (io.ReadCloser, error) { return buf.body(), nil }
+		}
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if req.ContentLength > 0 {
+		cfg.stats.requestBytes.Add(req.ContentLength)
+	} else if req.Body != nil {
+		// Count the bytes of a body of unknown length as they're sent.
+		req.Body = countingReader{req.Body, &cfg.stats.requestBytes}
+	}
+	c.setHeaders(req.Header)
+	if c.tracer != nil {
+		c.tracer.Inject(ctx, req.Header)
+	}
+	if cfg.idempotencyKey != "" {
+		req.Header.Set(c.idempotencyHeader, cfg.idempotencyKey)
+	}
+	if cfg.apolloTrace != nil {
+		req.Header.Set("Apollo-Federation-Include-Trace", "ftv1")
+	}
+	if cfg.fingerprint != "" {
+		req.Header.Set(c.fingerprintHeader, cfg.fingerprint)
+	}
+	if deadline, ok := ctx.Deadline(); ok && c.deadlineHeader != "" {
+		req.Header.Set(c.deadlineHeader, c.formatDeadline(time.Until(deadline)))
+	}
+	var validated *validatedResponse // Response kept for a conditional request, if any.
+	if c.validated != nil && method == http.MethodGet && !cfg.noCache {
+		validated = c.validated.get(req.URL.String())
+		if validated != nil {
+			validated.setConditionalHeaders(req.Header)
+		}
+	}
+	if c.dump != nil {
+		c.dumpRequest(req)
+	}
+	roundTripStart := time.Now()
+	resp, err := c.httpClient.Do(req)
+	cfg.stats.roundTrip += time.Since(roundTripStart)
+	if err != nil {
+		return nil, &NetworkError{Err: err}
+	}
+	defer resp.Body.Close()
+	status = resp.StatusCode
+	cfg.stats.cacheStatus = cacheStatus(resp.Header)
+	if c.dump != nil {
+		c.dumpResponse(resp)
+	}
+	var body io.Reader = countingReader{resp.Body, &cfg.stats.responseBytes}
+	if cfg.capture != nil {
+		cfg.capture.status = resp.StatusCode
+		body = io.TeeReader(body, &cfg.capture.response)
+	}
+	var rec *validatorRecorder
+	if resp.StatusCode == http.StatusNotModified && validated != nil {
+		cfg.stats.cacheStatus = "HIT"
+		resp.StatusCode = http.StatusOK
+		body = bytes.NewReader(validated.body)
+	} else if c.validated != nil && method == http.MethodGet && resp.StatusCode == http.StatusOK && !cfg.noCache {
+		rec, body = newValidatorRecorder(resp, body)
 	}
+	if c.verifyResponse != nil {
+		b, err := io.ReadAll(body)
+		if err != nil {
+			return nil, &NetworkError{Err: err}
+		}
+		err = c.verifyResponse(resp.Header, b)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newHTTPError(resp, body)
+	}
+	buf := getBuffer()
+	defer buf.release()
+	decodeStart := time.Now()
+	_, err = buf.ReadFrom(body)
+	if err != nil {
+		return nil, &NetworkError{Err: err}
+	}
+	out, err = c.decodeResponse(buf.Bytes())
+	cfg.stats.decode += time.Since(decodeStart)
+	if err != nil {
+		var prefix prefixWriter
+		prefix.Write(buf.Bytes())
+		return nil, &DecodeError{
+			Err:         err,
+			Status:      resp.Status,
+			ContentType: resp.Header.Get("Content-Type"),
+			Body:        prefix.b,
+			truncated:   prefix.truncated,
+		}
+	}
+	if rec != nil && len(out.Errors) == 0 && out.rawErrors == nil {
+		c.validated.set(req.URL.String(), rec.response())
+	}
+	out.header = resp.Header
+	c.prepareErrors(out.Errors)
+	return out, nil
 }
 
-// Error implements error interface.
-func (e errors) Error() string {
-	return e[0].Message
+// setHeaders sets the client's default headers, except Content-Type,
+// and its current authentication headers in h.
+func (c *Client) setHeaders(h http.Header) {
+	for k, vs := range c.header {
+		if k == "Content-Type" {
+			continue
+		}
+		h[k] = vs
+	}
+	c.authMu.RLock()
+	for k, vs := range c.authHeader {
+		h[k] = vs
+	}
+	c.authMu.RUnlock()
 }
@@ -2,12 +2,16 @@ package graphql_test
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
 
-	"github.com/shurcooL/graphql"
+	"github.com/isihu/graphql"
+	"github.com/isihu/graphql/ast"
 )
 
 func TestClient_Query_partialDataWithErrorResponse(t *testing.T) {
@@ -55,6 +59,12 @@ func TestClient_Query_partialDataWithErrorResponse(t *testing.T) {
 	if got, want := err.Error(), "Could not resolve to a node with the global id of 'NotExist'"; got != want {
 		t.Errorf("got error: %v, want: %v", got, want)
 	}
+	var pe *graphql.PartialDataError
+	if !errors.As(err, &pe) {
+		t.Errorf("got error: %T, want a *graphql.PartialDataError", err)
+	} else if got, want := pe.Errors[0].Path, []any{"node2"}; !slices.Equal(got, want) {
+		t.Errorf("got error path: %v, want: %v", got, want)
+	}
 	if q.Node1 == nil || q.Node1.ID != "MDEyOklzc3VlQ29tbWVudDE2OTQwNzk0Ng==" {
 		t.Errorf("got wrong q.Node1: %v", q.Node1)
 	}
@@ -63,6 +73,53 @@ func TestClient_Query_partialDataWithErrorResponse(t *testing.T) {
 	}
 }
 
+func TestClient_Query_errorObjects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{
+			"data": {"viewer": {"repositories": [null]}},
+			"errors": [
+				{
+					"message": "Resource not accessible",
+					"locations": [{"line": 1, "column": 21}],
+					"path": ["viewer", "repositories", 0],
+					"extensions": {"code": "FORBIDDEN", "retryAfter": 5}
+				},
+				{"message": "Rate limit exceeded"}
+			]
+		}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Viewer struct {
+			Repositories []*struct{ Name graphql.String }
+		}
+	}
+	err := client.Query(context.Background(), &q, nil)
+	var errs graphql.Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("got error: %v, want a graphql.Errors", err)
+	}
+	if got, want := len(errs), 2; got != want {
+		t.Fatalf("got %d errors, want: %d", got, want)
+	}
+	e := errs[0]
+	if got, want := e.Locations, []graphql.Location{{Line: 1, Column: 21}}; !slices.Equal(got, want) {
+		t.Errorf("got locations: %v, want: %v", got, want)
+	}
+	if got, want := e.Path, []any{"viewer", "repositories", 0}; !slices.Equal(got, want) {
+		t.Errorf("got path: %v, want: %v", got, want)
+	}
+	if got, want := e.Extensions["code"], "FORBIDDEN"; got != want {
+		t.Errorf("got code extension: %v, want: %v", got, want)
+	}
+	if got, want := errs[1].Message, "Rate limit exceeded"; got != want {
+		t.Errorf("got second message: %q, want: %q", got, want)
+	}
+}
+
 func TestClient_Query_noDataWithErrorResponse(t *testing.T) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
@@ -95,6 +152,9 @@ func TestClient_Query_noDataWithErrorResponse(t *testing.T) {
 	if got, want := err.Error(), "Field 'user' is missing required arguments: login"; got != want {
 		t.Errorf("got error: %v, want: %v", got, want)
 	}
+	if pe := (*graphql.PartialDataError)(nil); errors.As(err, &pe) {
+		t.Errorf("got a *graphql.PartialDataError for a response without data, want: graphql.Errors")
+	}
 	if q.User.Name != "" {
 		t.Errorf("got non-empty q.User.Name: %v", q.User.Name)
 	}
@@ -152,6 +212,276 @@ func TestClient_Query_emptyVariables(t *testing.T) {
 	}
 }
 
+func TestClient_Query_withURL(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		t.Error("request sent to default URL, want override URL")
+	})
+	mux.HandleFunc("/canary/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Canary"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+	err := client.Query(context.Background(), &q, nil, graphql.WithURL("/canary/graphql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.User.Name, "Canary"; got != want {
+		t.Errorf("got q.User.Name: %q, want: %q", got, want)
+	}
+}
+
+func TestClient_operationURLs(t *testing.T) {
+	var got []string
+	mux := http.NewServeMux()
+	for _, path := range []string{"/graphql", "/replica/graphql", "/primary/graphql"} {
+		mux.HandleFunc(path, func(w http.ResponseWriter, req *http.Request) {
+			got = append(got, req.URL.Path)
+			w.Header().Set("Content-Type", "application/json")
+			mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+		})
+	}
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithQueryURL("/replica/graphql"),
+		graphql.WithMutationURL("/primary/graphql"),
+	)
+
+	var v struct {
+		Viewer struct {
+			Login string
+		}
+	}
+	for _, err := range []error{
+		client.Query(context.Background(), &v, nil),
+		client.Mutate(context.Background(), &v, nil),
+		client.Query(context.Background(), &v, nil, graphql.WithURL("/graphql")),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []string{"/replica/graphql", "/primary/graphql", "/graphql"}; !slices.Equal(got, want) {
+		t.Errorf("got paths: %v, want: %v", got, want)
+	}
+}
+
+func TestClient_Query_withExtensions(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"{user{name}}","extensions":{"clientLibrary":{"name":"graphql"},"tracing":true}}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+	err := client.Query(context.Background(), &q, nil,
+		graphql.WithExtensions(map[string]any{"tracing": false}),
+		graphql.WithExtensions(map[string]any{"tracing": true, "clientLibrary": map[string]any{"name": "graphql"}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClient_Do_withOperationName(t *testing.T) {
+	const doc = `query GetUser{user{name}} mutation Follow{follow(login:"x"){ok}}`
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"`+strings.ReplaceAll(doc, `"`, `\"`)+`","operationName":"Follow"}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		// The selected operation is a mutation, so it gets an idempotency key.
+		if req.Header.Get("Idempotency-Key") == "" {
+			t.Error("got no idempotency key, want one")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"follow": {"ok": true}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithIdempotencyKeys(""))
+
+	var m struct {
+		Follow struct {
+			OK bool
+		}
+	}
+	if err := client.Do(context.Background(), doc, &m, false, nil, graphql.WithOperationName("Follow")); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Follow.OK {
+		t.Error("got ok: false, want: true")
+	}
+}
+
+func TestClient_Query_defaultHeaders(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		for _, h := range []struct{ key, want string }{
+			{"User-Agent", "test-agent/1.0"},
+			{"Apollographql-Client-Name", "test-app"},
+			{"Apollographql-Client-Version", "1.2.3"},
+			{"X-Tenant", "acme"},
+			{"Content-Type", "application/json"},
+		} {
+			if got := req.Header.Get(h.key); got != h.want {
+				t.Errorf("got %v header: %q, want: %q", h.key, got, h.want)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithUserAgent("test-agent/1.0"),
+		graphql.WithClientAwareness("test-app", "1.2.3"),
+		graphql.WithHeader("X-Tenant", "acme"),
+		graphql.WithHeader("Content-Type", "text/plain"), // Should be ignored.
+	)
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+	err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClient_Query_responseVerifier(t *testing.T) {
+	const body = `{"data": {"user": {"name": "Gopher"}}}`
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Signature", "valid")
+		mustWrite(w, body)
+	})
+	errBadSignature := errors.New("bad signature")
+	verifier := func(want string) func(http.Header, []byte) error {
+		return func(header http.Header, b []byte) error {
+			if string(b) != body {
+				t.Errorf("got body: %s, want: %s", b, body)
+			}
+			if header.Get("Signature") != want {
+				return errBadSignature
+			}
+			return nil
+		}
+	}
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithResponseVerifier(verifier("valid")))
+	err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.User.Name, "Gopher"; got != want {
+		t.Errorf("got q.User.Name: %q, want: %q", got, want)
+	}
+
+	q.User.Name = ""
+	client = graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithResponseVerifier(verifier("other")))
+	err = client.Query(context.Background(), &q, nil)
+	if err != errBadSignature {
+		t.Errorf("got error: %v, want: %v", err, errBadSignature)
+	}
+	if q.User.Name != "" {
+		t.Errorf("got non-empty q.User.Name: %v", q.User.Name)
+	}
+}
+
+func TestClient_Query_documentRewriter(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		if got, want := body, `{"query":"{user@cached{name,__typename}}"}`+"\n"; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher", "__typename": "User"}}}`)
+	})
+	addTypename := func(doc *ast.Document) error {
+		for _, op := range doc.Operations() {
+			for _, sel := range op.SelectionSet {
+				if f, ok := sel.(*ast.Field); ok {
+					f.SelectionSet = append(f.SelectionSet, &ast.Field{Name: "__typename"})
+				}
+			}
+		}
+		return nil
+	}
+	addDirective := func(doc *ast.Document) error {
+		f := doc.Operations()[0].SelectionSet[0].(*ast.Field)
+		f.Directives = append(f.Directives, &ast.Directive{Name: "cached"})
+		return nil
+	}
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithDocumentRewriter(addTypename),
+		graphql.WithDocumentRewriter(addDirective),
+	)
+
+	var q struct {
+		User struct {
+			Name     string
+			Typename string `graphql:"__typename"`
+		} `graphql:"user"`
+	}
+	// Query only for name, leaving __typename to the rewriter.
+	err := client.Do(context.Background(), `{user{name}}`, &q, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.User.Typename, "User"; got != want {
+		t.Errorf("got q.User.Typename: %q, want: %q", got, want)
+	}
+}
+
+func TestClient_Query_parallelDecoding(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"users": {"nodes": [{"name": "Gopher"}, {"name": "Ferris"}, {"name": "Duke"}]}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithParallelDecoding(2))
+
+	var q struct {
+		Users struct {
+			Nodes []struct{ Name string }
+		} `graphql:"users(first:3)"`
+	}
+	err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, n := range q.Users.Nodes {
+		names = append(names, n.Name)
+	}
+	if got, want := strings.Join(names, ","), "Gopher,Ferris,Duke"; got != want {
+		t.Errorf("got names: %q, want: %q", got, want)
+	}
+}
+
 // localRoundTripper is an http.RoundTripper that executes HTTP transactions
 // by using handler directly, instead of going over an HTTP connection.
 type localRoundTripper struct {
@@ -0,0 +1,181 @@
+package graphqltest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/isihu/graphql/ast"
+)
+
+// ExpectedRequest describes the GraphQL request a test expects its code
+// to send. Its zero fields match anything.
+type ExpectedRequest struct {
+	// Query is the expected document. It's compared with the query of
+	// a request but for formatting.
+	Query string
+
+	OperationName string
+
+	// Variables are the expected variables, compared as JSON, so they may
+	// be of graphql types, e.g., graphql.String. Nil means any variables.
+	Variables map[string]any
+
+	// PartialVariables makes Variables match requests whose variables
+	// have the expected ones, and others besides, also in input objects.
+	PartialVariables bool
+
+	// IgnoreVariables are the paths of variables, or of fields of input
+	// objects in variables, whose values aren't compared, such as
+	// "input.clientMutationId".
+	IgnoreVariables []string
+}
+
+// AssertRequest fails t if the request r doesn't match want,
+// reporting how they differ.
+func AssertRequest(t testing.TB, r Request, want ExpectedRequest) {
+	t.Helper()
+	if diff := want.diff(r); diff != "" {
+		t.Errorf("graphqltest: request for operation %q doesn't match:\n%s", r.OperationName, diff)
+	}
+}
+
+// AssertEquivalentDocuments fails t if the GraphQL documents got and want
+// aren't semantically the same, as ast.Equivalent reports, showing how their
+// canonical forms differ.
+func AssertEquivalentDocuments(t testing.TB, got, want string) {
+	t.Helper()
+	gd, err := ast.Parse(got)
+	if err != nil {
+		t.Fatalf("graphqltest: got document: %v", err)
+	}
+	wd, err := ast.Parse(want)
+	if err != nil {
+		t.Fatalf("graphqltest: want document: %v", err)
+	}
+	g, w := ast.Canonical(gd).Indent("\t"), ast.Canonical(wd).Indent("\t")
+	if g != w {
+		t.Errorf("graphqltest: documents aren't equivalent:\n%s", lineDiff(w, g))
+	}
+}
+
+// MatchRequest returns a Matcher of requests that match want,
+// for use with Server.Expect.
+func MatchRequest(want ExpectedRequest) Matcher {
+	return func(r *Request) bool { return want.diff(*r) == "" }
+}
+
+// diff returns a description of how r differs from e, or "" if it matches e.
+func (e ExpectedRequest) diff(r Request) string {
+	var sb strings.Builder
+	if e.OperationName != "" && r.OperationName != e.OperationName {
+		fmt.Fprintf(&sb, "operation name: got %q, want %q\n", r.OperationName, e.OperationName)
+	}
+	if e.Query != "" {
+		if got, want := indentDocument(r.Query), indentDocument(e.Query); got != want {
+			sb.WriteString("query:\n" + lineDiff(want, got))
+		}
+	}
+	if e.Variables != nil {
+		got := normalizeJSON(r.Variables)
+		if got == nil {
+			got = map[string]any{}
+		}
+		var diffs []string
+		diffValues(&diffs, "", got, normalizeJSON(e.Variables), e.PartialVariables, e.IgnoreVariables)
+		if len(diffs) > 0 {
+			sb.WriteString("variables:\n")
+			for _, d := range diffs {
+				sb.WriteString("  " + d + "\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+// indentDocument returns the GraphQL document query indented, one selection
+// per line, or query as is if it isn't valid.
+func indentDocument(query string) string {
+	doc, err := ast.Parse(query)
+	if err != nil {
+		return query
+	}
+	return doc.Indent("\t")
+}
+
+// normalizeJSON returns v as decoded JSON, or v as is if it can't be encoded.
+func normalizeJSON(v any) any {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var normalized any
+	json.Unmarshal(b, &normalized)
+	return normalized
+}
+
+// diffValues appends descriptions of how the decoded JSON value got differs
+// from want at path to diffs. If partial is true, objects may have keys
+// besides the wanted ones. Values at the paths ignored aren't compared.
+func diffValues(diffs *[]string, path string, got, want any, partial bool, ignored []string) {
+	if slices.Contains(ignored, path) {
+		return
+	}
+	gm, gok := got.(map[string]any)
+	wm, wok := want.(map[string]any)
+	if !gok || !wok {
+		gl, gok := got.([]any)
+		wl, wok := want.([]any)
+		if gok && wok && len(gl) == len(wl) {
+			for i := range gl {
+				diffValues(diffs, fmt.Sprintf("%s[%d]", path, i), gl[i], wl[i], partial, ignored)
+			}
+			return
+		}
+		if !reflect.DeepEqual(got, want) {
+			*diffs = append(*diffs, fmt.Sprintf("%s: got %s, want %s", path, formatJSON(got), formatJSON(want)))
+		}
+		return
+	}
+	keys := make([]string, 0, len(wm))
+	for k := range wm {
+		keys = append(keys, k)
+	}
+	if !partial {
+		for k := range gm {
+			if _, ok := wm[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		p := k
+		if path != "" {
+			p = path + "." + k
+		}
+		gv, gok := gm[k]
+		wv, wok := wm[k]
+		switch {
+		case slices.Contains(ignored, p):
+		case !gok:
+			*diffs = append(*diffs, fmt.Sprintf("%s: missing, want %s", p, formatJSON(wv)))
+		case !wok:
+			*diffs = append(*diffs, fmt.Sprintf("%s: unexpected %s", p, formatJSON(gv)))
+		default:
+			diffValues(diffs, p, gv, wv, partial, ignored)
+		}
+	}
+}
+
+// formatJSON formats the decoded JSON value v as JSON.
+func formatJSON(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
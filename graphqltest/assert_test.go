@@ -0,0 +1,88 @@
+package graphqltest_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/isihu/graphql"
+	"github.com/isihu/graphql/graphqltest"
+)
+
+type createIssueMutation struct {
+	CreateIssue struct {
+		Issue struct{ Number int }
+	} `graphql:"createIssue(input:$input)"`
+}
+
+type createIssueInput struct {
+	Title            graphql.String `json:"title"`
+	Body             graphql.String `json:"body"`
+	ClientMutationID graphql.String `json:"clientMutationId"`
+}
+
+func TestAssertRequest(t *testing.T) {
+	s := graphqltest.NewServer(t)
+	s.Expect(graphqltest.MatchRequest(graphqltest.ExpectedRequest{
+		Variables:        map[string]any{"input": map[string]any{"title": graphql.String("Bug")}},
+		PartialVariables: true,
+	})).RespondData(`{"createIssue": {"issue": {"number": 1}}}`)
+	client := s.Client()
+
+	var m createIssueMutation
+	input := createIssueInput{Title: "Bug", Body: "It crashes.", ClientMutationID: "abc123"}
+	if err := client.Mutate(context.Background(), &m, map[string]any{"input": input}); err != nil {
+		t.Fatal(err)
+	}
+	r := s.Requests()[0]
+
+	graphqltest.AssertRequest(t, r, graphqltest.ExpectedRequest{
+		Query: `mutation($input: createIssueInput!) {
+			createIssue(input: $input) { issue { number } }
+		}`,
+		Variables: map[string]any{"input": map[string]any{
+			"title":            "Bug",
+			"body":             "It crashes.",
+			"clientMutationId": "different",
+		}},
+		IgnoreVariables: []string{"input.clientMutationId"},
+	})
+
+	tb := new(fakeTB)
+	graphqltest.AssertRequest(tb, r, graphqltest.ExpectedRequest{
+		Query:         `mutation($input:createIssueInput!){createIssue(input:$input){issue{number,title}}}`,
+		OperationName: "CreateIssue",
+		Variables:     map[string]any{"input": map[string]any{"title": "Feature", "labels": []string{"bug"}}},
+	})
+	if got, want := len(tb.errs), 1; got != want {
+		t.Fatalf("got %d failures, want: %d", got, want)
+	}
+	for _, want := range []string{
+		`operation name: got "", want "CreateIssue"`,
+		"@@ line 5 @@\n- \t\t\ttitle\n",
+		`input.body: unexpected "It crashes."`,
+		`input.clientMutationId: unexpected "abc123"`,
+		`input.labels: missing, want ["bug"]`,
+		`input.title: got "Bug", want "Feature"`,
+	} {
+		if !strings.Contains(tb.errs[0], want) {
+			t.Errorf("got failure:\n%s\nwant it to contain:\n%s", tb.errs[0], want)
+		}
+	}
+}
+
+func TestAssertEquivalentDocuments(t *testing.T) {
+	graphqltest.AssertEquivalentDocuments(t,
+		`query($o:String!$n:String!){repository(owner:$o,name:$n){name,id}}`,
+		`query($name: String!, $owner: String!) { repository(name: $name, owner: $owner) { id name } }`,
+	)
+
+	tb := new(fakeTB)
+	graphqltest.AssertEquivalentDocuments(tb, `{viewer{login,id}}`, `{viewer{login}}`)
+	if got, want := len(tb.errs), 1; got != want {
+		t.Fatalf("got %d failures, want: %d", got, want)
+	}
+	if want := "@@ line 3 @@\n+ \t\tid\n"; !strings.Contains(tb.errs[0], want) {
+		t.Errorf("got failure:\n%s\nwant it to contain:\n%s", tb.errs[0], want)
+	}
+}
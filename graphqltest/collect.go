@@ -0,0 +1,98 @@
+package graphqltest
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/isihu/graphql/ast"
+)
+
+// collector collects the fields of selection sets that apply to objects,
+// as GraphQL servers do when executing a document.
+type collector struct {
+	doc       *ast.Document
+	variables map[string]any
+	schema    *ast.Schema // Resolves abstract type conditions, if non-nil.
+}
+
+// collect returns the fields of set that apply to an object of type
+// typename by response key, and their keys in order.
+func (c *collector) collect(typename string, set ast.SelectionSet) ([]string, map[string][]*ast.Field, error) {
+	var keys []string
+	fields := make(map[string][]*ast.Field)
+	err := c.collectInto(typename, set, &keys, fields)
+	return keys, fields, err
+}
+
+func (c *collector) collectInto(typename string, set ast.SelectionSet, keys *[]string, fields map[string][]*ast.Field) error {
+	for _, sel := range set {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			if !c.included(sel.Directives) {
+				continue
+			}
+			key := sel.ResponseKey()
+			if _, ok := fields[key]; !ok {
+				*keys = append(*keys, key)
+			}
+			fields[key] = append(fields[key], sel)
+		case *ast.InlineFragment:
+			if !c.included(sel.Directives) || !c.applies(sel.TypeCondition, typename) {
+				continue
+			}
+			if err := c.collectInto(typename, sel.SelectionSet, keys, fields); err != nil {
+				return err
+			}
+		case *ast.FragmentSpread:
+			if !c.included(sel.Directives) {
+				continue
+			}
+			frag := c.doc.Fragment(sel.Name)
+			if frag == nil {
+				return fmt.Errorf("unknown fragment %q", sel.Name)
+			}
+			if !c.applies(frag.TypeCondition, typename) {
+				continue
+			}
+			if err := c.collectInto(typename, frag.SelectionSet, keys, fields); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applies reports whether a fragment with the type condition cond
+// applies to an object of type typename.
+func (c *collector) applies(cond, typename string) bool {
+	if cond == "" || cond == typename {
+		return true
+	}
+	return c.schema != nil && slices.Contains(c.schema.PossibleTypes(cond), typename)
+}
+
+// included reports whether the @skip and @include directives of a
+// selection, if any, include it.
+func (c *collector) included(dirs []*ast.Directive) bool {
+	for _, d := range dirs {
+		if d.Name != "skip" && d.Name != "include" {
+			continue
+		}
+		cond := false
+		for _, arg := range d.Arguments {
+			if arg.Name != "if" {
+				continue
+			}
+			switch v := arg.Value.(type) {
+			case *ast.BooleanValue:
+				cond = v.Value
+			case *ast.Variable:
+				cond, _ = c.variables[v.Name].(bool)
+			}
+		}
+		if cond == (d.Name == "skip") {
+			return false
+		}
+	}
+	return true
+}
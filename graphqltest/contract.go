@@ -0,0 +1,130 @@
+package graphqltest
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/isihu/graphql/ast"
+)
+
+// Operation is a GraphQL document of operations a client sends,
+// and where it's from.
+type Operation struct {
+	Source string // E.g., "testdata/viewer.json: interaction 1".
+	Query  string
+}
+
+// LoadOperations returns the operations in the files matching the glob
+// patterns, without duplicate documents. Files may be:
+//
+//   - fixtures saved by a Recorder;
+//   - persisted query manifests, in the format of Apollo's, with an
+//     "operations" array of objects with a "body", or as a JSON object
+//     mapping IDs, such as hashes, to documents;
+//   - GraphQL documents, with the extension .graphql or .gql.
+//
+// Requests without documents, such as those of persisted queries sent
+// as hashes only, are skipped.
+func LoadOperations(patterns ...string) ([]Operation, error) {
+	var ops []Operation
+	seen := make(map[string]bool)
+	add := func(source, query string) {
+		if strings.TrimSpace(query) == "" {
+			return
+		}
+		if key := normalizeDocument(query); !seen[key] {
+			seen[key] = true
+			ops = append(ops, Operation{Source: source, Query: query})
+		}
+	}
+	for _, pattern := range patterns {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("graphqltest: no files match %s", pattern)
+		}
+		for _, path := range paths {
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if ext := filepath.Ext(path); ext == ".graphql" || ext == ".gql" {
+				add(path, string(b))
+				continue
+			}
+			var file struct {
+				Interactions []Interaction `json:"interactions"`
+				Operations   []struct {
+					ID   string `json:"id"`
+					Name string `json:"name"`
+					Body string `json:"body"`
+				} `json:"operations"`
+			}
+			if err := json.Unmarshal(b, &file); err != nil {
+				return nil, fmt.Errorf("graphqltest: %s: %v", path, err)
+			}
+			switch {
+			case file.Interactions != nil:
+				for i, in := range file.Interactions {
+					add(fmt.Sprintf("%s: interaction %d", path, i+1), in.Request.Query)
+				}
+			case file.Operations != nil:
+				for _, op := range file.Operations {
+					add(fmt.Sprintf("%s: operation %s", path, cmp.Or(op.Name, op.ID)), op.Body)
+				}
+			default:
+				var manifest map[string]string
+				if err := json.Unmarshal(b, &manifest); err != nil {
+					return nil, fmt.Errorf("graphqltest: %s isn't a fixture or manifest", path)
+				}
+				for _, id := range slices.Sorted(maps.Keys(manifest)) {
+					add(fmt.Sprintf("%s: %s", path, id), manifest[id])
+				}
+			}
+		}
+	}
+	return ops, nil
+}
+
+// CheckContract fails t for every operation in the files matching the glob
+// patterns, as LoadOperations loads them, that isn't valid against schema,
+// as ast.Validate reports. Run in CI against the server's current schema,
+// it catches schema changes that break the operations a client sends:
+//
+//	func TestContract(t *testing.T) {
+//		schema, err := ast.ParseSchema(mustRead(t, "schema.graphql"))
+//		...
+//		graphqltest.CheckContract(t, schema, "testdata/*.json")
+//	}
+func CheckContract(t testing.TB, schema *ast.Schema, patterns ...string) {
+	t.Helper()
+	ops, err := LoadOperations(patterns...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range ops {
+		doc, err := ast.Parse(op.Query)
+		if err != nil {
+			t.Errorf("graphqltest: %s: %v", op.Source, err)
+			continue
+		}
+		errs := ast.Validate(schema, doc)
+		if len(errs) == 0 {
+			continue
+		}
+		var sb strings.Builder
+		for _, err := range errs {
+			sb.WriteString("\n\t" + err.Error())
+		}
+		t.Errorf("graphqltest: %s isn't valid against the schema:%s", op.Source, sb.String())
+	}
+}
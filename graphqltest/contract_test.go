@@ -0,0 +1,53 @@
+package graphqltest_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/isihu/graphql/ast"
+	"github.com/isihu/graphql/graphqltest"
+)
+
+func TestCheckContract(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"fixture.json": `{"interactions": [
+			{"request": {"query": "", "extensions": {"persistedQuery": {"version": 1, "sha256Hash": "abc"}}}, "response": {"status": 200}},
+			{"request": {"query": "{viewer{login}}"}, "response": {"status": 200}},
+			{"request": {"query": "query { viewer { login } }"}, "response": {"status": 200}}
+		]}`,
+		"apollo.json": `{"format": "apollo-persisted-query-manifest", "version": 1, "operations": [
+			{"id": "1", "name": "Search", "type": "query", "body": "query Search($q:String!){search(query:$q){__typename}}"}
+		]}`,
+		"hashes.json":  `{"abc": "{viewer{email}}"}`,
+		"user.graphql": `query User($login: String!) { user(login: $login) { login } }`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ops, err := graphqltest.LoadOperations(filepath.Join(dir, "*.json"), filepath.Join(dir, "*.graphql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(ops), 4; got != want {
+		t.Errorf("got %d operations, want: %d: %v", got, want, ops)
+	}
+
+	schema, err := ast.ParseSchema(fakeSchema + `extend type Query { user(login: String!, first: Int!): User }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tb := new(fakeTB)
+	graphqltest.CheckContract(tb, schema, filepath.Join(dir, "*"))
+	if got, want := len(tb.errs), 1; got != want {
+		t.Fatalf("got %d failures, want: %d: %q", got, want, tb.errs)
+	}
+	if want := "user.graphql isn't valid against the schema:\n\tgraphql: User: user: missing required argument first of field Query.user"; !strings.Contains(tb.errs[0], want) {
+		t.Errorf("got failure: %s, want it to contain: %s", tb.errs[0], want)
+	}
+}
@@ -0,0 +1,307 @@
+package graphqltest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/isihu/graphql"
+	"github.com/isihu/graphql/ast"
+)
+
+// Resolver resolves a field of an object. parent is the object, which is
+// nil for the fields of root types, and args are the arguments of the field,
+// with variables substituted, as decoded from JSON by encoding/json.
+type Resolver func(ctx context.Context, parent any, args map[string]any) (any, error)
+
+// Resolvers are resolvers by type and field name, e.g., "Query.viewer".
+type Resolvers map[string]Resolver
+
+// ExecutorOption configures an Executor.
+type ExecutorOption func(*Executor)
+
+// WithSchema makes an Executor use schema for the names of root types,
+// and to apply fragments on interfaces and unions.
+func WithSchema(schema *ast.Schema) ExecutorOption {
+	return func(e *Executor) { e.schema = schema }
+}
+
+// Executor executes GraphQL operations in process, against resolver funcs,
+// so that tests of code building queries and decoding responses don't need
+// a server. It's an http.RoundTripper, so it's used as the transport of
+// a graphql.Client, without sending requests over the network:
+//
+//	e := graphqltest.NewExecutor(graphqltest.Resolvers{
+//		"Query.viewer": func(ctx context.Context, _ any, _ map[string]any) (any, error) {
+//			return User{Login: "gopher"}, nil
+//		},
+//	})
+//	client := e.Client()
+//
+// Fields without resolvers are resolved from their parent object, which may
+// be a map with string keys, or a struct, or a pointer to one, whose field
+// or method without arguments has the same name, but for the case of the
+// first letter, or whose field is tagged with it for JSON. The type of an
+// object, for its resolvers, fragments and __typename, is its "__typename"
+// if it's a map, or else the name of its Go type.
+//
+// Resolver errors are reported in the response, with null for their fields.
+type Executor struct {
+	resolvers Resolvers
+	schema    *ast.Schema
+}
+
+// NewExecutor returns an Executor resolving fields with resolvers.
+func NewExecutor(resolvers Resolvers, opts ...ExecutorOption) *Executor {
+	e := &Executor{resolvers: resolvers}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Client returns a graphql.Client executing operations with e, configured by opts.
+func (e *Executor) Client(opts ...graphql.ClientOption) *graphql.Client {
+	return graphql.NewClient("http://executor.invalid/graphql", &http.Client{Transport: e}, opts...)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (e *Executor) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+	r, err := readRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("graphqltest: malformed request: %v", err)
+	}
+	resp := e.Execute(req.Context(), r)
+	out := struct {
+		Data   any             `json:"data"`
+		Errors []graphql.Error `json:"errors,omitempty"`
+	}{resp.Data, resp.Errors}
+	body, err := json.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("graphqltest: encoding response: %v", err)
+	}
+	return newResponse(req, http.StatusOK, http.Header{"Content-Type": {"application/json"}}, body), nil
+}
+
+// Execute executes the operation of the request r, and returns its response.
+func (e *Executor) Execute(ctx context.Context, r *Request) Response {
+	doc, err := ast.Parse(r.Query)
+	if err != nil {
+		return Response{Errors: []graphql.Error{{Message: err.Error()}}}
+	}
+	var op *ast.OperationDefinition
+	for _, o := range doc.Operations() {
+		if r.OperationName == "" || o.Name == r.OperationName {
+			op = o
+			break
+		}
+	}
+	if op == nil {
+		return Response{Errors: []graphql.Error{{Message: fmt.Sprintf("unknown operation %q", r.OperationName)}}}
+	}
+	if op.Operation == "subscription" {
+		return Response{Errors: []graphql.Error{{Message: "subscriptions aren't supported"}}}
+	}
+	variables := make(map[string]any, len(op.VariableDefinitions))
+	for _, def := range op.VariableDefinitions {
+		if v, ok := r.Variables[def.Variable]; ok {
+			variables[def.Variable] = v
+		} else if def.DefaultValue != nil {
+			variables[def.Variable] = literal(def.DefaultValue, nil)
+		}
+	}
+	root := strings.ToUpper(op.Operation[:1]) + op.Operation[1:]
+	if e.schema != nil {
+		if t := e.schema.RootType(op.Operation); t != nil {
+			root = t.Name
+		}
+	}
+	x := &execution{
+		Executor:  e,
+		collector: collector{doc: doc, variables: variables, schema: e.schema},
+	}
+	data := x.object(ctx, root, nil, op.SelectionSet, nil)
+	return Response{Data: data, Errors: x.errs}
+}
+
+// execution is the state of executing an operation.
+type execution struct {
+	*Executor
+	collector
+	errs []graphql.Error
+}
+
+// object returns the data of the object parent of type typename for set.
+// path is the path of the object in the response.
+func (x *execution) object(ctx context.Context, typename string, parent any, set ast.SelectionSet, path []any) map[string]any {
+	keys, fields, err := x.collect(typename, set)
+	if err != nil {
+		x.errs = append(x.errs, graphql.Error{Message: err.Error(), Path: path})
+		return nil
+	}
+	data := make(map[string]any, len(keys))
+	for _, key := range keys {
+		selected := fields[key]
+		f := selected[0]
+		fieldPath := append(path[:len(path):len(path)], key)
+		if f.Name == "__typename" {
+			data[key] = typename
+			continue
+		}
+		v, err := x.resolve(ctx, typename, parent, f)
+		if err != nil {
+			x.errs = append(x.errs, graphql.Error{Message: err.Error(), Path: fieldPath})
+			data[key] = nil
+			continue
+		}
+		var subset ast.SelectionSet
+		for _, f := range selected {
+			subset = append(subset, f.SelectionSet...)
+		}
+		data[key] = x.complete(ctx, v, subset, fieldPath)
+	}
+	return data
+}
+
+// resolve resolves the field f of the object parent of type typename.
+func (x *execution) resolve(ctx context.Context, typename string, parent any, f *ast.Field) (any, error) {
+	if resolve := x.resolvers[typename+"."+f.Name]; resolve != nil {
+		args := make(map[string]any, len(f.Arguments))
+		for _, arg := range f.Arguments {
+			args[arg.Name] = literal(arg.Value, x.variables)
+		}
+		return resolve(ctx, parent, normalizeJSON(args).(map[string]any))
+	}
+	if parent == nil {
+		return nil, fmt.Errorf("no resolver for %s.%s", typename, f.Name)
+	}
+	v, ok := fieldOf(parent, f.Name)
+	if !ok {
+		return nil, fmt.Errorf("no resolver for %s.%s, and no such field of %T", typename, f.Name, parent)
+	}
+	return v, nil
+}
+
+// complete returns the response value of v for set,
+// whose path in the response is path.
+func (x *execution) complete(ctx context.Context, v any, set ast.SelectionSet, path []any) any {
+	if len(set) == 0 {
+		return v
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil
+		}
+		list := make([]any, rv.Len())
+		for i := range list {
+			list[i] = x.complete(ctx, rv.Index(i).Interface(), set, append(path[:len(path):len(path)], i))
+		}
+		return list
+	}
+	return x.object(ctx, typenameOf(rv.Interface()), v, set, path)
+}
+
+// typenameOf returns the GraphQL type name of the object v.
+func typenameOf(v any) string {
+	if m, ok := v.(map[string]any); ok {
+		if name, ok := m["__typename"].(string); ok {
+			return name
+		}
+	}
+	return reflect.TypeOf(v).Name()
+}
+
+// fieldOf returns the field named name of the object v, which is a map with
+// string keys, a struct or a pointer to one, and reports whether it has one.
+func fieldOf(v any, name string) (any, bool) {
+	rv := reflect.ValueOf(v)
+	if m := rv.MethodByName(exported(name)); m.IsValid() && m.Type().NumIn() == 0 && m.Type().NumOut() == 1 {
+		return m.Call(nil)[0].Interface(), true
+	}
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		fv := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+		if !fv.IsValid() {
+			return nil, true // Missing keys are null.
+		}
+		return fv.Interface(), true
+	case reflect.Struct:
+		t := rv.Type()
+		for i := range t.NumField() {
+			sf := t.Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			tag, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+			if tag == name || tag == "" && sf.Name == exported(name) {
+				return rv.Field(i).Interface(), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// exported returns name with its first letter in upper case.
+func exported(name string) string {
+	r, n := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[n:]
+}
+
+// literal returns the Go value of the input value v,
+// with the values of variables substituted.
+func literal(v ast.Value, variables map[string]any) any {
+	switch v := v.(type) {
+	case *ast.Variable:
+		return variables[v.Name]
+	case *ast.IntValue:
+		return json.Number(v.Raw)
+	case *ast.FloatValue:
+		return json.Number(v.Raw)
+	case *ast.StringValue:
+		return v.Value
+	case *ast.BooleanValue:
+		return v.Value
+	case *ast.EnumValue:
+		return v.Name
+	case *ast.ListValue:
+		list := make([]any, len(v.Values))
+		for i, elem := range v.Values {
+			list[i] = literal(elem, variables)
+		}
+		return list
+	case *ast.ObjectValue:
+		obj := make(map[string]any, len(v.Fields))
+		for _, f := range v.Fields {
+			obj[f.Name] = literal(f.Value, variables)
+		}
+		return obj
+	}
+	return nil // *ast.NullValue.
+}
@@ -0,0 +1,100 @@
+package graphqltest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/isihu/graphql"
+	"github.com/isihu/graphql/graphqltest"
+)
+
+type testUser struct {
+	Login     string
+	Email     string `json:"emailAddress"`
+	followers []string
+}
+
+func (u *testUser) FollowerCount() int { return len(u.followers) }
+
+type testRepository struct {
+	Name string
+}
+
+func TestExecutor(t *testing.T) {
+	gopher := &testUser{Login: "gopher", Email: "gopher@example.com", followers: []string{"a", "b"}}
+	e := graphqltest.NewExecutor(graphqltest.Resolvers{
+		"Query.viewer": func(ctx context.Context, _ any, _ map[string]any) (any, error) {
+			return gopher, nil
+		},
+		"Query.search": func(ctx context.Context, _ any, args map[string]any) (any, error) {
+			if got, want := args["first"], 2.0; got != want {
+				return nil, fmt.Errorf("got first: %v, want: %v", got, want)
+			}
+			return []any{testRepository{Name: args["query"].(string)}, gopher}, nil
+		},
+		"testUser.repositories": func(ctx context.Context, parent any, args map[string]any) (any, error) {
+			return []map[string]any{{"__typename": "Repository", "name": parent.(*testUser).Login + "/graphql"}}, nil
+		},
+	})
+	client := e.Client()
+
+	var q struct {
+		Viewer struct {
+			Login         string
+			EmailAddress  string
+			FollowerCount int
+			Repositories  []struct {
+				Typename string `graphql:"__typename"`
+				Name     string
+			}
+		}
+		Search []struct {
+			Typename   string         `graphql:"__typename"`
+			Repository testRepository `graphql:"... on testRepository"`
+			User       struct {
+				Login string
+			} `graphql:"... on testUser"`
+		} `graphql:"search(query:$query, first:2)"`
+	}
+	if err := client.Query(context.Background(), &q, map[string]any{"query": graphql.String("go")}); err != nil {
+		t.Fatal(err)
+	}
+	v := q.Viewer
+	if v.Login != "gopher" || v.EmailAddress != "gopher@example.com" || v.FollowerCount != 2 {
+		t.Errorf("got viewer: %+v", v)
+	}
+	if got, want := fmt.Sprint(v.Repositories), "[{Repository gopher/graphql}]"; got != want {
+		t.Errorf("got repositories: %s, want: %s", got, want)
+	}
+	if got, want := fmt.Sprint(q.Search), "[{testRepository {go} {}} {testUser {} {gopher}}]"; got != want {
+		t.Errorf("got search: %s, want: %s", got, want)
+	}
+}
+
+func TestExecutor_errors(t *testing.T) {
+	e := graphqltest.NewExecutor(graphqltest.Resolvers{
+		"Query.viewer": func(ctx context.Context, _ any, _ map[string]any) (any, error) {
+			return map[string]any{"login": "gopher"}, nil
+		},
+		"Query.repository": func(ctx context.Context, _ any, _ map[string]any) (any, error) {
+			return nil, errors.New("not found")
+		},
+	})
+	var q struct {
+		Viewer     struct{ Login, Name string }
+		Repository *struct{ Name string }
+	}
+	err := e.Client().Query(context.Background(), &q, nil)
+	var errs graphql.Errors
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Fatalf("got error: %v, want 1 GraphQL error", err)
+	}
+	if got, want := fmt.Sprintf("%v %s", errs[0].Path, errs[0].Message), "[repository] not found"; got != want {
+		t.Errorf("got error: %s, want: %s", got, want)
+	}
+	if got, want := q.Viewer.Login, "gopher"; got != want {
+		t.Errorf("got login: %q, want: %q", got, want)
+	}
+}
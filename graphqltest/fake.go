@@ -0,0 +1,208 @@
+package graphqltest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isihu/graphql"
+	"github.com/isihu/graphql/ast"
+)
+
+// FakeOption configures the data generated by a fake server.
+type FakeOption func(*faker)
+
+// WithListLength makes a fake server generate lists of n elements,
+// instead of 2.
+func WithListLength(n int) FakeOption {
+	return func(f *faker) { f.listLength = n }
+}
+
+// WithScalar makes a fake server generate the values of the scalar type
+// named scalar with gen, which is called with the name of the field and a
+// number that is different for every value of a response.
+func WithScalar(scalar string, gen func(field string, n int) any) FakeOption {
+	return func(f *faker) { f.scalars[scalar] = gen }
+}
+
+// NewFakeServer starts a Server for the test t that answers any request
+// with fake data generated from schema for its selection set, as
+// FakeResponder does, so that tests don't need canned responses.
+func NewFakeServer(t testing.TB, schema *ast.Schema, opts ...FakeOption) *Server {
+	s := NewServer(t)
+	s.Expect(AnyOperation()).AnyTimes().RespondFunc(FakeResponder(schema, opts...))
+	return s
+}
+
+// FakeResponder returns a function responding to requests with fake data
+// generated from schema for their selection sets, for use with
+// Expectation.RespondFunc. The data is plausible and deterministic:
+//
+//   - Strings are derived from field names, e.g., "login-1", and look like
+//     email addresses or URLs for fields named so.
+//   - Ints, Floats and IDs are increasing numbers.
+//   - Booleans are false, so that paginated queries end.
+//   - Enums are their first value.
+//   - Custom scalars are RFC 3339 timestamps if their names contain Date or
+//     Time, and strings otherwise, unless WithScalar sets them.
+//   - Lists have 2 elements, unless WithListLength sets it.
+//   - Interfaces and unions cycle through their possible object types.
+//   - Nullable fields aren't null.
+//
+// Requests that aren't valid against schema are answered with an error.
+func FakeResponder(schema *ast.Schema, opts ...FakeOption) func(r *Request) Response {
+	f := &faker{schema: schema, listLength: 2, scalars: make(map[string]func(string, int) any)}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return func(r *Request) Response {
+		data, err := f.generate(r)
+		if err != nil {
+			return Response{Errors: []graphql.Error{{Message: err.Error()}}}
+		}
+		return Response{Data: data}
+	}
+}
+
+// faker generates fake data from a schema.
+type faker struct {
+	schema     *ast.Schema
+	listLength int
+	scalars    map[string]func(field string, n int) any
+}
+
+// fakeRun is the state of generating the data of a response.
+type fakeRun struct {
+	*faker
+	collector
+	n     int // Number of the last generated value.
+	picks int // Number of objects generated for interfaces and unions.
+}
+
+// fakeEpoch is the time from which fake timestamps are generated.
+var fakeEpoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func (f *faker) generate(r *Request) (map[string]any, error) {
+	doc, err := ast.Parse(r.Query)
+	if err != nil {
+		return nil, err
+	}
+	var op *ast.OperationDefinition
+	for _, o := range doc.Operations() {
+		if r.OperationName == "" || o.Name == r.OperationName {
+			op = o
+			break
+		}
+	}
+	if op == nil {
+		return nil, fmt.Errorf("unknown operation %q", r.OperationName)
+	}
+	root := f.schema.RootType(op.Operation)
+	if root == nil {
+		return nil, fmt.Errorf("schema doesn't support %s operations", op.Operation)
+	}
+	run := &fakeRun{faker: f, collector: collector{doc: doc, variables: r.Variables, schema: f.schema}}
+	return run.object(root.Name, op.SelectionSet)
+}
+
+// object generates the data of the object type typename for set.
+func (r *fakeRun) object(typename string, set ast.SelectionSet) (map[string]any, error) {
+	t := r.faker.schema.Type(typename)
+	keys, fields, err := r.collect(typename, set)
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]any, len(keys))
+	for _, key := range keys {
+		selected := fields[key]
+		name := selected[0].Name
+		if name == "__typename" {
+			data[key] = typename
+			continue
+		}
+		def := t.Field(name)
+		if def == nil {
+			return nil, fmt.Errorf("cannot query field %q on type %q", name, typename)
+		}
+		var subset ast.SelectionSet
+		for _, f := range selected {
+			subset = append(subset, f.SelectionSet...)
+		}
+		v, err := r.value(def.Type, name, subset)
+		if err != nil {
+			return nil, err
+		}
+		data[key] = v
+	}
+	return data, nil
+}
+
+// value generates a value of type t for the field named field,
+// whose selection set is set.
+func (r *fakeRun) value(t *ast.Type, field string, set ast.SelectionSet) (any, error) {
+	if t.Elem != nil {
+		list := make([]any, r.listLength)
+		for i := range list {
+			v, err := r.value(t.Elem, field, set)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v
+		}
+		return list, nil
+	}
+	def := r.faker.schema.Type(t.NamedType)
+	if def == nil {
+		return nil, fmt.Errorf("unknown type %q", t.NamedType)
+	}
+	switch def.Kind {
+	case ast.Object:
+		return r.object(def.Name, set)
+	case ast.Interface, ast.Union:
+		possible := r.faker.schema.PossibleTypes(def.Name)
+		if len(possible) == 0 {
+			return nil, nil
+		}
+		r.picks++
+		return r.object(possible[(r.picks-1)%len(possible)], set)
+	case ast.Enum:
+		if len(def.EnumValues) == 0 {
+			return nil, nil
+		}
+		return def.EnumValues[0], nil
+	}
+	r.n++
+	return r.scalar(def.Name, field, r.n), nil
+}
+
+// scalar returns the nth value of the scalar type typename for the field named field.
+func (r *fakeRun) scalar(typename, field string, n int) any {
+	if gen := r.scalars[typename]; gen != nil {
+		return gen(field, n)
+	}
+	switch typename {
+	case "Int":
+		return n
+	case "Float":
+		return float64(n) + 0.5
+	case "Boolean":
+		return false
+	case "ID":
+		return strconv.Itoa(n)
+	case "String":
+		lower := strings.ToLower(field)
+		switch {
+		case strings.Contains(lower, "email"):
+			return fmt.Sprintf("user%d@example.com", n)
+		case strings.HasSuffix(lower, "url") || strings.HasSuffix(lower, "uri"):
+			return fmt.Sprintf("https://example.com/%s/%d", field, n)
+		}
+	default:
+		if strings.Contains(typename, "Date") || strings.Contains(typename, "Time") {
+			return fakeEpoch.Add(time.Duration(n) * time.Hour).Format(time.RFC3339)
+		}
+	}
+	return fmt.Sprintf("%s-%d", field, n)
+}
@@ -0,0 +1,136 @@
+package graphqltest_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/isihu/graphql"
+	"github.com/isihu/graphql/ast"
+	"github.com/isihu/graphql/graphqltest"
+)
+
+const fakeSchema = `
+type Query {
+	viewer: User!
+	search(query: String!): [SearchResult!]!
+}
+
+type User {
+	id: ID!
+	login: String!
+	email: String!
+	avatarUrl: String!
+	followers: Int!
+	isAdmin: Boolean!
+	status: Status!
+	createdAt: DateTime!
+}
+
+type Repository {
+	name: String!
+	stars: Int!
+}
+
+union SearchResult = User | Repository
+
+enum Status {
+	ACTIVE
+	SUSPENDED
+}
+
+scalar DateTime
+`
+
+func TestNewFakeServer(t *testing.T) {
+	schema, err := ast.ParseSchema(fakeSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := graphqltest.NewFakeServer(t, schema)
+	client := s.Client()
+
+	var q struct {
+		Viewer struct {
+			ID        graphql.ID
+			Login     string
+			Email     string
+			AvatarURL string `graphql:"avatarUrl"`
+			Followers int
+			IsAdmin   bool
+			Status    string
+			CreatedAt string
+		}
+		Search []struct {
+			Typename string `graphql:"__typename"`
+			User     struct {
+				Login string
+			} `graphql:"... on User"`
+			Repository struct {
+				Name string
+			} `graphql:"... on Repository"`
+		} `graphql:"search(query:$query)"`
+	}
+	if err := client.Query(context.Background(), &q, map[string]any{"query": graphql.String("go")}); err != nil {
+		t.Fatal(err)
+	}
+	v := q.Viewer
+	for _, tc := range []struct{ name, got, want string }{
+		{"id", v.ID.(string), "1"},
+		{"login", v.Login, "login-2"},
+		{"email", v.Email, "user3@example.com"},
+		{"avatarUrl", v.AvatarURL, "https://example.com/avatarUrl/4"},
+		{"status", v.Status, "ACTIVE"},
+		{"createdAt", v.CreatedAt, "2024-01-01T07:00:00Z"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("got %s: %q, want: %q", tc.name, tc.got, tc.want)
+		}
+	}
+	if v.Followers != 5 || v.IsAdmin {
+		t.Errorf("got followers, isAdmin: %v, %v, want: 5, false", v.Followers, v.IsAdmin)
+	}
+	if got, want := len(q.Search), 2; got != want {
+		t.Fatalf("got %d search results, want: %d", got, want)
+	}
+	if got, want := q.Search[0].Typename+" "+q.Search[0].Repository.Name, "Repository name-8"; got != want {
+		t.Errorf("got first result: %q, want: %q", got, want)
+	}
+	if got, want := q.Search[1].Typename+" "+q.Search[1].User.Login, "User login-9"; got != want {
+		t.Errorf("got second result: %q, want: %q", got, want)
+	}
+}
+
+func TestFakeResponder(t *testing.T) {
+	schema, err := ast.ParseSchema(fakeSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := graphqltest.NewServer(t)
+	s.Expect(graphqltest.AnyOperation()).RespondFunc(graphqltest.FakeResponder(schema,
+		graphqltest.WithListLength(1),
+		graphqltest.WithScalar("String", func(field string, n int) any { return strings.ToUpper(field) }),
+	))
+	client := s.Client()
+
+	var q struct {
+		Viewer struct {
+			Login string
+			Name  string `graphql:"login @include(if:$withName)"`
+		}
+	}
+	if err := client.Do(context.Background(), `query($withName:Boolean!){viewer{login,name:login @include(if:$withName)}}`, &q, false, map[string]any{"withName": false}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Viewer.Login+"|"+q.Viewer.Name, "LOGIN|"; got != want {
+		t.Errorf("got %q, want: %q", got, want)
+	}
+
+	var bad struct {
+		Viewer struct{ Nickname string }
+	}
+	err = client.Query(context.Background(), &bad, nil)
+	if err == nil || !strings.Contains(err.Error(), `cannot query field "nickname" on type "User"`) {
+		t.Errorf("got error: %v, want cannot query field", err)
+	}
+}
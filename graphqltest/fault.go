@@ -0,0 +1,196 @@
+package graphqltest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Faults configures the faults that a FaultTransport injects. Probabilities
+// are from 0, never, to 1, always. At most one of ServerError, Reset,
+// PartialBody and MalformedJSON is injected into a request, in that order
+// of precedence, besides Latency.
+type Faults struct {
+	// Seed seeds the random choices, so that runs are reproducible.
+	Seed uint64
+
+	// Latency is the probability of delaying a request by a random
+	// duration up to Delay, or until its context is done.
+	Latency float64
+	Delay   time.Duration
+
+	// ServerError is the probability of answering a request with Status,
+	// without sending it. Zero Status means 503 Service Unavailable.
+	ServerError float64
+	Status      int
+
+	// Reset is the probability of failing a request with a connection
+	// reset error after it's sent, so the server may have processed it.
+	Reset float64
+
+	// PartialBody is the probability of cutting a response body short,
+	// so that reading it fails with io.ErrUnexpectedEOF.
+	PartialBody float64
+
+	// MalformedJSON is the probability of replacing a response body
+	// with malformed JSON, read without error.
+	MalformedJSON float64
+}
+
+// FaultStats counts the faults that a FaultTransport injected.
+type FaultStats struct {
+	Requests      int
+	Latency       int
+	ServerError   int
+	Reset         int
+	PartialBody   int
+	MalformedJSON int
+}
+
+// FaultTransport is an http.RoundTripper that injects faults into requests
+// and their responses at random, to exercise the retries, timeouts and error
+// classification of clients:
+//
+//	ft := graphqltest.NewFaultTransport(nil, graphqltest.Faults{Reset: 0.3, ServerError: 0.2})
+//	client := graphql.NewClient(s.URL, &http.Client{Transport: ft}, graphql.WithRetry(policy))
+type FaultTransport struct {
+	next   http.RoundTripper
+	faults Faults
+
+	mu    sync.Mutex
+	rand  *rand.Rand
+	stats FaultStats
+}
+
+// NewFaultTransport returns a FaultTransport sending requests with next,
+// or http.DefaultTransport if it's nil, and injecting faults.
+func NewFaultTransport(next http.RoundTripper, faults Faults) *FaultTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if faults.Status == 0 {
+		faults.Status = http.StatusServiceUnavailable
+	}
+	return &FaultTransport{
+		next:   next,
+		faults: faults,
+		rand:   rand.New(rand.NewPCG(faults.Seed, faults.Seed)),
+	}
+}
+
+// Stats returns the counts of the faults injected so far.
+func (ft *FaultTransport) Stats() FaultStats {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	return ft.stats
+}
+
+// faultPlan is the faults chosen for a request.
+type faultPlan struct {
+	delay                                          time.Duration
+	serverError, reset, partialBody, malformedJSON bool
+}
+
+// plan chooses the faults to inject into a request, and counts them.
+func (ft *FaultTransport) plan() faultPlan {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	f := ft.faults
+	chance := func(p float64, count *int) bool {
+		if p <= 0 || ft.rand.Float64() >= p {
+			return false
+		}
+		*count++
+		return true
+	}
+	var p faultPlan
+	ft.stats.Requests++
+	if f.Delay > 0 && chance(f.Latency, &ft.stats.Latency) {
+		p.delay = time.Duration(ft.rand.Int64N(int64(f.Delay) + 1))
+	}
+	switch {
+	case chance(f.ServerError, &ft.stats.ServerError):
+		p.serverError = true
+	case chance(f.Reset, &ft.stats.Reset):
+		p.reset = true
+	case chance(f.PartialBody, &ft.stats.PartialBody):
+		p.partialBody = true
+	case chance(f.MalformedJSON, &ft.stats.MalformedJSON):
+		p.malformedJSON = true
+	}
+	return p
+}
+
+// RoundTrip implements http.RoundTripper.
+func (ft *FaultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	p := ft.plan()
+	if p.delay > 0 {
+		timer := time.NewTimer(p.delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, req.Context().Err()
+		}
+	}
+	if p.serverError {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		body := []byte(http.StatusText(ft.faults.Status) + "\n")
+		return newResponse(req, ft.faults.Status, http.Header{"Content-Type": {"text/plain; charset=utf-8"}}, body), nil
+	}
+
+	resp, err := ft.next.RoundTrip(req)
+	if err != nil || !p.reset && !p.partialBody && !p.malformedJSON {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case p.reset:
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+	case p.partialBody:
+		resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body[:len(body)/2]), errReader{io.ErrUnexpectedEOF}))
+	case p.malformedJSON:
+		body = append(body[:len(body)/2:len(body)/2], `,"\u`...)
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		resp.ContentLength = int64(len(body))
+		resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+	return resp, nil
+}
+
+// newResponse returns an HTTP/1.1 response to req with status, header and body.
+func newResponse(req *http.Request, status int, header http.Header, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// errReader is an io.Reader that fails with err.
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
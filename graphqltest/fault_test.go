@@ -0,0 +1,82 @@
+package graphqltest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/isihu/graphql"
+	"github.com/isihu/graphql/graphqltest"
+)
+
+func TestFaultTransport(t *testing.T) {
+	tests := []struct {
+		name   string
+		faults graphqltest.Faults
+		check  func(err error) bool
+	}{
+		{"server error", graphqltest.Faults{ServerError: 1, Status: http.StatusBadGateway}, func(err error) bool {
+			var he *graphql.HTTPError
+			return errors.As(err, &he) && he.StatusCode == http.StatusBadGateway
+		}},
+		{"reset", graphqltest.Faults{Reset: 1}, func(err error) bool {
+			var ne *graphql.NetworkError
+			return errors.As(err, &ne) && errors.Is(err, syscall.ECONNRESET)
+		}},
+		{"partial body", graphqltest.Faults{PartialBody: 1}, func(err error) bool {
+			return errors.Is(err, io.ErrUnexpectedEOF)
+		}},
+		{"malformed JSON", graphqltest.Faults{MalformedJSON: 1}, func(err error) bool {
+			var de *graphql.DecodeError
+			return errors.As(err, &de)
+		}},
+		{"latency", graphqltest.Faults{Latency: 1, Delay: time.Hour}, func(err error) bool {
+			return errors.Is(err, context.DeadlineExceeded)
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := graphqltest.NewServer(t)
+			s.Expect(graphqltest.AnyOperation()).RespondData(`{"viewer": {"login": "gopher"}}`).AnyTimes()
+			ft := graphqltest.NewFaultTransport(nil, tc.faults)
+			client := graphql.NewClient(s.URL, &http.Client{Transport: ft})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			var q struct {
+				Viewer struct{ Login string }
+			}
+			if err := client.Query(ctx, &q, nil); !tc.check(err) {
+				t.Errorf("got error: %v (%T)", err, err)
+			}
+			if got, want := ft.Stats().Requests, 1; got != want {
+				t.Errorf("got %d requests, want: %d", got, want)
+			}
+		})
+	}
+}
+
+func TestFaultTransport_retry(t *testing.T) {
+	s := graphqltest.NewServer(t)
+	s.Expect(graphqltest.AnyOperation()).RespondData(`{"viewer": {"login": "gopher"}}`)
+	ft := graphqltest.NewFaultTransport(nil, graphqltest.Faults{Seed: 1, ServerError: 0.5, Reset: 0.5})
+	client := graphql.NewClient(s.URL, &http.Client{Transport: ft}, graphql.WithRetry(graphql.RetryPolicy{
+		MaxAttempts: 20,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  time.Millisecond,
+	}))
+	var q struct {
+		Viewer struct{ Login string }
+	}
+	if err := client.Query(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+	stats := ft.Stats()
+	if stats.ServerError+stats.Reset == 0 || stats.ServerError+stats.Reset != stats.Requests-1 {
+		t.Errorf("got stats: %+v, want faults in all requests but the last", stats)
+	}
+}
@@ -0,0 +1,88 @@
+package graphqltest
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/isihu/graphql"
+	"github.com/isihu/graphql/ast"
+)
+
+// AssertGoldenQuery fails t if the query document that Client.Query sends
+// for q with variables differs from the one in the golden file at path,
+// as AssertGoldenDocument does.
+func AssertGoldenQuery(t testing.TB, path string, q any, variables map[string]any) {
+	t.Helper()
+	AssertGoldenDocument(t, path, graphql.ConstructQuery(q, variables))
+}
+
+// AssertGoldenMutation fails t if the mutation document that Client.Mutate
+// sends for m with variables differs from the one in the golden file at path,
+// as AssertGoldenDocument does.
+func AssertGoldenMutation(t testing.TB, path string, m any, variables map[string]any) {
+	t.Helper()
+	AssertGoldenDocument(t, path, graphql.ConstructMutation(m, variables))
+}
+
+// AssertGoldenDocument fails t if the GraphQL document differs from the one
+// in the golden file at path, which has it indented, one selection per line,
+// for readable diffs in code review. It catches unintended changes to
+// documents derived from Go types. If the GRAPHQLTEST_UPDATE environment
+// variable is set, as in
+//
+//	GRAPHQLTEST_UPDATE=1 go test ./...
+//
+// it writes the document to the golden file instead.
+func AssertGoldenDocument(t testing.TB, path, document string) {
+	t.Helper()
+	got := document
+	if doc, err := ast.Parse(document); err == nil {
+		got = doc.Indent("\t")
+	}
+	got += "\n"
+	if os.Getenv("GRAPHQLTEST_UPDATE") != "" {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("graphqltest: updating golden file: %v", err)
+		}
+		return
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("graphqltest: golden file %s doesn't exist; run with GRAPHQLTEST_UPDATE=1 to create it", path)
+	}
+	if err != nil {
+		t.Fatalf("graphqltest: %v", err)
+	}
+	if want := strings.ReplaceAll(string(b), "\r\n", "\n"); got != want {
+		t.Errorf("graphqltest: document differs from golden file %s:\n%s", path, lineDiff(want, got))
+	}
+}
+
+// lineDiff returns a description of how the lines of got differ from those
+// of want, starting at the first line that differs.
+func lineDiff(want, got string) string {
+	wl, gl := strings.Split(want, "\n"), strings.Split(got, "\n")
+	i := 0
+	for i < len(wl) && i < len(gl) && wl[i] == gl[i] {
+		i++
+	}
+	// Skip the common suffix too.
+	j, k := len(wl), len(gl)
+	for j > i && k > i && wl[j-1] == gl[k-1] {
+		j--
+		k--
+	}
+	var sb strings.Builder
+	sb.WriteString("@@ line " + strconv.Itoa(i+1) + " @@\n")
+	for _, l := range wl[i:j] {
+		sb.WriteString("- " + l + "\n")
+	}
+	for _, l := range gl[i:k] {
+		sb.WriteString("+ " + l + "\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,40 @@
+package graphqltest_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/isihu/graphql"
+	"github.com/isihu/graphql/graphqltest"
+)
+
+func TestAssertGoldenQuery(t *testing.T) {
+	variables := map[string]any{"owner": graphql.String("isihu"), "name": graphql.String("graphql")}
+	graphqltest.AssertGoldenQuery(t, "testdata/repository.golden", &repositoryQuery{}, variables)
+}
+
+func TestAssertGoldenDocument_mismatch(t *testing.T) {
+	tb := new(fakeTB)
+	graphqltest.AssertGoldenDocument(tb, "testdata/repository.golden", `query($name:String!$owner:String!){repository(owner:$owner, name:$name){name,id}}`)
+	if got, want := len(tb.errs), 1; got != want {
+		t.Fatalf("got %d failures, want: %d", got, want)
+	}
+	if want := "@@ line 4 @@\n+ \t\tid\n"; !strings.Contains(tb.errs[0], want) {
+		t.Errorf("got failure:\n%s\nwant it to contain:\n%s", tb.errs[0], want)
+	}
+}
+
+func TestAssertGoldenDocument_update(t *testing.T) {
+	t.Setenv("GRAPHQLTEST_UPDATE", "1")
+	path := filepath.Join(t.TempDir(), "viewer.golden")
+	graphqltest.AssertGoldenDocument(t, path, `{viewer{login}}`)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "{\n\tviewer {\n\t\tlogin\n\t}\n}\n"; got != want {
+		t.Errorf("got golden file: %q, want: %q", got, want)
+	}
+}
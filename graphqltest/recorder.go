@@ -0,0 +1,316 @@
+package graphqltest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/isihu/graphql/ast"
+)
+
+// Fixture is a recording of GraphQL operations and their responses,
+// as saved by a Recorder.
+type Fixture struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a GraphQL operation and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a recorded GraphQL request.
+type RecordedRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	Extensions    map[string]any `json:"extensions,omitempty"`
+}
+
+// RecordedResponse is a recorded HTTP response to a GraphQL request.
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+
+	// Body is the body of the response, if it's JSON, or else Text is.
+	Body json.RawMessage `json:"body,omitempty"`
+	Text string          `json:"text,omitempty"`
+}
+
+// LoadFixture reads the fixture saved at path.
+func LoadFixture(path string) (*Fixture, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f Fixture
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("graphqltest: fixture %s: %v", path, err)
+	}
+	return &f, nil
+}
+
+// Mode is the mode of a Recorder.
+type Mode int
+
+const (
+	Replay Mode = iota // Replay recorded interactions, and fail requests that weren't recorded.
+	Record             // Send requests, and record the interactions.
+)
+
+// Recorder is an http.RoundTripper that records the GraphQL operations sent
+// through it, and their responses, to a fixture file, and then replays them
+// in tests deterministically, without a server. Tests typically record once
+// against a real server, e.g., when run with an -update flag, and replay
+// the fixture afterwards:
+//
+//	mode := graphqltest.Replay
+//	if *update {
+//		mode = graphqltest.Record
+//	}
+//	rec := graphqltest.NewRecorder(t, "testdata/viewer.json", mode)
+//	client := graphql.NewClient(url, &http.Client{Transport: rec})
+//
+// A request is replayed with the first recorded interaction not replayed yet
+// whose document, operation name and variables match it. Documents match
+// if they're the same but for formatting.
+type Recorder struct {
+	path string
+	mode Mode
+	next http.RoundTripper
+
+	ignored       []string                                   // Names of variables not matched.
+	scrubbed      []string                                   // Names of variables whose values aren't recorded.
+	redactHeaders []string                                   // Response headers whose values aren't recorded.
+	scrub         func(in *Interaction)                      // Scrubs secrets from interactions before they're recorded, if non-nil.
+	match         func(recorded, actual map[string]any) bool // Matches variables, if non-nil.
+
+	mu       sync.Mutex
+	fixture  Fixture
+	replayed []bool
+}
+
+// RecorderOption configures a Recorder.
+type RecorderOption func(*Recorder)
+
+// WithTransport makes a Recorder send requests with next, when recording,
+// instead of http.DefaultTransport.
+func WithTransport(next http.RoundTripper) RecorderOption {
+	return func(r *Recorder) { r.next = next }
+}
+
+// IgnoreVariables makes a Recorder replay interactions regardless of the
+// values of the variables names, such as timestamps and random IDs.
+func IgnoreVariables(names ...string) RecorderOption {
+	return func(r *Recorder) { r.ignored = append(r.ignored, names...) }
+}
+
+// ScrubVariables makes a Recorder record the values of the variables names,
+// such as credentials, as "REDACTED", and replay interactions regardless
+// of their values.
+func ScrubVariables(names ...string) RecorderOption {
+	return func(r *Recorder) { r.scrubbed = append(r.scrubbed, names...) }
+}
+
+// RedactHeaders makes a Recorder record the values of the response headers
+// names as "REDACTED", in addition to Set-Cookie.
+func RedactHeaders(names ...string) RecorderOption {
+	return func(r *Recorder) { r.redactHeaders = append(r.redactHeaders, names...) }
+}
+
+// WithScrubber makes a Recorder call scrub with every interaction before
+// it's recorded, to remove secrets from it, such as tokens in responses.
+func WithScrubber(scrub func(in *Interaction)) RecorderOption {
+	return func(r *Recorder) { r.scrub = scrub }
+}
+
+// WithVariableMatcher makes a Recorder replay an interaction only if match
+// reports that its recorded variables match those of a request, instead of
+// requiring them to be equal. Ignored and scrubbed variables are left out
+// of both.
+func WithVariableMatcher(match func(recorded, actual map[string]any) bool) RecorderOption {
+	return func(r *Recorder) { r.match = match }
+}
+
+// NewRecorder returns a Recorder for the test t, in mode, which replays the
+// fixture at path, or records it. A recorded fixture is saved when t ends.
+func NewRecorder(t testing.TB, path string, mode Mode, opts ...RecorderOption) *Recorder {
+	t.Helper()
+	r := &Recorder{path: path, mode: mode, next: http.DefaultTransport, redactHeaders: []string{"Set-Cookie"}}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if mode == Replay {
+		f, err := LoadFixture(path)
+		if err != nil {
+			t.Fatalf("graphqltest: %v", err)
+		}
+		r.fixture = *f
+		r.replayed = make([]bool, len(f.Interactions))
+		return r
+	}
+	t.Cleanup(func() {
+		if err := r.save(); err != nil {
+			t.Errorf("graphqltest: saving fixture: %v", err)
+		}
+	})
+	return r
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+	in, err := readRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("graphqltest: malformed request: %v", err)
+	}
+	rec := RecordedRequest{
+		Query:         in.Query,
+		OperationName: in.OperationName,
+		Variables:     in.Variables,
+		Extensions:    in.Extensions,
+	}
+	if r.mode == Replay {
+		return r.replay(req, rec)
+	}
+
+	// readRequest consumed the body.
+	if req.GetBody != nil {
+		req = req.Clone(req.Context())
+		if req.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	} else if req.Method != http.MethodGet {
+		return nil, errors.New("graphqltest: request body can't be replayed to record it")
+	}
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	r.record(rec, resp, body)
+	return resp, nil
+}
+
+// record records the interaction of the request rec and the response resp with body.
+func (r *Recorder) record(rec RecordedRequest, resp *http.Response, body []byte) {
+	in := Interaction{
+		Request:  rec,
+		Response: RecordedResponse{Status: resp.StatusCode, Header: resp.Header.Clone()},
+	}
+	if json.Valid(body) {
+		in.Response.Body = json.RawMessage(body)
+	} else {
+		in.Response.Text = string(body)
+	}
+	if len(r.scrubbed) > 0 && rec.Variables != nil {
+		in.Request.Variables = make(map[string]any, len(rec.Variables))
+		for name, v := range rec.Variables {
+			if slices.Contains(r.scrubbed, name) {
+				v = "REDACTED"
+			}
+			in.Request.Variables[name] = v
+		}
+	}
+	for _, name := range r.redactHeaders {
+		if in.Response.Header.Get(name) != "" {
+			in.Response.Header.Set(name, "REDACTED")
+		}
+	}
+	if r.scrub != nil {
+		r.scrub(&in)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fixture.Interactions = append(r.fixture.Interactions, in)
+}
+
+// replay returns the recorded response to the request rec.
+func (r *Recorder) replay(req *http.Request, rec RecordedRequest) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, in := range r.fixture.Interactions {
+		if r.replayed[i] || !r.matches(in.Request, rec) {
+			continue
+		}
+		r.replayed[i] = true
+		body := []byte(in.Response.Body)
+		if in.Response.Body == nil {
+			body = []byte(in.Response.Text)
+		}
+		header := in.Response.Header.Clone()
+		if header == nil {
+			header = make(http.Header)
+		}
+		return newResponse(req, in.Response.Status, header, body), nil
+	}
+	return nil, fmt.Errorf("graphqltest: no recorded interaction matches operation %q: %s, variables: %v", rec.OperationName, rec.Query, rec.Variables)
+}
+
+// matches reports whether the request actual matches the recorded one.
+func (r *Recorder) matches(recorded, actual RecordedRequest) bool {
+	if recorded.OperationName != actual.OperationName ||
+		normalizeDocument(recorded.Query) != normalizeDocument(actual.Query) {
+		return false
+	}
+	rv, av := r.matchedVariables(recorded.Variables), r.matchedVariables(actual.Variables)
+	if r.match != nil {
+		return r.match(rv, av)
+	}
+	return reflect.DeepEqual(rv, av)
+}
+
+// matchedVariables returns variables without the ignored and scrubbed ones,
+// normalized as decoded JSON.
+func (r *Recorder) matchedVariables(variables map[string]any) map[string]any {
+	m := make(map[string]any, len(variables))
+	for name, v := range variables {
+		if !slices.Contains(r.ignored, name) && !slices.Contains(r.scrubbed, name) {
+			m[name] = v
+		}
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return m
+	}
+	var normalized map[string]any
+	json.Unmarshal(b, &normalized)
+	return normalized
+}
+
+// normalizeDocument returns the GraphQL document query without formatting,
+// or query as is if it isn't valid.
+func normalizeDocument(query string) string {
+	doc, err := ast.Parse(query)
+	if err != nil {
+		return query
+	}
+	return doc.String()
+}
+
+// save saves the recorded fixture.
+func (r *Recorder) save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, err := json.MarshalIndent(r.fixture, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(b, '\n'), 0o644)
+}
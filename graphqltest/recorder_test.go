@@ -0,0 +1,84 @@
+package graphqltest_test
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/isihu/graphql"
+	"github.com/isihu/graphql/graphqltest"
+)
+
+type repositoryQuery struct {
+	Repository struct {
+		Name string
+	} `graphql:"repository(owner:$owner, name:$name)"`
+}
+
+func TestRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	variables := map[string]any{"owner": graphql.String("isihu"), "name": graphql.String("graphql")}
+
+	t.Run("record", func(t *testing.T) {
+		s := graphqltest.NewServer(t)
+		s.Expect(graphqltest.QueryContains("repository")).Respond(graphqltest.Response{
+			Data:   map[string]any{"repository": map[string]any{"name": "graphql"}},
+			Header: http.Header{"Set-Cookie": {"session=secret"}},
+		})
+		rec := graphqltest.NewRecorder(t, path, graphqltest.Record, graphqltest.ScrubVariables("owner"))
+		client := graphql.NewClient(s.URL, &http.Client{Transport: rec})
+		var q repositoryQuery
+		if err := client.Query(context.Background(), &q, variables); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := q.Repository.Name, "graphql"; got != want {
+			t.Errorf("got name: %q, want: %q", got, want)
+		}
+	})
+
+	f, err := graphqltest.LoadFixture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(f.Interactions), 1; got != want {
+		t.Fatalf("got %d interactions, want: %d", got, want)
+	}
+	in := f.Interactions[0]
+	if got, want := in.Request.Variables["owner"], "REDACTED"; got != want {
+		t.Errorf("got recorded owner: %v, want: %v", got, want)
+	}
+	if got, want := in.Response.Header.Get("Set-Cookie"), "REDACTED"; got != want {
+		t.Errorf("got recorded Set-Cookie: %v, want: %v", got, want)
+	}
+
+	t.Run("replay", func(t *testing.T) {
+		rec := graphqltest.NewRecorder(t, path, graphqltest.Replay, graphqltest.ScrubVariables("owner"))
+		client := graphql.NewClient("http://example.invalid/graphql", &http.Client{Transport: rec})
+		var q repositoryQuery
+		variables := map[string]any{"owner": graphql.String("someone"), "name": graphql.String("graphql")}
+		if err := client.Query(context.Background(), &q, variables); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := q.Repository.Name, "graphql"; got != want {
+			t.Errorf("got name: %q, want: %q", got, want)
+		}
+
+		// The interaction was replayed already.
+		err := client.Query(context.Background(), &q, variables)
+		if err == nil || !strings.Contains(err.Error(), "no recorded interaction") {
+			t.Errorf("got error: %v, want no recorded interaction", err)
+		}
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		rec := graphqltest.NewRecorder(t, path, graphqltest.Replay)
+		client := graphql.NewClient("http://example.invalid/graphql", &http.Client{Transport: rec})
+		var q repositoryQuery
+		variables := map[string]any{"owner": graphql.String("isihu"), "name": graphql.String("other")}
+		if err := client.Query(context.Background(), &q, variables); err == nil {
+			t.Error("got no error for a request that wasn't recorded, want one")
+		}
+	})
+}
@@ -0,0 +1,291 @@
+// Package graphqltest provides utilities for testing code that uses
+// package graphql, such as a GraphQL server answering operations
+// with canned responses.
+package graphqltest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/isihu/graphql"
+	"github.com/isihu/graphql/ast"
+)
+
+// Request is a GraphQL request received by a Server.
+type Request struct {
+	Query         string
+	OperationName string // Name of the operation, from the request or else its document.
+	Variables     map[string]any
+	Extensions    map[string]any
+	Header        http.Header
+}
+
+// Response is a canned GraphQL response.
+type Response struct {
+	// Data is the data of the response, marshaled as JSON.
+	// Use a json.RawMessage for literal JSON.
+	Data       any
+	Errors     []graphql.Error
+	Extensions map[string]any
+
+	Status int         // Status code of the HTTP response. Zero means 200.
+	Header http.Header // Additional headers of the HTTP response.
+}
+
+// Matcher reports whether a request matches an expectation.
+type Matcher func(r *Request) bool
+
+// OperationName returns a Matcher of requests for the operation named name.
+func OperationName(name string) Matcher {
+	return func(r *Request) bool { return r.OperationName == name }
+}
+
+// QueryContains returns a Matcher of requests whose query contains s.
+func QueryContains(s string) Matcher {
+	return func(r *Request) bool { return strings.Contains(r.Query, s) }
+}
+
+// AnyOperation returns a Matcher of all requests.
+func AnyOperation() Matcher {
+	return func(*Request) bool { return true }
+}
+
+// Server is a GraphQL server for tests, which answers requests with the
+// response of the first expectation registered with Expect that matches
+// them. It fails the test when a request matches no expectation, and when
+// the test ends, if an expectation wasn't met.
+type Server struct {
+	URL string // Base URL of the server, to which requests are sent.
+
+	t   testing.TB
+	srv *httptest.Server
+
+	mu           sync.Mutex
+	expectations []*Expectation
+	requests     []Request
+}
+
+// NewServer starts a Server for the test t, which is closed when t ends.
+func NewServer(t testing.TB) *Server {
+	s := &Server{t: t}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.srv.URL
+	t.Cleanup(func() {
+		s.srv.Close()
+		s.verify()
+	})
+	return s
+}
+
+// Client returns a graphql.Client sending requests to s, configured by opts.
+func (s *Server) Client(opts ...graphql.ClientOption) *graphql.Client {
+	return graphql.NewClient(s.URL, s.srv.Client(), opts...)
+}
+
+// Close shuts down s. It's called when the test ends.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Requests returns the requests received by s so far, in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Expect registers an expectation of requests that m matches, which is
+// answered with an empty response unless a Respond method sets it.
+// By default, it must match at least one request.
+func (s *Server) Expect(m Matcher) *Expectation {
+	e := &Expectation{s: s, match: m, min: 1, max: -1}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expectations = append(s.expectations, e)
+	return e
+}
+
+// ExpectOperation registers an expectation of requests for the operation
+// named name, as Expect(OperationName(name)) does.
+func (s *Server) ExpectOperation(name string) *Expectation {
+	return s.Expect(OperationName(name))
+}
+
+// Expectation is an expected request of a Server, and its response.
+// Its methods return it, so that they can be chained:
+//
+//	s.ExpectOperation("Viewer").RespondData(`{"viewer": {"login": "gopher"}}`).Times(1)
+type Expectation struct {
+	s       *Server
+	match   Matcher
+	respond func(r *Request) Response
+
+	min, max int // Number of requests to match. A negative max means it's unlimited.
+	matched  int
+}
+
+// Respond sets the response to r.
+func (e *Expectation) Respond(r Response) *Expectation {
+	return e.RespondFunc(func(*Request) Response { return r })
+}
+
+// RespondData sets the response to one with the JSON data.
+func (e *Expectation) RespondData(data string) *Expectation {
+	return e.Respond(Response{Data: json.RawMessage(data)})
+}
+
+// RespondErrors sets the response to one with errs, and no data.
+func (e *Expectation) RespondErrors(errs ...graphql.Error) *Expectation {
+	return e.Respond(Response{Errors: errs})
+}
+
+// RespondFunc sets the response to the one that respond returns for each request.
+// respond may be called concurrently.
+func (e *Expectation) RespondFunc(respond func(r *Request) Response) *Expectation {
+	e.s.mu.Lock()
+	defer e.s.mu.Unlock()
+	e.respond = respond
+	return e
+}
+
+// Times makes e match exactly n requests. Later requests fall through
+// to the next matching expectation.
+func (e *Expectation) Times(n int) *Expectation {
+	e.s.mu.Lock()
+	defer e.s.mu.Unlock()
+	e.min, e.max = n, n
+	return e
+}
+
+// AnyTimes makes e match any number of requests, including none.
+func (e *Expectation) AnyTimes() *Expectation {
+	e.s.mu.Lock()
+	defer e.s.mu.Unlock()
+	e.min, e.max = 0, -1
+	return e
+}
+
+// verify fails the test if an expectation wasn't met.
+func (s *Server) verify() {
+	s.t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, e := range s.expectations {
+		if e.matched < e.min {
+			s.t.Errorf("graphqltest: expectation %d matched %d requests, want at least %d", i+1, e.matched, e.min)
+		}
+	}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	r, err := readRequest(req)
+	if err != nil {
+		s.t.Errorf("graphqltest: malformed request: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, *r)
+	var e *Expectation
+	for _, x := range s.expectations {
+		if (x.max < 0 || x.matched < x.max) && x.match(r) {
+			e = x
+			e.matched++
+			break
+		}
+	}
+	s.mu.Unlock()
+	if e == nil {
+		s.t.Errorf("graphqltest: unexpected request for operation %q: %s, variables: %v", r.OperationName, r.Query, r.Variables)
+		http.Error(w, "graphqltest: no expectation matches the request", http.StatusInternalServerError)
+		return
+	}
+
+	var resp Response
+	if e.respond != nil {
+		resp = e.respond(r)
+	}
+	writeResponse(w, resp)
+}
+
+// readRequest reads a GraphQL request sent with a POST or GET request.
+func readRequest(req *http.Request) (*Request, error) {
+	var in struct {
+		Query         string         `json:"query"`
+		OperationName string         `json:"operationName"`
+		Variables     map[string]any `json:"variables"`
+		Extensions    map[string]any `json:"extensions"`
+	}
+	if req.Method == http.MethodGet {
+		params := req.URL.Query()
+		in.Query, in.OperationName = params.Get("query"), params.Get("operationName")
+		for name, v := range map[string]*map[string]any{"variables": &in.Variables, "extensions": &in.Extensions} {
+			if s := params.Get(name); s != "" {
+				if err := json.Unmarshal([]byte(s), v); err != nil {
+					return nil, fmt.Errorf("%s: %v", name, err)
+				}
+			}
+		}
+	} else {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(body, &in); err != nil {
+			return nil, err
+		}
+	}
+	r := &Request{
+		Query:         in.Query,
+		OperationName: in.OperationName,
+		Variables:     in.Variables,
+		Extensions:    in.Extensions,
+		Header:        req.Header,
+	}
+	if r.OperationName == "" {
+		r.OperationName = operationName(r.Query)
+	}
+	return r, nil
+}
+
+// operationName returns the name of the only operation in query,
+// or "" if it's anonymous or there isn't exactly one.
+func operationName(query string) string {
+	doc, err := ast.Parse(query)
+	if err != nil {
+		return ""
+	}
+	if ops := doc.Operations(); len(ops) == 1 {
+		return ops[0].Name
+	}
+	return ""
+}
+
+// writeResponse writes resp as the GraphQL response to a request.
+func writeResponse(w http.ResponseWriter, resp Response) {
+	out := struct {
+		Data       any             `json:"data,omitempty"`
+		Errors     []graphql.Error `json:"errors,omitempty"`
+		Extensions map[string]any  `json:"extensions,omitempty"`
+	}{resp.Data, resp.Errors, resp.Extensions}
+	if out.Data == nil && len(out.Errors) == 0 {
+		out.Data = json.RawMessage("{}")
+	}
+	for k, vs := range resp.Header {
+		w.Header()[k] = vs
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Status != 0 {
+		w.WriteHeader(resp.Status)
+	}
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
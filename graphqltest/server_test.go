@@ -0,0 +1,101 @@
+package graphqltest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/isihu/graphql"
+	"github.com/isihu/graphql/graphqltest"
+)
+
+// fakeTB records the failures of a test, and runs its cleanups when done.
+type fakeTB struct {
+	testing.TB
+
+	mu       sync.Mutex
+	errs     []string
+	cleanups []func()
+}
+
+func (t *fakeTB) Helper() {}
+
+func (t *fakeTB) Errorf(format string, args ...any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.errs = append(t.errs, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeTB) Cleanup(f func()) {
+	t.cleanups = append(t.cleanups, f)
+}
+
+func (t *fakeTB) done() []string {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+	return t.errs
+}
+
+func TestServer(t *testing.T) {
+	s := graphqltest.NewServer(t)
+	s.ExpectOperation("Viewer").RespondData(`{"viewer": {"login": "gopher"}}`).Times(1)
+	s.Expect(graphqltest.QueryContains("repository")).RespondErrors(graphql.Error{
+		Message:    "not found",
+		Extensions: map[string]any{"code": "NOT_FOUND"},
+	})
+	client := s.Client()
+
+	var viewer struct {
+		Viewer struct{ Login string }
+	}
+	if err := client.Do(context.Background(), "query Viewer{viewer{login}}", &viewer, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := viewer.Viewer.Login, "gopher"; got != want {
+		t.Errorf("got login: %q, want: %q", got, want)
+	}
+
+	var repo struct {
+		Repository struct{ Name string } `graphql:"repository(name:$name)"`
+	}
+	err := client.Query(context.Background(), &repo, map[string]any{"name": graphql.String("graphql")})
+	var errs graphql.Errors
+	if !errors.As(err, &errs) || errs[0].Message != "not found" {
+		t.Errorf("got error: %v, want: not found", err)
+	}
+
+	requests := s.Requests()
+	if got, want := len(requests), 2; got != want {
+		t.Fatalf("got %d requests, want: %d", got, want)
+	}
+	if got, want := requests[1].Variables["name"], "graphql"; got != want {
+		t.Errorf("got variable: %v, want: %v", got, want)
+	}
+}
+
+func TestServer_failures(t *testing.T) {
+	tb := new(fakeTB)
+	s := graphqltest.NewServer(tb)
+	s.ExpectOperation("Viewer").RespondData(`{"viewer": {"login": "gopher"}}`)
+	s.ExpectOperation("Unused")
+
+	var q struct {
+		Repository struct{ Name string }
+	}
+	if err := s.Client().Query(context.Background(), &q, nil); err == nil {
+		t.Error("got no error for an unexpected request, want one")
+	}
+	errs := tb.done()
+	if got, want := len(errs), 3; got != want {
+		t.Fatalf("got %d failures, want: %d: %q", got, want, errs)
+	}
+	for i, want := range []string{"unexpected request", "expectation 1 matched 0 requests", "expectation 2 matched 0 requests"} {
+		if !strings.Contains(errs[i], want) {
+			t.Errorf("got failure: %s, want it to contain: %s", errs[i], want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Group runs independent operations concurrently, like errgroup.Group,
+// and collects their errors. Operations are queued with Query, Mutate
+// and Do, which return right away, and Wait waits for all of them.
+// Results are populated into the values given to Query, Mutate and Do,
+// which mustn't be used until Wait returns.
+//
+// A Group must not be reused after Wait returns.
+type Group struct {
+	c   *Client
+	ctx context.Context
+	sem chan struct{} // Nil means operations aren't limited.
+
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	errs []error // Errors of operations, by the order they were queued in.
+}
+
+// Group returns a new Group running operations with ctx.
+func (c *Client) Group(ctx context.Context) *Group {
+	return &Group{c: c, ctx: ctx}
+}
+
+// SetLimit limits the number of operations running at once to n.
+// A negative or zero n means they aren't limited, which is the default.
+// It must be called before any operations are queued.
+func (g *Group) SetLimit(n int) {
+	g.mu.Lock()
+	queued := len(g.errs) > 0
+	g.mu.Unlock()
+	if queued {
+		panic("graphql: SetLimit called after operations were queued")
+	}
+	g.sem = nil
+	if n > 0 {
+		g.sem = make(chan struct{}, n)
+	}
+}
+
+// Query queues a query derived from q, as Client.Query sends it.
+// It returns the index of the operation in the errors of a GroupError.
+func (g *Group) Query(q any, variables map[string]any, opts ...CallOption) int {
+	return g.Do(constructQuery(q, variables), q, false, variables, opts...)
+}
+
+// Mutate queues a mutation derived from m, as Client.Mutate sends it.
+// It returns the index of the operation in the errors of a GroupError.
+func (g *Group) Mutate(m any, variables map[string]any, opts ...CallOption) int {
+	return g.Do(constructMutation(m, variables), m, false, variables, opts...)
+}
+
+// Do queues an operation, as Client.Do sends it.
+// It returns the index of the operation in the errors of a GroupError.
+func (g *Group) Do(query string, res any, merge bool, variables map[string]any, opts ...CallOption) int {
+	g.mu.Lock()
+	i := len(g.errs)
+	g.errs = append(g.errs, nil)
+	g.mu.Unlock()
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if g.sem != nil {
+			select {
+			case g.sem <- struct{}{}:
+				defer func() { <-g.sem }()
+			case <-g.ctx.Done():
+				g.setErr(i, g.ctx.Err())
+				return
+			}
+		}
+		g.setErr(i, g.c.Do(g.ctx, query, res, merge, variables, opts...))
+	}()
+	return i
+}
+
+func (g *Group) setErr(i int, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.errs[i] = err
+}
+
+// Wait waits for all queued operations to finish. If any of them failed,
+// it returns a *GroupError with their errors.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, err := range g.errs {
+		if err != nil {
+			return &GroupError{Errors: g.errs}
+		}
+	}
+	return nil
+}
+
+// GroupError is returned by Group.Wait when operations failed.
+// It unwraps to the errors of the failed operations.
+type GroupError struct {
+	// Errors are the errors of all operations, by the order they were
+	// queued in, which is nil for the operations that succeeded.
+	Errors []error
+}
+
+func (e *GroupError) Error() string {
+	var failed int
+	var first error
+	for _, err := range e.Errors {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	if failed == 1 {
+		return fmt.Sprintf("1 of %d operations failed: %v", len(e.Errors), first)
+	}
+	return fmt.Sprintf("%d of %d operations failed, first: %v", failed, len(e.Errors), first)
+}
+
+func (e *GroupError) Unwrap() []error {
+	var errs []error
+	for _, err := range e.Errors {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
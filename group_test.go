@@ -0,0 +1,94 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+func TestClient_Group(t *testing.T) {
+	var mu sync.Mutex
+	var running, maxRunning int
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		var in struct{ Variables struct{ ID string } }
+		mustUnmarshal(mustRead(req.Body), &in)
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+		<-release
+		mu.Lock()
+		running--
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if in.Variables.ID == "missing" {
+			mustWrite(w, `{"data": {"user": null}, "errors": [{"message": "user not found", "extensions": {"code": "NOT_FOUND"}}]}`)
+			return
+		}
+		mustWrite(w, fmt.Sprintf(`{"data": {"user": {"name": "user %s"}}}`, in.Variables.ID))
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	g := client.Group(context.Background())
+	g.SetLimit(2)
+	type userQuery struct {
+		User *struct{ Name graphql.String } `graphql:"user(id:$id)"`
+	}
+	queries := make([]userQuery, 4)
+	ids := []string{"1", "2", "missing", "4"}
+	for i, id := range ids {
+		if got := g.Query(&queries[i], map[string]any{"id": graphql.ID(id)}); got != i {
+			t.Errorf("got index: %d, want: %d", got, i)
+		}
+	}
+	close(release)
+	err := g.Wait()
+
+	var ge *graphql.GroupError
+	if !errors.As(err, &ge) {
+		t.Fatalf("got error: %v, want a *graphql.GroupError", err)
+	}
+	for i, err := range ge.Errors {
+		if got, want := err != nil, ids[i] == "missing"; got != want {
+			t.Errorf("got error of operation %d: %v", i, err)
+		}
+	}
+	if !errors.Is(err, graphql.ErrNotFound) {
+		t.Errorf("got error: %v, want graphql.ErrNotFound", err)
+	}
+	if got, want := queries[3].User.Name, graphql.String("user 4"); got != want {
+		t.Errorf("got name: %q, want: %q", got, want)
+	}
+	if maxRunning > 2 {
+		t.Errorf("got %d operations running at once, want at most 2", maxRunning)
+	}
+}
+
+func TestClient_Group_success(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	g := client.Group(context.Background())
+	var a, b struct {
+		Viewer struct{ Login graphql.String }
+	}
+	g.Query(&a, nil)
+	g.Query(&b, nil)
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if a.Viewer.Login != "gopher" || b.Viewer.Login != "gopher" {
+		t.Errorf("got logins: %q and %q, want: %q", a.Viewer.Login, b.Viewer.Login, "gopher")
+	}
+}
@@ -0,0 +1,76 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// SubscriptionHooks are callbacks for events in the lifecycle of subscriptions
+// and their connections, for driving application state and alerting from
+// connection health. Any of them may be nil. They may be called concurrently,
+// and must not block.
+type SubscriptionHooks struct {
+	// Connected is called when a connection to url is established.
+	// Over WebSocket, it's called before the connection is initialized.
+	Connected func(url string)
+
+	// Acknowledged is called when the server acknowledges the initialization
+	// of a WebSocket connection to url, with the payload of its connection_ack
+	// message, if any. Server-Sent Events connections aren't acknowledged.
+	Acknowledged func(url string, payload json.RawMessage)
+
+	// Started is called when the server accepts a subscription with the document
+	// query, including when it's resubscribed after reconnecting. ctx is the
+	// context passed to Client.Subscribe.
+	Started func(ctx context.Context, query string)
+
+	// TransportError is called when establishing a connection to url fails,
+	// or an established connection fails, with the reason.
+	TransportError func(url string, err error)
+
+	// Completed is called when a subscription with the document query stops,
+	// with the error that stopped it as reported by Subscription.Err.
+	// ctx is the context passed to Client.Subscribe.
+	Completed func(ctx context.Context, query string, err error)
+}
+
+// WithSubscriptionHooks sets callbacks for events in the lifecycle of subscriptions.
+func WithSubscriptionHooks(hooks SubscriptionHooks) ClientOption {
+	return func(c *Client) { c.subscriptionHooks = hooks }
+}
+
+func (h *SubscriptionHooks) connected(url string) {
+	if h.Connected != nil {
+		h.Connected(url)
+	}
+}
+
+func (h *SubscriptionHooks) acknowledged(url string, payload json.RawMessage) {
+	if h.Acknowledged != nil {
+		h.Acknowledged(url, payload)
+	}
+}
+
+func (h *SubscriptionHooks) started(ctx context.Context, query string) {
+	if h.Started != nil {
+		h.Started(ctx, query)
+	}
+}
+
+// transportError calls h.TransportError, unless err is nil,
+// or a GraphQL error rather than a failure of the connection.
+func (h *SubscriptionHooks) transportError(url string, err error) {
+	switch err.(type) {
+	case nil, Errors, *SubscriptionError:
+		return
+	}
+	if h.TransportError != nil {
+		h.TransportError(url, err)
+	}
+}
+
+func (h *SubscriptionHooks) completed(ctx context.Context, query string, err error) {
+	if h.Completed != nil {
+		h.Completed(ctx, query, err)
+	}
+}
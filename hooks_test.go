@@ -0,0 +1,82 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+// recordHooks returns hooks that record the events they're called for.
+func recordHooks() (graphql.SubscriptionHooks, func() []string) {
+	var mu sync.Mutex
+	var events []string
+	record := func(format string, args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, fmt.Sprintf(format, args...))
+	}
+	hooks := graphql.SubscriptionHooks{
+		Connected:      func(url string) { record("connected") },
+		Acknowledged:   func(url string, payload json.RawMessage) { record("acknowledged %s", payload) },
+		Started:        func(ctx context.Context, query string) { record("started %s", query) },
+		TransportError: func(url string, err error) { record("transport error") },
+		Completed:      func(ctx context.Context, query string, err error) { record("completed %s: %v", query, err) },
+	}
+	return hooks, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return events
+	}
+}
+
+func TestClient_Subscribe_hooks(t *testing.T) {
+	srv := transportWSServer(t, func(payload map[string]any, send func(typ, payload string)) {
+		send("next", `{"data":{"tick":1}}`)
+		send("complete", "")
+	}, nil)
+	defer srv.Close()
+	hooks, events := recordHooks()
+	client := graphql.NewClient(srv.URL, nil, graphql.WithSubscriptionHooks(hooks))
+
+	sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range sub.Events() {
+	}
+	got := strings.Join(events(), "\n")
+	want := strings.Join([]string{
+		`connected`,
+		`acknowledged {"version":1}`,
+		`started subscription{tick}`,
+		`completed subscription{tick}: <nil>`,
+	}, "\n")
+	if got != want {
+		t.Errorf("got events:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestClient_Subscribe_hooksTransportError(t *testing.T) {
+	srv := transportWSServer(t, func(payload map[string]any, send func(typ, payload string)) {
+		// Drop the connection.
+	}, nil)
+	defer srv.Close()
+	hooks, events := recordHooks()
+	client := graphql.NewClient(srv.URL, nil, graphql.WithSubscriptionHooks(hooks))
+
+	sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range sub.Events() {
+	}
+	got := events()
+	if len(got) != 5 || got[3] != "transport error" || !strings.HasPrefix(got[4], "completed subscription{tick}: ") || strings.HasSuffix(got[4], "<nil>") {
+		t.Errorf("got events: %q, want transport error and completion with an error", got)
+	}
+}
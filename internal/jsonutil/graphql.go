@@ -10,6 +10,7 @@ import (
 	"io"
 	"reflect"
 	"strings"
+	"sync"
 )
 
 // UnmarshalGraphQL parses the JSON-encoded GraphQL response data and stores
@@ -57,6 +58,57 @@ func MergeUnmarshalGraphQL(data []byte, v any) error {
 	}
 }
 
+// FieldPaths returns the paths of the fields of a GraphQL query data
+// structure of type t, such as "Repository.Issues[2].Author", that path,
+// the path of a field in the GraphQL response data, refers to. There may
+// be more than one, if the field is selected by several fragments.
+func FieldPaths(t reflect.Type, path []any) []string {
+	if len(path) == 0 {
+		return nil
+	}
+	return fieldPaths(t, path, "")
+}
+
+func fieldPaths(t reflect.Type, path []any, prefix string) []string {
+	if len(path) == 0 {
+		return []string{prefix}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch elem := path[0].(type) {
+	case int:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return nil
+		}
+		return fieldPaths(t.Elem(), path[1:], fmt.Sprintf("%s[%d]", prefix, elem))
+	case string:
+		if t.Kind() != reflect.Struct {
+			return nil
+		}
+		var paths []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				// Skip unexported field.
+				continue
+			}
+			name := f.Name
+			if prefix != "" {
+				name = prefix + "." + f.Name
+			}
+			switch {
+			case hasGraphQLName(f, elem):
+				paths = append(paths, fieldPaths(f.Type, path[1:], name)...)
+			case isGraphQLFragment(f) || f.Anonymous:
+				paths = append(paths, fieldPaths(f.Type, path, name)...)
+			}
+		}
+		return paths
+	}
+	return nil
+}
+
 // decoder is a JSON decoder that performs custom unmarshaling behavior
 // for GraphQL query data structures. It's implemented on top of a JSON tokenizer.
 type decoder struct {
@@ -205,12 +257,10 @@ func (d *decoder) decode(val any, merge bool) error {
 					if v.Kind() != reflect.Struct {
 						continue
 					}
-					for i := 0; i < v.NumField(); i++ {
-						if isGraphQLFragment(v.Type().Field(i)) || v.Type().Field(i).Anonymous {
-							// Add GraphQL fragment or embedded struct.
-							d.vs = append(d.vs, []reflect.Value{v.Field(i)})
-							frontier = append(frontier, v.Field(i))
-						}
+					for _, i := range cachedFields(v.Type()).fragments {
+						// Add GraphQL fragment or embedded struct.
+						d.vs = append(d.vs, []reflect.Value{v.Field(i)})
+						frontier = append(frontier, v.Field(i))
 					}
 				}
 			case '[':
@@ -281,16 +331,87 @@ func (d *decoder) popAllVs() {
 // fieldByGraphQLName returns an exported struct field of struct v
 // that matches GraphQL name, or invalid reflect.Value if none found.
 func fieldByGraphQLName(v reflect.Value, name string) reflect.Value {
-	for i := 0; i < v.NumField(); i++ {
-		if v.Type().Field(i).PkgPath != "" {
+	for _, f := range cachedFields(v.Type()).named {
+		if f.matches(name) {
+			return v.Field(f.index)
+		}
+	}
+	return reflect.Value{}
+}
+
+// structFields are the fields of a struct type that decoding looks up,
+// computed once per type since parsing their tags is relatively slow.
+type structFields struct {
+	named     []namedField // Exported fields with GraphQL names, in order.
+	fragments []int        // Indices of GraphQL fragments and embedded structs.
+}
+
+// namedField is a struct field with a GraphQL name.
+type namedField struct {
+	index  int
+	name   string // GraphQL name if tagged, Go name otherwise.
+	tagged bool
+}
+
+// matches reports whether f has the GraphQL name.
+func (f namedField) matches(name string) bool {
+	if f.tagged {
+		return f.name == name
+	}
+	// TODO: caseconv package is relatively slow. Optimize it, then consider using it here.
+	return strings.EqualFold(f.name, name)
+}
+
+// fieldCache caches structFields by struct type.
+var fieldCache sync.Map // map[reflect.Type]*structFields
+
+// cachedFields returns the structFields of the struct type t.
+func cachedFields(t reflect.Type) *structFields {
+	if fields, ok := fieldCache.Load(t); ok {
+		return fields.(*structFields)
+	}
+	fields := new(structFields)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if isGraphQLFragment(f) || f.Anonymous {
+			fields.fragments = append(fields.fragments, i)
+		}
+		if f.PkgPath != "" {
 			// Skip unexported field.
 			continue
 		}
-		if hasGraphQLName(v.Type().Field(i), name) {
-			return v.Field(i)
+		value, ok := f.Tag.Lookup("graphql")
+		if !ok {
+			fields.named = append(fields.named, namedField{index: i, name: f.Name})
+			continue
+		}
+		if name := graphQLName(value); name != "" {
+			fields.named = append(fields.named, namedField{index: i, name: name, tagged: true})
 		}
 	}
-	return reflect.Value{}
+	loaded, _ := fieldCache.LoadOrStore(t, fields)
+	return loaded.(*structFields)
+}
+
+// Warmup computes the metadata that decoding into a value of type t uses
+// ahead of time, so that the first decode doesn't spend time on it.
+func Warmup(t reflect.Type) {
+	for {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array:
+			t = t.Elem()
+			continue
+		case reflect.Struct:
+			if _, ok := fieldCache.Load(t); ok {
+				return
+			}
+			cachedFields(t)
+			for i := 0; i < t.NumField(); i++ {
+				Warmup(t.Field(i).Type)
+			}
+		}
+		return
+	}
 }
 
 // hasGraphQLName reports whether struct field f has GraphQL name.
@@ -301,17 +422,23 @@ func hasGraphQLName(f reflect.StructField, name string) bool {
 		//return caseconv.MixedCapsToLowerCamelCase(f.Name) == name
 		return strings.EqualFold(f.Name, name)
 	}
+	return graphQLName(value) == name
+}
+
+// graphQLName returns the GraphQL name in the graphql tag value of a field,
+// or "" if the field is a GraphQL fragment, which doesn't have a name.
+func graphQLName(value string) string {
 	value = strings.TrimSpace(value) // TODO: Parse better.
 	if strings.HasPrefix(value, "...") {
 		// GraphQL fragment. It doesn't have a name.
-		return false
+		return ""
 	}
 	// Cut off anything that follows the field name,
 	// such as field arguments, aliases, directives.
 	if i := strings.IndexAny(value, "(:@"); i != -1 {
 		value = value[:i]
 	}
-	return strings.TrimSpace(value) == name
+	return strings.TrimSpace(value)
 }
 
 // isGraphQLFragment reports whether struct field f is a GraphQL fragment.
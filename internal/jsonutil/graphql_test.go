@@ -522,3 +522,62 @@ func TestUnmarshalGraphQL_arrayInsideInlineFragment(t *testing.T) {
 		t.Error("not equal")
 	}
 }
+
+func TestFieldPaths(t *testing.T) {
+	type query struct {
+		Repository struct {
+			Issues []struct {
+				Author *struct {
+					Login string
+				}
+			} `graphql:"issues(first: 3)"`
+			Node struct {
+				Issue struct {
+					Title string
+				} `graphql:"... on Issue"`
+				PullRequest struct {
+					Title string
+				} `graphql:"... on PullRequest"`
+			} `graphql:"latest: node(id: $id)"`
+		}
+	}
+	for _, tc := range []struct {
+		path []any
+		want []string
+	}{
+		{path: []any{"repository", "issues", 2, "author"}, want: []string{"Repository.Issues[2].Author"}},
+		{path: []any{"repository", "latest", "title"}, want: []string{"Repository.Node.Issue.Title", "Repository.Node.PullRequest.Title"}},
+		{path: []any{"repository", "issues", "author"}, want: nil},
+		{path: []any{"viewer"}, want: nil},
+		{path: nil, want: nil},
+	} {
+		got := jsonutil.FieldPaths(reflect.TypeOf(&query{}), tc.path)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%v: got %q, want: %q", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	type query struct {
+		Me struct {
+			Name   graphql.String
+			Height graphql.Float `graphql:"height(unit:METER)"`
+		}
+	}
+	jsonutil.Warmup(reflect.TypeOf((*query)(nil)))
+	var got query
+	err := jsonutil.UnmarshalGraphQL([]byte(`{"me": {"name": "Luke Skywalker", "height": 1.72}}`), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want query
+	want.Me.Name = "Luke Skywalker"
+	want.Me.Height = 1.72
+	if got != want {
+		t.Errorf("got: %+v, want: %+v", got, want)
+	}
+	if allocs := testing.AllocsPerRun(10, func() { jsonutil.Warmup(reflect.TypeOf((*query)(nil))) }); allocs != 0 {
+		t.Errorf("got %v allocations warming up a warmed-up type, want: 0", allocs)
+	}
+}
@@ -0,0 +1,348 @@
+package jsonutil
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// Parallel decodes GraphQL response data like UnmarshalGraphQL, except that
+// the elements of the largest array in the data are decoded by several
+// goroutines, if it has at least MinElements elements. It speeds up
+// decoding responses dominated by a single huge array, such as a page
+// of a connection with many nodes.
+//
+// Only arrays reached through fields of the GraphQL query data structure,
+// rather than through other arrays or GraphQL fragments, are decoded in
+// parallel. Other data is decoded as UnmarshalGraphQL does. Data with
+// objects that have duplicate keys, other than in arrays, is decoded
+// sequentially, so that the last value of a key wins, as it does there.
+type Parallel struct {
+	MinElements int // Minimum length of arrays to decode in parallel.
+	Workers     int // Number of goroutines decoding an array.
+}
+
+// Unmarshal is like UnmarshalGraphQL.
+func (p Parallel) Unmarshal(data []byte, v any) error {
+	return p.unmarshal(data, v, false)
+}
+
+// MergeUnmarshal is like MergeUnmarshalGraphQL.
+func (p Parallel) MergeUnmarshal(data []byte, v any) error {
+	return p.unmarshal(data, v, true)
+}
+
+func (p Parallel) unmarshal(data []byte, v any, merge bool) error {
+	sequential := UnmarshalGraphQL
+	if merge {
+		sequential = MergeUnmarshalGraphQL
+	}
+	if p.MinElements <= 0 || p.Workers < 2 {
+		return sequential(data, v)
+	}
+	s := scanner{data: data}
+	if !s.scan() || s.duplicateKeys || s.largest.length < p.MinElements {
+		return sequential(data, v)
+	}
+	fields, ok := fieldIndices(reflect.TypeOf(v), s.largest.path)
+	if !ok {
+		return sequential(data, v)
+	}
+
+	// Decode the data with the array left empty, then decode its elements.
+	rest := make([]byte, 0, len(data)-(s.largest.end-s.largest.start)+2)
+	rest = append(rest, data[:s.largest.start]...)
+	rest = append(rest, "[]"...)
+	rest = append(rest, data[s.largest.end:]...)
+	if err := sequential(rest, v); err != nil {
+		return err
+	}
+	target := reflect.ValueOf(v)
+	for _, i := range fields {
+		target = indirect(target).Field(i)
+	}
+	target = indirect(target)
+	elems := reflect.MakeSlice(target.Type(), s.largest.length, s.largest.length)
+	if err := p.decodeElements(s.elements(s.largest), elems); err != nil {
+		return err
+	}
+	if merge {
+		elems = reflect.AppendSlice(target, elems)
+	}
+	target.Set(elems)
+	return nil
+}
+
+// decodeElements decodes the JSON values of elements into the elements of
+// the slice elems, using p.Workers goroutines.
+func (p Parallel) decodeElements(elements [][]byte, elems reflect.Value) error {
+	workers := min(p.Workers, len(elements))
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for w := range workers {
+		// Each worker decodes a contiguous chunk of the elements.
+		lo, hi := w*len(elements)/workers, (w+1)*len(elements)/workers
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := lo; i < hi; i++ {
+				if err := UnmarshalGraphQL(elements[i], elems.Index(i).Addr().Interface()); err != nil {
+					errs[w] = err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldIndices returns the indices of the struct fields that the GraphQL
+// names in path refer to, starting from the type t of a GraphQL query data
+// structure, and reports whether they end in a slice that can be decoded
+// in parallel.
+func fieldIndices(t reflect.Type, path []string) ([]int, bool) {
+	indices := make([]int, len(path))
+	for i, name := range path {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return nil, false
+		}
+		fields := cachedFields(t)
+		if len(fields.fragments) > 0 {
+			// Fragments may select the array too.
+			return nil, false
+		}
+		indices[i] = -1
+		for _, f := range fields.named {
+			if f.matches(name) {
+				indices[i] = f.index
+				break
+			}
+		}
+		if indices[i] == -1 {
+			return nil, false
+		}
+		t = t.Field(indices[i]).Type
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return indices, t.Kind() == reflect.Slice && len(path) > 0
+}
+
+// indirect returns the value v points to, allocating it if needed.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// arraySpan is an array in JSON data.
+type arraySpan struct {
+	path       []string // Keys of the object members leading to the array.
+	start, end int      // Offsets of the array's brackets, end exclusive.
+	length     int      // Number of elements.
+}
+
+// scanner scans JSON data for its largest array reached only through
+// object members. It doesn't fully validate the data, which the decoder
+// does.
+type scanner struct {
+	data          []byte
+	pos           int
+	path          []string
+	largest       arraySpan
+	duplicateKeys bool // Whether an object outside arrays has duplicate keys.
+}
+
+// scan scans the data, and reports whether it's well-formed enough to have
+// its largest array decoded separately.
+func (s *scanner) scan() bool {
+	if !s.value(true) {
+		return false
+	}
+	s.space()
+	return s.pos == len(s.data)
+}
+
+// elements returns the JSON values of the elements of the array a.
+func (s *scanner) elements(a arraySpan) [][]byte {
+	elements := make([][]byte, 0, a.length)
+	s.pos = a.start + 1
+	for {
+		s.space()
+		if s.data[s.pos] == ']' {
+			return elements
+		}
+		start := s.pos
+		s.value(false)
+		elements = append(elements, s.data[start:s.pos])
+		s.space()
+		if s.data[s.pos] == ',' {
+			s.pos++
+		}
+	}
+}
+
+func (s *scanner) space() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\n', '\r':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+// value scans a value, which mustn't be empty. If reachable is true, arrays
+// in it reached only through object members are candidates for the largest
+// array.
+func (s *scanner) value(reachable bool) bool {
+	s.space()
+	if s.pos == len(s.data) {
+		return false
+	}
+	switch s.data[s.pos] {
+	case '{':
+		return s.object(reachable)
+	case '[':
+		return s.array(reachable)
+	case '"':
+		_, ok := s.skipString()
+		return ok
+	}
+	start := s.pos
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ',', ']', '}', ' ', '\t', '\n', '\r':
+			return s.pos > start
+		}
+		s.pos++
+	}
+	return true
+}
+
+func (s *scanner) object(reachable bool) bool {
+	s.pos++
+	var keys []string // Keys of the object, if it's reachable.
+	for first := true; ; first = false {
+		s.space()
+		if s.pos == len(s.data) {
+			return false
+		}
+		if s.data[s.pos] == '}' {
+			s.pos++
+			return true
+		}
+		if !first {
+			if s.data[s.pos] != ',' {
+				return false
+			}
+			s.pos++
+			s.space()
+		}
+		key, ok := s.string()
+		if !ok {
+			return false
+		}
+		if reachable {
+			if slices.Contains(keys, key) {
+				s.duplicateKeys = true
+			}
+			keys = append(keys, key)
+		}
+		s.space()
+		if s.pos == len(s.data) || s.data[s.pos] != ':' {
+			return false
+		}
+		s.pos++
+		s.path = append(s.path, key)
+		ok = s.value(reachable)
+		s.path = s.path[:len(s.path)-1]
+		if !ok {
+			return false
+		}
+	}
+}
+
+func (s *scanner) array(reachable bool) bool {
+	start := s.pos
+	s.pos++
+	length := 0
+	for {
+		s.space()
+		if s.pos == len(s.data) {
+			return false
+		}
+		if s.data[s.pos] == ']' {
+			s.pos++
+			break
+		}
+		if length > 0 {
+			if s.data[s.pos] != ',' {
+				return false
+			}
+			s.pos++
+		}
+		if !s.value(false) {
+			return false
+		}
+		length++
+	}
+	if reachable && length > s.largest.length {
+		s.largest = arraySpan{
+			path:   append([]string(nil), s.path...),
+			start:  start,
+			end:    s.pos,
+			length: length,
+		}
+	}
+	return true
+}
+
+// string scans a string, and returns its value.
+func (s *scanner) string() (string, bool) {
+	start := s.pos
+	escaped, ok := s.skipString()
+	if !ok {
+		return "", false
+	}
+	if !escaped {
+		return string(s.data[start+1 : s.pos-1]), true
+	}
+	var v string
+	err := json.Unmarshal(s.data[start:s.pos], &v)
+	return v, err == nil
+}
+
+// skipString scans a string, and reports whether it has escape sequences.
+func (s *scanner) skipString() (escaped, ok bool) {
+	if s.pos == len(s.data) || s.data[s.pos] != '"' {
+		return false, false
+	}
+	for s.pos++; s.pos < len(s.data); s.pos++ {
+		switch s.data[s.pos] {
+		case '\\':
+			escaped = true
+			s.pos++
+		case '"':
+			s.pos++
+			return escaped, true
+		}
+	}
+	return escaped, false
+}
@@ -0,0 +1,145 @@
+package jsonutil_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/isihu/graphql"
+	"github.com/isihu/graphql/internal/jsonutil"
+)
+
+// connection returns the response data of a repository with n issues.
+func connection(n int) []byte {
+	nodes := make([]string, n)
+	for i := range nodes {
+		nodes[i] = fmt.Sprintf(`{"number": %d, "title": "issue \"%d\"", "labels": [{"name": "bug"}]}`, i, i)
+	}
+	return []byte(`{"repository": {"name": "graphql", "issues": {"totalCount": ` + fmt.Sprint(n) +
+		`, "nodes": [` + strings.Join(nodes, ", ") + `]}, "tags": ["v1", "v2"]}}`)
+}
+
+type repositoryQuery struct {
+	Repository struct {
+		Name   graphql.String
+		Issues struct {
+			TotalCount graphql.Int
+			Nodes      []struct {
+				Number graphql.Int
+				Title  graphql.String
+				Labels []struct{ Name graphql.String }
+			}
+		} `graphql:"issues(first:100)"`
+		Tags []graphql.String
+	}
+}
+
+func TestParallel_Unmarshal(t *testing.T) {
+	data := connection(100)
+	var want repositoryQuery
+	if err := jsonutil.UnmarshalGraphQL(data, &want); err != nil {
+		t.Fatal(err)
+	}
+	var got repositoryQuery
+	if err := (jsonutil.Parallel{MinElements: 10, Workers: 4}).Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %+v, want: %+v", got, want)
+	}
+	if got, want := got.Repository.Issues.Nodes[42].Title, graphql.String(`issue "42"`); got != want {
+		t.Errorf("got title: %q, want: %q", got, want)
+	}
+}
+
+func TestParallel_MergeUnmarshal(t *testing.T) {
+	var got repositoryQuery
+	p := jsonutil.Parallel{MinElements: 10, Workers: 4}
+	if err := p.MergeUnmarshal(connection(20), &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.MergeUnmarshal(connection(30), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(got.Repository.Issues.Nodes), 50; got != want {
+		t.Fatalf("got %d nodes, want: %d", got, want)
+	}
+	if got, want := got.Repository.Issues.Nodes[20].Number, graphql.Int(0); got != want {
+		t.Errorf("got number: %v, want: %v", got, want)
+	}
+}
+
+func TestParallel_Unmarshal_error(t *testing.T) {
+	data := []byte(`{"repository": {"issues": {"nodes": [{"number": 1}, {"number": 2}, {"author": "gopher"}]}}}`)
+	var got repositoryQuery
+	err := (jsonutil.Parallel{MinElements: 2, Workers: 2}).Unmarshal(data, &got)
+	if err == nil {
+		t.Fatal("got no error decoding an unknown field")
+	}
+	if got, want := err.Error(), `struct field for "author" doesn't exist in any of 1 places to unmarshal`; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestParallel_Unmarshal_fragment(t *testing.T) {
+	// Arrays selected by fragments are decoded sequentially into every fragment.
+	var got struct {
+		Node struct {
+			Repository struct {
+				Tags []graphql.String
+			} `graphql:"... on Repository"`
+			Tags []graphql.String
+		}
+	}
+	data := []byte(`{"node": {"tags": ["v1", "v2", "v3"]}}`)
+	if err := (jsonutil.Parallel{MinElements: 2, Workers: 2}).Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []graphql.String{"v1", "v2", "v3"}
+	if !reflect.DeepEqual(got.Node.Tags, want) || !reflect.DeepEqual(got.Node.Repository.Tags, want) {
+		t.Errorf("got tags: %v and %v, want: %v", got.Node.Tags, got.Node.Repository.Tags, want)
+	}
+}
+
+func BenchmarkParallel_Unmarshal(b *testing.B) {
+	data := connection(10000)
+	p := jsonutil.Parallel{MinElements: 1000, Workers: 8}
+	for b.Loop() {
+		var q repositoryQuery
+		if err := p.Unmarshal(data, &q); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParallel_Unmarshal_sequentialEquivalence(t *testing.T) {
+	// Parallel decoding must agree with sequential decoding, on both the
+	// decoded data and whether decoding fails.
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"trailingComma", `{"repository": {"issues": {"nodes": [{"number": 1}, {"number": 2}, {"number": 3},]}}}`},
+		{"leadingComma", `{"repository": {"issues": {"nodes": [, {"number": 1}, {"number": 2}, {"number": 3}]}}}`},
+		{"emptyElement", `{"repository": {"issues": {"nodes": [{"number": 1}, , {"number": 3}]}}}`},
+		{"emptyValue", `{"repository": {"name": , "issues": {"nodes": [{"number": 1}, {"number": 2}]}}}`},
+		{"duplicateArrayKey", `{"repository": {"issues": {"nodes": [{"number": 1}, {"number": 2}, {"number": 3}], "nodes": [{"number": 4}]}}}`},
+		{"duplicateParentKey", `{"repository": {"issues": {"nodes": [{"number": 1}, {"number": 2}, {"number": 3}]}, "issues": {"nodes": []}}}`},
+		{"duplicateOtherKey", `{"repository": {"name": "a", "name": "b", "issues": {"nodes": [{"number": 1}, {"number": 2}, {"number": 3}]}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want repositoryQuery
+			wantErr := jsonutil.UnmarshalGraphQL([]byte(tt.data), &want)
+			var got repositoryQuery
+			gotErr := (jsonutil.Parallel{MinElements: 2, Workers: 2}).Unmarshal([]byte(tt.data), &got)
+			if (gotErr == nil) != (wantErr == nil) {
+				t.Fatalf("got error: %v, want: %v", gotErr, wantErr)
+			}
+			if wantErr == nil && !reflect.DeepEqual(got, want) {
+				t.Errorf("got: %+v, want: %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Compression configures the permessage-deflate extension, which compresses
+// data messages. By default, every message is compressed with the context of
+// the previous ones in the same direction ("context takeover"), which improves
+// the compression of similar messages at the cost of keeping up to 32 KiB of
+// context per direction for the lifetime of the connection.
+//
+// Specification: https://datatracker.ietf.org/doc/html/rfc7692.
+type Compression struct {
+	// ClientNoContextTakeover makes the client compress every message independently.
+	ClientNoContextTakeover bool
+
+	// ServerNoContextTakeover makes the server compress every message independently.
+	ServerNoContextTakeover bool
+}
+
+const deflateExtension = "permessage-deflate"
+
+// deflateWindow is the size of the LZ77 window of compress/flate,
+// which is the largest that permessage-deflate allows.
+const deflateWindow = 32 << 10
+
+// deflateTail is the end of a sync flush, which compressed messages omit.
+// It's followed by an empty final block when decompressing, so that the
+// decompressor reports the end of the message.
+const deflateTail = "\x00\x00\xff\xff"
+const deflateFinal = "\x01\x00\x00\xff\xff"
+
+// offer returns the Sec-WebSocket-Extensions header value offering c.
+func (c *Compression) offer() string {
+	ext := deflateExtension
+	if c.ClientNoContextTakeover {
+		ext += "; client_no_context_takeover"
+	}
+	if c.ServerNoContextTakeover {
+		ext += "; server_no_context_takeover"
+	}
+	return ext
+}
+
+// extension is an extension in a Sec-WebSocket-Extensions header.
+type extension struct {
+	name   string
+	params map[string]string
+}
+
+// parseExtensions parses the Sec-WebSocket-Extensions headers of h.
+func parseExtensions(h http.Header) []extension {
+	var exts []extension
+	for _, v := range h.Values("Sec-WebSocket-Extensions") {
+		for _, e := range strings.Split(v, ",") {
+			parts := strings.Split(e, ";")
+			ext := extension{name: strings.TrimSpace(parts[0]), params: make(map[string]string)}
+			if ext.name == "" {
+				continue
+			}
+			for _, p := range parts[1:] {
+				k, v, _ := strings.Cut(p, "=")
+				ext.params[strings.TrimSpace(k)] = strings.Trim(strings.TrimSpace(v), `"`)
+			}
+			exts = append(exts, ext)
+		}
+	}
+	return exts
+}
+
+// acceptDeflate returns the compression negotiated by the response header h
+// of a handshake that offered c, or nil if the server declined it.
+func acceptDeflate(c *Compression, h http.Header) (*deflate, error) {
+	exts := parseExtensions(h)
+	if len(exts) == 0 {
+		return nil, nil
+	}
+	if c == nil || len(exts) > 1 || exts[0].name != deflateExtension {
+		return nil, fmt.Errorf("websocket: bad handshake: server chose unoffered extensions %q", h.Values("Sec-WebSocket-Extensions"))
+	}
+	d := &deflate{writeNoContextTakeover: c.ClientNoContextTakeover}
+	for k := range exts[0].params {
+		switch k {
+		case "client_no_context_takeover":
+			d.writeNoContextTakeover = true
+		case "server_no_context_takeover":
+			d.readNoContextTakeover = true
+		case "server_max_window_bits":
+			// Decompression supports any window size.
+		default:
+			return nil, fmt.Errorf("websocket: bad handshake: unsupported permessage-deflate parameter %q", k)
+		}
+	}
+	return d, nil
+}
+
+// negotiateDeflate picks the first of the offers of compression in the request
+// header h of a handshake that's compatible with c, if c is non-nil. It returns
+// the Sec-WebSocket-Extensions header value of the response, and the compression,
+// or nil if none was picked.
+func negotiateDeflate(c *Compression, h http.Header) (string, *deflate) {
+	if c == nil {
+		return "", nil
+	}
+offers:
+	for _, ext := range parseExtensions(h) {
+		if ext.name != deflateExtension {
+			continue
+		}
+		d := &deflate{readNoContextTakeover: c.ClientNoContextTakeover, writeNoContextTakeover: c.ServerNoContextTakeover}
+		for k, v := range ext.params {
+			switch {
+			case k == "client_no_context_takeover":
+				d.readNoContextTakeover = true
+			case k == "server_no_context_takeover":
+				d.writeNoContextTakeover = true
+			case k == "client_max_window_bits":
+				// The client's window may be as large as it likes.
+			case k == "server_max_window_bits" && v == "15":
+			default:
+				// Compression with a smaller window isn't supported.
+				continue offers
+			}
+		}
+		resp := deflateExtension
+		if d.readNoContextTakeover {
+			resp += "; client_no_context_takeover"
+		}
+		if d.writeNoContextTakeover {
+			resp += "; server_no_context_takeover"
+		}
+		return resp, d
+	}
+	return "", nil
+}
+
+// deflate compresses and decompresses the messages of a connection.
+type deflate struct {
+	readNoContextTakeover  bool
+	writeNoContextTakeover bool
+
+	fr   io.ReadCloser // Decompressor. Only used by the reader.
+	dict []byte        // End of the previously decompressed messages.
+
+	fw  *flate.Writer // Compressor, guarded by Conn.wmu, like buf.
+	buf bytes.Buffer
+}
+
+// decompress decompresses the message msg, which may be at most limit bytes long.
+func (d *deflate) decompress(msg []byte, limit int64) ([]byte, error) {
+	r := io.MultiReader(bytes.NewReader(msg), strings.NewReader(deflateTail+deflateFinal))
+	if d.readNoContextTakeover {
+		d.dict = nil
+	}
+	if d.fr == nil {
+		d.fr = flate.NewReaderDict(r, d.dict)
+	} else {
+		d.fr.(flate.Resetter).Reset(r, d.dict)
+	}
+	out, err := io.ReadAll(io.LimitReader(d.fr, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(out)) > limit {
+		return nil, errors.New("websocket: message exceeds read limit")
+	}
+	if !d.readNoContextTakeover {
+		dict := append(d.dict, out...)
+		if len(dict) > deflateWindow {
+			dict = append([]byte(nil), dict[len(dict)-deflateWindow:]...)
+		}
+		d.dict = dict
+	}
+	return out, nil
+}
+
+// compress compresses the message msg. The result is valid until the next call.
+func (d *deflate) compress(msg []byte) ([]byte, error) {
+	d.buf.Reset()
+	if d.fw == nil {
+		d.fw, _ = flate.NewWriter(&d.buf, flate.DefaultCompression)
+	} else if d.writeNoContextTakeover {
+		d.fw.Reset(&d.buf)
+	}
+	_, err := d.fw.Write(msg)
+	if err == nil {
+		err = d.fw.Flush()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(d.buf.Bytes(), []byte(deflateTail)), nil
+}
@@ -0,0 +1,491 @@
+// Package websocket provides a minimal implementation of the WebSocket protocol,
+// sufficient for running GraphQL subscriptions.
+//
+// Specification: https://datatracker.ietf.org/doc/html/rfc6455.
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// MessageType is the type of a data message.
+type MessageType int
+
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+// Opcodes of frames.
+const (
+	opContinuation = 0
+	opText         = 1
+	opBinary       = 2
+	opClose        = 8
+	opPing         = 9
+	opPong         = 10
+)
+
+// Close codes used by this package.
+const (
+	CloseNormalClosure   = 1000
+	CloseProtocolError   = 1002
+	CloseNoStatus        = 1005
+	CloseMessageTooBig   = 1009
+	CloseAbnormalClosure = 1006
+)
+
+// DefaultReadLimit is the default maximum size of a message that can be read.
+const DefaultReadLimit = 32 << 20
+
+// acceptGUID is used for computing the Sec-WebSocket-Accept header.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// CloseError is returned by ReadMessage when the peer closes the connection.
+type CloseError struct {
+	Code   int    // Close status code, or CloseNoStatus if there wasn't one.
+	Reason string // Close reason, if any.
+}
+
+func (e *CloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket: closed with code %d", e.Code)
+	}
+	return fmt.Sprintf("websocket: closed with code %d: %s", e.Code, e.Reason)
+}
+
+// Conn is a WebSocket connection. It supports one concurrent reader
+// and any number of concurrent writers.
+type Conn struct {
+	rw     io.ReadWriteCloser
+	br     *bufio.Reader
+	client bool // Whether this is the client side, which must mask frames.
+
+	// Subprotocol is the subprotocol negotiated during the handshake, if any.
+	Subprotocol string
+
+	readLimit   int64
+	pongHandler func(data []byte)
+	deflate     *deflate // Nil if compression wasn't negotiated.
+
+	wmu       sync.Mutex // Guards writes to rw.
+	closeOnce sync.Once
+	closeErr  error
+	sentClose bool // Whether a close frame was sent, guarded by wmu.
+}
+
+func newConn(rw io.ReadWriteCloser, br *bufio.Reader, client bool, subprotocol string) *Conn {
+	if br == nil {
+		br = bufio.NewReader(rw)
+	}
+	return &Conn{rw: rw, br: br, client: client, Subprotocol: subprotocol, readLimit: DefaultReadLimit}
+}
+
+// Dial opens a WebSocket connection to url, using hc to make the opening handshake.
+// url may have a ws, wss, http or https scheme. header is sent with the handshake
+// request, and subprotocols are offered in order of preference. If compression
+// is non-nil, it's offered too, and used if the server accepts it.
+// If the server responds with a status code other than 101 Switching Protocols,
+// Dial returns the response with up to 1 KiB of its body.
+func Dial(ctx context.Context, hc *http.Client, url string, header http.Header, subprotocols []string, compression *Compression) (*Conn, *http.Response, error) {
+	switch {
+	case strings.HasPrefix(url, "ws://"):
+		url = "http://" + strings.TrimPrefix(url, "ws://")
+	case strings.HasPrefix(url, "wss://"):
+		url = "https://" + strings.TrimPrefix(url, "wss://")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	var nonce [16]byte
+	rand.Read(nonce[:])
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	if len(subprotocols) > 0 {
+		req.Header.Set("Sec-WebSocket-Protocol", strings.Join(subprotocols, ", "))
+	}
+	if compression != nil {
+		req.Header.Set("Sec-WebSocket-Extensions", compression.offer())
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		// Keep the start of the body, which may explain the failure.
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return nil, resp, fmt.Errorf("websocket: bad handshake: %v", resp.Status)
+	}
+	rw, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		resp.Body.Close()
+		return nil, resp, errors.New("websocket: HTTP client transport doesn't support protocol upgrades")
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		rw.Close()
+		return nil, resp, errors.New("websocket: bad handshake: invalid Sec-WebSocket-Accept header")
+	}
+	protocol := resp.Header.Get("Sec-WebSocket-Protocol")
+	if protocol != "" && !slices.Contains(subprotocols, protocol) {
+		rw.Close()
+		return nil, resp, fmt.Errorf("websocket: bad handshake: server chose unoffered subprotocol %q", protocol)
+	}
+	d, err := acceptDeflate(compression, resp.Header)
+	if err != nil {
+		rw.Close()
+		return nil, resp, err
+	}
+	conn := newConn(rw, nil, true, protocol)
+	conn.deflate = d
+	return conn, resp, nil
+}
+
+// Upgrade upgrades the HTTP server connection to the WebSocket protocol.
+// The first of subprotocols that's also offered by the client is chosen.
+// If compression is non-nil, it's used if the client offers it.
+// If the upgrade fails, Upgrade replies to the client with an HTTP error.
+func Upgrade(w http.ResponseWriter, r *http.Request, subprotocols []string, compression *Compression) (*Conn, error) {
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "websocket: not a websocket handshake", http.StatusBadRequest)
+		return nil, errors.New("websocket: not a websocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "websocket: unsupported version", http.StatusUpgradeRequired)
+		return nil, errors.New("websocket: unsupported version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "websocket: missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, errors.New("websocket: missing Sec-WebSocket-Key")
+	}
+	var protocol string
+	offered := headerTokens(r.Header, "Sec-WebSocket-Protocol")
+	for _, p := range subprotocols {
+		if slices.Contains(offered, p) {
+			protocol = p
+			break
+		}
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket: response doesn't support hijacking", http.StatusInternalServerError)
+		return nil, errors.New("websocket: response doesn't support hijacking")
+	}
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	var resp strings.Builder
+	resp.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	resp.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	if protocol != "" {
+		resp.WriteString("Sec-WebSocket-Protocol: " + protocol + "\r\n")
+	}
+	ext, d := negotiateDeflate(compression, r.Header)
+	if ext != "" {
+		resp.WriteString("Sec-WebSocket-Extensions: " + ext + "\r\n")
+	}
+	resp.WriteString("\r\n")
+	if _, err := io.WriteString(conn, resp.String()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c := newConn(conn, brw.Reader, false, protocol)
+	c.deflate = d
+	return c, nil
+}
+
+// SetReadLimit sets the maximum size of a message that can be read.
+// Larger messages fail ReadMessage and close the connection.
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+// SetPongHandler sets a function that's called with the payload of
+// every pong frame received. It's called from ReadMessage.
+func (c *Conn) SetPongHandler(h func(data []byte)) {
+	c.pongHandler = h
+}
+
+// ReadMessage reads the next data message. Control frames are handled
+// along the way: pings are answered with pongs, and a close frame makes
+// ReadMessage reply with a close frame and return a *CloseError.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	var typ MessageType
+	var msg []byte
+	var compressed bool
+	for {
+		f, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch f.opcode {
+		case opPing:
+			err := c.writeFrame(opPong, f.payload)
+			if err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			if c.pongHandler != nil {
+				c.pongHandler(f.payload)
+			}
+			continue
+		case opClose:
+			ce := &CloseError{Code: CloseNoStatus}
+			if len(f.payload) >= 2 {
+				ce.Code = int(binary.BigEndian.Uint16(f.payload))
+				ce.Reason = string(f.payload[2:])
+			}
+			c.closeWith(ce.Code, "")
+			return 0, nil, ce
+		case opText, opBinary:
+			if typ != 0 {
+				c.closeWith(CloseProtocolError, "expected continuation frame")
+				return 0, nil, errors.New("websocket: expected continuation frame")
+			}
+			typ = MessageType(f.opcode)
+			compressed = f.compressed
+		case opContinuation:
+			if typ == 0 {
+				c.closeWith(CloseProtocolError, "unexpected continuation frame")
+				return 0, nil, errors.New("websocket: unexpected continuation frame")
+			}
+		default:
+			c.closeWith(CloseProtocolError, "unknown opcode")
+			return 0, nil, fmt.Errorf("websocket: unknown opcode %d", f.opcode)
+		}
+		if int64(len(msg))+int64(len(f.payload)) > c.readLimit {
+			c.closeWith(CloseMessageTooBig, "")
+			return 0, nil, errors.New("websocket: message exceeds read limit")
+		}
+		msg = append(msg, f.payload...)
+		if !f.fin {
+			continue
+		}
+		if compressed {
+			msg, err = c.deflate.decompress(msg, c.readLimit)
+			if err != nil {
+				c.closeWith(CloseProtocolError, "bad compressed message")
+				return 0, nil, err
+			}
+		}
+		return typ, msg, nil
+	}
+}
+
+// WriteMessage writes a data message of type typ as a single frame,
+// compressing it if compression was negotiated.
+func (c *Conn) WriteMessage(typ MessageType, data []byte) error {
+	if c.deflate == nil {
+		return c.writeFrame(byte(typ), data)
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	data, err := c.deflate.compress(data)
+	if err != nil {
+		return err
+	}
+	return c.writeFrameLocked(rsv1|byte(typ), data)
+}
+
+// Ping writes a ping frame with payload data.
+func (c *Conn) Ping(data []byte) error {
+	return c.writeFrame(opPing, data)
+}
+
+// Close closes the connection, first sending a close frame
+// with the normal closure code if one wasn't sent yet.
+func (c *Conn) Close() error {
+	return c.CloseWithCode(CloseNormalClosure, "")
+}
+
+// CloseWithCode closes the connection, first sending a close frame
+// with code and reason if one wasn't sent yet.
+func (c *Conn) CloseWithCode(code int, reason string) error {
+	c.closeWith(code, reason)
+	return c.closeErr
+}
+
+func (c *Conn) closeWith(code int, reason string) {
+	c.closeOnce.Do(func() {
+		payload := make([]byte, 2+len(reason))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		copy(payload[2:], reason)
+		c.writeFrame(opClose, payload)
+		c.closeErr = c.rw.Close()
+	})
+}
+
+// frame is a single WebSocket frame, with its payload unmasked.
+type frame struct {
+	fin        bool
+	compressed bool // Whether the RSV1 bit is set, which marks compressed messages.
+	opcode     byte
+	payload    []byte
+}
+
+// rsv1 is the bit of the first byte of a frame that marks compressed messages.
+const rsv1 = 0x40
+
+func (c *Conn) readFrame() (frame, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(c.br, hdr[:]); err != nil {
+		return frame{}, err
+	}
+	f := frame{fin: hdr[0]&0x80 != 0, compressed: hdr[0]&rsv1 != 0, opcode: hdr[0] & 0x0f}
+	reserved := hdr[0] & 0x70
+	if f.compressed && c.deflate != nil && (f.opcode == opText || f.opcode == opBinary) {
+		reserved &^= rsv1
+	}
+	if reserved != 0 {
+		c.closeWith(CloseProtocolError, "unexpected reserved bits")
+		return frame{}, errors.New("websocket: unexpected reserved bits set")
+	}
+	masked := hdr[1]&0x80 != 0
+	if masked == c.client {
+		c.closeWith(CloseProtocolError, "bad masking")
+		return frame{}, errors.New("websocket: bad frame masking")
+	}
+	length := int64(hdr[1] & 0x7f)
+	switch length {
+	case 126:
+		var b [2]byte
+		if _, err := io.ReadFull(c.br, b[:]); err != nil {
+			return frame{}, err
+		}
+		length = int64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err := io.ReadFull(c.br, b[:]); err != nil {
+			return frame{}, err
+		}
+		length = int64(binary.BigEndian.Uint64(b[:]) & (1<<63 - 1))
+	}
+	if f.opcode >= opClose && (length > 125 || !f.fin) {
+		c.closeWith(CloseProtocolError, "bad control frame")
+		return frame{}, errors.New("websocket: bad control frame")
+	}
+	if length > c.readLimit {
+		c.closeWith(CloseMessageTooBig, "")
+		return frame{}, errors.New("websocket: message exceeds read limit")
+	}
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+			return frame{}, err
+		}
+	}
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, f.payload); err != nil {
+		return frame{}, err
+	}
+	if masked {
+		maskBytes(mask, f.payload)
+	}
+	return f, nil
+}
+
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	return c.writeFrameLocked(opcode, payload)
+}
+
+// writeFrameLocked writes a frame with the reserved bits and opcode of bits.
+// c.wmu must be held.
+func (c *Conn) writeFrameLocked(bits byte, payload []byte) error {
+	opcode := bits & 0x0f
+	if c.sentClose {
+		return errors.New("websocket: write after close")
+	}
+	if opcode == opClose {
+		c.sentClose = true
+	}
+	buf := make([]byte, 0, 14+len(payload))
+	buf = append(buf, 0x80|bits)
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		buf = append(buf, maskBit|byte(n))
+	case n <= 0xffff:
+		buf = append(buf, maskBit|126)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, maskBit|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+	if c.client {
+		var mask [4]byte
+		rand.Read(mask[:])
+		buf = append(buf, mask[:]...)
+		start := len(buf)
+		buf = append(buf, payload...)
+		maskBytes(mask, buf[start:])
+	} else {
+		buf = append(buf, payload...)
+	}
+	_, err := c.rw.Write(buf)
+	return err
+}
+
+func maskBytes(mask [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= mask[i%4]
+	}
+}
+
+// acceptKey computes the Sec-WebSocket-Accept header value for key.
+func acceptKey(key string) string {
+	h := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// headerTokens returns the comma-separated tokens of header key.
+func headerTokens(h http.Header, key string) []string {
+	var tokens []string
+	for _, v := range h.Values(key) {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tokens = append(tokens, t)
+			}
+		}
+	}
+	return tokens
+}
+
+// headerContains reports whether header key contains token, case-insensitively.
+func headerContains(h http.Header, key, token string) bool {
+	for _, t := range headerTokens(h, key) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
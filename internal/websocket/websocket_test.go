@@ -0,0 +1,176 @@
+package websocket_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/isihu/graphql/internal/websocket"
+)
+
+func TestDial(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := websocket.Upgrade(w, req, []string{"graphql-transport-ws"}, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		// Echo messages back until the client closes the connection.
+		for {
+			typ, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			err = conn.WriteMessage(typ, msg)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}))
+	defer ts.Close()
+
+	conn, _, err := websocket.Dial(context.Background(), http.DefaultClient, "ws"+strings.TrimPrefix(ts.URL, "http"), nil, []string{"graphql-ws", "graphql-transport-ws"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if got, want := conn.Subprotocol, "graphql-transport-ws"; got != want {
+		t.Errorf("got subprotocol: %q, want: %q", got, want)
+	}
+	var pongs [][]byte
+	conn.SetPongHandler(func(data []byte) { pongs = append(pongs, data) })
+
+	for _, msg := range [][]byte{
+		[]byte(`{"type":"connection_init"}`),
+		bytes.Repeat([]byte("x"), 200),    // 16-bit length.
+		bytes.Repeat([]byte("y"), 100000), // 64-bit length.
+	} {
+		err := conn.Ping([]byte("ping"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = conn.WriteMessage(websocket.TextMessage, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		typ, got, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ != websocket.TextMessage || !bytes.Equal(got, msg) {
+			t.Errorf("got message of type %v and length %v, want echo of length %v", typ, len(got), len(msg))
+		}
+	}
+	if got, want := len(pongs), 3; got != want {
+		t.Errorf("got %v pongs, want %v", got, want)
+	}
+}
+
+func TestDial_compression(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		client  *websocket.Compression
+		server  *websocket.Compression
+		wantExt string
+	}{
+		{"contextTakeover", &websocket.Compression{}, &websocket.Compression{}, "permessage-deflate"},
+		{"clientNoContextTakeover", &websocket.Compression{ClientNoContextTakeover: true}, &websocket.Compression{}, "permessage-deflate; client_no_context_takeover"},
+		{"serverNoContextTakeover", &websocket.Compression{}, &websocket.Compression{ServerNoContextTakeover: true}, "permessage-deflate; server_no_context_takeover"},
+		{"declined", &websocket.Compression{}, nil, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				conn, err := websocket.Upgrade(w, req, nil, tt.server)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				defer conn.Close()
+				for {
+					typ, msg, err := conn.ReadMessage()
+					if err != nil {
+						return
+					}
+					conn.WriteMessage(typ, msg)
+				}
+			}))
+			defer ts.Close()
+
+			conn, resp, err := websocket.Dial(context.Background(), http.DefaultClient, ts.URL, nil, nil, tt.client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			if got := resp.Header.Get("Sec-WebSocket-Extensions"); got != tt.wantExt {
+				t.Errorf("got extensions: %q, want: %q", got, tt.wantExt)
+			}
+			// Similar messages exercise the compression context.
+			for i, msg := range []string{
+				`{"type":"next","payload":{"data":{"tick":1}}}`,
+				`{"type":"next","payload":{"data":{"tick":2}}}`,
+				"",
+				strings.Repeat(`{"type":"next","payload":{"data":{"tick":3}}}`, 2000),
+				`{"type":"next","payload":{"data":{"tick":4}}}`,
+			} {
+				err := conn.WriteMessage(websocket.TextMessage, []byte(msg))
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, got, err := conn.ReadMessage()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != msg {
+					t.Errorf("message %d: got echo of length %v, want %v", i, len(got), len(msg))
+				}
+			}
+		})
+	}
+}
+
+func TestConn_closeError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := websocket.Upgrade(w, req, nil, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.CloseWithCode(4403, "Forbidden")
+	}))
+	defer ts.Close()
+
+	conn, _, err := websocket.Dial(context.Background(), http.DefaultClient, ts.URL, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, _, err = conn.ReadMessage()
+	var ce *websocket.CloseError
+	if !errors.As(err, &ce) {
+		t.Fatalf("got error: %v, want *websocket.CloseError", err)
+	}
+	if got, want := *ce, (websocket.CloseError{Code: 4403, Reason: "Forbidden"}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestDial_badHandshake(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "no websockets here", http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	_, resp, err := websocket.Dial(context.Background(), http.DefaultClient, ts.URL, nil, nil, nil)
+	if err == nil {
+		t.Fatal("got error: nil, want: non-nil")
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("got response: %v, want 404 response", resp)
+	}
+}
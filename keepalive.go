@@ -0,0 +1,184 @@
+package graphql
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// KeepAlive configures how subscription connections are kept alive
+// and checked for liveness. Connections that are found dead are closed,
+// which fails their subscriptions, or reconnects them if enabled
+// by WithSubscriptionReconnect.
+type KeepAlive struct {
+	// PingInterval is how often the client pings the server over WebSocket,
+	// which keeps idle connections from being closed by load balancers and proxies.
+	// The graphql-transport-ws protocol uses ping messages, and the legacy protocol
+	// uses WebSocket ping frames. Server-Sent Events connections can't be pinged.
+	// Zero means no pings.
+	PingInterval time.Duration
+
+	// PongTimeout is how long the client waits for a pong after a ping.
+	// Zero means PingInterval.
+	PongTimeout time.Duration
+
+	// ServerTimeout is how long the client tolerates not receiving anything
+	// from the server, including keep-alive messages and comments, before
+	// it considers the connection dead. Zero means no limit.
+	ServerTimeout time.Duration
+}
+
+// WithSubscriptionKeepAlive configures how subscription connections are kept alive.
+// By default, the client doesn't ping the server, and tolerates idle connections.
+func WithSubscriptionKeepAlive(ka KeepAlive) ClientOption {
+	if ka.PongTimeout == 0 {
+		ka.PongTimeout = ka.PingInterval
+	}
+	return func(c *Client) { c.subscriptionKeepAlive = ka }
+}
+
+// keepAliveMonitor pings a subscription connection and checks it for
+// liveness according to a KeepAlive, closing it if it's found dead.
+type keepAliveMonitor struct {
+	ka    KeepAlive
+	ping  func() error // Pings the server. Nil if the connection can't be pinged.
+	close func()       // Closes the connection.
+	stop  chan struct{}
+
+	mu       sync.Mutex
+	received time.Time // When something was last received.
+	pinged   time.Time // When the outstanding ping was sent. Zero if there's none.
+	err      error     // Why the connection was closed, if it was.
+}
+
+// startKeepAlive starts monitoring a connection according to ka,
+// or returns nil if ka doesn't call for monitoring.
+// The returned monitor must be finished when the connection is closed.
+func startKeepAlive(ka KeepAlive, ping func() error, closeConn func()) *keepAliveMonitor {
+	if ping == nil {
+		ka.PingInterval = 0
+	}
+	if ka.PingInterval == 0 && ka.ServerTimeout == 0 {
+		return nil
+	}
+	m := &keepAliveMonitor{
+		ka:       ka,
+		ping:     ping,
+		close:    closeConn,
+		stop:     make(chan struct{}),
+		received: time.Now(),
+	}
+	go m.run()
+	return m
+}
+
+// run pings the connection and checks it for liveness until m is stopped.
+func (m *keepAliveMonitor) run() {
+	lastPing := time.Now()
+	for {
+		now := time.Now()
+		m.mu.Lock()
+		var err error
+		var next time.Time
+		switch {
+		case m.ka.ServerTimeout > 0 && now.Sub(m.received) >= m.ka.ServerTimeout:
+			err = fmt.Errorf("graphql: subscription connection timed out: nothing received from server for %v", m.ka.ServerTimeout)
+		case !m.pinged.IsZero() && now.Sub(m.pinged) >= m.ka.PongTimeout:
+			err = fmt.Errorf("graphql: subscription connection timed out: no pong received within %v", m.ka.PongTimeout)
+		}
+		if err == nil && m.ka.PingInterval > 0 {
+			if now.Sub(lastPing) >= m.ka.PingInterval {
+				lastPing = now
+				if m.pinged.IsZero() {
+					m.pinged = now
+				}
+				m.mu.Unlock()
+				err = m.ping()
+				m.mu.Lock()
+			}
+			next = lastPing.Add(m.ka.PingInterval)
+			if !m.pinged.IsZero() {
+				next = earliest(next, m.pinged.Add(m.ka.PongTimeout))
+			}
+		}
+		if err == nil && m.ka.ServerTimeout > 0 {
+			next = earliest(next, m.received.Add(m.ka.ServerTimeout))
+		}
+		if err != nil {
+			m.err = err
+			m.mu.Unlock()
+			m.close()
+			return
+		}
+		m.mu.Unlock()
+
+		t := time.NewTimer(time.Until(next))
+		select {
+		case <-m.stop:
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// earliest returns the earliest of t and u, ignoring zero times.
+func earliest(t, u time.Time) time.Time {
+	if t.IsZero() || !u.IsZero() && u.Before(t) {
+		return u
+	}
+	return t
+}
+
+// activity records that something was received from the server.
+// It's a no-op on a nil monitor, as are the other methods.
+func (m *keepAliveMonitor) activity() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.received = time.Now()
+	m.mu.Unlock()
+}
+
+// ponged records that the server answered the outstanding ping.
+func (m *keepAliveMonitor) ponged() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.received = time.Now()
+	m.pinged = time.Time{}
+	m.mu.Unlock()
+}
+
+// finish stops m. It returns the error that m closed the connection with,
+// if it did, which takes precedence over the error err that reading
+// from the connection failed with.
+func (m *keepAliveMonitor) finish(err error) error {
+	if m == nil {
+		return err
+	}
+	close(m.stop)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	return err
+}
+
+// activityReader calls m.activity on every read from r.
+type activityReader struct {
+	r io.Reader
+	m *keepAliveMonitor
+}
+
+func (r activityReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.m.activity()
+	}
+	return n, err
+}
@@ -0,0 +1,96 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isihu/graphql"
+	"github.com/isihu/graphql/internal/websocket"
+)
+
+func TestClient_Subscribe_keepAlivePing(t *testing.T) {
+	for _, answer := range []bool{true, false} {
+		pings := make(chan struct{}, 10)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			conn, err := websocket.Upgrade(w, req, []string{"graphql-transport-ws"}, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer conn.Close()
+			conn.ReadMessage() // connection_init.
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"connection_ack"}`))
+			for {
+				_, data, err := conn.ReadMessage()
+				if err != nil {
+					return
+				}
+				if string(data) != `{"type":"ping"}` {
+					continue
+				}
+				pings <- struct{}{}
+				if answer {
+					conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"pong"}`))
+				}
+			}
+		}))
+		client := graphql.NewClient(srv.URL, nil, graphql.WithSubscriptionKeepAlive(graphql.KeepAlive{
+			PingInterval: 10 * time.Millisecond,
+		}))
+
+		sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if answer {
+			for range 3 {
+				<-pings
+			}
+			sub.Unsubscribe()
+			if err := sub.Err(); err != nil {
+				t.Errorf("got error: %v, want: nil", err)
+			}
+		} else {
+			for range sub.Events() {
+			}
+			if got, want := sub.Err(), "graphql: subscription connection timed out: no pong received within 10ms"; got == nil || got.Error() != want {
+				t.Errorf("got error: %v, want: %v", got, want)
+			}
+		}
+		srv.Close()
+	}
+}
+
+func TestClient_Subscribe_keepAliveServerTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for range 3 {
+			mustWrite(w, ":\n\n") // Keep-alive comment.
+			w.(http.Flusher).Flush()
+			time.Sleep(10 * time.Millisecond)
+		}
+		<-req.Context().Done()
+	}))
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil,
+		graphql.WithSubscriptionProtocol(graphql.GraphQLSSE),
+		graphql.WithSubscriptionKeepAlive(graphql.KeepAlive{ServerTimeout: 50 * time.Millisecond}))
+
+	start := time.Now()
+	sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range sub.Events() {
+	}
+	if got, want := sub.Err(), "nothing received from server for 50ms"; got == nil || !strings.HasSuffix(got.Error(), want) {
+		t.Errorf("got error: %v, want suffix: %v", got, want)
+	}
+	if elapsed := time.Since(start); elapsed < 70*time.Millisecond {
+		t.Errorf("subscription timed out after %v, despite keep-alive comments", elapsed)
+	}
+}
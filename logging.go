@@ -0,0 +1,108 @@
+package graphql
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+)
+
+// LogOptions configures what WithLogger logs.
+type LogOptions struct {
+	// Document and Variables make the records include the GraphQL document
+	// and variables of the operation, which are left out by default.
+	Document  bool
+	Variables bool
+
+	// Redact, if non-nil, returns the value to log for the variable named
+	// name with value, so that secrets and personal data can be masked.
+	Redact func(name string, value any) any
+}
+
+// WithLogger makes the client log every HTTP request for an operation
+// sent by Query, Mutate and Do to logger, with the operation's name and type,
+// the duration of the request, the status code of the response, and the
+// error, if any. Successful requests are logged at the debug level,
+// and failed ones at the warning level.
+func WithLogger(logger *slog.Logger, opts LogOptions) ClientOption {
+	return func(c *Client) {
+		c.logger = logger
+		c.logOptions = opts
+	}
+}
+
+// logRequest logs a request that sent in, taking d, and got a response with
+// status, which is 0 if there was no response, and failed with err, if non-nil.
+func (c *Client) logRequest(ctx context.Context, in request, d time.Duration, status int, err error) {
+	level := slog.LevelDebug
+	if err != nil {
+		level = slog.LevelWarn
+	}
+	if !c.logger.Enabled(ctx, level) {
+		return
+	}
+	query := in.Query
+	if query == "" {
+		query = in.hashedQuery
+	}
+	attrs := []slog.Attr{
+		slog.String("operation", operationName(query)),
+		slog.String("type", operationType(query)),
+		slog.Duration("duration", d),
+		slog.Int("status", status),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	if c.logOptions.Document {
+		attrs = append(attrs, slog.String("document", query))
+	}
+	if c.logOptions.Variables && len(in.Variables) > 0 {
+		vars := make([]any, 0, len(in.Variables))
+		for _, name := range slices.Sorted(maps.Keys(in.Variables)) {
+			value := in.Variables[name]
+			if c.logOptions.Redact != nil {
+				value = c.logOptions.Redact(name, value)
+			}
+			vars = append(vars, slog.Any(name, value))
+		}
+		attrs = append(attrs, slog.Group("variables", vars...))
+	}
+	c.logger.LogAttrs(ctx, level, "graphql request", attrs...)
+}
+
+// WithSlowOperationThreshold makes the client report operations sent by
+// Query, Mutate and Do that take d or longer as slow. They're logged at
+// the warning level if WithLogger is used, with the names of their variables
+// and a breakdown of the time spent, and reported as slow to the Metrics of
+// WithMetrics.
+func WithSlowOperationThreshold(d time.Duration) ClientOption {
+	return func(c *Client) { c.slowThreshold = d }
+}
+
+// logSlowOperation logs an operation with the GraphQL document query and
+// variables that took d, with stats collected while sending it.
+func (c *Client) logSlowOperation(ctx context.Context, query string, variables map[string]any, d time.Duration, stats *callStats) {
+	c.logger.LogAttrs(ctx, slog.LevelWarn, "slow graphql operation",
+		slog.String("operation", operationName(query)),
+		slog.String("type", operationType(query)),
+		slog.Duration("duration", d),
+		slog.Int("attempts", stats.attempts),
+		slog.Duration("round_trip", stats.roundTrip),
+		slog.Group("last_request", connAttrs(stats.conn.timings())...),
+		slog.Duration("decode", stats.decode),
+		slog.Any("variables", slices.Sorted(maps.Keys(variables))),
+	)
+}
+
+// connAttrs returns the attributes logging the connection-level timings t.
+func connAttrs(t Timings) []any {
+	return []any{
+		slog.Duration("dns", t.DNS),
+		slog.Duration("connect", t.Connect),
+		slog.Duration("tls", t.TLS),
+		slog.Duration("server", t.Server),
+		slog.Bool("conn_reused", t.ConnReused),
+	}
+}
@@ -0,0 +1,99 @@
+package graphql_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isihu/graphql"
+)
+
+func TestWithLogger(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if strings.Contains(mustRead(req.Body), "fail") {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "duration" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithLogger(logger, graphql.LogOptions{
+			Variables: true,
+			Redact: func(name string, value any) any {
+				if name == "token" {
+					return "[REDACTED]"
+				}
+				return value
+			},
+		}))
+
+	var res struct{ User struct{ Name graphql.String } }
+	query := "query GetUser($login:String!$token:String!){user(login:$login, token:$token){name}}"
+	err := client.Do(context.Background(), query, &res, false, map[string]any{"login": "gopher", "token": "s3cr3t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Do(context.Background(), "mutation fail{user{name}}", &res, false, nil)
+
+	want := `level=DEBUG msg="graphql request" operation=GetUser type=query status=200 variables.login=gopher variables.token=[REDACTED]
+level=WARN msg="graphql request" operation=fail type=mutation status=500 error="non-200 OK status code: 500 Internal Server Error body: \"boom\\n\""
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got logs:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWithSlowOperationThreshold(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+	for _, tc := range []struct {
+		threshold time.Duration
+		want      bool
+	}{
+		{threshold: time.Nanosecond, want: true},
+		{threshold: time.Hour, want: false},
+	} {
+		var buf bytes.Buffer
+		var slow bool
+		client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+			graphql.WithLogger(slog.New(slog.NewTextHandler(&buf, nil)), graphql.LogOptions{}),
+			graphql.WithMetrics(metricsFunc(func(m graphql.OperationMetrics) { slow = m.Slow })),
+			graphql.WithSlowOperationThreshold(tc.threshold))
+
+		var res struct{ User struct{ Name graphql.String } }
+		query := "query GetUser($login:String!){user(login:$login){name}}"
+		err := client.Do(context.Background(), query, &res, false, map[string]any{"login": "gopher"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slow != tc.want {
+			t.Errorf("%v: got slow metric: %v, want: %v", tc.threshold, slow, tc.want)
+		}
+		logged := strings.Contains(buf.String(), `msg="slow graphql operation" operation=GetUser type=query duration=`)
+		if logged != tc.want {
+			t.Errorf("%v: got logs:\n%s\nwant slow operation logged: %v", tc.threshold, buf.String(), tc.want)
+		}
+		if tc.want && !strings.Contains(buf.String(), " attempts=1 round_trip=") || tc.want && !strings.Contains(buf.String(), " variables=[login]\n") {
+			t.Errorf("%v: got logs:\n%s\nwant attempts, timings and variable names", tc.threshold, buf.String())
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package graphql
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryCache is an in-memory Cache, which evicts the least recently used
+// results when it's full. Expired results are removed when they're read, and
+// swept as others are stored. The zero value isn't usable; use NewMemoryCache.
+type MemoryCache struct {
+	mu         sync.Mutex
+	entries    map[string]*list.Element // Of lru, holding *cacheEntry.
+	lru        *list.List               // Entries, most recently used first.
+	maxEntries int
+	maxBytes   int64
+	bytes      int64
+	sets       int // Values stored since expired entries were last swept.
+	sweepAfter int // Values to store before sweeping them again.
+	stats      CacheStats
+}
+
+// cacheEntry is an entry of a MemoryCache.
+type cacheEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// size returns the number of bytes e counts for against the limit of a MemoryCache.
+func (e *cacheEntry) size() int64 {
+	return int64(len(e.key) + len(e.value))
+}
+
+// CacheStats are statistics of a MemoryCache.
+type CacheStats struct {
+	Hits      int64 // Gets that found a value.
+	Misses    int64 // Gets that found no value, or an expired one.
+	Evictions int64 // Values removed to make room for others.
+	Entries   int   // Values stored.
+	Bytes     int64 // Size of the keys and values stored.
+}
+
+// NewMemoryCache returns a MemoryCache that holds at most maxEntries values,
+// whose keys and values are at most maxBytes in size in all. Zero limits
+// mean the cache isn't limited by the number or size of values. Values larger
+// than maxBytes on their own aren't stored.
+func NewMemoryCache(maxEntries int, maxBytes int64) *MemoryCache {
+	return &MemoryCache{
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+	}
+}
+
+func (m *MemoryCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.entries[key]
+	if !ok {
+		m.stats.Misses++
+		return nil, false, nil
+	}
+	e := el.Value.(*cacheEntry)
+	if time.Now().After(e.expires) {
+		m.remove(el)
+		m.stats.Misses++
+		return nil, false, nil
+	}
+	m.lru.MoveToFront(el)
+	m.stats.Hits++
+	return e.value, true, nil
+}
+
+func (m *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.entries[key]; ok {
+		m.remove(el)
+	}
+	e := &cacheEntry{key: key, value: value, expires: time.Now().Add(ttl)}
+	if m.maxBytes > 0 && e.size() > m.maxBytes {
+		return nil
+	}
+	m.entries[key] = m.lru.PushFront(e)
+	m.bytes += e.size()
+	// Sweeping after as many values are stored as there were entries
+	// after the last sweep takes constant time per value, amortized.
+	if m.sets++; m.sets >= m.sweepAfter {
+		m.sweep()
+	}
+	for m.full() {
+		m.remove(m.lru.Back())
+		m.stats.Evictions++
+	}
+	return nil
+}
+
+func (m *MemoryCache) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.entries[key]; ok {
+		m.remove(el)
+	}
+	return nil
+}
+
+// Stats returns statistics of m since it was created.
+func (m *MemoryCache) Stats() CacheStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.stats
+	s.Entries = m.lru.Len()
+	s.Bytes = m.bytes
+	return s
+}
+
+// full reports whether m holds more than its limits allow.
+func (m *MemoryCache) full() bool {
+	return m.maxEntries > 0 && m.lru.Len() > m.maxEntries ||
+		m.maxBytes > 0 && m.bytes > m.maxBytes
+}
+
+// sweep removes the expired entries of m.
+func (m *MemoryCache) sweep() {
+	m.sets = 0
+	now := time.Now()
+	for el := m.lru.Front(); el != nil; {
+		next := el.Next()
+		if now.After(el.Value.(*cacheEntry).expires) {
+			m.remove(el)
+		}
+		el = next
+	}
+	m.sweepAfter = m.lru.Len()
+}
+
+// remove removes the entry el from m.
+func (m *MemoryCache) remove(el *list.Element) {
+	e := m.lru.Remove(el).(*cacheEntry)
+	delete(m.entries, e.key)
+	m.bytes -= e.size()
+}
@@ -0,0 +1,87 @@
+package graphql_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/isihu/graphql"
+)
+
+func TestMemoryCache_maxEntries(t *testing.T) {
+	ctx := context.Background()
+	cache := graphql.NewMemoryCache(2, 0)
+	cache.Set(ctx, "a", []byte("1"), time.Hour)
+	cache.Set(ctx, "b", []byte("2"), time.Hour)
+	cache.Get(ctx, "a") // Makes b the least recently used.
+	cache.Set(ctx, "c", []byte("3"), time.Hour)
+
+	if _, ok, _ := cache.Get(ctx, "b"); ok {
+		t.Error("got b cached, want it evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok, _ := cache.Get(ctx, key); !ok {
+			t.Errorf("got %s evicted, want it cached", key)
+		}
+	}
+	want := graphql.CacheStats{Hits: 3, Misses: 1, Evictions: 1, Entries: 2, Bytes: 4}
+	if got := cache.Stats(); got != want {
+		t.Errorf("got stats: %+v, want: %+v", got, want)
+	}
+}
+
+func TestMemoryCache_maxBytes(t *testing.T) {
+	ctx := context.Background()
+	cache := graphql.NewMemoryCache(0, 10)
+	cache.Set(ctx, "a", []byte("1234"), time.Hour)
+	cache.Set(ctx, "b", []byte("1234"), time.Hour)
+	cache.Set(ctx, "c", []byte("1234"), time.Hour)
+	cache.Set(ctx, "huge", []byte("12345678901"), time.Hour)
+
+	if _, ok, _ := cache.Get(ctx, "a"); ok {
+		t.Error("got a cached, want it evicted")
+	}
+	if _, ok, _ := cache.Get(ctx, "huge"); ok {
+		t.Error("got a value larger than the cache cached")
+	}
+	if got, want := cache.Stats().Bytes, int64(10); got != want {
+		t.Errorf("got %d bytes cached, want: %d", got, want)
+	}
+
+	// Replacing a value accounts for its new size.
+	cache.Set(ctx, "c", []byte("1"), time.Hour)
+	if got, want := cache.Stats().Bytes, int64(7); got != want {
+		t.Errorf("got %d bytes cached after replacing a value, want: %d", got, want)
+	}
+}
+
+func TestMemoryCache_expiry(t *testing.T) {
+	ctx := context.Background()
+	cache := graphql.NewMemoryCache(0, 0)
+	cache.Set(ctx, "a", []byte("1"), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, ok, _ := cache.Get(ctx, "a"); ok {
+		t.Error("got an expired value cached")
+	}
+	want := graphql.CacheStats{Misses: 1}
+	if got := cache.Stats(); got != want {
+		t.Errorf("got stats: %+v, want: %+v", got, want)
+	}
+}
+
+func TestMemoryCache_sweep(t *testing.T) {
+	ctx := context.Background()
+	cache := graphql.NewMemoryCache(0, 0)
+	for i := range 10 {
+		cache.Set(ctx, fmt.Sprint("expired", i), []byte("1"), time.Millisecond)
+	}
+	time.Sleep(2 * time.Millisecond)
+	for i := range 10 {
+		cache.Set(ctx, fmt.Sprint("live", i), []byte("1"), time.Hour)
+	}
+	// Expired values are removed without being read.
+	if got, want := cache.Stats().Entries, 10; got != want {
+		t.Errorf("got %d entries, want: %d", got, want)
+	}
+}
@@ -0,0 +1,62 @@
+package graphql
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics records metrics about operations, such as request totals,
+// duration histograms and payload sizes in Prometheus collectors,
+// typically labeled by operation name.
+type Metrics interface {
+	// Observe is called after every operation sent by Query, Mutate and Do.
+	Observe(m OperationMetrics)
+}
+
+// OperationMetrics are the metrics of a single operation.
+type OperationMetrics struct {
+	Operation     Operation
+	Duration      time.Duration // How long the operation took, including retries.
+	Attempts      int           // Number of HTTP requests made; more than 1 if the operation was retried.
+	RequestBytes  int64         // Size of the bodies of the requests.
+	ResponseBytes int64         // Size of the bodies of the responses that were read.
+	Timings       Timings       // Connection-level timings of the last HTTP request.
+	Slow          bool          // Whether Duration reached the threshold set by WithSlowOperationThreshold.
+
+	// Err is the error the operation failed with, or nil if it succeeded.
+	// Classify reports its kind.
+	Err error
+}
+
+// WithMetrics makes the client report the metrics of every operation
+// sent by Query, Mutate and Do to m.
+func WithMetrics(m Metrics) ClientOption {
+	return func(c *Client) { c.metrics = m }
+}
+
+// callStats are statistics collected while sending an operation.
+// Byte counts are updated by the transport's goroutines,
+// so they're accessed atomically.
+type callStats struct {
+	attempts      int
+	roundTrip     time.Duration // Time spent waiting for responses, until their headers.
+	decode        time.Duration // Time spent reading and decoding response bodies.
+	requestBytes  atomic.Int64
+	responseBytes atomic.Int64
+	conn          connTimings // Timings of the last request's connection.
+	cacheStatus   string      // Cache status reported by the last response.
+}
+
+// countingReader is an io.ReadCloser that adds the number of bytes
+// read from it to n.
+type countingReader struct {
+	io.ReadCloser
+	n *atomic.Int64
+}
+
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,57 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/isihu/graphql"
+)
+
+type metricsFunc func(m graphql.OperationMetrics)
+
+func (f metricsFunc) Observe(m graphql.OperationMetrics) { f(m) }
+
+func TestWithMetrics(t *testing.T) {
+	var calls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		if calls == 1 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+	var got []graphql.OperationMetrics
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithRetry(graphql.RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}),
+		graphql.WithMetrics(metricsFunc(func(m graphql.OperationMetrics) { got = append(got, m) })))
+
+	var res struct{ User struct{ Name graphql.String } }
+	err := client.Do(context.Background(), "query GetUser{user{name}}", &res, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %d observations, want: 1", len(got))
+	}
+	m := got[0]
+	if got, want := m.Operation.Name, "GetUser"; got != want {
+		t.Errorf("got operation name: %q, want: %q", got, want)
+	}
+	if got, want := m.Attempts, 2; got != want {
+		t.Errorf("got attempts: %v, want: %v", got, want)
+	}
+	if got, want := m.RequestBytes, int64(2*len(`{"query":"query GetUser{user{name}}"}`+"\n")); got != want {
+		t.Errorf("got request bytes: %v, want: %v", got, want)
+	}
+	if got, want := m.ResponseBytes, int64(len("try again\n")+len(`{"data": {"user": {"name": "Gopher"}}}`)); got != want {
+		t.Errorf("got response bytes: %v, want: %v", got, want)
+	}
+	if m.Duration <= 0 || m.Err != nil {
+		t.Errorf("got duration: %v, error: %v, want a positive duration and no error", m.Duration, m.Err)
+	}
+}
@@ -0,0 +1,112 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+)
+
+// multipartAccept is the Accept header of multipart subscription requests.
+const multipartAccept = `multipart/mixed;subscriptionSpec="1.0", application/json`
+
+// connectMultipart starts an operation with in over Apollo's multipart HTTP
+// protocol. It returns a function that receives the operation's results
+// and delivers them to s.
+func (c *Client) connectMultipart(ctx context.Context, url string, in request, s *Subscription) (recv func() error, err error) {
+	defer func() {
+		if err != nil {
+			c.subscriptionHooks.transportError(url, err)
+		}
+	}()
+	body, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", multipartAccept)
+	c.setHeaders(req.Header)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, &NetworkError{Err: err}
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, newHTTPError(resp, resp.Body)
+	}
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		// The server responded with a single result, such as
+		// because it rejected the operation.
+		return func() error {
+			defer resp.Body.Close()
+			var out response
+			err := json.NewDecoder(resp.Body).Decode(&out)
+			if err != nil {
+				return err
+			}
+			if out.Data == nil && len(out.Errors) > 0 {
+				return s.fail(ctx, out.Errors)
+			}
+			return s.deliver(ctx, &out)
+		}, nil
+	}
+	if params["boundary"] == "" {
+		resp.Body.Close()
+		return nil, errors.New("graphql: multipart subscription response without boundary")
+	}
+	c.subscriptionHooks.connected(url)
+	return func() error {
+		defer resp.Body.Close()
+		m := startKeepAlive(c.subscriptionKeepAlive, nil, func() { resp.Body.Close() })
+		err := m.finish(recvMultipart(ctx, activityReader{resp.Body, m}, params["boundary"], s))
+		if ctx.Err() == nil {
+			c.subscriptionHooks.transportError(url, err)
+		}
+		return err
+	}, nil
+}
+
+// recvMultipart reads the parts of body, which are separated by boundary,
+// and delivers their results to s, until the operation completes, fails,
+// or ctx is done. Parts without a payload are heartbeats.
+func recvMultipart(ctx context.Context, body io.Reader, boundary string, s *Subscription) error {
+	parts := multipart.NewReader(body, boundary)
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			// The closing boundary completes the operation.
+			return nil
+		} else if u, ok := err.(interface{ Unwrap() error }); ok && (u.Unwrap() == io.EOF || u.Unwrap() == io.ErrUnexpectedEOF) {
+			return errUnexpectedEnd
+		} else if err != nil {
+			return err
+		}
+		var msg struct {
+			Payload *response `json:"payload"`
+			Errors  Errors    `json:"errors"` // Errors of the transport, which end the operation.
+		}
+		err = json.NewDecoder(part).Decode(&msg)
+		if err != nil {
+			return err
+		}
+		if len(msg.Errors) > 0 {
+			return s.fail(ctx, msg.Errors)
+		}
+		if msg.Payload == nil {
+			continue
+		}
+		err = s.deliver(ctx, msg.Payload)
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+// multipartServer returns a test server that responds to subscriptions
+// with a multipart/mixed stream of parts.
+func multipartServer(t *testing.T, parts ...string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.Header.Get("Accept"), `multipart/mixed;subscriptionSpec="1.0", application/json`; got != want {
+			t.Errorf("got Accept header: %q, want: %q", got, want)
+		}
+		if got, want := mustRead(req.Body), `{"query":"subscription{tick}"}`; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", `multipart/mixed;boundary="graphql";subscriptionSpec="1.0"`)
+		mustWrite(w, "\r\n")
+		for _, p := range parts {
+			mustWrite(w, "--graphql\r\nContent-Type: application/json\r\n\r\n"+p+"\r\n")
+			w.(http.Flusher).Flush()
+		}
+		mustWrite(w, "--graphql--\r\n")
+	}))
+}
+
+func TestClient_Subscribe_apolloMultipart(t *testing.T) {
+	srv := multipartServer(t,
+		`{}`,
+		`{"payload":{"data":{"tick":1}}}`,
+		`{}`,
+		`{"payload":{"data":{"tick":2}}}`,
+	)
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil, graphql.WithSubscriptionProtocol(graphql.ApolloMultipart))
+
+	sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for ev := range sub.Events() {
+		got = append(got, string(ev.Data))
+	}
+	if err := sub.Err(); err != nil {
+		t.Errorf("got error: %v, want: nil", err)
+	}
+	if want := []string{`{"tick":1}`, `{"tick":2}`}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got events: %q, want: %q", got, want)
+	}
+}
+
+func TestClient_Subscribe_apolloMultipartTransportError(t *testing.T) {
+	srv := multipartServer(t,
+		`{"payload":{"data":{"tick":1}}}`,
+		`{"payload":null,"errors":[{"message":"subgraph unavailable"}]}`,
+	)
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil, graphql.WithSubscriptionProtocol(graphql.ApolloMultipart))
+
+	sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range sub.Events() {
+	}
+	if got, want := sub.Err(), "subgraph unavailable"; got == nil || got.Error() != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
@@ -0,0 +1,227 @@
+package graphql
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/isihu/graphql/ast"
+)
+
+// ClientOption configures a Client.
+// It can be passed to NewClient.
+type ClientOption func(*Client)
+
+// CallOption configures a single GraphQL operation.
+// It can be passed to Query, Mutate and Do.
+type CallOption func(*callConfig)
+
+// callConfig holds the per-call configuration assembled from CallOptions.
+type callConfig struct {
+	url        string         // GraphQL server URL override. Empty means the client's URL.
+	extensions map[string]any // Request extensions. Nil means none.
+
+	operationName string // Name of the operation of the document to execute. Empty means its only one.
+
+	idempotencyKey string // Idempotency key for a mutation. Empty means none.
+
+	streamBody  bool // Whether to stream the encoded request body.
+	knownLength bool // Whether to precompute Content-Length of a streamed body.
+
+	errorPolicy  ErrorPolicy
+	ignoredCodes map[string]bool // Codes of GraphQL errors to drop from the response.
+
+	apolloTrace *ApolloTrace // Where to decode the trace of the operation, if non-nil.
+	fingerprint string       // Fingerprint of the operation sent in the fingerprint header, if any.
+
+	capture *payloadCapture // Where to capture the payload of the operation, if non-nil.
+
+	responseHeader *http.Header // Where to store the header of the response, if non-nil.
+
+	cacheTTL     time.Duration // How long to cache the result of a query. Zero means the client's TTL.
+	noCache      bool          // Whether to bypass the client's cache.
+	refreshCache bool          // Whether to send a query even if it's cached, and cache its result.
+
+	stats callStats // Statistics collected while sending the operation.
+}
+
+// newCallConfig applies opts in order and returns the resulting configuration.
+func newCallConfig(opts []CallOption) *callConfig {
+	cfg := new(callConfig)
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithURL sends the operation to url instead of the client's GraphQL server URL.
+// The rest of the client configuration, such as its HTTP client, is kept as is.
+// It's useful for routing individual operations to another region or a canary.
+func WithURL(url string) CallOption {
+	return func(cfg *callConfig) { cfg.url = url }
+}
+
+// WithOperationName sends name as the "operationName" member of the request,
+// selecting which operation of a document with several the server executes.
+func WithOperationName(name string) CallOption {
+	return func(cfg *callConfig) { cfg.operationName = name }
+}
+
+// WithExtensions adds ext to the "extensions" member of the request,
+// such as tracing flags, cost hints or client identity.
+// If used more than once, the extensions are merged,
+// with later values taking precedence for the same key.
+func WithExtensions(ext map[string]any) CallOption {
+	return func(cfg *callConfig) {
+		if cfg.extensions == nil {
+			cfg.extensions = make(map[string]any, len(ext))
+		}
+		for k, v := range ext {
+			cfg.extensions[k] = v
+		}
+	}
+}
+
+// WithIdempotencyKey sends key as the idempotency key of a mutation,
+// which allows the server to detect repeated attempts at the same write.
+// Mutations are only retried if they have an idempotency key, either given
+// with this option or generated by the client; see WithIdempotencyKeys.
+// It has no effect on other operation types.
+func WithIdempotencyKey(key string) CallOption {
+	return func(cfg *callConfig) { cfg.idempotencyKey = key }
+}
+
+// WithStreamingBody streams the JSON-encoded request body to the server
+// as it's being encoded, instead of buffering it in memory first.
+// It's meant to cap memory use for very large mutation payloads.
+//
+// If knownLength is true, the body is encoded twice: once to compute
+// its Content-Length, and once more while it's being sent. Otherwise,
+// the body is sent with chunked transfer encoding.
+func WithStreamingBody(knownLength bool) CallOption {
+	return func(cfg *callConfig) {
+		cfg.streamBody = true
+		cfg.knownLength = knownLength
+	}
+}
+
+// WithQueryURL sends query operations to url instead of the client's
+// GraphQL server URL, e.g., to route reads to a read replica.
+func WithQueryURL(url string) ClientOption {
+	return withOperationURL("query", url)
+}
+
+// WithMutationURL sends mutation operations to url instead of the client's
+// GraphQL server URL, e.g., to route writes to the primary gateway.
+func WithMutationURL(url string) ClientOption {
+	return withOperationURL("mutation", url)
+}
+
+// WithSubscriptionURL sends subscription operations to url instead of
+// the client's GraphQL server URL, typically a WebSocket endpoint.
+func WithSubscriptionURL(url string) ClientOption {
+	return withOperationURL("subscription", url)
+}
+
+func withOperationURL(opType, url string) ClientOption {
+	return func(c *Client) {
+		if c.urls == nil {
+			c.urls = make(map[string]string)
+		}
+		c.urls[opType] = url
+	}
+}
+
+// WithHeader adds the header key with value to every request sent by the client.
+// It may be used more than once, including for the same key.
+// The Content-Type header is set by the client and can't be overridden.
+func WithHeader(key, value string) ClientOption {
+	return func(c *Client) {
+		if c.header == nil {
+			c.header = make(http.Header)
+		}
+		c.header.Add(key, value)
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *Client) {
+		if c.header == nil {
+			c.header = make(http.Header)
+		}
+		c.header.Set("User-Agent", userAgent)
+	}
+}
+
+// WithClientAwareness identifies the client to the server by sending the
+// apollographql-client-name and apollographql-client-version headers with
+// every request, as used by Apollo client awareness.
+func WithClientAwareness(name, version string) ClientOption {
+	return func(c *Client) {
+		if c.header == nil {
+			c.header = make(http.Header)
+		}
+		c.header.Set("Apollographql-Client-Name", name)
+		c.header.Set("Apollographql-Client-Version", version)
+	}
+}
+
+// WithResponseVerifier makes the client call verify with the headers and
+// the raw body of every response it receives, before the body is decoded.
+// It's meant for verifying HMAC signatures or content digests, or for
+// capturing responses for auditing. If verify returns a non-nil error,
+// the operation fails with that error and the response isn't decoded.
+func WithResponseVerifier(verify func(header http.Header, body []byte) error) ClientOption {
+	return func(c *Client) { c.verifyResponse = verify }
+}
+
+// WithDocumentRewriter makes the client pass the document of every operation,
+// parsed into a syntax tree, to rewrite before it's sent. rewrite may modify
+// the document in place, e.g., to inject fields, add directives or strip
+// deprecated selections. If rewrite returns a non-nil error, the operation
+// fails with that error. If used more than once, rewriters run in order.
+func WithDocumentRewriter(rewrite func(doc *ast.Document) error) ClientOption {
+	return func(c *Client) { c.rewriters = append(c.rewriters, rewrite) }
+}
+
+// WithIdempotencyKeys makes the client generate a random idempotency key
+// for every mutation that isn't given one with WithIdempotencyKey, which
+// makes it safe to retry mutations. The key is sent in the header named
+// header, or in the Idempotency-Key header if header is empty.
+func WithIdempotencyKeys(header string) ClientOption {
+	return func(c *Client) {
+		if header != "" {
+			c.idempotencyHeader = header
+		}
+		c.generateIdempotencyKeys = true
+	}
+}
+
+// WithPersistedQueries enables Automatic Persisted Queries (APQ).
+// Operations are first sent as a SHA-256 hash of the document only,
+// and the full document is sent only if the server doesn't know the hash yet.
+//
+// If useGET is true, hash-only query operations are sent as GET requests,
+// so that CDN and edge caches can serve repeat queries. Mutations and
+// requests that carry the full document are always sent as POST requests.
+//
+// If the server reports that it doesn't support persisted queries,
+// the client stops sending hashes, and sends full documents instead.
+//
+// See https://www.apollographql.com/docs/apollo-server/performance/apq.
+func WithPersistedQueries(useGET bool) ClientOption {
+	return func(c *Client) {
+		c.persistedQueries = true
+		c.persistedQueriesGET = useGET
+	}
+}
+
+// WithParallelDecoding makes the client decode the elements of the largest
+// array in response data on several goroutines, one per CPU, if it has at
+// least minElements elements, which uses multiple cores for responses
+// dominated by a huge array, such as a connection with many nodes.
+// Only arrays selected by fields of the result, rather than by GraphQL
+// fragments or inside other arrays, are decoded in parallel.
+func WithParallelDecoding(minElements int) ClientOption {
+	return func(c *Client) { c.parallelDecoding = minElements }
+}
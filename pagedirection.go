@@ -0,0 +1,134 @@
+package graphql
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Direction is the direction in which a Paginator pages through a connection.
+type Direction int
+
+const (
+	Forward  Direction = iota // From the first page on, after the end cursor of each page.
+	Backward                  // From the last page back, before the start cursor of each page.
+)
+
+// WithDirection makes a Paginator page in the direction d, instead of Forward.
+// Paging Backward passes the start cursor of the previous page in the
+// "before" variable, until the connection's pageInfo says there are no
+// previous pages, as in "load older items":
+//
+//	Messages struct {
+//		Nodes    []Message
+//		PageInfo struct {
+//			StartCursor     string
+//			HasPreviousPage bool
+//		}
+//	} `graphql:"messages(last:20, before:$before)"`
+//
+// The nodes of each page are still in the order of the connection.
+func WithDirection(d Direction) PageOption {
+	return func(p *Paginator) { p.backward = d == Backward }
+}
+
+// WithBackwardCursorVariable makes a Paginator paging Backward pass cursors
+// in the variable name, instead of "before".
+func WithBackwardCursorVariable(name string) PageOption {
+	return func(p *Paginator) { p.beforeVar = name }
+}
+
+// WithPageSizeVariables makes a Paginator pass the page size n in the
+// variable first when paging Forward, and in the variable last when paging
+// Backward, and null in the other one, so that SetDirection can change the
+// direction while paging a connection selected with both, as in
+//
+//	`graphql:"messages(first:$first, after:$after, last:$last, before:$before)"`
+//
+// The variables are nullable Ints, unless given to Paginate with other types.
+func WithPageSizeVariables(first, last string, n int) PageOption {
+	return func(p *Paginator) { p.firstVar, p.lastVar, p.size = first, last, n }
+}
+
+// SetDirection changes the direction of paging to d. The next page is the
+// one before the last page fetched if d is Backward, or the one after it if
+// d is Forward, even if paging was done in the other direction, so that
+// e.g. older items can be loaded after paging to the newest ones. If no page
+// was fetched yet, paging starts at the last or first page. The query must
+// have the cursor variables of both directions, and page size variables
+// set by WithPageSizeVariables, if it doesn't fix the page size of both.
+// SetDirection does nothing when paging by offset.
+func (p *Paginator) SetDirection(d Direction) {
+	backward := d == Backward
+	if backward == p.backward || p.offsetVar != "" {
+		return
+	}
+	from, _ := p.cursorVariables()
+	p.backward = backward
+	to, _ := p.cursorVariables()
+	p.variables[from] = nullValue(p.variables[from], (*String)(nil))
+	p.setPageSize()
+	if p.pages == 0 {
+		p.variables[to] = nullValue(p.variables[to], (*String)(nil))
+		return
+	}
+	cursor := p.info.endCursor
+	if backward {
+		cursor = p.info.startCursor
+	}
+	p.variables[to] = cursorValue(reflect.TypeOf(p.variables[to]), cursor)
+	p.done = cursor == ""
+}
+
+// cursorVariables returns the name of the cursor variable of the direction
+// of paging, and that of the other direction.
+func (p *Paginator) cursorVariables() (cursorVar, otherVar string) {
+	if p.backward {
+		return p.beforeVar, p.cursorVar
+	}
+	return p.cursorVar, p.beforeVar
+}
+
+// setPageSize sets the page size variables for the direction of paging.
+func (p *Paginator) setPageSize() {
+	if p.firstVar == "" && p.lastVar == "" {
+		return
+	}
+	sizeVar, otherVar := p.firstVar, p.lastVar
+	if p.backward {
+		sizeVar, otherVar = otherVar, sizeVar
+	}
+	t := reflect.TypeOf(p.variables[sizeVar])
+	if t == nil {
+		t = reflect.TypeFor[*Int]()
+	}
+	p.variables[sizeVar] = intValue(t, p.size)
+	p.variables[otherVar] = nullValue(p.variables[otherVar], (*Int)(nil))
+}
+
+// nullValue returns the zero value of the type of the variable v, which is
+// null for pointers, or def if v is nil.
+func nullValue(v, def any) any {
+	if v == nil {
+		return def
+	}
+	return reflect.Zero(reflect.TypeOf(v)).Interface()
+}
+
+// usesVariable reports whether query refers to the variable name.
+func usesVariable(query, name string) bool {
+	for i := 0; ; {
+		j := strings.Index(query[i:], "$"+name)
+		if j == -1 {
+			return false
+		}
+		i += j + 1 + len(name)
+		if i == len(query) || !isNameChar(query[i]) {
+			return true
+		}
+	}
+}
+
+// isNameChar reports whether c may be part of a GraphQL name.
+func isNameChar(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
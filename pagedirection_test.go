@@ -0,0 +1,137 @@
+package graphql_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+// messagesHandler serves a connection of messages, numbered from 1 to total,
+// paged forward with the first and after variables or backward with the last
+// and before variables, with cursors that are the numbers of messages.
+func messagesHandler(total int) (http.Handler, *[]map[string]any) {
+	var variables []map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		var in struct {
+			Variables map[string]any
+		}
+		mustUnmarshal(mustRead(req.Body), &in)
+		variables = append(variables, in.Variables)
+		cursor := func(name string, def int) int {
+			if c, ok := in.Variables[name].(string); ok {
+				n, _ := strconv.Atoi(c)
+				return n
+			}
+			return def
+		}
+		lo, hi := cursor("after", 0)+1, cursor("before", total+1)-1
+		if first, ok := in.Variables["first"].(float64); ok {
+			hi = min(hi, lo+int(first)-1)
+		} else {
+			last := 2
+			if l, ok := in.Variables["last"].(float64); ok {
+				last = int(l)
+			}
+			lo = max(lo, hi-last+1)
+		}
+		var nodes string
+		for i := lo; i <= hi; i++ {
+			if nodes != "" {
+				nodes += ","
+			}
+			nodes += fmt.Sprintf(`{"number": %d}`, i)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, fmt.Sprintf(`{"data": {"messages": {"nodes": [%s], "pageInfo": {"startCursor": "%d", "endCursor": "%d", "hasPreviousPage": %t, "hasNextPage": %t}}}}`,
+			nodes, lo, hi, lo > 1, hi < total))
+	})
+	return mux, &variables
+}
+
+type messagesQuery struct {
+	Messages struct {
+		Nodes    []struct{ Number graphql.Int }
+		PageInfo struct {
+			StartCursor     graphql.String
+			EndCursor       graphql.String
+			HasPreviousPage graphql.Boolean
+			HasNextPage     graphql.Boolean
+		}
+	} `graphql:"messages(first:$first, after:$after, last:$last, before:$before)"`
+}
+
+func TestWithDirection(t *testing.T) {
+	handler, _ := messagesHandler(5)
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}})
+
+	var q struct {
+		Messages struct {
+			Nodes    []struct{ Number graphql.Int }
+			PageInfo struct {
+				StartCursor     graphql.String
+				EndCursor       graphql.String
+				HasPreviousPage graphql.Boolean
+				HasNextPage     graphql.Boolean
+			}
+		} `graphql:"messages(last:2, before:$before)"`
+	}
+	var pages []string
+	err := client.QueryAll(context.Background(), &q, nil, func(int) error {
+		var numbers []int
+		for _, n := range q.Messages.Nodes {
+			numbers = append(numbers, int(n.Number))
+		}
+		pages = append(pages, fmt.Sprint(numbers))
+		return nil
+	}, graphql.WithDirection(graphql.Backward))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(pages), "[[4 5] [2 3] [1]]"; got != want {
+		t.Errorf("got pages: %v, want: %v", got, want)
+	}
+}
+
+func TestPaginator_SetDirection(t *testing.T) {
+	handler, variables := messagesHandler(7)
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}})
+
+	var q messagesQuery
+	p := client.Paginate(&q, nil, graphql.WithDirection(graphql.Backward), graphql.WithPageSizeVariables("first", "last", 2))
+	var pages []string
+	next := func() {
+		t.Helper()
+		if !p.Next(context.Background()) {
+			t.Fatalf("got no page: %v", p.Err())
+		}
+		var numbers []int
+		for _, n := range q.Messages.Nodes {
+			numbers = append(numbers, int(n.Number))
+		}
+		pages = append(pages, fmt.Sprint(numbers))
+	}
+	next()
+	next()
+	p.SetDirection(graphql.Forward)
+	next()
+	if p.Next(context.Background()) {
+		t.Error("got a page after the last one, want none")
+	}
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(pages), "[[6 7] [4 5] [6 7]]"; got != want {
+		t.Errorf("got pages: %v, want: %v", got, want)
+	}
+	if got, want := fmt.Sprint((*variables)[0]), "map[after:<nil> before:<nil> first:<nil> last:2]"; got != want {
+		t.Errorf("got variables of the first page: %v, want: %v", got, want)
+	}
+	if got, want := fmt.Sprint((*variables)[2]), "map[after:5 before:<nil> first:2 last:<nil>]"; got != want {
+		t.Errorf("got variables after changing direction: %v, want: %v", got, want)
+	}
+}
@@ -0,0 +1,115 @@
+package graphql
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// PaginateNested returns a Paginator for the rest of a connection nested in
+// an object fetched by another query, such as the comments of each issue of
+// a repository, whose pages can't be fetched by passing cursors to that query,
+// since they'd apply to the connections of all the objects at once. parent
+// is a pointer to the struct of the object, which has an id field, and the
+// connection, with its first page fetched:
+//
+//	type issue struct {
+//		ID       graphql.ID
+//		Comments struct {
+//			Nodes    []struct{ Body string }
+//			PageInfo struct {
+//				EndCursor   string
+//				HasNextPage bool
+//			}
+//		} `graphql:"comments(first:100)"`
+//	}
+//
+// Each call to Next fetches the page after the one in the connection into it,
+// with a query anchored on the object by its ID, node(id:$id), in a fragment
+// on typename, which passes the cursor in the "after" variable added to the
+// connection's arguments. Pages are fetched forward, by cursor.
+func (c *Client) PaginateNested(parent any, typename string, opts ...PageOption) *Paginator {
+	v := reflect.ValueOf(parent)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return &Paginator{c: c, err: errors.New("graphql: PaginateNested requires a non-nil pointer to a struct")}
+	}
+	v = v.Elem()
+	id, ok := fieldByName(v, "id")
+	if !ok {
+		return &Paginator{c: c, err: errors.New("graphql: PaginateNested requires an object with an id field")}
+	}
+	conn, ok := findPath(v.Type(), nil, hasName("pageInfo"))
+	if !ok || len(conn) < 2 {
+		return &Paginator{c: c, err: errNoConnection}
+	}
+	conn = conn[:len(conn)-1]
+
+	// The query selects only the connection of the object.
+	q := reflect.New(reflect.StructOf([]reflect.StructField{{
+		Name: "Node",
+		Type: reflect.StructOf([]reflect.StructField{{
+			Name: "Fragment",
+			Type: anchoredType(v.Type(), conn),
+			Tag:  reflect.StructTag(`graphql:"... on ` + typename + `"`),
+		}}),
+		Tag: `graphql:"node(id:$id)"`,
+	}}))
+	opts = append(opts[:len(opts):len(opts)], WithCursorVariable("after"), WithDirection(Forward))
+	p := c.Paginate(q.Interface(), map[string]any{"id": ID(id.Interface())}, opts...)
+	if p.err != nil {
+		return p
+	}
+
+	// Continue from the page in the object.
+	info, ok := readPageInfo(v, conn)
+	if !ok || !info.hasNextPage || info.endCursor == "" {
+		p.done = true
+	} else {
+		p.variables["after"] = NewString(String(info.endCursor))
+	}
+	p.afterPage = func() {
+		src := q.Elem().Field(0).Field(0)
+		for range conn {
+			src = src.Field(0)
+		}
+		if dst, ok := fieldAt(v, conn[:len(conn)-1]); ok {
+			dst.Field(conn[len(conn)-1]).Set(src)
+		}
+	}
+	return p
+}
+
+// anchoredType returns a struct type that selects only the field at the
+// field indices path in the struct type t, with the cursor variable "after"
+// added to its arguments.
+func anchoredType(t reflect.Type, path []int) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	f := t.Field(path[0])
+	field := reflect.StructField{Name: f.Name, Type: f.Type, Tag: f.Tag, Anonymous: f.Anonymous}
+	if len(path) == 1 {
+		selector, ok := f.Tag.Lookup("graphql")
+		if !ok {
+			selector = graphQLFieldName(f)
+		}
+		field.Tag = reflect.StructTag(`graphql:"` + withArgument(selector, "after:$after") + `"`)
+	} else {
+		field.Type = anchoredType(f.Type, path[1:])
+	}
+	return reflect.StructOf([]reflect.StructField{field})
+}
+
+// withArgument returns the GraphQL field selector with arg added to its
+// arguments, before its directives, if any.
+func withArgument(selector, arg string) string {
+	head, directives := selector, ""
+	if i := strings.Index(selector, "@"); i != -1 {
+		head, directives = selector[:i], " "+selector[i:]
+	}
+	head = strings.TrimSpace(head)
+	if strings.HasSuffix(head, ")") {
+		return head[:len(head)-1] + ", " + arg + ")" + directives
+	}
+	return head + "(" + arg + ")" + directives
+}
@@ -0,0 +1,88 @@
+package graphql_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+type issueWithComments struct {
+	ID       graphql.ID
+	Comments struct {
+		Nodes    []struct{ Body graphql.String }
+		PageInfo struct {
+			EndCursor   graphql.String
+			HasNextPage graphql.Boolean
+		}
+	} `graphql:"comments(first:2)"`
+}
+
+func TestClient_PaginateNested(t *testing.T) {
+	var queries []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		var in struct {
+			Query     string
+			Variables map[string]any
+		}
+		mustUnmarshal(mustRead(req.Body), &in)
+		queries = append(queries, in.Query)
+		if in.Variables["id"] != "I_2" {
+			t.Errorf("got id: %v, want: I_2", in.Variables["id"])
+		}
+		after, _ := strconv.Atoi(in.Variables["after"].(string))
+		end := min(after+2, 5)
+		var nodes string
+		for i := after + 1; i <= end; i++ {
+			if nodes != "" {
+				nodes += ","
+			}
+			nodes += fmt.Sprintf(`{"body": "comment %d"}`, i)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, fmt.Sprintf(`{"data": {"node": {"comments": {"nodes": [%s], "pageInfo": {"endCursor": "%d", "hasNextPage": %t}}}}}`,
+			nodes, end, end < 5))
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	// The first page of comments was fetched along with the issue.
+	var issue issueWithComments
+	mustUnmarshal(`{"id": "I_2", "comments": {"nodes": [{"body": "comment 1"}, {"body": "comment 2"}], "pageInfo": {"endCursor": "2", "hasNextPage": true}}}`, &issue)
+
+	bodies := []string{"comment 1", "comment 2"}
+	p := client.PaginateNested(&issue, "Issue")
+	for p.Next(context.Background()) {
+		for _, c := range issue.Comments.Nodes {
+			bodies = append(bodies, string(c.Body))
+		}
+	}
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(bodies), "[comment 1 comment 2 comment 3 comment 4 comment 5]"; got != want {
+		t.Errorf("got comments: %v, want: %v", got, want)
+	}
+	if got, want := len(queries), 2; got != want {
+		t.Fatalf("got %d queries, want: %d", got, want)
+	}
+	if got, want := queries[0], `query($after:String$id:ID!){node(id:$id){... on Issue{comments(first:2, after:$after){nodes{body},pageInfo{endCursor,hasNextPage}}}}}`; got != want {
+		t.Errorf("got query: %s, want: %s", got, want)
+	}
+}
+
+func TestClient_PaginateNested_lastPage(t *testing.T) {
+	client := graphql.NewClient("/graphql", nil)
+	var issue issueWithComments
+	mustUnmarshal(`{"id": "I_1", "comments": {"nodes": [], "pageInfo": {"endCursor": "", "hasNextPage": false}}}`, &issue)
+	p := client.PaginateNested(&issue, "Issue")
+	if p.Next(context.Background()) {
+		t.Error("got a page, want none")
+	}
+	if err := p.Err(); err != nil {
+		t.Error(err)
+	}
+}
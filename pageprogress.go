@@ -0,0 +1,55 @@
+package graphql
+
+import (
+	"math"
+	"time"
+)
+
+// PageProgress is the progress of a Paginator, such as for showing
+// a progress bar of a long-running export.
+type PageProgress struct {
+	Page    int           // Number of the last page fetched, starting at 1.
+	Items   int           // Number of items in the pages fetched so far.
+	Total   int           // Total count of items, as Paginator.Total reports, or -1 if unknown.
+	Elapsed time.Duration // Time since paging started, in this process.
+
+	// RemainingPages is the estimated number of pages left to fetch,
+	// or -1 if it's unknown because the total count of items is.
+	// It's 0 once there are no more pages.
+	RemainingPages int
+}
+
+// WithProgress makes a Paginator call progress after fetching every page,
+// before Next returns, with its progress.
+func WithProgress(progress func(PageProgress)) PageOption {
+	return func(p *Paginator) { p.progress = progress }
+}
+
+// Progress returns the progress of p. The remaining pages are estimated
+// from the total count of items and the number of items per page so far,
+// or the limit, if paging by offset.
+func (p *Paginator) Progress() PageProgress {
+	pp := PageProgress{Page: p.pages, Items: p.items, Total: -1, RemainingPages: -1}
+	if !p.start.IsZero() {
+		pp.Elapsed = time.Since(p.start)
+	}
+	if p.hasTotal {
+		pp.Total = p.total
+	}
+	switch {
+	case p.done:
+		pp.RemainingPages = 0
+	case p.hasTotal && p.pages > 0:
+		// Items before the offset count as fetched even if it was given
+		// rather than paged to.
+		fetched, perPage := p.items, float64(p.items)/float64(p.pages)
+		if p.offsetVar != "" {
+			fetched, perPage = p.offset, float64(p.limit)
+		}
+		pp.RemainingPages = 1
+		if left := p.total - fetched; left > 0 && perPage > 0 {
+			pp.RemainingPages = max(int(math.Ceil(float64(left)/perPage)), 1)
+		}
+	}
+	return pp
+}
@@ -0,0 +1,53 @@
+package graphql_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+func TestWithProgress(t *testing.T) {
+	handler, _ := usersHandler(5, "offset", "limit", func(users string, total int) string {
+		return fmt.Sprintf(`{"users": [%s], "users_aggregate": {"aggregate": {"count": %d}}}`, users, total)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}})
+
+	var q usersQuery
+	var got []string
+	err := client.QueryAll(context.Background(), &q, nil, func(int) error { return nil },
+		graphql.WithOffsetPagination("", "", 2),
+		graphql.WithProgress(func(p graphql.PageProgress) {
+			if p.Elapsed <= 0 {
+				t.Errorf("got elapsed time: %v, want it positive", p.Elapsed)
+			}
+			got = append(got, fmt.Sprintf("%d:%d/%d+%d", p.Page, p.Items, p.Total, p.RemainingPages))
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(got), "[1:2/5+2 2:4/5+1 3:5/5+0]"; got != want {
+		t.Errorf("got progress: %v, want: %v", got, want)
+	}
+}
+
+func TestPaginator_Progress_unknownTotal(t *testing.T) {
+	handler, _ := issuesHandler(3, 2, "after")
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}})
+
+	var q issuesQuery
+	p := client.Paginate(&q, map[string]any{"owner": graphql.String("isihu"), "name": graphql.String("graphql")})
+	var got []string
+	for p.Next(context.Background()) {
+		pp := p.Progress()
+		got = append(got, fmt.Sprintf("%d:%d/%d+%d", pp.Page, pp.Items, pp.Total, pp.RemainingPages))
+	}
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(got), "[1:2/-1+-1 2:3/-1+0]"; got != want {
+		t.Errorf("got progress: %v, want: %v", got, want)
+	}
+}
@@ -0,0 +1,109 @@
+package graphql
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// pageToken is the state of a Paginator, as encoded in a resume token.
+type pageToken struct {
+	Query     string                     `json:"q"` // Hash of the query, to tell tokens of other queries apart.
+	Variables map[string]json.RawMessage `json:"v"` // Variables of the next page.
+	Pages     int                        `json:"p"`
+	Items     int                        `json:"i"`
+	Offset    int                        `json:"o,omitempty"`
+	Total     *int                       `json:"t,omitempty"` // Nil if the total count isn't known.
+	Done      bool                       `json:"d,omitempty"`
+	Backward  bool                       `json:"b,omitempty"`
+	Start     string                     `json:"s,omitempty"` // Start cursor of the last page, if any.
+	End       string                     `json:"e,omitempty"` // End cursor of the last page, if any.
+}
+
+// Token returns an opaque token for the state of p, such as its cursor or
+// offset, direction, variables and number of pages fetched, which
+// WithResumeToken resumes paging from, e.g., in a new process after
+// a long-running job crashed or was redeployed. Persist it after handling each page, so that
+// paging resumes at the page after it. If fetching a page failed, paging
+// resumes at that page.
+func (p *Paginator) Token() (string, error) {
+	tok := pageToken{
+		Query:     documentHash(constructQuery(p.q, p.variables)),
+		Variables: make(map[string]json.RawMessage, len(p.variables)),
+		Pages:     p.pages,
+		Items:     p.items,
+		Offset:    p.offset,
+		Done:      p.done,
+		Backward:  p.backward,
+		Start:     p.info.startCursor,
+		End:       p.info.endCursor,
+	}
+	if p.hasTotal {
+		tok.Total = &p.total
+	}
+	for name, value := range p.variables {
+		b, err := json.Marshal(value)
+		if err != nil {
+			return "", fmt.Errorf("graphql: encoding variable %q of page token: %w", name, err)
+		}
+		tok.Variables[name] = b
+	}
+	b, err := json.Marshal(tok)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// WithResumeToken makes a Paginator resume paging from the state in token,
+// returned by Paginator.Token for the same query. The values of the variables
+// in the token replace those given to Paginate, which must have all of them.
+func WithResumeToken(token string) PageOption {
+	return func(p *Paginator) { p.token = token }
+}
+
+// errTokenMismatch is returned when a resume token was made for another query.
+var errTokenMismatch = errors.New("graphql: page token is for another query")
+
+// resume restores the state of p from token.
+func (p *Paginator) resume(token string) error {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return fmt.Errorf("graphql: malformed page token: %w", err)
+	}
+	var tok pageToken
+	if err := json.Unmarshal(b, &tok); err != nil {
+		return fmt.Errorf("graphql: malformed page token: %w", err)
+	}
+	if tok.Query != documentHash(constructQuery(p.q, p.variables)) {
+		return errTokenMismatch
+	}
+	// Decode the variables into the types of the given ones, which the query
+	// declares them by.
+	variables := make(map[string]any, len(p.variables))
+	for name, raw := range tok.Variables {
+		value, ok := p.variables[name]
+		if !ok {
+			return errTokenMismatch
+		}
+		v := reflect.New(reflect.TypeOf(&value).Elem())
+		if t := reflect.TypeOf(value); t != nil {
+			v = reflect.New(t)
+		}
+		if err := json.Unmarshal(raw, v.Interface()); err != nil {
+			return fmt.Errorf("graphql: decoding variable %q of page token: %w", name, err)
+		}
+		variables[name] = v.Elem().Interface()
+	}
+	for name, value := range variables {
+		p.variables[name] = value
+	}
+	p.pages, p.items, p.offset, p.done = tok.Pages, tok.Items, tok.Offset, tok.Done
+	p.backward, p.info.startCursor, p.info.endCursor = tok.Backward, tok.Start, tok.End
+	if tok.Total != nil {
+		p.total, p.hasTotal = *tok.Total, true
+	}
+	return nil
+}
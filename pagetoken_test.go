@@ -0,0 +1,111 @@
+package graphql_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+func TestPaginator_Token(t *testing.T) {
+	handler, queries := issuesHandler(5, 2, "after")
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}})
+	variables := map[string]any{"owner": graphql.String("isihu"), "name": graphql.String("graphql")}
+
+	var q issuesQuery
+	p := client.Paginate(&q, variables)
+	for p.Next(context.Background()) && p.Pages() < 2 {
+	}
+	token, err := p.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Resume in a new paginator, as a restarted job would.
+	var numbers []int
+	p = client.Paginate(&q, variables, graphql.WithResumeToken(token))
+	for p.Next(context.Background()) {
+		for _, n := range q.Repository.Issues.Nodes {
+			numbers = append(numbers, int(n.Number))
+		}
+	}
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(numbers), "[5]"; got != want {
+		t.Errorf("got issues: %v, want: %v", got, want)
+	}
+	if got, want := p.Pages(), 3; got != want {
+		t.Errorf("got %d pages, want: %d", got, want)
+	}
+	if got, want := p.Items(), 5; got != want {
+		t.Errorf("got %d items, want: %d", got, want)
+	}
+	if got, want := (*queries)[2], (*queries)[0]; got != want {
+		t.Errorf("got resumed query: %s, want: %s", got, want)
+	}
+}
+
+func TestPaginator_Token_offset(t *testing.T) {
+	handler, requests := usersHandler(5, "offset", "limit", func(users string, total int) string {
+		return fmt.Sprintf(`{"users": [%s], "users_aggregate": {"aggregate": {"count": %d}}}`, users, total)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}})
+
+	var q usersQuery
+	p := client.Paginate(&q, nil, graphql.WithOffsetPagination("", "", 2))
+	p.Next(context.Background())
+	token, err := p.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int
+	p = client.Paginate(&q, nil, graphql.WithOffsetPagination("", "", 2), graphql.WithResumeToken(token))
+	for p.Next(context.Background()) {
+		for _, u := range q.Users {
+			ids = append(ids, int(u.ID))
+		}
+	}
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(ids), "[3 4 5]"; got != want {
+		t.Errorf("got users: %v, want: %v", got, want)
+	}
+	if got, want := fmt.Sprint((*requests)[1]), "map[limit:2 offset:2]"; got != want {
+		t.Errorf("got variables: %v, want: %v", got, want)
+	}
+	if total, ok := p.Total(); !ok || total != 5 {
+		t.Errorf("got total: %d, %t, want: 5, true", total, ok)
+	}
+}
+
+func TestPaginator_Token_mismatch(t *testing.T) {
+	handler, _ := issuesHandler(5, 2, "after")
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}})
+	variables := map[string]any{"owner": graphql.String("isihu"), "name": graphql.String("graphql")}
+
+	var q issuesQuery
+	p := client.Paginate(&q, variables)
+	p.Next(context.Background())
+	token, err := p.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var other usersQuery
+	p = client.Paginate(&other, nil, graphql.WithOffsetPagination("", "", 2), graphql.WithResumeToken(token))
+	if p.Next(context.Background()) {
+		t.Error("got a page, want none")
+	}
+	if p.Err() == nil {
+		t.Error("got no error, want one")
+	}
+	p = client.Paginate(&q, variables, graphql.WithResumeToken("not a token"))
+	if p.Err() == nil {
+		t.Error("got no error for a malformed token, want one")
+	}
+}
@@ -0,0 +1,721 @@
+package graphql
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/isihu/graphql/ident"
+)
+
+// Paginator pages through a Relay connection selected by a query, such as
+//
+//	var q struct {
+//		Repository struct {
+//			Issues struct {
+//				Nodes    []struct{ Title string }
+//				PageInfo struct {
+//					EndCursor   string
+//					HasNextPage bool
+//				}
+//			} `graphql:"issues(first:100, after:$after)"`
+//		} `graphql:"repository(owner:$owner, name:$name)"`
+//	}
+//
+// Each call to Next fetches the next page into the query, passing the end
+// cursor of the previous page in the "after" variable, until the connection's
+// pageInfo says there are no more pages:
+//
+//	p := client.Paginate(&q, variables)
+//	for p.Next(ctx) {
+//		// Use the page in q.
+//	}
+//	if err := p.Err(); err != nil {
+//		// Handle the error.
+//	}
+//
+// The connection is the first struct with a pageInfo field found in the
+// query, depth-first. If the variables don't have the cursor variable, it's
+// added as a nullable String, for the first page.
+//
+// With WithOffsetPagination, a Paginator pages through a list by offset
+// instead, for schemas that don't have Relay connections.
+type Paginator struct {
+	c         *Client
+	q         any
+	variables map[string]any
+	cursorVar string
+	beforeVar string // Name of the cursor variable when paging backward.
+	backward  bool
+	callOpts  []CallOption
+	token     string // Token to resume paging from, if any.
+
+	offsetVar string // Empty means pages are fetched by cursor.
+	limitVar  string
+	limit     int
+	firstVar  string // Names of the page size variables, if any.
+	lastVar   string
+	size      int
+	parallel  int // Number of pages fetched at once by offset. Less than 2 means one.
+
+	maxPages int         // Zero means pages aren't limited.
+	maxItems int         // Zero means items aren't limited.
+	budget   *rateBudget // Nil means the rate limit budget isn't tracked.
+
+	progress  func(PageProgress) // Called after every page, if non-nil.
+	afterPage func()             // Called after every page, before progress, if non-nil.
+	start     time.Time          // When paging started.
+
+	conn      []int // Indices of the fields leading to the connection, if paging by cursor.
+	list      []int // Indices of the fields leading to the list of items, if any.
+	totalPath []int // Indices of the fields leading to the total count of items, if any.
+	offset    int
+	pages     int
+	items     int
+	total     int // Total count of items as of the last page, if hasTotal.
+	hasTotal  bool
+	info      pageInfo     // pageInfo of the last page, if paging by cursor.
+	ahead     []*pageFetch // Pages being fetched ahead, in order.
+	done      bool
+	err       error
+}
+
+// pageFetch is a page being fetched ahead of the others.
+type pageFetch struct {
+	q    reflect.Value // Pointer to the query struct the page is fetched into.
+	done chan struct{} // Closed when the page is fetched.
+	err  error
+}
+
+// PageOption configures a Paginator.
+type PageOption func(*Paginator)
+
+// WithCursorVariable makes a Paginator pass cursors in the variable name,
+// instead of "after".
+func WithCursorVariable(name string) PageOption {
+	return func(p *Paginator) { p.cursorVar = name }
+}
+
+// WithPageCallOptions makes a Paginator send the query of every page with opts.
+func WithPageCallOptions(opts ...CallOption) PageOption {
+	return func(p *Paginator) { p.callOpts = append(p.callOpts, opts...) }
+}
+
+// WithOffsetPagination makes a Paginator page through a list of items by
+// offset rather than by cursor, as many schemas that don't have Relay
+// connections do, such as those of Hasura and PostGraphile's defaults:
+//
+//	var q struct {
+//		Users []struct {
+//			Name string
+//		} `graphql:"users(offset:$offset, limit:$limit, order_by:{id:asc})"`
+//		UsersAggregate struct {
+//			Aggregate struct{ Count int }
+//		} `graphql:"users_aggregate"`
+//	}
+//
+// Each page is fetched with limit in the variable limitVar, and the number of
+// items skipped in the variable offsetVar, which are "limit" and "offset" if
+// empty. The first page starts at the offset in the variables, if any.
+// The list is the first list of objects found in the query, depth-first.
+// Paging stops at a page that has fewer than limit items, or once the total
+// count of items is reached, if the query selects it, as Total reports.
+func WithOffsetPagination(offsetVar, limitVar string, limit int) PageOption {
+	return func(p *Paginator) {
+		p.offsetVar, p.limitVar, p.limit = cmp.Or(offsetVar, "offset"), cmp.Or(limitVar, "limit"), limit
+	}
+}
+
+// WithParallelPages makes a Paginator paging by offset fetch up to n pages
+// at once, once the total count of items is known from the first page,
+// as Total reports, which speeds up fetching large lists. Next still
+// returns the pages in order. Pages fetched ahead are discarded if paging
+// stops before they're returned, e.g., at a page with fewer items than
+// expected. Pages are fetched one at a time when paging by cursor,
+// or with WithRateLimitBudget.
+func WithParallelPages(n int) PageOption {
+	return func(p *Paginator) { p.parallel = n }
+}
+
+// WithMaxPages limits a Paginator to n pages. If there are more,
+// paging stops with ErrPageLimit.
+func WithMaxPages(n int) PageOption {
+	return func(p *Paginator) { p.maxPages = n }
+}
+
+// WithMaxItems limits a Paginator to the pages up to the one that has the
+// n-th item, counted as the elements of the nodes or edges field of the
+// connection, or of the list paged by offset. If there are more, paging stops with ErrPageLimit.
+func WithMaxItems(n int) PageOption {
+	return func(p *Paginator) { p.maxItems = n }
+}
+
+// ErrPageLimit is reported by a Paginator that stopped at a limit set by
+// WithMaxPages or WithMaxItems while there were more pages.
+var ErrPageLimit = errors.New("graphql: pagination stopped at its limit")
+
+// Paginate returns a Paginator for the connection selected by q,
+// a pointer to a query struct, sent with variables.
+// variables isn't modified.
+func (c *Client) Paginate(q any, variables map[string]any, opts ...PageOption) *Paginator {
+	p := &Paginator{c: c, q: q, variables: maps.Clone(variables), cursorVar: "after", beforeVar: "before"}
+	if p.variables == nil {
+		p.variables = make(map[string]any)
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	v := reflect.ValueOf(q)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		p.err = errors.New("graphql: Paginate requires a non-nil pointer to a query struct")
+		return p
+	}
+	if err := p.init(v.Type().Elem()); err != nil {
+		p.err = err
+		return p
+	}
+	if p.token != "" {
+		p.err = p.resume(p.token)
+	}
+	return p
+}
+
+// init prepares p to page through the query struct type t.
+func (p *Paginator) init(t reflect.Type) error {
+	if p.budget != nil {
+		p.budget.path, _ = findPath(t, nil, hasName("rateLimit"))
+		p.callOpts = append(p.callOpts[:len(p.callOpts):len(p.callOpts)], func(cfg *callConfig) {
+			cfg.responseHeader = &p.budget.header
+		})
+	}
+	total, ok := findPath(t, nil, isIntField("totalCount"))
+	if !ok {
+		total, _ = findPath(t, nil, isIntField("count"))
+	}
+	p.totalPath = total
+	if p.offsetVar != "" {
+		if p.limit <= 0 {
+			return errors.New("graphql: offset pagination requires a positive limit")
+		}
+		list, ok := findPath(t, nil, isObjectList)
+		if !ok {
+			return errNoList
+		}
+		p.list = list
+		p.offset = intVariable(p.variables[p.offsetVar])
+		p.variables[p.offsetVar] = intValue(reflect.TypeOf(p.variables[p.offsetVar]), p.offset)
+		p.variables[p.limitVar] = intValue(reflect.TypeOf(p.variables[p.limitVar]), p.limit)
+		return nil
+	}
+	cursorVar, otherVar := p.cursorVariables()
+	if _, ok := p.variables[cursorVar]; !ok {
+		p.variables[cursorVar] = (*String)(nil)
+	}
+	if _, ok := p.variables[otherVar]; !ok && usesVariable(queryOf(t), otherVar) {
+		p.variables[otherVar] = (*String)(nil)
+	}
+	p.setPageSize()
+	conn, ok := findPath(t, nil, hasName("pageInfo"))
+	if !ok {
+		return errNoConnection
+	}
+	p.conn = conn[:len(conn)-1]
+	connType := typeAt(t, p.conn)
+	for _, name := range [...]string{"nodes", "edges"} {
+		if i, ok := fieldIndex(connType, name); ok && isList(connType.Field(i).Type) {
+			p.list = append(p.conn[:len(p.conn):len(p.conn)], i)
+			break
+		}
+	}
+	return nil
+}
+
+var (
+	// errNoConnection is returned when a query doesn't select a connection.
+	errNoConnection = errors.New("graphql: query has no connection with a pageInfo field")
+
+	// errNoList is returned when a query paged by offset doesn't select a list of objects.
+	errNoList = errors.New("graphql: query has no list of objects")
+)
+
+// Next fetches the next page into the query, and reports whether there was
+// one. It returns false when there are no more pages, or fetching a page
+// failed, which Err reports.
+func (p *Paginator) Next(ctx context.Context) bool {
+	if p.start.IsZero() {
+		p.start = time.Now()
+	}
+	if !p.next(ctx) {
+		return false
+	}
+	if p.afterPage != nil {
+		p.afterPage()
+	}
+	if p.progress != nil {
+		p.progress(p.Progress())
+	}
+	return true
+}
+
+func (p *Paginator) next(ctx context.Context) bool {
+	if p.done || p.err != nil {
+		return false
+	}
+	if p.maxPages > 0 && p.pages >= p.maxPages || p.maxItems > 0 && p.items >= p.maxItems {
+		p.err = ErrPageLimit
+		return false
+	}
+	v := reflect.ValueOf(p.q).Elem()
+	if p.parallel > 1 && p.offsetVar != "" && p.hasTotal && p.budget == nil {
+		if err := p.fetchAhead(ctx, v); err != nil {
+			p.err = err
+			return false
+		}
+	} else {
+		if p.budget != nil {
+			if err := p.budget.wait(ctx); err != nil {
+				p.err = err
+				return false
+			}
+			p.budget.header = nil
+		}
+		v.SetZero()
+		if err := p.c.Query(ctx, p.q, p.variables, p.callOpts...); err != nil {
+			p.err = err
+			return false
+		}
+	}
+	p.pages++
+	if p.budget != nil {
+		p.budget.update(v)
+	}
+	n := listLen(v, p.list)
+	p.items += n
+	if p.totalPath != nil {
+		if f, ok := fieldAt(v, p.totalPath); ok {
+			p.total, p.hasTotal = int(f.Int()), true
+		}
+	}
+	if p.offsetVar != "" {
+		p.offset += n
+		if n < p.limit || p.hasTotal && p.offset >= p.total {
+			p.done = true
+			p.ahead = nil
+			return true
+		}
+		p.variables[p.offsetVar] = intValue(reflect.TypeOf(p.variables[p.offsetVar]), p.offset)
+		return true
+	}
+	info, ok := readPageInfo(v, p.conn)
+	p.info = info
+	cursor, more := info.endCursor, info.hasNextPage
+	if p.backward {
+		cursor, more = info.startCursor, info.hasPreviousPage
+	}
+	if !ok || !more || cursor == "" {
+		p.done = true
+		return true
+	}
+	cursorVar, _ := p.cursorVariables()
+	p.variables[cursorVar] = cursorValue(reflect.TypeOf(p.variables[cursorVar]), cursor)
+	return true
+}
+
+// fetchAhead fetches the next page into the query struct v, and starts
+// fetching the pages after it, up to p.parallel pages at once, as long as
+// they're within the total count of items and the limits.
+func (p *Paginator) fetchAhead(ctx context.Context, v reflect.Value) error {
+	for len(p.ahead) < p.parallel {
+		next := p.offset + len(p.ahead)*p.limit
+		if next >= p.total ||
+			p.maxPages > 0 && p.pages+len(p.ahead) >= p.maxPages ||
+			p.maxItems > 0 && p.items+len(p.ahead)*p.limit >= p.maxItems {
+			break
+		}
+		f := &pageFetch{q: reflect.New(v.Type()), done: make(chan struct{})}
+		variables := maps.Clone(p.variables)
+		variables[p.offsetVar] = intValue(reflect.TypeOf(variables[p.offsetVar]), next)
+		go func() {
+			defer close(f.done)
+			f.err = p.c.Query(ctx, f.q.Interface(), variables, p.callOpts...)
+		}()
+		p.ahead = append(p.ahead, f)
+	}
+	f := p.ahead[0]
+	p.ahead = p.ahead[1:]
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if f.err != nil {
+		return f.err
+	}
+	v.Set(f.q.Elem())
+	return nil
+}
+
+// Err returns the error that stopped paging, if any.
+func (p *Paginator) Err() error {
+	return p.err
+}
+
+// Pages returns the number of pages fetched so far.
+func (p *Paginator) Pages() int {
+	return p.pages
+}
+
+// Items returns the number of items in the pages fetched so far.
+func (p *Paginator) Items() int {
+	return p.items
+}
+
+// Total returns the total count of items, as of the last page fetched, and
+// whether it's known. It's known if the query selects it, as a totalCount
+// field, such as that of Relay connections and PostGraphile lists, or else
+// as a count field, such as that of Hasura aggregates.
+func (p *Paginator) Total() (int, bool) {
+	return p.total, p.hasTotal
+}
+
+// QueryAll fetches all pages of the connection selected by q, as a Paginator
+// does, and calls accumulate after each one is fetched into q, with the
+// number of the page, starting at 1. If accumulate returns an error,
+// QueryAll stops and returns it. Use WithMaxPages and WithMaxItems
+// to guard against unexpectedly large connections.
+func (c *Client) QueryAll(ctx context.Context, q any, variables map[string]any, accumulate func(page int) error, opts ...PageOption) error {
+	p := c.Paginate(q, variables, opts...)
+	for p.Next(ctx) {
+		if err := accumulate(p.Pages()); err != nil {
+			return err
+		}
+	}
+	return p.Err()
+}
+
+// pageInfo is the pageInfo of a Relay connection.
+type pageInfo struct {
+	hasNextPage     bool
+	hasPreviousPage bool
+	startCursor     string
+	endCursor       string
+}
+
+// readPageInfo reads the pageInfo of the connection at the field indices
+// conn in the query struct v, and reports whether the connection isn't null.
+func readPageInfo(v reflect.Value, conn []int) (pageInfo, bool) {
+	v, ok := fieldAt(v, conn)
+	if !ok {
+		return pageInfo{}, false
+	}
+	var info pageInfo
+	v, ok = fieldByName(v, "pageInfo")
+	if !ok {
+		return pageInfo{}, false
+	}
+	if f, ok := fieldByName(v, "hasNextPage"); ok && f.Kind() == reflect.Bool {
+		info.hasNextPage = f.Bool()
+	}
+	if f, ok := fieldByName(v, "hasPreviousPage"); ok && f.Kind() == reflect.Bool {
+		info.hasPreviousPage = f.Bool()
+	}
+	if f, ok := fieldByName(v, "startCursor"); ok && f.Kind() == reflect.String {
+		info.startCursor = f.String()
+	}
+	if f, ok := fieldByName(v, "endCursor"); ok && f.Kind() == reflect.String {
+		info.endCursor = f.String()
+	}
+	return info, true
+}
+
+// Pages returns an iterator over the pages of the connection selected by q,
+// as fetched by a Paginator, which yields the number of each page, starting
+// at 1, once it's fetched into q. Pages are fetched as the iteration goes,
+// and stopping it stops fetching, canceling the fetches of pages ahead
+// started by WithParallelPages. If fetching a page fails, the iterator
+// yields the error last.
+func (c *Client) Pages(ctx context.Context, q any, variables map[string]any, opts ...PageOption) iter.Seq2[int, error] {
+	return func(yield func(int, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		p := c.Paginate(q, variables, opts...)
+		for p.Next(ctx) {
+			if !yield(p.Pages(), nil) {
+				return
+			}
+		}
+		if err := p.Err(); err != nil {
+			yield(0, err)
+		}
+	}
+}
+
+// Nodes returns an iterator over the nodes of the connection selected by q,
+// across all of its pages, as fetched by client's Paginator. N is the type
+// of the elements of the connection's nodes or edges field, or of the node
+// field of its edges, or, if paging by offset, of the list's elements.
+// Pages are fetched as the iteration goes, and stopping it stops fetching,
+// canceling the fetches of pages ahead started by WithParallelPages.
+// If fetching a page fails, the iterator yields the error last.
+func Nodes[N any](ctx context.Context, client *Client, q any, variables map[string]any, opts ...PageOption) iter.Seq2[N, error] {
+	return func(yield func(N, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		var zero N
+		p := client.Paginate(q, variables, opts...)
+		for p.Next(ctx) {
+			list, ok := fieldAt(reflect.ValueOf(q).Elem(), p.list)
+			if !ok || p.list == nil {
+				continue
+			}
+			nodes, err := listNodes(list, reflect.TypeFor[N]())
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			for _, n := range nodes {
+				if !yield(n.Interface().(N), nil) {
+					return
+				}
+			}
+		}
+		if err := p.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+// listNodes returns the nodes of type t in the list of items list, which
+// are its elements, or the node fields of its elements if they're edges.
+func listNodes(list reflect.Value, t reflect.Type) ([]reflect.Value, error) {
+	var nodes []reflect.Value
+	switch elem := list.Type().Elem(); {
+	case elem == t:
+		for i := 0; i < list.Len(); i++ {
+			nodes = append(nodes, list.Index(i))
+		}
+		return nodes, nil
+	case elem.Kind() == reflect.Struct:
+		if node, ok := structField(reflect.Zero(elem), "node"); !ok || node.Type() != t {
+			break
+		}
+		for i := 0; i < list.Len(); i++ {
+			node, _ := structField(list.Index(i), "node")
+			nodes = append(nodes, node)
+		}
+		return nodes, nil
+	}
+	return nil, fmt.Errorf("graphql: list has no nodes of type %v", t)
+}
+
+// listLen returns the number of items in the list at the field indices
+// list in the query struct v, or 0 if there's no list.
+func listLen(v reflect.Value, list []int) int {
+	if list == nil {
+		return 0
+	}
+	if v, ok := fieldAt(v, list); ok && v.Kind() == reflect.Slice {
+		return v.Len()
+	}
+	return 0
+}
+
+// intVariable returns the value of a variable v of an integer type,
+// such as Int or *Int, or 0 if it isn't one.
+func intVariable(v any) int {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.IsValid() && rv.CanInt() {
+		return int(rv.Int())
+	}
+	return 0
+}
+
+// intValue returns n as a value of the type t of an integer variable,
+// such as Int or *Int.
+func intValue(t reflect.Type, n int) any {
+	if t != nil && t.Kind() == reflect.Ptr && t.Elem().Kind() >= reflect.Int && t.Elem().Kind() <= reflect.Int64 {
+		v := reflect.New(t.Elem())
+		v.Elem().SetInt(int64(n))
+		return v.Interface()
+	}
+	if t != nil && t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64 {
+		return reflect.ValueOf(int64(n)).Convert(t).Interface()
+	}
+	return Int(n)
+}
+
+// cursorValue returns cursor as a value of the type t of a cursor variable,
+// such as *String or string.
+func cursorValue(t reflect.Type, cursor string) any {
+	if t == nil {
+		return NewString(String(cursor))
+	}
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.String {
+		v := reflect.New(t.Elem())
+		v.Elem().SetString(cursor)
+		return v.Interface()
+	}
+	if t.Kind() == reflect.String {
+		return reflect.ValueOf(cursor).Convert(t).Interface()
+	}
+	return NewString(String(cursor))
+}
+
+// findPath returns the indices of the fields leading from the struct type t
+// to the first field in it that match accepts, found depth-first outside of
+// lists, and whether there's one. Types in seen aren't searched again.
+func findPath(t reflect.Type, seen []reflect.Type, match func(reflect.StructField) bool) ([]int, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || reflect.PointerTo(t).Implements(jsonUnmarshaler) {
+		return nil, false
+	}
+	for _, s := range seen {
+		if s == t {
+			return nil, false
+		}
+	}
+	seen = append(seen, t)
+	for i := 0; i < t.NumField(); i++ {
+		if match(t.Field(i)) {
+			return []int{i}, true
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if path, ok := findPath(t.Field(i).Type, seen, match); ok {
+			return append([]int{i}, path...), true
+		}
+	}
+	return nil, false
+}
+
+// hasName returns a function reporting whether a struct field selects
+// the GraphQL field name.
+func hasName(name string) func(reflect.StructField) bool {
+	return func(f reflect.StructField) bool { return graphQLFieldName(f) == name }
+}
+
+// isIntField returns a function reporting whether a struct field selects
+// the GraphQL field name, and is an integer.
+func isIntField(name string) func(reflect.StructField) bool {
+	return func(f reflect.StructField) bool {
+		k := f.Type.Kind()
+		return graphQLFieldName(f) == name && k >= reflect.Int && k <= reflect.Int64
+	}
+}
+
+// isObjectList reports whether a struct field selects a list of objects.
+func isObjectList(f reflect.StructField) bool {
+	if graphQLFieldName(f) == "" || !isList(f.Type) {
+		return false
+	}
+	elem := f.Type.Elem()
+	for elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	return elem.Kind() == reflect.Struct && !reflect.PointerTo(elem).Implements(jsonUnmarshaler)
+}
+
+// isList reports whether t is a slice that isn't decoded as a scalar.
+func isList(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && !reflect.PointerTo(t).Implements(jsonUnmarshaler)
+}
+
+// typeAt returns the type of the field at the field indices path in the
+// struct type t, dereferenced.
+func typeAt(t reflect.Type, path []int) reflect.Type {
+	for _, i := range path {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		t = t.Field(i).Type
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// fieldIndex returns the index of the field of the struct type t with
+// the GraphQL name, and reports whether there's one.
+func fieldIndex(t reflect.Type, name string) (int, bool) {
+	if t.Kind() != reflect.Struct {
+		return 0, false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if graphQLFieldName(t.Field(i)) == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// fieldAt returns the field at the field indices path in the struct v,
+// dereferencing pointers, and reports whether none of them is nil.
+func fieldAt(v reflect.Value, path []int) (reflect.Value, bool) {
+	for _, i := range path {
+		v = reflect.Indirect(v)
+		if !v.IsValid() {
+			return reflect.Value{}, false
+		}
+		v = v.Field(i)
+	}
+	v = reflect.Indirect(v)
+	return v, v.IsValid()
+}
+
+// fieldByName returns the field of the struct v with the GraphQL name,
+// dereferenced, and reports whether there's one and it isn't nil.
+func fieldByName(v reflect.Value, name string) (reflect.Value, bool) {
+	f, ok := structField(v, name)
+	if !ok {
+		return reflect.Value{}, false
+	}
+	f = reflect.Indirect(f)
+	return f, f.IsValid()
+}
+
+// structField returns the field of the struct v with the GraphQL name,
+// and reports whether there's one.
+func structField(v reflect.Value, name string) (reflect.Value, bool) {
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	for i := 0; i < v.NumField(); i++ {
+		if graphQLFieldName(v.Type().Field(i)) == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// graphQLFieldName returns the name of the GraphQL field that the struct
+// field f selects, or "" if it's a fragment or an embedded struct.
+func graphQLFieldName(f reflect.StructField) string {
+	value, ok := f.Tag.Lookup("graphql")
+	if !ok {
+		if f.Anonymous {
+			return ""
+		}
+		return ident.ParseMixedCaps(f.Name).ToLowerCamelCase()
+	}
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "...") {
+		return ""
+	}
+	// Cut off arguments and directives, and the alias, if any.
+	if i := strings.IndexAny(value, "(@"); i != -1 {
+		value = value[:i]
+	}
+	if i := strings.Index(value, ":"); i != -1 {
+		value = value[i+1:]
+	}
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,523 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/isihu/graphql"
+)
+
+// issuesHandler serves a connection of issues, numbered from 1 to total,
+// in pages of size issues, with cursors that are the numbers of issues.
+func issuesHandler(total, size int, cursorVar string) (http.Handler, *[]string) {
+	var queries []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		var in struct {
+			Query     string
+			Variables map[string]any
+		}
+		mustUnmarshal(mustRead(req.Body), &in)
+		queries = append(queries, in.Query)
+		after := 0
+		if c, ok := in.Variables[cursorVar].(string); ok {
+			fmt.Sscan(c, &after)
+		}
+		var nodes string
+		end := min(after+size, total)
+		for i := after + 1; i <= end; i++ {
+			if nodes != "" {
+				nodes += ","
+			}
+			nodes += fmt.Sprintf(`{"number": %d}`, i)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, fmt.Sprintf(`{"data": {"repository": {"issues": {"nodes": [%s], "pageInfo": {"endCursor": "%d", "hasNextPage": %t}}}}}`,
+			nodes, end, end < total))
+	})
+	return mux, &queries
+}
+
+type issuesQuery struct {
+	Repository struct {
+		Issues struct {
+			Nodes    []struct{ Number graphql.Int }
+			PageInfo struct {
+				EndCursor   graphql.String
+				HasNextPage graphql.Boolean
+			}
+		} `graphql:"issues(first:2, after:$after)"`
+	} `graphql:"repository(owner:$owner, name:$name)"`
+}
+
+func TestClient_Paginate(t *testing.T) {
+	handler, queries := issuesHandler(5, 2, "after")
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}})
+
+	var q issuesQuery
+	variables := map[string]any{"owner": graphql.String("isihu"), "name": graphql.String("graphql")}
+	p := client.Paginate(&q, variables)
+	var numbers []int
+	for p.Next(context.Background()) {
+		for _, n := range q.Repository.Issues.Nodes {
+			numbers = append(numbers, int(n.Number))
+		}
+	}
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(numbers), "[1 2 3 4 5]"; got != want {
+		t.Errorf("got issues: %v, want: %v", got, want)
+	}
+	if got, want := p.Pages(), 3; got != want {
+		t.Errorf("got %d pages, want: %d", got, want)
+	}
+	if got, want := (*queries)[0], `query($after:String$name:String!$owner:String!){repository(owner:$owner, name:$name){issues(first:2, after:$after){nodes{number},pageInfo{endCursor,hasNextPage}}}}`; got != want {
+		t.Errorf("got query: %s, want: %s", got, want)
+	}
+	if _, ok := variables["after"]; ok {
+		t.Error("got variables modified")
+	}
+}
+
+func TestClient_Paginate_cursorVariable(t *testing.T) {
+	handler, _ := issuesHandler(3, 2, "cursor")
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}})
+
+	var q struct {
+		Repository struct {
+			Issues struct {
+				Nodes    []struct{ Number graphql.Int }
+				PageInfo *struct {
+					EndCursor   *graphql.String
+					HasNextPage graphql.Boolean
+				}
+			} `graphql:"issues(first:2, after:$cursor)"`
+		}
+	}
+	p := client.Paginate(&q, map[string]any{"cursor": (*graphql.String)(nil)}, graphql.WithCursorVariable("cursor"))
+	var last graphql.Int
+	for p.Next(context.Background()) {
+		nodes := q.Repository.Issues.Nodes
+		last = nodes[len(nodes)-1].Number
+	}
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := last, graphql.Int(3); got != want {
+		t.Errorf("got last issue: %v, want: %v", got, want)
+	}
+}
+
+func TestClient_Paginate_noConnection(t *testing.T) {
+	client := graphql.NewClient("/graphql", nil)
+	var q struct {
+		Viewer struct{ Login graphql.String }
+	}
+	p := client.Paginate(&q, nil)
+	if p.Next(context.Background()) {
+		t.Error("got a page of a query without a connection")
+	}
+	if p.Err() == nil {
+		t.Error("got no error for a query without a connection")
+	}
+}
+
+func TestClient_Paginate_error(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+	var q issuesQuery
+	p := client.Paginate(&q, map[string]any{"owner": graphql.String("isihu"), "name": graphql.String("graphql")})
+	if p.Next(context.Background()) {
+		t.Error("got a page from a failing server")
+	}
+	var he *graphql.HTTPError
+	if !errors.As(p.Err(), &he) {
+		t.Errorf("got error: %v, want a *graphql.HTTPError", p.Err())
+	}
+}
+
+func TestClient_QueryAll(t *testing.T) {
+	handler, _ := issuesHandler(5, 2, "after")
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}})
+	variables := map[string]any{"owner": graphql.String("isihu"), "name": graphql.String("graphql")}
+
+	var q issuesQuery
+	var numbers []int
+	var pages []int
+	err := client.QueryAll(context.Background(), &q, variables, func(page int) error {
+		pages = append(pages, page)
+		for _, n := range q.Repository.Issues.Nodes {
+			numbers = append(numbers, int(n.Number))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(numbers), "[1 2 3 4 5]"; got != want {
+		t.Errorf("got issues: %v, want: %v", got, want)
+	}
+	if got, want := fmt.Sprint(pages), "[1 2 3]"; got != want {
+		t.Errorf("got pages: %v, want: %v", got, want)
+	}
+
+	errStop := errors.New("stop")
+	err = client.QueryAll(context.Background(), &q, variables, func(page int) error { return errStop })
+	if err != errStop {
+		t.Errorf("got error: %v, want: %v", err, errStop)
+	}
+}
+
+func TestClient_QueryAll_limits(t *testing.T) {
+	handler, _ := issuesHandler(10, 2, "after")
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}})
+	variables := map[string]any{"owner": graphql.String("isihu"), "name": graphql.String("graphql")}
+
+	tests := []struct {
+		name      string
+		opt       graphql.PageOption
+		wantPages int
+	}{
+		{"max pages", graphql.WithMaxPages(2), 2},
+		{"max items", graphql.WithMaxItems(5), 3},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var q issuesQuery
+			var pages int
+			err := client.QueryAll(context.Background(), &q, variables, func(int) error {
+				pages++
+				return nil
+			}, tc.opt)
+			if err != graphql.ErrPageLimit {
+				t.Errorf("got error: %v, want: %v", err, graphql.ErrPageLimit)
+			}
+			if pages != tc.wantPages {
+				t.Errorf("got %d pages, want: %d", pages, tc.wantPages)
+			}
+		})
+	}
+
+	// Limits that aren't reached don't fail.
+	var q issuesQuery
+	err := client.QueryAll(context.Background(), &q, variables, func(int) error { return nil }, graphql.WithMaxPages(5))
+	if err != nil {
+		t.Errorf("got error: %v", err)
+	}
+}
+
+func TestClient_Pages(t *testing.T) {
+	handler, queries := issuesHandler(5, 2, "after")
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}})
+	variables := map[string]any{"owner": graphql.String("isihu"), "name": graphql.String("graphql")}
+
+	var q issuesQuery
+	var pages []int
+	for page, err := range client.Pages(context.Background(), &q, variables) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, page)
+		if page == 2 {
+			break
+		}
+	}
+	if got, want := fmt.Sprint(pages), "[1 2]"; got != want {
+		t.Errorf("got pages: %v, want: %v", got, want)
+	}
+	if got, want := len(*queries), 2; got != want {
+		t.Errorf("got %d requests after breaking, want: %d", got, want)
+	}
+}
+
+type issueNode struct{ Number graphql.Int }
+
+func TestNodes(t *testing.T) {
+	handler, queries := issuesHandler(5, 2, "after")
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}})
+	variables := map[string]any{"owner": graphql.String("isihu"), "name": graphql.String("graphql")}
+
+	var q struct {
+		Repository struct {
+			Issues struct {
+				Nodes    []issueNode
+				PageInfo struct {
+					EndCursor   graphql.String
+					HasNextPage graphql.Boolean
+				}
+			} `graphql:"issues(first:2, after:$after)"`
+		} `graphql:"repository(owner:$owner, name:$name)"`
+	}
+	var numbers []int
+	for node, err := range graphql.Nodes[issueNode](context.Background(), client, &q, variables) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		numbers = append(numbers, int(node.Number))
+		if node.Number == 3 {
+			break
+		}
+	}
+	if got, want := fmt.Sprint(numbers), "[1 2 3]"; got != want {
+		t.Errorf("got issues: %v, want: %v", got, want)
+	}
+	if got, want := len(*queries), 2; got != want {
+		t.Errorf("got %d requests after breaking, want: %d", got, want)
+	}
+
+	// Nodes of another type aren't found.
+	for _, err := range graphql.Nodes[string](context.Background(), client, &q, variables) {
+		if err == nil {
+			t.Error("got no error for nodes of the wrong type")
+		}
+	}
+}
+
+func TestNodes_edges(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"starred": {"edges": [{"node": {"number": 1}}, {"node": {"number": 2}}], "pageInfo": {"endCursor": "2", "hasNextPage": false}}}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+	var q struct {
+		Viewer struct {
+			Starred struct {
+				Edges    []struct{ Node issueNode }
+				PageInfo struct {
+					EndCursor   graphql.String
+					HasNextPage graphql.Boolean
+				}
+			} `graphql:"starred(first:2, after:$after)"`
+		}
+	}
+	var numbers []int
+	for node, err := range graphql.Nodes[issueNode](context.Background(), client, &q, nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		numbers = append(numbers, int(node.Number))
+	}
+	if got, want := fmt.Sprint(numbers), "[1 2]"; got != want {
+		t.Errorf("got issues: %v, want: %v", got, want)
+	}
+}
+
+// usersHandler serves a list of users, numbered from 1 to total, paged by
+// the offset and limit variables, as the data that format returns for the
+// users of a page.
+func usersHandler(total int, offsetVar, limitVar string, format func(users string, total int) string) (http.Handler, *[]map[string]any) {
+	var variables []map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		var in struct {
+			Query     string
+			Variables map[string]any
+		}
+		mustUnmarshal(mustRead(req.Body), &in)
+		variables = append(variables, in.Variables)
+		offset, _ := in.Variables[offsetVar].(float64)
+		limit, _ := in.Variables[limitVar].(float64)
+		var users string
+		for i := int(offset) + 1; i <= min(int(offset+limit), total); i++ {
+			if users != "" {
+				users += ","
+			}
+			users += fmt.Sprintf(`{"id": %d}`, i)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": `+format(users, total)+`}`)
+	})
+	return mux, &variables
+}
+
+type usersQuery struct {
+	Users []struct {
+		ID graphql.Int
+	} `graphql:"users(offset:$offset, limit:$limit, order_by:{id:asc})"`
+	UsersAggregate *struct {
+		Aggregate struct{ Count int }
+	} `graphql:"users_aggregate"`
+}
+
+func TestClient_Paginate_offset(t *testing.T) {
+	tests := []struct {
+		name         string
+		total        int
+		count        bool
+		variables    map[string]any
+		want         string
+		wantRequests int
+	}{
+		{name: "short page", total: 5, want: "[1 2 3 4 5]", wantRequests: 3},
+		{name: "empty page", total: 4, want: "[1 2 3 4]", wantRequests: 3},
+		{name: "total count", total: 4, count: true, want: "[1 2 3 4]", wantRequests: 2},
+		{name: "start offset", total: 5, variables: map[string]any{"offset": graphql.Int(1)}, want: "[2 3 4 5]", wantRequests: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, requests := usersHandler(tt.total, "offset", "limit", func(users string, total int) string {
+				if tt.count {
+					return fmt.Sprintf(`{"users": [%s], "users_aggregate": {"aggregate": {"count": %d}}}`, users, total)
+				}
+				return fmt.Sprintf(`{"users": [%s]}`, users)
+			})
+			client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}})
+
+			var q usersQuery
+			p := client.Paginate(&q, tt.variables, graphql.WithOffsetPagination("", "", 2))
+			var ids []int
+			for p.Next(context.Background()) {
+				for _, u := range q.Users {
+					ids = append(ids, int(u.ID))
+				}
+			}
+			if err := p.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprint(ids); got != tt.want {
+				t.Errorf("got users: %v, want: %v", got, tt.want)
+			}
+			if got := len(*requests); got != tt.wantRequests {
+				t.Errorf("got %d requests, want: %d", got, tt.wantRequests)
+			}
+			if total, ok := p.Total(); ok != tt.count || ok && total != tt.total {
+				t.Errorf("got total: %d, %t, want: %d, %t", total, ok, tt.total, tt.count)
+			}
+		})
+	}
+}
+
+func TestClient_Paginate_offsetVariables(t *testing.T) {
+	handler, requests := usersHandler(3, "skip", "first", func(users string, total int) string {
+		return fmt.Sprintf(`{"allUsers": {"nodes": [%s], "totalCount": %d}}`, users, total)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}})
+
+	// PostGraphile's connections have no cursors when paged by offset.
+	var q struct {
+		AllUsers struct {
+			Nodes []struct {
+				ID graphql.Int
+			}
+			TotalCount graphql.Int
+		} `graphql:"allUsers(offset:$skip, first:$first)"`
+	}
+	var ids []int
+	for n, err := range graphql.Nodes[struct{ ID graphql.Int }](context.Background(), client, &q, nil, graphql.WithOffsetPagination("skip", "first", 2)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, int(n.ID))
+	}
+	if got, want := fmt.Sprint(ids), "[1 2 3]"; got != want {
+		t.Errorf("got users: %v, want: %v", got, want)
+	}
+	if got, want := len(*requests), 2; got != want {
+		t.Errorf("got %d requests, want: %d", got, want)
+	}
+	if got, want := fmt.Sprint((*requests)[1]), "map[first:2 skip:2]"; got != want {
+		t.Errorf("got variables: %v, want: %v", got, want)
+	}
+}
+
+func TestClient_Paginate_offsetNoList(t *testing.T) {
+	client := graphql.NewClient("/graphql", nil)
+	var q struct {
+		Viewer struct{ Login graphql.String }
+	}
+	p := client.Paginate(&q, nil, graphql.WithOffsetPagination("", "", 10))
+	if p.Next(context.Background()) {
+		t.Error("got a page, want none")
+	}
+	if p.Err() == nil {
+		t.Error("got no error, want one")
+	}
+}
+
+func TestWithParallelPages(t *testing.T) {
+	var mu sync.Mutex
+	var running, maxRunning int
+	handler, _ := usersHandler(9, "offset", "limit", func(users string, total int) string {
+		return fmt.Sprintf(`{"users": [%s], "users_aggregate": {"aggregate": {"count": %d}}}`, users, total)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		handler.ServeHTTP(w, req)
+		running--
+		mu.Unlock()
+	})}})
+
+	var q usersQuery
+	var ids []int
+	err := client.QueryAll(context.Background(), &q, nil, func(int) error {
+		for _, u := range q.Users {
+			ids = append(ids, int(u.ID))
+		}
+		return nil
+	}, graphql.WithOffsetPagination("", "", 2), graphql.WithParallelPages(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(ids), "[1 2 3 4 5 6 7 8 9]"; got != want {
+		t.Errorf("got users: %v, want: %v", got, want)
+	}
+	if got, want := maxRunning, 3; got != want {
+		t.Errorf("got %d pages fetched at once, want: %d", got, want)
+	}
+}
+
+func TestWithParallelPages_stop(t *testing.T) {
+	handler, _ := usersHandler(9, "offset", "limit", func(users string, total int) string {
+		return fmt.Sprintf(`{"users": [%s], "users_aggregate": {"aggregate": {"count": %d}}}`, users, total)
+	})
+	var mu sync.Mutex
+	canceled := make(chan struct{}, 3)
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body := mustRead(req.Body)
+		var in struct{ Variables struct{ Offset int } }
+		mustUnmarshal(body, &in)
+		if in.Variables.Offset > 2 {
+			// Pages after the second are fetched until they're canceled.
+			<-req.Context().Done()
+			canceled <- struct{}{}
+			return
+		}
+		req.Body = io.NopCloser(strings.NewReader(body))
+		mu.Lock()
+		handler.ServeHTTP(w, req)
+		mu.Unlock()
+	})}})
+
+	var q usersQuery
+	for page := range client.Pages(context.Background(), &q, nil, graphql.WithOffsetPagination("", "", 2), graphql.WithParallelPages(3)) {
+		if page == 2 {
+			break
+		}
+	}
+	// Fetching the second page started fetching the 2 pages after it.
+	for range 2 {
+		select {
+		case <-canceled:
+		case <-time.After(time.Second):
+			t.Fatal("got fetches of pages ahead running after the iteration stopped")
+		}
+	}
+}
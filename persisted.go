@@ -0,0 +1,114 @@
+package graphql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+// persistedQuery is the "persistedQuery" request extension
+// used by Automatic Persisted Queries.
+type persistedQuery struct {
+	Version    int    `json:"version"`
+	SHA256Hash string `json:"sha256Hash"`
+}
+
+// doPersisted sends in to u using the Automatic Persisted Queries protocol.
+// It first sends only the document hash, and if the server reports
+// that it doesn't know the hash, it sends the full document along with it.
+// If the server reports that it doesn't support persisted queries,
+// it sends the full document without the hash, as it does from then on.
+func (c *Client) doPersisted(ctx context.Context, cfg *callConfig, u string, in request) (*response, error) {
+	if c.persistedQueriesUnsupported.Load() {
+		return c.send(ctx, cfg, http.MethodPost, u, in)
+	}
+	ext := make(map[string]any, len(in.Extensions)+1)
+	for k, v := range in.Extensions {
+		ext[k] = v
+	}
+	ext["persistedQuery"] = persistedQuery{Version: 1, SHA256Hash: documentHash(in.Query)}
+
+	hashed := request{OperationName: in.OperationName, Variables: in.Variables, Extensions: ext, hashedQuery: in.Query}
+	method := http.MethodPost
+	if c.persistedQueriesGET && namedOperationType(in.Query, in.OperationName) == "query" {
+		method = http.MethodGet
+	}
+	out, err := c.send(ctx, cfg, method, u, hashed)
+	switch {
+	case err != nil:
+		return nil, err
+	case out.Errors.persistedQueryNotSupported():
+		c.persistedQueriesUnsupported.Store(true)
+		return c.send(ctx, cfg, http.MethodPost, u, in)
+	case !out.Errors.persistedQueryNotFound():
+		return out, nil
+	}
+
+	// The server doesn't know the hash yet. Register it by sending the full document.
+	full := request{Query: in.Query, OperationName: in.OperationName, Variables: in.Variables, Extensions: ext}
+	return c.send(ctx, cfg, http.MethodPost, u, full)
+}
+
+// documentHash returns the hex-encoded SHA-256 hash of the GraphQL document query.
+func documentHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// persistedQueryNotFound reports whether e indicates that the server
+// doesn't have the document for a persisted query hash.
+func (e Errors) persistedQueryNotFound() bool {
+	for _, err := range e {
+		if err.Message == "PersistedQueryNotFound" || err.Code() == "PERSISTED_QUERY_NOT_FOUND" {
+			return true
+		}
+	}
+	return false
+}
+
+// persistedQueryNotSupported reports whether e indicates that the server
+// doesn't support persisted queries.
+func (e Errors) persistedQueryNotSupported() bool {
+	for _, err := range e {
+		if err.Message == "PersistedQueryNotSupported" || err.Code() == "PERSISTED_QUERY_NOT_SUPPORTED" {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeGET returns the URL for sending in to u with a GET request,
+// per the GraphQL over HTTP specification. Variables and extensions
+// are JSON-encoded into their own query parameters.
+func encodeGET(u string, in request) (string, error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "", err
+	}
+	params := parsed.Query()
+	if in.Query != "" {
+		params.Set("query", in.Query)
+	}
+	if in.OperationName != "" {
+		params.Set("operationName", in.OperationName)
+	}
+	if len(in.Variables) > 0 {
+		b, err := json.Marshal(in.Variables)
+		if err != nil {
+			return "", err
+		}
+		params.Set("variables", string(b))
+	}
+	if len(in.Extensions) > 0 {
+		b, err := json.Marshal(in.Extensions)
+		if err != nil {
+			return "", err
+		}
+		params.Set("extensions", string(b))
+	}
+	parsed.RawQuery = params.Encode()
+	return parsed.String(), nil
+}
@@ -0,0 +1,207 @@
+package graphql_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+func TestClient_Query_persistedQueries(t *testing.T) {
+	const query = `query($id:ID!){node(id: $id){id}}`
+	sum := sha256.Sum256([]byte(query))
+	wantHash := hex.EncodeToString(sum[:])
+
+	known := make(map[string]bool) // Persisted query hashes known to server.
+	var methods []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		methods = append(methods, req.Method)
+		var in struct {
+			Query      string
+			Variables  map[string]any
+			Extensions struct {
+				PersistedQuery struct {
+					Version    int
+					SHA256Hash string
+				}
+			}
+		}
+		switch req.Method {
+		case http.MethodGet:
+			if req.URL.Query().Has("query") {
+				t.Error("GET request unexpectedly has query parameter")
+			}
+			mustUnmarshal(req.URL.Query().Get("variables"), &in.Variables)
+			mustUnmarshal(req.URL.Query().Get("extensions"), &in.Extensions)
+		default:
+			err := json.NewDecoder(req.Body).Decode(&in)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := in.Extensions.PersistedQuery.SHA256Hash; got != wantHash {
+			t.Errorf("got hash: %q, want: %q", got, wantHash)
+		}
+		if got, want := in.Variables, map[string]any{"id": "MDQ6VXNlcjE="}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got variables: %v, want: %v", got, want)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case in.Query != "":
+			known[wantHash] = true
+		case !known[wantHash]:
+			mustWrite(w, `{"errors": [{"message": "PersistedQueryNotFound", "extensions": {"code": "PERSISTED_QUERY_NOT_FOUND"}}]}`)
+			return
+		}
+		mustWrite(w, `{"data": {"node": {"id": "MDQ6VXNlcjE="}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithPersistedQueries(true))
+
+	for i, want := range [][]string{
+		{http.MethodGet, http.MethodPost}, // First call registers the document.
+		{http.MethodGet},                  // Second call is served by hash alone.
+	} {
+		methods = nil
+		var q struct {
+			Node struct {
+				ID graphql.ID
+			}
+		}
+		err := client.Do(context.Background(), query, &q, false, map[string]any{"id": graphql.ID("MDQ6VXNlcjE=")})
+		if err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		if got, want := q.Node.ID, graphql.ID("MDQ6VXNlcjE="); got != want {
+			t.Errorf("call %d: got q.Node.ID: %v, want: %v", i, got, want)
+		}
+		if !reflect.DeepEqual(methods, want) {
+			t.Errorf("call %d: got methods: %v, want: %v", i, methods, want)
+		}
+	}
+}
+
+// Test that mutations are never sent with GET, even if GET is enabled for persisted queries.
+func TestClient_Mutate_persistedQueriesUsePOST(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			t.Errorf("got method: %v, want: POST", req.Method)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"addStar": {"starrable": {"stargazerCount": 1}}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithPersistedQueries(true))
+
+	var m struct {
+		AddStar struct {
+			Starrable struct {
+				StargazerCount int
+			}
+		} `graphql:"addStar(input:{starrableId:\"R_1\"})"`
+	}
+	err := client.Mutate(context.Background(), &m, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClient_Query_persistedQueryNotFoundCode(t *testing.T) {
+	// Servers may report unknown hashes by the error code only.
+	var queries []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		var in struct{ Query string }
+		mustUnmarshal(mustRead(req.Body), &in)
+		queries = append(queries, in.Query)
+		w.Header().Set("Content-Type", "application/json")
+		if in.Query == "" {
+			mustWrite(w, `{"errors": [{"message": "Persisted query not found", "extensions": {"code": "PERSISTED_QUERY_NOT_FOUND"}}]}`)
+			return
+		}
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithPersistedQueries(false))
+
+	var q struct {
+		Viewer struct{ Login graphql.String }
+	}
+	if err := client.Query(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := queries, []string{"", "{viewer{login}}"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got queries: %q, want: %q", got, want)
+	}
+}
+
+func TestClient_Query_persistedQueriesNotSupported(t *testing.T) {
+	var hashed []bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		var in struct {
+			Query      string
+			Extensions map[string]any
+		}
+		mustUnmarshal(mustRead(req.Body), &in)
+		_, ok := in.Extensions["persistedQuery"]
+		hashed = append(hashed, ok)
+		w.Header().Set("Content-Type", "application/json")
+		if ok {
+			mustWrite(w, `{"errors": [{"message": "PersistedQueryNotSupported", "extensions": {"code": "PERSISTED_QUERY_NOT_SUPPORTED"}}]}`)
+			return
+		}
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithPersistedQueries(false))
+
+	for range 2 {
+		var q struct {
+			Viewer struct{ Login graphql.String }
+		}
+		if err := client.Query(context.Background(), &q, nil); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := q.Viewer.Login, graphql.String("gopher"); got != want {
+			t.Errorf("got login: %q, want: %q", got, want)
+		}
+	}
+	// Once the server reports it doesn't support them, hashes aren't sent.
+	if got, want := hashed, []bool{true, false, false}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got requests with hashes: %v, want: %v", got, want)
+	}
+}
+
+func TestClient_Do_persistedQueriesFragmentFirst(t *testing.T) {
+	// Documents starting with fragments are sent by their operation's type.
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			t.Errorf("got method: %v, want: POST", req.Method)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"follow": {"user": {"login": "gopher"}}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithPersistedQueries(true))
+
+	var m struct {
+		Follow struct {
+			User struct{ Login graphql.String }
+		}
+	}
+	err := client.Do(context.Background(), `fragment U on User{login} mutation{follow(login:"gopher"){user{...U}}}`, &m, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustUnmarshal(s string, v any) {
+	err := json.Unmarshal([]byte(s), v)
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,242 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/isihu/graphql/internal/websocket"
+)
+
+// phoenixControlTopic is the topic of the channel that Absinthe
+// subscriptions are started and stopped on.
+const phoenixControlTopic = "__absinthe__:control"
+
+// phoenixCodec is the codec of the AbsinthePhoenix protocol. It translates
+// wsMessages to and from Phoenix channel messages, which are JSON arrays of
+// the join reference, reference, topic, event and payload of the message.
+//
+// Every operation is pushed as a document to the control channel, and the
+// reply to the push carries the ID of the subscription, which is also
+// the topic that its results are sent on.
+type phoenixCodec struct {
+	conn *websocket.Conn
+
+	mu      sync.Mutex
+	lastRef int
+	joinRef string
+	refs    map[string]string // Operation IDs by the reference of their document push.
+	subs    map[string]string // Subscription IDs by operation ID. Empty until the push is replied to.
+	topics  map[string]string // Operation IDs by subscription ID.
+}
+
+func newPhoenixCodec(conn *websocket.Conn) wsCodec {
+	return &phoenixCodec{
+		conn:   conn,
+		refs:   make(map[string]string),
+		subs:   make(map[string]string),
+		topics: make(map[string]string),
+	}
+}
+
+// phoenixMessage is a Phoenix channel message.
+type phoenixMessage struct {
+	JoinRef *string
+	Ref     *string
+	Topic   string
+	Event   string
+	Payload json.RawMessage
+}
+
+// phoenixReply is the payload of a phx_reply message.
+type phoenixReply struct {
+	Status   string          `json:"status"`
+	Response json.RawMessage `json:"response"`
+}
+
+// handshake joins the control channel, with payload as the join parameters.
+func (c *phoenixCodec) handshake(payload json.RawMessage) (json.RawMessage, error) {
+	if payload == nil {
+		payload = json.RawMessage("{}")
+	}
+	c.mu.Lock()
+	ref, err := c.push(phoenixControlTopic, "phx_join", payload)
+	c.joinRef = ref
+	c.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		msg, err := c.readMessage()
+		if err != nil {
+			return nil, rejection(err)
+		}
+		if msg.Event != "phx_reply" || msg.Ref == nil || *msg.Ref != ref {
+			continue
+		}
+		var reply phoenixReply
+		err = json.Unmarshal(msg.Payload, &reply)
+		if err != nil {
+			return nil, err
+		}
+		if reply.Status != "ok" {
+			return nil, &ConnectionRejectedError{Payload: reply.Response}
+		}
+		return reply.Response, nil
+	}
+}
+
+func (c *phoenixCodec) read() (wsMessage, error) {
+	for {
+		msg, err := c.readMessage()
+		if err != nil {
+			return wsMessage{}, err
+		}
+		switch {
+		case msg.Event == "phx_reply" && msg.Topic == "phoenix":
+			return wsMessage{Type: "pong"}, nil
+		case msg.Event == "phx_reply" && msg.Topic == phoenixControlTopic:
+			out, ok, err := c.reply(msg)
+			if err != nil || ok {
+				return out, err
+			}
+		case msg.Event == "subscription:data":
+			var payload struct {
+				Result json.RawMessage `json:"result"`
+			}
+			err := json.Unmarshal(msg.Payload, &payload)
+			if err != nil {
+				return wsMessage{}, err
+			}
+			c.mu.Lock()
+			id, ok := c.topics[msg.Topic]
+			c.mu.Unlock()
+			if ok {
+				return wsMessage{ID: id, Type: "subscription:data", Payload: payload.Result}, nil
+			}
+		case msg.Topic == phoenixControlTopic && (msg.Event == "phx_error" || msg.Event == "phx_close"):
+			return wsMessage{}, fmt.Errorf("graphql: Absinthe control channel closed by %s message", msg.Event)
+		}
+	}
+}
+
+// reply handles a reply to a push to the control channel. If the push
+// started an operation that was rejected, it returns an error message for it.
+func (c *phoenixCodec) reply(msg phoenixMessage) (_ wsMessage, ok bool, _ error) {
+	if msg.Ref == nil {
+		return wsMessage{}, false, nil
+	}
+	var reply phoenixReply
+	err := json.Unmarshal(msg.Payload, &reply)
+	if err != nil {
+		return wsMessage{}, false, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id, ok := c.refs[*msg.Ref]
+	if !ok {
+		return wsMessage{}, false, nil
+	}
+	delete(c.refs, *msg.Ref)
+	if reply.Status != "ok" {
+		delete(c.subs, id)
+		var response struct {
+			Errors json.RawMessage `json:"errors"`
+		}
+		json.Unmarshal(reply.Response, &response)
+		if len(response.Errors) == 0 {
+			response.Errors, _ = json.Marshal([]map[string]string{{"message": "subscription rejected: " + string(reply.Response)}})
+		}
+		return wsMessage{ID: id, Type: "error", Payload: response.Errors}, true, nil
+	}
+	var response struct {
+		SubscriptionID string `json:"subscriptionId"`
+	}
+	err = json.Unmarshal(reply.Response, &response)
+	if err != nil {
+		return wsMessage{}, false, err
+	}
+	if _, ok := c.subs[id]; !ok {
+		// The operation was stopped before the server accepted it.
+		_, err := c.push(phoenixControlTopic, "unsubscribe", map[string]string{"subscriptionId": response.SubscriptionID})
+		return wsMessage{}, false, err
+	}
+	c.subs[id] = response.SubscriptionID
+	c.topics[response.SubscriptionID] = id
+	return wsMessage{}, false, nil
+}
+
+func (c *phoenixCodec) write(msg wsMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch msg.Type {
+	case "doc":
+		ref, err := c.push(phoenixControlTopic, "doc", msg.Payload)
+		if err != nil {
+			return err
+		}
+		c.refs[ref] = msg.ID
+		c.subs[msg.ID] = ""
+	case "unsubscribe":
+		sub := c.subs[msg.ID]
+		delete(c.subs, msg.ID)
+		if sub == "" {
+			// The server hasn't accepted the operation yet. It's
+			// unsubscribed when it does, by reply.
+			return nil
+		}
+		delete(c.topics, sub)
+		_, err := c.push(phoenixControlTopic, "unsubscribe", map[string]string{"subscriptionId": sub})
+		return err
+	case "ping":
+		_, err := c.push("phoenix", "heartbeat", json.RawMessage("{}"))
+		return err
+	}
+	return nil
+}
+
+// push sends a message with a new reference, and returns the reference.
+// c.mu must be held.
+func (c *phoenixCodec) push(topic, event string, payload any) (string, error) {
+	c.lastRef++
+	ref := strconv.Itoa(c.lastRef)
+	var joinRef *string
+	if topic == phoenixControlTopic {
+		if c.joinRef == "" {
+			// This message joins the channel.
+			joinRef = &ref
+		} else {
+			joinRef = &c.joinRef
+		}
+	}
+	data, err := json.Marshal([]any{joinRef, ref, topic, event, payload})
+	if err != nil {
+		return "", err
+	}
+	return ref, c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (c *phoenixCodec) readMessage() (phoenixMessage, error) {
+	var msg phoenixMessage
+	_, data, err := c.conn.ReadMessage()
+	if err != nil {
+		return msg, err
+	}
+	var fields []json.RawMessage
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return msg, err
+	}
+	if len(fields) != 5 {
+		return msg, fmt.Errorf("graphql: malformed Phoenix message with %d fields", len(fields))
+	}
+	for i, v := range []any{&msg.JoinRef, &msg.Ref, &msg.Topic, &msg.Event} {
+		err = json.Unmarshal(fields[i], v)
+		if err != nil {
+			return msg, err
+		}
+	}
+	msg.Payload = fields[4]
+	return msg, nil
+}
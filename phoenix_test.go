@@ -0,0 +1,95 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/isihu/graphql"
+	"github.com/isihu/graphql/internal/websocket"
+)
+
+// phoenixServer returns a test server speaking Absinthe's protocol over
+// Phoenix channels. It replies to document pushes with reply, and then
+// sends the results in data. It reports unsubscribed subscription IDs
+// on unsubscribed.
+func phoenixServer(t *testing.T, reply string, data []string, unsubscribed chan<- string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.URL.Query().Get("vsn"), "2.0.0"; got != want {
+			t.Errorf("got vsn: %q, want: %q", got, want)
+		}
+		conn, err := websocket.Upgrade(w, req, nil, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		send := func(msg string) { conn.WriteMessage(websocket.TextMessage, []byte(msg)) }
+		for {
+			_, b, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var msg [5]any
+			mustUnmarshal(string(b), &msg)
+			ref, topic, event, payload := msg[1], msg[2], msg[3], msg[4]
+			switch event {
+			case "phx_join":
+				if got, want := topic, "__absinthe__:control"; got != want {
+					t.Errorf("got join topic: %q, want: %q", got, want)
+				}
+				send(`["` + ref.(string) + `","` + ref.(string) + `","__absinthe__:control","phx_reply",{"status":"ok","response":{}}]`)
+			case "doc":
+				if got, want := payload.(map[string]any)["query"], "subscription{tick}"; got != want {
+					t.Errorf("got query: %q, want: %q", got, want)
+				}
+				send(`["1","` + ref.(string) + `","__absinthe__:control","phx_reply",` + reply + `]`)
+				for _, d := range data {
+					send(`[null,null,"__absinthe__:doc:1","subscription:data",{"result":` + d + `,"subscriptionId":"__absinthe__:doc:1"}]`)
+				}
+			case "unsubscribe":
+				unsubscribed <- payload.(map[string]any)["subscriptionId"].(string)
+			}
+		}
+	}))
+}
+
+func TestClient_Subscribe_absinthePhoenix(t *testing.T) {
+	unsubscribed := make(chan string, 1)
+	srv := phoenixServer(t, `{"status":"ok","response":{"subscriptionId":"__absinthe__:doc:1"}}`,
+		[]string{`{"data":{"tick":1}}`, `{"data":{"tick":2}}`}, unsubscribed)
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil, graphql.WithSubscriptionProtocol(graphql.AbsinthePhoenix))
+
+	sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`{"tick":1}`, `{"tick":2}`} {
+		if got := string((<-sub.Events()).Data); got != want {
+			t.Errorf("got event data: %s, want: %s", got, want)
+		}
+	}
+	sub.Unsubscribe()
+	if got, want := <-unsubscribed, "__absinthe__:doc:1"; got != want {
+		t.Errorf("got unsubscribed subscription ID: %q, want: %q", got, want)
+	}
+}
+
+func TestClient_Subscribe_absinthePhoenixRejected(t *testing.T) {
+	srv := phoenixServer(t, `{"status":"error","response":{"errors":[{"message":"unknown field tick"}]}}`, nil, nil)
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil, graphql.WithSubscriptionProtocol(graphql.AbsinthePhoenix))
+
+	sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range sub.Events() {
+	}
+	if got, want := sub.Err(), "unknown field tick"; got == nil || got.Error() != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
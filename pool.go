@@ -0,0 +1,71 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// maxPooledBuffer is the capacity above which buffers aren't returned
+// to bufferPool, so that a few huge payloads don't pin memory.
+const maxPooledBuffer = 1 << 20
+
+// pooledBuffer is a buffer, along with an encoder writing to it,
+// reused across requests to encode request bodies and read response bodies.
+type pooledBuffer struct {
+	bytes.Buffer
+	enc *json.Encoder
+
+	// refs counts the users of the buffer: the request sending it, and the
+	// request bodies reading from it, which the transport may close after
+	// the request is done.
+	refs atomic.Int32
+}
+
+var bufferPool = sync.Pool{
+	New: func() any {
+		b := new(pooledBuffer)
+		b.enc = json.NewEncoder(&b.Buffer)
+		return b
+	},
+}
+
+// getBuffer returns an empty buffer from bufferPool, referenced once.
+// Call release when done with it.
+func getBuffer() *pooledBuffer {
+	b := bufferPool.Get().(*pooledBuffer)
+	b.Reset()
+	b.refs.Store(1)
+	return b
+}
+
+// release drops a reference to b, returning it to bufferPool
+// once it's no longer referenced.
+func (b *pooledBuffer) release() {
+	if b.refs.Add(-1) == 0 && b.Cap() <= maxPooledBuffer {
+		bufferPool.Put(b)
+	}
+}
+
+// body returns a request body reading the contents of b,
+// which references b until it's closed.
+func (b *pooledBuffer) body() io.ReadCloser {
+	b.refs.Add(1)
+	return &pooledBody{Reader: bytes.NewReader(b.Bytes()), b: b}
+}
+
+// pooledBody is a request body reading from a pooledBuffer.
+type pooledBody struct {
+	*bytes.Reader
+	b      *pooledBuffer
+	closed atomic.Bool
+}
+
+func (p *pooledBody) Close() error {
+	if p.closed.CompareAndSwap(false, true) {
+		p.b.release()
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package graphql_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+// Test that requests sent concurrently with pooled buffers keep their own bodies,
+// including when they're replayed for a redirect.
+func TestClient_Do_pooledBuffers(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, req *http.Request) {
+		http.Redirect(w, req, "/graphql", http.StatusTemporaryRedirect)
+	})
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		var in struct {
+			Variables struct{ N int }
+		}
+		mustUnmarshal(mustRead(req.Body), &in)
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, fmt.Sprintf(`{"data": {"echo": %d}}`, in.Variables.N))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL+"/old", nil)
+
+	var wg sync.WaitGroup
+	for n := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var res struct{ Echo int }
+			err := client.Do(context.Background(), "query($n:Int!){echo(n:$n)}", &res, false, map[string]any{"n": n})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if res.Echo != n {
+				t.Errorf("got echo: %v, want: %v", res.Echo, n)
+			}
+		}()
+	}
+	wg.Wait()
+}
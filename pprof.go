@@ -0,0 +1,30 @@
+package graphql
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// WithProfilerLabels makes the client run operations sent by Query, Mutate
+// and Do with pprof labels identifying them, so that CPU and goroutine
+// profiles can be sliced by operation. The labels are "graphql.operation.type"
+// and, for named operations, "graphql.operation.name". They're also set
+// on the goroutines the operation's HTTP requests start, as those
+// inherit the labels of the goroutine starting them.
+func WithProfilerLabels() ClientOption {
+	return func(c *Client) { c.profilerLabels = true }
+}
+
+// withProfilerLabels calls f with ctx, labeled for the GraphQL document query
+// if the client sets profiler labels.
+func (c *Client) withProfilerLabels(ctx context.Context, query string, f func(ctx context.Context)) {
+	if !c.profilerLabels {
+		f(ctx)
+		return
+	}
+	labels := []string{"graphql.operation.type", operationType(query)}
+	if name := operationName(query); name != "" {
+		labels = append(labels, "graphql.operation.name", name)
+	}
+	pprof.Do(ctx, pprof.Labels(labels...), f)
+}
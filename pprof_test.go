@@ -0,0 +1,34 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+func TestWithProfilerLabels(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		for key, want := range map[string]string{
+			"graphql.operation.type": "query",
+			"graphql.operation.name": "GetUser",
+		} {
+			if got, _ := pprof.Label(req.Context(), key); got != want {
+				t.Errorf("got label %s: %q, want: %q", key, got, want)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithProfilerLabels())
+
+	var res struct{ User struct{ Name graphql.String } }
+	err := client.Do(context.Background(), "query GetUser{user{name}}", &res, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,66 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Preconnect establishes n connections to the client's GraphQL server,
+// including their TLS handshakes, and leaves them idle in the transport's
+// pool, so that bursts of operations don't wait for new connections.
+// It sends n concurrent HEAD requests to the server's URL for queries, whose
+// responses don't matter, with the client's default and authentication
+// headers, so that proxies and gateways accept them. Connections are kept
+// by the transport only up to its limit of idle connections per host, which
+// is 2 for http.DefaultTransport, so the transport's MaxIdleConnsPerHost
+// should be at least n. Idle
+// connections are closed by the transport after its IdleConnTimeout,
+// so Preconnect may need to be called again before bursts.
+//
+// Over HTTP/2, a single connection is shared by all requests.
+// Preconnect returns the errors of requests that failed to connect.
+// It does nothing if n isn't positive.
+func (c *Client) Preconnect(ctx context.Context, n int) error {
+	if c.err != nil {
+		return c.err
+	}
+	if n <= 0 {
+		return nil
+	}
+	url := c.endpoint(new(callConfig), "query")
+	resps := make([]*http.Response, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			c.setHeaders(req.Header)
+			if c.tracer != nil {
+				c.tracer.Inject(ctx, req.Header)
+			}
+			resps[i], err = c.httpClient.Do(req)
+			if err != nil {
+				errs[i] = &NetworkError{Err: err}
+			}
+		}()
+	}
+	// Hold on to every connection until all are established, so that
+	// requests don't reuse each other's connections.
+	wg.Wait()
+	for _, resp := range resps {
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,99 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+func TestClient_Preconnect(t *testing.T) {
+	var conns atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"viewer": {"login": "gopher"}}}`)
+	}))
+	srv.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+	transport := &http.Transport{MaxIdleConnsPerHost: 4}
+	defer transport.CloseIdleConnections()
+	client := graphql.NewClient(srv.URL, &http.Client{Transport: transport})
+
+	if err := client.Preconnect(context.Background(), 3); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := conns.Load(), int32(3); got != want {
+		t.Errorf("got %d connections, want: %d", got, want)
+	}
+	var q struct {
+		Viewer struct{ Login graphql.String }
+	}
+	if err := client.Query(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := conns.Load(), int32(3); got != want {
+		t.Errorf("got %d connections after a query, want: %d", got, want)
+	}
+}
+
+func TestClient_Preconnect_headers(t *testing.T) {
+	var heads atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		heads.Add(1)
+		for _, h := range []struct{ key, want string }{
+			{"User-Agent", "test-agent/1.0"},
+			{"X-Tenant", "acme"},
+			{"Authorization", "Bearer token"},
+		} {
+			if got := req.Header.Get(h.key); got != h.want {
+				t.Errorf("got %s: %q, want: %q", h.key, got, h.want)
+			}
+		}
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithUserAgent("test-agent/1.0"),
+		graphql.WithHeader("X-Tenant", "acme"),
+		graphql.WithHeader("Authorization", "Bearer token"),
+	)
+	if err := client.Preconnect(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := heads.Load(), int32(2); got != want {
+		t.Errorf("got %d requests, want: %d", got, want)
+	}
+}
+
+func TestClient_Preconnect_error(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	client := graphql.NewClient(srv.URL, nil)
+	err := client.Preconnect(context.Background(), 2)
+	var ne *graphql.NetworkError
+	if !errors.As(err, &ne) {
+		t.Errorf("got error: %v, want a network error", err)
+	}
+}
+
+func TestClient_Preconnect_none(t *testing.T) {
+	client := graphql.NewClient("http://localhost:1/graphql", nil)
+	for _, n := range []int{0, -1} {
+		if err := client.Preconnect(context.Background(), n); err != nil {
+			t.Errorf("got error preconnecting %d connections: %v, want: nil", n, err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Prefetch keeps the result of the query derived from q with variables warm
+// in the client's cache, so that Query answers it from the cache rather than
+// waiting for the server, e.g., when rendering a dashboard. It sends the
+// query right away, and then every interval in the background until ctx is
+// done, refreshing the cached result each time. The interval should be
+// shorter than the cache's TTL, so that the result doesn't expire between
+// refreshes. q is only used to derive the query, and isn't populated.
+//
+// Prefetch returns the error of the first fetch, in which case it doesn't
+// keep the query warm. Errors of later fetches are reported only to the
+// client's logger, metrics and hooks, and don't stop the refreshes.
+// The client must have a cache, set by WithCache, WithCacheStore
+// or WithNormalizedCache.
+func (c *Client) Prefetch(ctx context.Context, q any, variables map[string]any, interval time.Duration, opts ...CallOption) error {
+	if c.cache == nil && c.entities == nil {
+		return errors.New("graphql: Prefetch requires a cache")
+	}
+	if interval <= 0 {
+		return errors.New("graphql: Prefetch requires a positive interval")
+	}
+	query := constructQuery(q, variables)
+	opts = append(opts[:len(opts):len(opts)], WithCacheRefresh())
+	if err := c.Do(ctx, query, nil, false, variables, opts...); err != nil {
+		return err
+	}
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				c.Do(ctx, query, nil, false, variables, opts...)
+			}
+		}
+	}()
+	return nil
+}
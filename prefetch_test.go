@@ -0,0 +1,66 @@
+package graphql_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/isihu/graphql"
+)
+
+func TestClient_Prefetch(t *testing.T) {
+	var calls atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, fmt.Sprintf(`{"data": {"stats": {"version": %d}}}`, n))
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithCache(time.Hour))
+	var q struct {
+		Stats struct{ Version graphql.Int }
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := client.Prefetch(ctx, &q, nil, 5*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Stats.Version, graphql.Int(0); got != want {
+		t.Errorf("got version: %v after Prefetch, want: %v", got, want)
+	}
+	for deadline := time.Now().Add(time.Second); calls.Load() < 3; {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d requests, want refreshes", calls.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := client.Query(context.Background(), &q, nil); err != nil {
+		t.Fatal(err)
+	}
+	if q.Stats.Version < 2 {
+		t.Errorf("got version: %v, want a refreshed one", q.Stats.Version)
+	}
+
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	n := calls.Load()
+	time.Sleep(20 * time.Millisecond)
+	if got := calls.Load(); got != n {
+		t.Errorf("got %d requests after ctx was done, want: %d", got, n)
+	}
+}
+
+func TestClient_Prefetch_noCache(t *testing.T) {
+	client := graphql.NewClient("/graphql", nil)
+	var q struct {
+		Viewer struct{ Login graphql.String }
+	}
+	if err := client.Prefetch(context.Background(), &q, nil, time.Minute); err == nil {
+		t.Error("got no error from Prefetch without a cache")
+	}
+}
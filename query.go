@@ -6,10 +6,25 @@ import (
 	"io"
 	"reflect"
 	"sort"
+	"strings"
+	"sync"
 
+	"github.com/isihu/graphql/ast"
 	"github.com/isihu/graphql/ident"
 )
 
+// ConstructQuery returns the query document that Client.Query sends for q
+// with variables, e.g., to inspect it, or compare it to a golden file.
+func ConstructQuery(q any, variables map[string]any) string {
+	return constructQuery(q, variables)
+}
+
+// ConstructMutation returns the mutation document that Client.Mutate sends
+// for m with variables.
+func ConstructMutation(m any, variables map[string]any) string {
+	return constructMutation(m, variables)
+}
+
 func constructQuery(v any, variables map[string]any) string {
 	query := query(v)
 	if len(variables) > 0 {
@@ -26,6 +41,14 @@ func constructMutation(v any, variables map[string]any) string {
 	return "mutation" + query
 }
 
+func constructSubscription(v any, variables map[string]any) string {
+	query := query(v)
+	if len(variables) > 0 {
+		return "subscription(" + queryArguments(variables) + ")" + query
+	}
+	return "subscription" + query
+}
+
 // queryArguments constructs a minified arguments string for variables.
 //
 // E.g., map[string]any{"a": Int(123), "b": NewBoolean(true)} -> "$a:Int!$b:Boolean".
@@ -43,7 +66,7 @@ func queryArguments(variables map[string]any) string {
 		io.WriteString(&buf, "$")
 		io.WriteString(&buf, k)
 		io.WriteString(&buf, ":")
-		writeArgumentType(&buf, reflect.TypeOf(variables[k]), true)
+		io.WriteString(&buf, argumentType(reflect.TypeOf(variables[k])))
 		// Don't insert a comma here.
 		// Commas in GraphQL are insignificant, and we want minified output.
 		// See https://spec.graphql.org/October2021/#sec-Insignificant-Commas.
@@ -51,6 +74,21 @@ func queryArguments(variables map[string]any) string {
 	return buf.String()
 }
 
+// argumentTypes caches the GraphQL types of variables by their Go types.
+var argumentTypes sync.Map // map[reflect.Type]string
+
+// argumentType returns the minified GraphQL type of a variable of type t,
+// memoized so that steady-state calls skip reflection.
+func argumentType(t reflect.Type) string {
+	if s, ok := argumentTypes.Load(t); ok {
+		return s.(string)
+	}
+	var buf bytes.Buffer
+	writeArgumentType(&buf, t, true)
+	s, _ := argumentTypes.LoadOrStore(t, buf.String())
+	return s.(string)
+}
+
 // writeArgumentType writes a minified GraphQL type for t to w.
 // value indicates whether t is a value (required) type or pointer (optional) type.
 // If value is true, then "!" is written at the end of t.
@@ -87,11 +125,24 @@ func writeArgumentType(w io.Writer, t reflect.Type, value bool) {
 //
 // E.g., struct{Foo Int, BarBaz *Boolean} -> "{foo,barBaz}".
 func query(v any) string {
+	return queryOf(reflect.TypeOf(v))
+}
+
+// queryOf returns the query of the type t, as query does.
+func queryOf(t reflect.Type) string {
+	if q, ok := queries.Load(t); ok {
+		return q.(string)
+	}
 	var buf bytes.Buffer
-	writeQuery(&buf, reflect.TypeOf(v), false)
-	return buf.String()
+	writeQuery(&buf, t, false)
+	q, _ := queries.LoadOrStore(t, buf.String())
+	return q.(string)
 }
 
+// queries caches the queries of types by type, since constructing them
+// reflects on the whole type, and the same types are used over and over.
+var queries sync.Map // map[reflect.Type]string
+
 // writeQuery writes a minified query for t to w.
 // If inline is true, the struct fields of t are inlined into parent struct.
 func writeQuery(w io.Writer, t reflect.Type, inline bool) {
@@ -129,3 +180,77 @@ func writeQuery(w io.Writer, t reflect.Type, inline bool) {
 }
 
 var jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// operationType reports the type of the operation in the GraphQL document query,
+// which is one of "query", "mutation" or "subscription".
+// A query shorthand document such as "{viewer{login}}" reports "query".
+func operationType(query string) string {
+	return namedOperationType(query, "")
+}
+
+// namedOperationType is like operationType, but reports the type of the
+// operation called name if it isn't empty, for documents with several.
+func namedOperationType(query, name string) string {
+	trimmed := strings.TrimLeft(query, " \t\r\n,")
+	if name != "" || !startsWithOperation(trimmed) {
+		return parsedOperation(trimmed, name).typ
+	}
+	for _, typ := range [...]string{"mutation", "subscription"} {
+		if strings.HasPrefix(trimmed, typ) {
+			return typ
+		}
+	}
+	return "query"
+}
+
+// operationName returns the name of the operation in the GraphQL document query,
+// or "" if it's anonymous, such as "GetUser" for "query GetUser($id:ID!){...}".
+func operationName(query string) string {
+	query = strings.TrimLeft(query, " \t\r\n,")
+	if !startsWithOperation(query) {
+		return parsedOperation(query, "").name
+	}
+	typ := operationType(query)
+	if !strings.HasPrefix(query, typ) {
+		// Query shorthand.
+		return ""
+	}
+	query = strings.TrimLeft(query[len(typ):], " \t\r\n,")
+	end := strings.IndexFunc(query, func(r rune) bool {
+		return r != '_' && !('a' <= r && r <= 'z') && !('A' <= r && r <= 'Z') && !('0' <= r && r <= '9')
+	})
+	if end == -1 {
+		end = len(query)
+	}
+	return query[:end]
+}
+
+// startsWithOperation reports whether the GraphQL document query, without
+// leading white space, starts with its operation, as the documents that
+// Query, Mutate and SubscribeStruct construct do, rather than with a comment
+// or a fragment.
+func startsWithOperation(query string) bool {
+	return strings.HasPrefix(query, "{") || strings.HasPrefix(query, "query") ||
+		strings.HasPrefix(query, "mutation") || strings.HasPrefix(query, "subscription")
+}
+
+// documentOperation is the type and name of the operation in a document.
+type documentOperation struct {
+	typ, name string
+}
+
+// parsedOperation returns the type and name of the operation called name in
+// the GraphQL document query, or of its first operation if name is empty,
+// parsing it. Documents that don't parse or lack the operation are queries.
+func parsedOperation(query, name string) documentOperation {
+	doc, err := ast.Parse(query)
+	if err != nil {
+		return documentOperation{typ: "query", name: name}
+	}
+	for _, op := range doc.Operations() {
+		if name == "" || op.Name == name {
+			return documentOperation{typ: op.Operation, name: op.Name}
+		}
+	}
+	return documentOperation{typ: "query", name: name}
+}
@@ -2,6 +2,7 @@ package graphql
 
 import (
 	"net/url"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -271,6 +272,38 @@ func TestConstructMutation(t *testing.T) {
 	}
 }
 
+func TestConstructSubscription(t *testing.T) {
+	tests := []struct {
+		inV         any
+		inVariables map[string]any
+		want        string
+	}{
+		{
+			inV: struct {
+				CommentAdded struct {
+					Body String
+				} `graphql:"commentAdded(issueId:$issueId)"`
+			}{},
+			inVariables: map[string]any{
+				"issueId": ID("MDU6SXNzdWUyMzE1MjcyNzk="),
+			},
+			want: `subscription($issueId:ID!){commentAdded(issueId:$issueId){body}}`,
+		},
+		{
+			inV: struct {
+				Tick Int
+			}{},
+			want: `subscription{tick}`,
+		},
+	}
+	for _, tc := range tests {
+		got := constructSubscription(tc.inV, tc.inVariables)
+		if got != tc.want {
+			t.Errorf("\ngot:  %q\nwant: %q\n", got, tc.want)
+		}
+	}
+}
+
 func TestQueryArguments(t *testing.T) {
 	tests := []struct {
 		in   map[string]any
@@ -322,6 +355,67 @@ func TestQueryArguments(t *testing.T) {
 	}
 }
 
+// Test that documents are constructed from cached pieces once a type has been seen.
+func TestConstructQuery_memoized(t *testing.T) {
+	var q struct {
+		Viewer struct {
+			Login     String
+			CreatedAt DateTime
+		}
+	}
+	variables := map[string]any{"id": ID("someID")}
+	want := constructQuery(&q, variables)
+	if got := constructQuery(&q, variables); got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { constructQuery(&q, nil) }); allocs != 0 {
+		t.Errorf("got %v allocations constructing a query of a seen type, want: 0", allocs)
+	}
+	var other struct{ Viewer struct{ Login String } }
+	if got, want := constructQuery(&other, nil), "{viewer{login}}"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
+
+func TestOperationType(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: `{viewer{login}}`, want: "query"},
+		{in: `query($id:ID!){node(id:$id){id}}`, want: "query"},
+		{in: `mutation{addStar(input:{}){clientMutationId}}`, want: "mutation"},
+		{in: `  subscription{issueUpdated{title}}`, want: "subscription"},
+		{in: "# Stars a repository.\nmutation{addStar(input:{}){clientMutationId}}", want: "mutation"},
+		{in: `fragment F on User{login} mutation{follow(login:"x"){user{...F}}}`, want: "mutation"},
+	}
+	for _, tc := range tests {
+		if got := operationType(tc.in); got != tc.want {
+			t.Errorf("operationType(%q): got %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestNamedOperationType(t *testing.T) {
+	const doc = `query GetUser{user{name}} mutation Follow{follow(login:"x"){ok}}`
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "", want: "query"},
+		{name: "GetUser", want: "query"},
+		{name: "Follow", want: "mutation"},
+	}
+	for _, tc := range tests {
+		if got := namedOperationType(doc, tc.name); got != tc.want {
+			t.Errorf("namedOperationType(%q): got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+	if got, want := namedOperationType(`mutation Follow{follow(login:"x"){ok}}`, "Follow"), "mutation"; got != want {
+		t.Errorf("got type: %q, want: %q", got, want)
+	}
+}
+
 // Custom GraphQL types for testing.
 type (
 	// DateTime is an ISO-8601 encoded UTC date.
@@ -365,3 +459,20 @@ type AddReactionInput struct {
 	// A unique identifier for the client performing the mutation. (Optional.)
 	ClientMutationID *String `json:"clientMutationId,omitempty"`
 }
+
+func TestClient_Warmup(t *testing.T) {
+	type warmQuery struct {
+		Viewer struct {
+			Login  String
+			Issues []struct{ Title String } `graphql:"issues(first:10)"`
+		}
+	}
+	NewClient("/graphql", nil).Warmup(warmQuery{})
+	q, ok := queries.Load(reflect.TypeOf(&warmQuery{}))
+	if !ok {
+		t.Fatal("got no query for a warmed-up type")
+	}
+	if got, want := q, "{viewer{login,issues(first:10){title}}}"; got != want {
+		t.Errorf("got: %q, want: %q", got, want)
+	}
+}
@@ -0,0 +1,139 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// WithRateLimitBudget makes a Paginator spend the server's rate limit budget
+// carefully, keeping at least reserve points of it for other uses. Before
+// fetching a page whose estimated cost would leave less than reserve points,
+// it waits until the budget resets, if that's within maxWait, and otherwise
+// stops with ErrRateLimitBudget.
+//
+// The budget is read after each page from the rateLimit field of the query,
+// if it selects one, as GitHub's API has:
+//
+//	RateLimit struct {
+//		Cost      int
+//		Remaining int
+//		ResetAt   time.Time
+//	}
+//
+// and otherwise from the X-RateLimit-Remaining and X-RateLimit-Reset headers
+// of the response, the latter in seconds since the Unix epoch. The cost of
+// a page is estimated as that of the last one, which is the cost field of
+// rateLimit, if selected, or else by how much the budget dropped, or 1.
+func WithRateLimitBudget(reserve int, maxWait time.Duration) PageOption {
+	return func(p *Paginator) {
+		p.budget = &rateBudget{reserve: reserve, maxWait: maxWait, cost: 1}
+	}
+}
+
+// ErrRateLimitBudget is reported by a Paginator that stopped because fetching
+// the next page would have left less of the rate limit budget than reserved
+// by WithRateLimitBudget, and the budget didn't reset soon enough.
+var ErrRateLimitBudget = errors.New("graphql: pagination stopped to preserve the rate limit budget")
+
+// rateBudget tracks the rate limit budget of a Paginator.
+type rateBudget struct {
+	reserve int
+	maxWait time.Duration
+
+	path   []int       // Indices of the fields leading to the query's rateLimit field, if any.
+	header http.Header // Header of the response to the last page, if any.
+
+	known     bool // Whether the budget is known.
+	remaining int
+	cost      int       // Estimated cost of a page.
+	resetAt   time.Time // When the budget resets, if known.
+}
+
+// wait waits until the budget allows fetching a page, if needed.
+func (b *rateBudget) wait(ctx context.Context) error {
+	if !b.known || b.remaining-b.cost >= b.reserve {
+		return nil
+	}
+	d := time.Until(b.resetAt)
+	if b.resetAt.IsZero() || d > b.maxWait {
+		return ErrRateLimitBudget
+	}
+	if d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	// The budget has been reset, but it's unknown by how much.
+	b.known = false
+	return nil
+}
+
+// update reads the budget after a page was fetched into the query struct v.
+func (b *rateBudget) update(v reflect.Value) {
+	remaining, cost, resetAt, ok := b.readField(v)
+	if !ok {
+		remaining, resetAt, ok = readRateLimitHeader(b.header)
+	}
+	if !ok {
+		b.known = false
+		return
+	}
+	switch {
+	case cost > 0:
+		b.cost = cost
+	case b.known && remaining < b.remaining:
+		b.cost = b.remaining - remaining
+	}
+	b.known, b.remaining, b.resetAt = true, remaining, resetAt
+}
+
+// readField reads the budget from the rateLimit field of the query struct v,
+// and reports whether it has one with the remaining budget. cost is 0 if it
+// doesn't select it.
+func (b *rateBudget) readField(v reflect.Value) (remaining, cost int, resetAt time.Time, ok bool) {
+	if b.path == nil {
+		return 0, 0, time.Time{}, false
+	}
+	v, ok = fieldAt(v, b.path)
+	if !ok {
+		return 0, 0, time.Time{}, false
+	}
+	f, ok := fieldByName(v, "remaining")
+	if !ok || !f.CanInt() {
+		return 0, 0, time.Time{}, false
+	}
+	remaining = int(f.Int())
+	if f, ok := fieldByName(v, "cost"); ok && f.CanInt() {
+		cost = int(f.Int())
+	}
+	if f, ok := fieldByName(v, "resetAt"); ok {
+		switch timeType := reflect.TypeFor[time.Time](); {
+		case f.Type().ConvertibleTo(timeType):
+			resetAt = f.Convert(timeType).Interface().(time.Time)
+		case f.Kind() == reflect.String:
+			resetAt, _ = time.Parse(time.RFC3339, f.String())
+		}
+	}
+	return remaining, cost, resetAt, true
+}
+
+// readRateLimitHeader reads the budget from the X-RateLimit-Remaining and
+// X-RateLimit-Reset headers in h, and reports whether h has the former.
+func readRateLimitHeader(h http.Header) (remaining int, resetAt time.Time, ok bool) {
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	if secs, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		resetAt = time.Unix(secs, 0)
+	}
+	return remaining, resetAt, true
+}
@@ -0,0 +1,114 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/isihu/graphql"
+)
+
+// budgetHandler serves a connection of total issues, one per page, each
+// costing cost points of a rate limit budget of limit points, which resets
+// every time the reset time returned by resetAt passes. The budget is
+// reported in the rateLimit field, or in headers if header is true.
+func budgetHandler(total, cost, limit int, resetAt func() time.Time, header bool) (http.Handler, *int) {
+	var requests int
+	remaining := limit
+	reset := resetAt()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		var in struct {
+			Variables map[string]any
+		}
+		mustUnmarshal(mustRead(req.Body), &in)
+		requests++
+		if time.Now().After(reset) {
+			remaining, reset = limit, resetAt()
+		}
+		remaining -= cost
+		after := 0
+		if c, ok := in.Variables["after"].(string); ok {
+			fmt.Sscan(c, &after)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if header {
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+			mustWrite(w, fmt.Sprintf(`{"data": {"repository": {"issues": {"nodes": [{"number": %d}], "pageInfo": {"endCursor": "%d", "hasNextPage": %t}}}}}`,
+				after+1, after+1, after+1 < total))
+			return
+		}
+		mustWrite(w, fmt.Sprintf(`{"data": {"rateLimit": {"cost": %d, "remaining": %d, "resetAt": %q}, "repository": {"issues": {"nodes": [{"number": %d}], "pageInfo": {"endCursor": "%d", "hasNextPage": %t}}}}}`,
+			cost, remaining, reset.Format(time.RFC3339Nano), after+1, after+1, after+1 < total))
+	})
+	return mux, &requests
+}
+
+type budgetQuery struct {
+	RateLimit struct {
+		Cost      int
+		Remaining int
+		ResetAt   time.Time
+	}
+	issuesQuery
+}
+
+func TestWithRateLimitBudget_stop(t *testing.T) {
+	handler, requests := budgetHandler(10, 2, 10, func() time.Time { return time.Now().Add(time.Hour) }, false)
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}})
+
+	var q budgetQuery
+	p := client.Paginate(&q, nil, graphql.WithRateLimitBudget(3, time.Second))
+	for p.Next(context.Background()) {
+	}
+	if err := p.Err(); !errors.Is(err, graphql.ErrRateLimitBudget) {
+		t.Fatalf("got error: %v, want: %v", err, graphql.ErrRateLimitBudget)
+	}
+	// Remaining after each page: 8, 6, 4; a fourth page would leave 2.
+	if got, want := *requests, 3; got != want {
+		t.Errorf("got %d requests, want: %d", got, want)
+	}
+}
+
+func TestWithRateLimitBudget_wait(t *testing.T) {
+	handler, requests := budgetHandler(4, 1, 2, func() time.Time { return time.Now().Add(50 * time.Millisecond) }, false)
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}})
+
+	var q budgetQuery
+	p := client.Paginate(&q, nil, graphql.WithRateLimitBudget(0, time.Second))
+	start := time.Now()
+	for p.Next(context.Background()) {
+	}
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := *requests, 4; got != want {
+		t.Errorf("got %d requests, want: %d", got, want)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("got pagination done in %v, want it to wait for the budget to reset", elapsed)
+	}
+}
+
+func TestWithRateLimitBudget_header(t *testing.T) {
+	handler, requests := budgetHandler(10, 3, 10, func() time.Time { return time.Now().Add(time.Hour) }, true)
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: handler}})
+
+	var q issuesQuery
+	p := client.Paginate(&q, nil, graphql.WithRateLimitBudget(0, 0))
+	for p.Next(context.Background()) {
+	}
+	if err := p.Err(); !errors.Is(err, graphql.ErrRateLimitBudget) {
+		t.Fatalf("got error: %v, want: %v", err, graphql.ErrRateLimitBudget)
+	}
+	// Remaining after each page: 7, 4, 1; the cost of a page is estimated
+	// from the second page on.
+	if got, want := *requests, 3; got != want {
+		t.Errorf("got %d requests, want: %d", got, want)
+	}
+}
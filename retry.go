@@ -0,0 +1,190 @@
+package graphql
+
+import (
+	"context"
+	crand "crypto/rand"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how operations that fail to get
+// a response from the server are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one.
+	MaxAttempts int
+
+	// MinBackoff is the delay before the first retry. It doubles for
+	// every following retry, up to MaxBackoff. Actual delays are randomized
+	// between half and all of the computed delay. Zero means 100ms.
+	MinBackoff time.Duration
+
+	// MaxBackoff is the maximum delay between attempts. Zero means 10s.
+	MaxBackoff time.Duration
+
+	// Retryable reports whether an attempt that failed with err should be retried.
+	// If nil, IsRetryable is used.
+	Retryable func(err error) bool
+}
+
+// WithRetry makes the client retry operations that fail according to policy.
+// Mutations are retried only if they have an idempotency key;
+// see WithIdempotencyKey and WithIdempotencyKeys.
+// When retries are enabled, such operations fail with a *RetryError,
+// which records every attempt that was made.
+func WithRetry(policy RetryPolicy) ClientOption {
+	policy.setDefaults(IsRetryable)
+	return func(c *Client) { c.retry = &policy }
+}
+
+// setDefaults sets unset fields of p to their defaults,
+// with retryable as the default Retryable.
+func (p *RetryPolicy) setDefaults(retryable func(err error) bool) {
+	if p.MinBackoff == 0 {
+		p.MinBackoff = 100 * time.Millisecond
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = 10 * time.Second
+	}
+	if p.Retryable == nil {
+		p.Retryable = retryable
+	}
+}
+
+// Attempt records a single attempt at sending an operation.
+// If the context is done while waiting to retry, that's recorded
+// as a final attempt with zero Duration and the context's error.
+type Attempt struct {
+	Start      time.Time     // When the attempt started.
+	Duration   time.Duration // How long the attempt took.
+	StatusCode int           // HTTP status code of the response, or 0 if there wasn't one.
+	Err        error         // Why the attempt failed, or nil if it succeeded.
+}
+
+// RetryError is returned when retries are enabled and an operation fails,
+// either after exhausting all attempts, or because its failure wasn't retryable.
+// It unwraps to the error of the last attempt.
+type RetryError struct {
+	Attempts []Attempt // All attempts that were made, in order. Non-empty.
+}
+
+func (e *RetryError) Error() string {
+	first, last := e.Attempts[0], e.Attempts[len(e.Attempts)-1]
+	elapsed := last.Start.Add(last.Duration).Sub(first.Start)
+	if len(e.Attempts) == 1 {
+		return fmt.Sprintf("failed after 1 attempt: %v", last.Err)
+	}
+	return fmt.Sprintf("failed after %d attempts over %v: %v", len(e.Attempts), elapsed.Round(time.Millisecond), last.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Attempts[len(e.Attempts)-1].Err
+}
+
+// sendWithRetry sends in to url, retrying according to c.retry.
+func (c *Client) sendWithRetry(ctx context.Context, cfg *callConfig, url string, in request) (*response, error) {
+	if c.retry == nil {
+		return c.sendOnce(ctx, cfg, url, in)
+	}
+	var attempts []Attempt
+	for {
+		start := time.Now()
+		out, err := c.sendOnce(ctx, cfg, url, in)
+		if err == nil && out.Data == nil && out.Errors.Retryable() {
+			// The server failed the operation as a whole, but only transiently.
+			err = out.Errors
+		}
+		a := Attempt{Start: start, Duration: time.Since(start), Err: err}
+		if se, ok := err.(*HTTPError); ok {
+			a.StatusCode = se.StatusCode
+		} else if out != nil {
+			a.StatusCode = http.StatusOK
+		}
+		attempts = append(attempts, a)
+		if err == nil {
+			return out, nil
+		}
+		if len(attempts) >= c.retry.MaxAttempts || ctx.Err() != nil || !c.retry.Retryable(err) ||
+			// Retrying a mutation is only safe if the server can detect repeats.
+			namedOperationType(in.Query, in.OperationName) == "mutation" && cfg.idempotencyKey == "" {
+			return nil, &RetryError{Attempts: attempts}
+		}
+		t := time.NewTimer(c.retry.backoff(len(attempts), err))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			attempts = append(attempts, Attempt{Start: time.Now(), Err: ctx.Err()})
+			return nil, &RetryError{Attempts: attempts}
+		case <-t.C:
+		}
+	}
+}
+
+// newIdempotencyKey returns a new random idempotency key,
+// formatted as a version 4 UUID.
+func newIdempotencyKey() string {
+	var b [16]byte
+	crand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40 // Version 4.
+	b[8] = b[8]&0x3f | 0x80 // Variant 10.
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// backoff returns the delay before retrying after the given number of
+// failed attempts, the last of which failed with err.
+// A Retry-After header in the response, if any, takes precedence.
+func (p *RetryPolicy) backoff(attempts int, err error) time.Duration {
+	if se, ok := err.(*HTTPError); ok {
+		if secs, err := strconv.Atoi(se.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			return min(time.Duration(secs)*time.Second, p.MaxBackoff)
+		}
+	}
+	d := p.MaxBackoff
+	if shift := attempts - 1; shift < 32 {
+		d = min(p.MinBackoff<<shift, p.MaxBackoff)
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// IsRetryable is the default RetryPolicy.Retryable. It reports whether err,
+// or an error it wraps, has a Retryable method that returns true.
+// The client's errors implement it as follows: network errors are
+// retryable, as are 429, 502, 503 and 504 status codes, and GraphQL errors
+// whose codes all indicate that the server is throttling or unavailable.
+func IsRetryable(err error) bool {
+	var r interface{ Retryable() bool }
+	return errors.As(err, &r) && r.Retryable()
+}
+
+// Retryable reports whether the status code is one that retrying may resolve.
+func (e *HTTPError) Retryable() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Retryable reports true, as network errors are typically transient.
+func (e *NetworkError) Retryable() bool { return true }
+
+// retryableCodes are the error codes that servers use to report that they're
+// throttling requests or temporarily unavailable.
+var retryableCodes = map[string]bool{
+	"THROTTLED":           true, // Shopify.
+	"RATE_LIMITED":        true,
+	"SERVICE_UNAVAILABLE": true,
+}
+
+// Retryable reports whether every error has a code in retryableCodes.
+func (e Errors) Retryable() bool {
+	for _, err := range e {
+		if !retryableCodes[err.Code()] {
+			return false
+		}
+	}
+	return len(e) > 0
+}
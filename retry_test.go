@@ -0,0 +1,214 @@
+package graphql_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/isihu/graphql"
+)
+
+func TestClient_Query_retry(t *testing.T) {
+	var calls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		if calls < 3 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithRetry(graphql.RetryPolicy{MaxAttempts: 5, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}))
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+	err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := calls, 3; got != want {
+		t.Errorf("got calls: %v, want: %v", got, want)
+	}
+	if got, want := q.User.Name, "Gopher"; got != want {
+		t.Errorf("got q.User.Name: %q, want: %q", got, want)
+	}
+}
+
+func TestClient_Query_retryHistory(t *testing.T) {
+	var calls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		if calls == 1 {
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithRetry(graphql.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}))
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+	err := client.Query(context.Background(), &q, nil)
+	var re *graphql.RetryError
+	if !errors.As(err, &re) {
+		t.Fatalf("got error: %v, want *graphql.RetryError", err)
+	}
+	var got []int
+	for _, a := range re.Attempts {
+		if a.Err == nil {
+			t.Error("got attempt with nil Err")
+		}
+		if a.Start.IsZero() {
+			t.Error("got attempt with zero Start")
+		}
+		got = append(got, a.StatusCode)
+	}
+	if want := []int{429, 503, 503}; !slices.Equal(got, want) {
+		t.Errorf("got attempt status codes: %v, want: %v", got, want)
+	}
+	if got, want := errors.Unwrap(err).Error(), `non-200 OK status code: 503 Service Unavailable body: "unavailable\n"`; got != want {
+		t.Errorf("got unwrapped error: %v, want: %v", got, want)
+	}
+}
+
+// Test that errors that aren't retryable aren't retried.
+func TestClient_Query_retryNotRetryable(t *testing.T) {
+	var calls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		http.Error(w, "bad request", http.StatusBadRequest)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithRetry(graphql.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}))
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+	err := client.Query(context.Background(), &q, nil)
+	var re *graphql.RetryError
+	if !errors.As(err, &re) {
+		t.Fatalf("got error: %v, want *graphql.RetryError", err)
+	}
+	if got, want := len(re.Attempts), 1; got != want {
+		t.Errorf("got %v attempts, want: %v", got, want)
+	}
+	if got, want := calls, 1; got != want {
+		t.Errorf("got calls: %v, want: %v", got, want)
+	}
+}
+
+func TestClient_Mutate_retryRequiresIdempotencyKey(t *testing.T) {
+	var calls int
+	var keys []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		keys = append(keys, req.Header.Get("Idempotency-Key"))
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+	retry := graphql.WithRetry(graphql.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+
+	var m struct {
+		AddStar struct {
+			ClientMutationID string
+		} `graphql:"addStar(input:{starrableId:\"R_1\"})"`
+	}
+
+	// Without an idempotency key, the mutation isn't retried.
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, retry)
+	err := client.Mutate(context.Background(), &m, nil)
+	if err == nil {
+		t.Fatal("got error: nil, want: non-nil")
+	}
+	if got, want := keys, []string{""}; !slices.Equal(got, want) {
+		t.Errorf("got keys: %q, want: %q", got, want)
+	}
+
+	// With a caller-supplied key, it's retried with the same key.
+	calls, keys = 0, nil
+	err = client.Mutate(context.Background(), &m, nil, graphql.WithIdempotencyKey("key-1"))
+	if err == nil {
+		t.Fatal("got error: nil, want: non-nil")
+	}
+	if got, want := keys, []string{"key-1", "key-1", "key-1"}; !slices.Equal(got, want) {
+		t.Errorf("got keys: %q, want: %q", got, want)
+	}
+
+	// With generated keys, it's retried with the same generated key.
+	calls, keys = 0, nil
+	client = graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, retry, graphql.WithIdempotencyKeys(""))
+	err = client.Mutate(context.Background(), &m, nil)
+	if err == nil {
+		t.Fatal("got error: nil, want: non-nil")
+	}
+	if got, want := calls, 3; got != want {
+		t.Fatalf("got calls: %v, want: %v", got, want)
+	}
+	if keys[0] == "" || keys[0] != keys[1] || keys[1] != keys[2] {
+		t.Errorf("got keys: %q, want the same non-empty generated key", keys)
+	}
+}
+
+func TestClient_Query_retryThrottled(t *testing.T) {
+	var calls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		if calls < 2 {
+			mustWrite(w, `{"errors": [{"message": "Throttled", "extensions": {"code": "THROTTLED"}}]}`)
+			return
+		}
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	})
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}},
+		graphql.WithRetry(graphql.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}))
+
+	var q struct {
+		User struct {
+			Name graphql.String
+		}
+	}
+	err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := calls, 2; got != want {
+		t.Errorf("got %d calls, want: %d", got, want)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{err: &graphql.NetworkError{Err: errors.New("connection reset")}, want: true},
+		{err: graphql.Errors{{Message: "Throttled", Extensions: map[string]any{"code": "THROTTLED"}}}, want: true},
+		{err: graphql.Errors{{Message: "Throttled", Extensions: map[string]any{"code": "THROTTLED"}}, {Message: "Not found"}}, want: false},
+		{err: graphql.Errors{{Message: "Forbidden", Extensions: map[string]any{"code": "FORBIDDEN"}}}, want: false},
+		{err: &graphql.DecodeError{Err: errors.New("unexpected EOF")}, want: false},
+		{err: errors.New("other"), want: false},
+	} {
+		if got := graphql.IsRetryable(tc.err); got != tc.want {
+			t.Errorf("%v: got IsRetryable: %v, want: %v", tc.err, got, tc.want)
+		}
+	}
+}
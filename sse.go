@@ -0,0 +1,270 @@
+package graphql
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sseTokenHeader carries the reservation token of an event stream
+// in single connection mode.
+const sseTokenHeader = "X-GraphQL-Event-Stream-Token"
+
+// sseStopTimeout limits how long stopping an operation
+// in single connection mode may take.
+const sseStopTimeout = 5 * time.Second
+
+// connectSSE starts an operation with in over Server-Sent Events
+// in distinct connections mode. It returns a function that receives
+// the operation's results and delivers them to s.
+func (c *Client) connectSSE(ctx context.Context, url string, in request, s *Subscription) (recv func() error, err error) {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.sseRequest(ctx, http.MethodPost, url, "", s.lastEventID, body, http.StatusOK)
+	if err != nil {
+		c.subscriptionHooks.transportError(url, err)
+		return nil, err
+	}
+	c.subscriptionHooks.connected(url)
+	return func() error {
+		defer resp.Body.Close()
+		m := startKeepAlive(c.subscriptionKeepAlive, nil, func() { resp.Body.Close() })
+		err := m.finish(recvSSE(ctx, activityReader{resp.Body, m}, "", s))
+		if ctx.Err() == nil {
+			c.subscriptionHooks.transportError(url, err)
+		}
+		return err
+	}, nil
+}
+
+// connectSSESingleConnection starts an operation with in over Server-Sent Events
+// in single connection mode, reserving a stream for it and then starting the
+// operation on that stream. It returns a function that receives the operation's
+// results and delivers them to s.
+func (c *Client) connectSSESingleConnection(ctx context.Context, u string, in request, s *Subscription) (recv func() error, err error) {
+	defer func() {
+		if err != nil {
+			c.subscriptionHooks.transportError(u, err)
+		}
+	}()
+	resp, err := c.sseRequest(ctx, http.MethodPut, u, "", "", nil, http.StatusCreated)
+	if err != nil {
+		return nil, err
+	}
+	token, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := c.sseRequest(ctx, http.MethodGet, u, string(token), "", nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	c.subscriptionHooks.connected(u)
+	id := newIdempotencyKey()
+	in.Extensions = maps.Clone(in.Extensions)
+	if in.Extensions == nil {
+		in.Extensions = make(map[string]any)
+	}
+	in.Extensions["operationId"] = id
+	body, err := json.Marshal(in)
+	if err == nil {
+		resp, err = c.sseRequest(ctx, http.MethodPost, u, string(token), "", body, http.StatusAccepted)
+	}
+	if err != nil {
+		stream.Body.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+
+	return func() error {
+		defer stream.Body.Close()
+		m := startKeepAlive(c.subscriptionKeepAlive, nil, func() { stream.Body.Close() })
+		err := m.finish(recvSSE(ctx, activityReader{stream.Body, m}, id, s))
+		if ctx.Err() == nil {
+			c.subscriptionHooks.transportError(u, err)
+		} else {
+			// Tell the server to stop the operation.
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sseStopTimeout)
+			defer cancel()
+			resp, err := c.sseRequest(ctx, http.MethodDelete, u+querySeparator(u)+"operationId="+url.QueryEscape(id), string(token), "", nil, http.StatusOK)
+			if err == nil {
+				resp.Body.Close()
+			}
+		}
+		return err
+	}, nil
+}
+
+// querySeparator returns the separator for appending a query parameter to u.
+func querySeparator(u string) string {
+	if strings.Contains(u, "?") {
+		return "&"
+	}
+	return "?"
+}
+
+// sseRequest makes a request of the graphql-sse protocol, with body as
+// a JSON request body if it's non-nil, token as the reservation token
+// if it's non-empty, and lastEventID as the ID of the last event received
+// before reconnecting if it's non-empty. It returns an error if the response
+// status code isn't want. The caller must close the response body.
+func (c *Client) sseRequest(ctx context.Context, method, url, token, lastEventID string, body []byte, want int) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if method == http.MethodGet || method == http.MethodPost && token == "" {
+		// The response is an event stream.
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	c.setHeaders(req.Header)
+	if token != "" {
+		req.Header.Set(sseTokenHeader, token)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, &NetworkError{Err: err}
+	}
+	if resp.StatusCode != want {
+		defer resp.Body.Close()
+		return nil, newHTTPError(resp, resp.Body)
+	}
+	return resp, nil
+}
+
+// recvSSE reads events from body and delivers their results to s, until the
+// operation completes, fails, or ctx is done. In single connection mode, id is
+// the ID of the operation, and events of other operations are skipped.
+// In distinct connections mode, id is empty, and the ID of the last event is
+// recorded in s, so that the stream resumes from it if it's reconnected.
+func recvSSE(ctx context.Context, body io.Reader, id string, s *Subscription) error {
+	events := newSSEReader(body)
+	if id == "" {
+		events.lastID = s.lastEventID
+	}
+	for {
+		ev, err := events.next()
+		if err == io.EOF {
+			return errUnexpectedEnd
+		} else if err != nil {
+			return err
+		}
+		if id == "" {
+			s.lastEventID = ev.ID
+		}
+		if ev.Event != "next" && ev.Event != "complete" {
+			continue
+		}
+		out := new(response)
+		if id != "" {
+			// Single connection mode events are wrapped with the operation ID.
+			var msg struct {
+				ID      string    `json:"id"`
+				Payload *response `json:"payload"`
+			}
+			msg.Payload = out
+			err = json.Unmarshal(ev.Data, &msg)
+			if err != nil {
+				return err
+			}
+			if msg.ID != id {
+				continue
+			}
+		} else if ev.Event == "next" {
+			err = json.Unmarshal(ev.Data, out)
+			if err != nil {
+				return err
+			}
+		}
+		if ev.Event == "complete" {
+			return nil
+		}
+		err = s.deliver(ctx, out)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// sseEvent is a Server-Sent Event.
+type sseEvent struct {
+	Event string // Event type. Empty means "message".
+	Data  []byte
+	ID    string // Last event ID.
+}
+
+// sseReader reads Server-Sent Events from an event stream.
+// Lines terminated by a lone carriage return aren't supported.
+//
+// Specification: https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation.
+type sseReader struct {
+	r      *bufio.Reader
+	lastID string
+	bom    bool // Whether a leading byte order mark was checked for.
+}
+
+func newSSEReader(r io.Reader) *sseReader {
+	return &sseReader{r: bufio.NewReader(r)}
+}
+
+// next returns the next event. It returns io.EOF at the end of the stream,
+// discarding an incomplete event.
+func (r *sseReader) next() (sseEvent, error) {
+	var ev sseEvent
+	var data []byte
+	for {
+		line, err := r.r.ReadString('\n')
+		if err != nil {
+			return sseEvent{}, err
+		}
+		if !r.bom {
+			line = strings.TrimPrefix(line, "\uFEFF")
+			r.bom = true
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if line == "" {
+			// Unlike the specification, dispatch events with a type but no data,
+			// since some servers send "complete" events that way.
+			if data == nil && ev.Event == "" {
+				continue
+			}
+			if len(data) > 0 {
+				ev.Data = data[:len(data)-1] // Trim the last newline.
+			}
+			ev.ID = r.lastID
+			return ev, nil
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "":
+			// A comment.
+		case "event":
+			ev.Event = value
+		case "data":
+			data = append(data, value...)
+			data = append(data, '\n')
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				r.lastID = value
+			}
+		}
+	}
+}
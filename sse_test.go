@@ -0,0 +1,189 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/isihu/graphql"
+)
+
+func TestClient_Subscribe_sse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if got, want := req.Method, http.MethodPost; got != want {
+			t.Errorf("got method: %v, want: %v", got, want)
+		}
+		if got, want := req.Header.Get("Accept"), "text/event-stream"; got != want {
+			t.Errorf("got Accept header: %q, want: %q", got, want)
+		}
+		if got, want := mustRead(req.Body), `{"query":"subscription{tick}"}`; got != want {
+			t.Errorf("got body: %v, want %v", got, want)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		mustWrite(w, ": a comment\n\n")
+		mustWrite(w, "event: next\r\ndata: {\"data\":\r\ndata: {\"tick\":1}}\r\n\r\n")
+		w.(http.Flusher).Flush()
+		mustWrite(w, "event: next\ndata: {\"data\":{\"tick\":2}}\n\n")
+		mustWrite(w, "event: complete\ndata:\n\n")
+	}))
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil, graphql.WithSubscriptionProtocol(graphql.GraphQLSSE))
+
+	sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for ev := range sub.Events() {
+		got = append(got, string(ev.Data))
+	}
+	if err := sub.Err(); err != nil {
+		t.Errorf("got error: %v, want: nil", err)
+	}
+	if want := []string{`{"tick":1}`, `{"tick":2}`}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got events: %q, want: %q", got, want)
+	}
+}
+
+func TestClient_Subscribe_sseUnexpectedEnd(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		mustWrite(w, "event: next\ndata: {\"data\":{\"tick\":1}}\n\n")
+	}))
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil, graphql.WithSubscriptionProtocol(graphql.GraphQLSSE))
+
+	sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range sub.Events() {
+	}
+	if got, want := sub.Err(), "graphql: subscription stream ended unexpectedly"; got == nil || got.Error() != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestClient_Subscribe_sseLastEventID(t *testing.T) {
+	var connections atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		switch connections.Add(1) {
+		case 1:
+			if got := req.Header.Get("Last-Event-ID"); got != "" {
+				t.Errorf("got Last-Event-ID header: %q, want none", got)
+			}
+			mustWrite(w, "id: 1\nevent: next\ndata: {\"data\":{\"tick\":1}}\n\n")
+			// The connection drops without completing.
+		case 2:
+			if got, want := req.Header.Get("Last-Event-ID"), "1"; got != want {
+				t.Errorf("got Last-Event-ID header: %q, want: %q", got, want)
+			}
+			mustWrite(w, "id: 2\nevent: next\ndata: {\"data\":{\"tick\":2}}\n\n")
+			mustWrite(w, "event: complete\ndata:\n\n")
+		}
+	}))
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil,
+		graphql.WithSubscriptionProtocol(graphql.GraphQLSSE),
+		graphql.WithSubscriptionReconnect(graphql.RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}))
+
+	sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for ev := range sub.Events() {
+		if !ev.Reconnected {
+			got = append(got, string(ev.Data))
+		}
+	}
+	if err := sub.Err(); err != nil {
+		t.Errorf("got error: %v, want: nil", err)
+	}
+	if want := []string{`{"tick":1}`, `{"tick":2}`}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got events: %q, want: %q", got, want)
+	}
+}
+
+func TestClient_Subscribe_sseSingleConnection(t *testing.T) {
+	const token = "t0k3n"
+	events := make(chan string)
+	deleted := make(chan string)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPut {
+			if got := req.Header.Get("X-GraphQL-Event-Stream-Token"); got != token {
+				t.Errorf("got token: %q, want: %q", got, token)
+			}
+		}
+		switch req.Method {
+		case http.MethodPut:
+			w.WriteHeader(http.StatusCreated)
+			mustWrite(w, token)
+		case http.MethodGet:
+			if got, want := req.Header.Get("Accept"), "text/event-stream"; got != want {
+				t.Errorf("got Accept header: %q, want: %q", got, want)
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.(http.Flusher).Flush()
+			for {
+				select {
+				case ev := <-events:
+					mustWrite(w, ev)
+					w.(http.Flusher).Flush()
+				case <-req.Context().Done():
+					return
+				}
+			}
+		case http.MethodPost:
+			var body struct {
+				Query      string
+				Extensions struct{ OperationID string }
+			}
+			mustUnmarshal(mustRead(req.Body), &body)
+			if got, want := body.Query, "subscription{tick}"; got != want {
+				t.Errorf("got query: %q, want: %q", got, want)
+			}
+			w.WriteHeader(http.StatusAccepted)
+			go func() {
+				id := body.Extensions.OperationID
+				events <- "event: next\ndata: {\"id\":\"other\",\"payload\":{\"data\":{\"tick\":0}}}\n\n"
+				events <- "event: next\ndata: {\"id\":\"" + id + "\",\"payload\":{\"data\":{\"tick\":1}}}\n\n"
+			}()
+		case http.MethodDelete:
+			deleted <- req.URL.Query().Get("operationId")
+		}
+	}))
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil, graphql.WithSubscriptionProtocol(graphql.GraphQLSSESingleConnection))
+
+	sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev := <-sub.Events(); string(ev.Data) != `{"tick":1}` {
+		t.Errorf("got event data: %s, want: %s", ev.Data, `{"tick":1}`)
+	}
+	go sub.Unsubscribe()
+	if id := <-deleted; id == "" {
+		t.Error("got empty operationId in DELETE request")
+	}
+}
+
+func TestClient_Subscribe_sseStatusError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		mustWrite(w, "no streams")
+	}))
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil, graphql.WithSubscriptionProtocol(graphql.GraphQLSSE))
+
+	_, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if got, want := err, `non-200 OK status code: 405 Method Not Allowed body: "no streams"`; got == nil || got.Error() != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
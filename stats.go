@@ -0,0 +1,158 @@
+package graphql
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Stats are statistics about a single operation sent by Query, Mutate or Do.
+type Stats struct {
+	Operation     Operation
+	Duration      time.Duration // How long the operation took, including retries.
+	Attempts      int           // Number of HTTP requests made.
+	BytesSent     int64         // Size of the bodies of the requests.
+	BytesReceived int64         // Size of the bodies of the responses that were read.
+
+	// Timings are the connection-level timings of the last HTTP request.
+	Timings
+
+	Decode time.Duration // Time spent reading and decoding response bodies.
+
+	// CacheStatus is how caches between the client and the server handled
+	// the last request, as reported by the response's Cache-Status,
+	// CF-Cache-Status or X-Cache header, such as "HIT" or "MISS".
+	// It's "HIT" if the operation was answered from the client's cache,
+	// and "" if the response reported none.
+	CacheStatus string
+
+	// Err is the error the operation failed with, or nil if it succeeded.
+	Err error
+}
+
+// WithStatsHandler makes the client call handle with the statistics of every
+// operation sent by Query, Mutate and Do, after the operation completes.
+func WithStatsHandler(handle func(ctx context.Context, s Stats)) ClientOption {
+	return func(c *Client) { c.handleStats = handle }
+}
+
+// Timings are the connection-level timings of an HTTP request, which tell
+// connection setup apart from server processing. Phases that didn't happen,
+// such as DNS, Connect and TLS when an idle connection was reused, are zero.
+type Timings struct {
+	DNS        time.Duration // Time spent resolving the endpoint's host.
+	Connect    time.Duration // Time spent establishing the TCP connection.
+	TLS        time.Duration // Time spent in the TLS handshake.
+	Server     time.Duration // Time from writing the request to the first byte of the response.
+	TTFB       time.Duration // Time from the start of the request to the first byte of the response.
+	ConnReused bool          // Whether an idle connection was reused.
+}
+
+// connTimings collects the Timings of an HTTP request with an
+// httptrace.ClientTrace. The trace's hooks may be called from
+// the transport's goroutines, so they're guarded by mu.
+type connTimings struct {
+	mu                     sync.Mutex
+	start                  time.Time
+	dnsStart, connectStart time.Time
+	tlsStart, wrote        time.Time
+	t                      Timings
+}
+
+// connTimingsKey is the context key of the *connTimings of a request.
+type connTimingsKey struct{}
+
+// trace resets t for a request starting now, and returns ctx with an
+// httptrace.ClientTrace recording the request's timings in t.
+// Traces already in ctx keep being called.
+func (t *connTimings) trace(ctx context.Context) context.Context {
+	t.mu.Lock()
+	t.start = time.Now()
+	t.t = Timings{}
+	t.mu.Unlock()
+	record := func(f func()) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		f()
+	}
+	ctx = context.WithValue(ctx, connTimingsKey{}, t)
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { record(func() { t.dnsStart = time.Now() }) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { record(func() { t.t.DNS = time.Since(t.dnsStart) }) },
+		ConnectStart: func(network, addr string) {
+			record(func() { t.connectStart = time.Now() })
+		},
+		ConnectDone: func(network, addr string, err error) {
+			record(func() { t.t.Connect = time.Since(t.connectStart) })
+		},
+		TLSHandshakeStart: func() { record(func() { t.tlsStart = time.Now() }) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			record(func() { t.t.TLS = time.Since(t.tlsStart) })
+		},
+		GotConn:      func(info httptrace.GotConnInfo) { record(func() { t.t.ConnReused = info.Reused }) },
+		WroteRequest: func(httptrace.WroteRequestInfo) { record(func() { t.wrote = time.Now() }) },
+		GotFirstResponseByte: func() {
+			record(func() {
+				t.t.TTFB = time.Since(t.start)
+				if !t.wrote.IsZero() {
+					t.t.Server = time.Since(t.wrote)
+				}
+			})
+		},
+	})
+}
+
+// set sets the timings of t to timings collected for it elsewhere.
+func (t *connTimings) set(timings Timings) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.t = timings
+}
+
+// timings returns the timings t has collected so far.
+func (t *connTimings) timings() Timings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.t
+}
+
+// TimingsFromContext returns the timings collected so far for the HTTP
+// request of an operation with context ctx, and whether ctx is such a context.
+// It lets middleware in the http.Client's transport, which gets the
+// request's context, report timings after the response arrives.
+func TimingsFromContext(ctx context.Context) (Timings, bool) {
+	t, ok := ctx.Value(connTimingsKey{}).(*connTimings)
+	if !ok {
+		return Timings{}, false
+	}
+	return t.timings(), true
+}
+
+// cacheStatus returns the cache status reported by the headers h of a response.
+func cacheStatus(h http.Header) string {
+	for _, k := range []string{"Cache-Status", "Cf-Cache-Status", "X-Cache"} {
+		if v := h.Get(k); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// stats returns the Stats of the operation op, which took d and failed
+// with err, if non-nil, from the statistics s collected while sending it.
+func (s *callStats) stats(op Operation, d time.Duration, err error) Stats {
+	return Stats{
+		Operation:     op,
+		Duration:      d,
+		Attempts:      s.attempts,
+		BytesSent:     s.requestBytes.Load(),
+		BytesReceived: s.responseBytes.Load(),
+		Timings:       s.conn.timings(),
+		Decode:        s.decode,
+		CacheStatus:   s.cacheStatus,
+		Err:           err,
+	}
+}
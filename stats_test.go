@@ -0,0 +1,110 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"testing"
+	"time"
+
+	"github.com/isihu/graphql"
+)
+
+func TestWithStatsHandler(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Cache", "MISS")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	}))
+	defer srv.Close()
+	var got []graphql.Stats
+	client := graphql.NewClient(srv.URL, nil, graphql.WithStatsHandler(func(ctx context.Context, s graphql.Stats) {
+		got = append(got, s)
+	}))
+
+	for range 2 {
+		var res struct{ User struct{ Name graphql.String } }
+		err := client.Do(context.Background(), "query GetUser{user{name}}", &res, false, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d stats, want: 2", len(got))
+	}
+	s := got[0]
+	if got, want := s.Operation.Name, "GetUser"; got != want {
+		t.Errorf("got operation name: %q, want: %q", got, want)
+	}
+	if got, want := s.Attempts, 1; got != want {
+		t.Errorf("got attempts: %v, want: %v", got, want)
+	}
+	if got, want := s.BytesSent, int64(len(`{"query":"query GetUser{user{name}}"}`+"\n")); got != want {
+		t.Errorf("got bytes sent: %v, want: %v", got, want)
+	}
+	if got, want := s.BytesReceived, int64(len(`{"data": {"user": {"name": "Gopher"}}}`)); got != want {
+		t.Errorf("got bytes received: %v, want: %v", got, want)
+	}
+	if s.ConnReused || s.Connect <= 0 {
+		t.Errorf("got connection reused: %v, connect: %v, want a new connection", s.ConnReused, s.Connect)
+	}
+	if s.TTFB <= 0 || s.TTFB > s.Duration {
+		t.Errorf("got TTFB: %v, want a positive duration up to %v", s.TTFB, s.Duration)
+	}
+	if got, want := s.CacheStatus, "MISS"; got != want {
+		t.Errorf("got cache status: %q, want: %q", got, want)
+	}
+	if s.Err != nil {
+		t.Errorf("got error: %v, want: nil", s.Err)
+	}
+	if s := got[1]; !s.ConnReused || s.Connect != 0 {
+		t.Errorf("got connection reused: %v, connect: %v, want a reused connection", s.ConnReused, s.Connect)
+	}
+}
+
+// timingsTransport is middleware recording the timings of the requests it sends.
+type timingsTransport struct {
+	got []graphql.Timings
+}
+
+func (t *timingsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if timings, ok := graphql.TimingsFromContext(req.Context()); ok {
+		t.got = append(t.got, timings)
+	}
+	return resp, err
+}
+
+func TestTimingsFromContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	}))
+	defer srv.Close()
+	transport := new(timingsTransport)
+	var gotFirstByte bool
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		GotFirstResponseByte: func() { gotFirstByte = true },
+	})
+	client := graphql.NewClient(srv.URL, &http.Client{Transport: transport})
+
+	var res struct{ User struct{ Name graphql.String } }
+	err := client.Do(ctx, "query GetUser{user{name}}", &res, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transport.got) != 1 {
+		t.Fatalf("got %d timings, want: 1", len(transport.got))
+	}
+	if got := transport.got[0]; got.Server < 10*time.Millisecond || got.TTFB < got.Server || got.Connect <= 0 {
+		t.Errorf("got timings: %+v, want a new connection and at least 10ms of server time", got)
+	}
+	if !gotFirstByte {
+		t.Error("got the context's trace not called, want it called")
+	}
+	if _, ok := graphql.TimingsFromContext(context.Background()); ok {
+		t.Error("got timings from a context without a request, want none")
+	}
+}
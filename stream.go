@@ -0,0 +1,47 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// newStreamingRequest returns a POST request to url whose body is in,
+// JSON-encoded on the fly into a pipe as the transport reads it.
+// If knownLength is true, the encoded length is computed up front
+// and set as the request's Content-Length.
+func newStreamingRequest(ctx context.Context, url string, in request, knownLength bool) (*http.Request, error) {
+	length := int64(-1) // Unknown.
+	if knownLength {
+		var cw countingWriter
+		err := json.NewEncoder(&cw).Encode(in)
+		if err != nil {
+			return nil, err
+		}
+		length = cw.n
+	}
+	pr, pw := io.Pipe()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, pr)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = length
+	// The transport always closes the request body, which unblocks
+	// the encoder below if the request fails before the body is read.
+	go func() {
+		pw.CloseWithError(json.NewEncoder(pw).Encode(in))
+	}()
+	return req, nil
+}
+
+// countingWriter is an io.Writer that discards what's written to it,
+// keeping count of the number of bytes.
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,47 @@
+package graphql_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+func TestClient_Mutate_withStreamingBody(t *testing.T) {
+	input := strings.Repeat("x", 1<<20) // A large payload.
+	wantBody := `{"query":"mutation($input:String!){upload(input:$input){ok}}","variables":{"input":"` + input + `"}}` + "\n"
+
+	for _, knownLength := range []bool{false, true} {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+			wantLength := int64(-1)
+			if knownLength {
+				wantLength = int64(len(wantBody))
+			}
+			if got := req.ContentLength; got != wantLength {
+				t.Errorf("knownLength=%v: got ContentLength: %v, want: %v", knownLength, got, wantLength)
+			}
+			if got := mustRead(req.Body); got != wantBody {
+				t.Errorf("knownLength=%v: got body of length %v, want %v", knownLength, len(got), len(wantBody))
+			}
+			w.Header().Set("Content-Type", "application/json")
+			mustWrite(w, `{"data": {"upload": {"ok": true}}}`)
+		})
+		client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}})
+
+		var m struct {
+			Upload struct {
+				OK bool
+			} `graphql:"upload(input:$input)"`
+		}
+		err := client.Mutate(context.Background(), &m, map[string]any{"input": graphql.String(input)}, graphql.WithStreamingBody(knownLength))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !m.Upload.OK {
+			t.Errorf("knownLength=%v: got m.Upload.OK: false, want: true", knownLength)
+		}
+	}
+}
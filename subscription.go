@@ -0,0 +1,499 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/isihu/graphql/internal/jsonutil"
+)
+
+// Subscribe starts a GraphQL subscription with the subscription document query,
+// at the client's subscription URL (see WithSubscriptionURL), using the client's
+// subscription protocol (see WithSubscriptionProtocol). For protocols over WebSocket,
+// a URL with an http or https scheme is dialed as ws or wss, respectively, and all
+// concurrent subscriptions to the same URL share a single connection, which is
+// closed when the last of them stops.
+//
+// Subscribe returns once the server has accepted the subscription.
+// The subscription then delivers events until the server completes it,
+// it fails, ctx is done, Unsubscribe is called, or the client is closed.
+func (c *Client) Subscribe(ctx context.Context, query string, variables map[string]any, opts ...CallOption) (*Subscription, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if len(c.rewriters) > 0 {
+		var err error
+		query, err = c.rewrite(query)
+		if err != nil {
+			return nil, err
+		}
+	}
+	cfg := newCallConfig(opts)
+	url := c.endpoint(cfg, "subscription")
+	in := request{
+		Query:      query,
+		Variables:  variables,
+		Extensions: cfg.extensions,
+	}
+	s, sctx := newSubscription(ctx, c.subscriptionBuffer)
+	s.overflow = c.subscriptionOverflow
+	s.prepareErrors = c.prepareErrors
+	s.reconnect = c.subscriptionReconnect
+	s.connect = func() (recv func() error, err error) {
+		switch c.subscriptionProtocol {
+		case GraphQLSSE:
+			recv, err = c.connectSSE(sctx, url, in, s)
+		case GraphQLSSESingleConnection:
+			recv, err = c.connectSSESingleConnection(sctx, url, in, s)
+		case ApolloMultipart:
+			recv, err = c.connectMultipart(sctx, url, in, s)
+		default:
+			recv, err = c.connectWS(sctx, url, in, s)
+		}
+		if err == nil {
+			c.subscriptionHooks.started(ctx, query)
+		}
+		return recv, err
+	}
+	s.onStop = func(err error) {
+		c.forgetSubscription(s)
+		c.subscriptionHooks.completed(ctx, query, err)
+	}
+	c.subsMu.Lock()
+	if c.closed {
+		c.subsMu.Unlock()
+		s.cancel()
+		return nil, ErrClientClosed
+	}
+	if c.subs == nil {
+		c.subs = make(map[*Subscription]struct{})
+	}
+	c.subs[s] = struct{}{}
+	c.subsMu.Unlock()
+	recv, err := s.connect()
+	if err != nil {
+		c.forgetSubscription(s)
+		s.cancel()
+		return nil, err
+	}
+	go s.run(sctx, recv)
+	return s, nil
+}
+
+// SubscribeStruct starts a GraphQL subscription like Subscribe, with a
+// subscription document derived from s, like Query and Mutate do.
+// s should be a pointer to struct that corresponds to the GraphQL schema.
+// It isn't populated: decode events into values of its type with Event.Decode.
+func (c *Client) SubscribeStruct(ctx context.Context, s any, variables map[string]any, opts ...CallOption) (*Subscription, error) {
+	return c.Subscribe(ctx, constructSubscription(s, variables), variables, opts...)
+}
+
+// ErrClientClosed is returned by Client.Subscribe after Client.Close is called.
+var ErrClientClosed = errors.New("graphql: client closed")
+
+// Close gracefully stops the client's subscriptions, for shutting down cleanly.
+// It tells the server to stop them, and waits until the results that were
+// already received are delivered and the subscriptions have stopped, or until
+// ctx is done, at which point it discards the remaining results. Subscriptions
+// stopped by Close report no error. Close then closes the idle connections
+// of the client's HTTP client, unless it's http.DefaultClient. Later calls
+// to Subscribe fail with ErrClientClosed, but queries and mutations are
+// unaffected.
+//
+// Close returns ctx.Err() if ctx is done before all subscriptions have stopped.
+func (c *Client) Close(ctx context.Context) error {
+	c.subsMu.Lock()
+	c.closed = true
+	subs := slices.Collect(maps.Keys(c.subs))
+	c.subsMu.Unlock()
+
+	for _, s := range subs {
+		s.shutdown()
+	}
+	var err error
+	for _, s := range subs {
+		select {
+		case <-s.done:
+		case <-ctx.Done():
+			err = ctx.Err()
+			s.cancel()
+			<-s.done
+		}
+	}
+	if c.httpClient != http.DefaultClient {
+		// Don't close connections that other clients may share.
+		c.httpClient.CloseIdleConnections()
+	}
+	return err
+}
+
+func (c *Client) forgetSubscription(s *Subscription) {
+	c.subsMu.Lock()
+	delete(c.subs, s)
+	c.subsMu.Unlock()
+}
+
+// SubscriptionProtocol is a protocol for running subscriptions.
+type SubscriptionProtocol int
+
+const (
+	// GraphQLTransportWS is the graphql-transport-ws protocol over WebSocket,
+	// as implemented by graphql-ws. It's the default.
+	//
+	// Protocol: https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md.
+	GraphQLTransportWS SubscriptionProtocol = iota
+
+	// SubscriptionsTransportWS is the legacy protocol over WebSocket of Apollo's
+	// subscriptions-transport-ws, which is negotiated as the "graphql-ws" subprotocol.
+	// It's still the only protocol spoken by many older servers.
+	//
+	// Protocol: https://github.com/apollographql/subscriptions-transport-ws/blob/master/PROTOCOL.md.
+	SubscriptionsTransportWS
+
+	// GraphQLSSE is GraphQL over Server-Sent Events in "distinct connections mode",
+	// as implemented by graphql-sse. Every subscription is a POST request whose
+	// response is an event stream. It works through plain HTTP infrastructure,
+	// where WebSockets may be blocked. When a subscription is reconnected
+	// (see WithSubscriptionReconnect), the ID of the last event it received is
+	// sent as the Last-Event-ID header, so that servers that support resumable
+	// delivery resume the stream without gaps.
+	//
+	// Protocol: https://github.com/enisdenjo/graphql-sse/blob/master/PROTOCOL.md.
+	GraphQLSSE
+
+	// GraphQLSSESingleConnection is GraphQL over Server-Sent Events in
+	// "single connection mode", as implemented by graphql-sse. The client
+	// reserves an event stream with a PUT request, opens it with a GET request,
+	// and then starts and stops operations on it with POST and DELETE requests.
+	GraphQLSSESingleConnection
+
+	// AbsinthePhoenix is the protocol of Elixir's Absinthe over Phoenix channels,
+	// with version 2.0.0 of Phoenix's serializer. The subscription URL is the
+	// WebSocket endpoint of the socket, such as "wss://example.com/socket/websocket".
+	// Subscriptions are pushed as documents to the "__absinthe__:control" channel,
+	// and their results arrive as subscription:data messages. The client sends
+	// Phoenix heartbeats every 30 seconds, unless WithSubscriptionKeepAlive sets
+	// another ping interval.
+	//
+	// Absinthe: https://hexdocs.pm/absinthe_phoenix.
+	AbsinthePhoenix
+
+	// ApolloMultipart is Apollo's protocol of subscriptions over HTTP multipart
+	// responses. Every subscription is a POST request whose response is
+	// a multipart/mixed stream of results and heartbeats. It's supported by
+	// Apollo Router without any WebSocket setup.
+	//
+	// Protocol: https://www.apollographql.com/docs/graphos/routing/operations/subscriptions/multipart-protocol.
+	ApolloMultipart
+)
+
+// WithSubscriptionProtocol sets the protocol that Client.Subscribe uses.
+// The default is GraphQLTransportWS.
+func WithSubscriptionProtocol(p SubscriptionProtocol) ClientOption {
+	return func(c *Client) { c.subscriptionProtocol = p }
+}
+
+// WithSubscriptionReconnect makes subscriptions reconnect and resubscribe
+// when their connection drops, retrying failed connection attempts with
+// exponential backoff according to policy. Once re-established, a subscription
+// delivers an event with Reconnected set. Results sent by the server while the
+// subscription was disconnected are lost.
+//
+// policy.MaxAttempts limits the consecutive connection attempts after the
+// connection drops, counting the dropped connection as the first attempt.
+// If policy.Retryable is nil, failures other than GraphQL errors, rejected
+// connections and overflowing buffers are retried, except for status codes
+// that aren't retried by WithRetry.
+func WithSubscriptionReconnect(policy RetryPolicy) ClientOption {
+	policy.setDefaults(isReconnectable)
+	return func(c *Client) { c.subscriptionReconnect = &policy }
+}
+
+// isReconnectable is the default RetryPolicy.Retryable of WithSubscriptionReconnect.
+func isReconnectable(err error) bool {
+	switch err.(type) {
+	case Errors, *SubscriptionError, *ConnectionRejectedError:
+		return false
+	case *HTTPError:
+		return IsRetryable(err)
+	}
+	return err != ErrSubscriptionOverflow
+}
+
+// Subscription is a GraphQL subscription started by Client.Subscribe.
+type Subscription struct {
+	events   chan Event
+	overflow OverflowPolicy
+	cancel   context.CancelFunc
+	done     chan struct{} // Closed when the subscription has stopped.
+
+	// connect connects to the server and starts the operation. It returns
+	// a function that receives the operation's results and delivers them,
+	// until the operation stops, returning nil if the server completed it.
+	connect   func() (recv func() error, err error)
+	reconnect *RetryPolicy    // Nil means subscriptions don't reconnect.
+	onStop    func(err error) // Called with the error recorded when the subscription stops.
+
+	prepareErrors func(Errors) // Applies the client's error options to the errors received.
+
+	// lastEventID is the ID of the last Server-Sent Event received in distinct
+	// connections mode. It's only accessed by connect and recv, which don't run
+	// concurrently.
+	lastEventID string
+
+	mu           sync.Mutex
+	unsubscribed bool
+	err          error
+	stopOp       func() // Stops the current operation, letting received results be delivered. Nil if that's not supported.
+}
+
+// newSubscription returns a new subscription that buffers up to buffer events,
+// and a context derived from ctx that's canceled when the subscription stops.
+func newSubscription(ctx context.Context, buffer int) (*Subscription, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Subscription{
+		events: make(chan Event, buffer),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	return s, ctx
+}
+
+// Event is a single result of a subscription.
+type Event struct {
+	// Data is the "data" member of the result.
+	Data json.RawMessage
+
+	// Err is the GraphQL errors of the result, if any, which may accompany
+	// partial Data. They don't stop the subscription. An event with Err
+	// of type *SubscriptionError is the last one, and has no Data.
+	Err error
+
+	// Reconnected is set on an event without data that's delivered when
+	// the subscription was re-established after its connection dropped.
+	// See WithSubscriptionReconnect.
+	Reconnected bool
+}
+
+// Decode decodes the event's data into v, which should be a pointer
+// to a struct that corresponds to the GraphQL schema, like in Client.Query.
+func (e Event) Decode(v any) error {
+	return jsonutil.UnmarshalGraphQL(e.Data, v)
+}
+
+// Events returns the channel on which events are delivered.
+// It's closed when the subscription stops.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Err returns the error that stopped the subscription, if any.
+// It returns nil if the subscription hasn't stopped yet,
+// if the server completed it, or if Unsubscribe was called.
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Unsubscribe stops the subscription, telling the server to stop it too,
+// and waits until its events channel is closed. Pending events are discarded.
+func (s *Subscription) Unsubscribe() {
+	s.mu.Lock()
+	s.unsubscribed = true
+	s.mu.Unlock()
+	s.cancel()
+	<-s.done
+}
+
+// run calls recv, and reconnects according to s.reconnect when it fails,
+// until the subscription stops. It then records the error that stopped it,
+// unless Unsubscribe was called, and calls s.onStop with it.
+// ctx is the context returned by newSubscription.
+func (s *Subscription) run(ctx context.Context, recv func() error) {
+	defer close(s.done)
+	defer close(s.events)
+	defer s.cancel()
+
+	err := recv()
+	for err != nil && ctx.Err() == nil && !s.stopped() && s.reconnect != nil && s.reconnect.Retryable(err) {
+		recv, err = s.reestablish(ctx, err)
+		if err != nil {
+			break
+		}
+		// Deliver the event regardless of the overflow policy,
+		// since dropping it could hide the gap in results.
+		select {
+		case s.events <- Event{Reconnected: true}:
+		case <-ctx.Done():
+		}
+		err = recv()
+	}
+	if err != nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	s.mu.Lock()
+	if !s.unsubscribed {
+		s.err = err
+	}
+	err = s.err
+	s.mu.Unlock()
+	s.onStop(err)
+}
+
+// stopped reports whether Unsubscribe or Client.Close was called.
+func (s *Subscription) stopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.unsubscribed
+}
+
+// shutdown stops the subscription for Client.Close. It stops the current
+// operation gracefully if the transport supports it, and cancels it otherwise.
+func (s *Subscription) shutdown() {
+	s.mu.Lock()
+	s.unsubscribed = true
+	stopOp := s.stopOp
+	s.mu.Unlock()
+	if stopOp == nil {
+		s.cancel()
+		return
+	}
+	stopOp()
+}
+
+// setStopOp sets the function that stops the current operation gracefully.
+func (s *Subscription) setStopOp(stopOp func()) {
+	s.mu.Lock()
+	s.stopOp = stopOp
+	s.mu.Unlock()
+}
+
+// reestablish reconnects after the connection failed with err,
+// retrying according to s.reconnect.
+func (s *Subscription) reestablish(ctx context.Context, err error) (recv func() error, _ error) {
+	for attempts := 1; attempts < s.reconnect.MaxAttempts; attempts++ {
+		t := time.NewTimer(s.reconnect.backoff(attempts, err))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+		recv, err = s.connect()
+		if err == nil {
+			return recv, nil
+		}
+		if !s.reconnect.Retryable(err) {
+			break
+		}
+	}
+	return nil, err
+}
+
+// deliver delivers the result out as an event.
+func (s *Subscription) deliver(ctx context.Context, out *response) error {
+	var ev Event
+	if out.Data != nil {
+		ev.Data = *out.Data
+	}
+	if len(out.Errors) > 0 {
+		s.prepareErrors(out.Errors)
+		ev.Err = out.Errors
+	}
+	if ev.Data == nil && ev.Err == nil {
+		return nil
+	}
+	return s.send(ctx, ev)
+}
+
+// fail delivers the error err that the server failed the operation with
+// as a whole as the last event, and returns it as a *SubscriptionError.
+func (s *Subscription) fail(ctx context.Context, err error) error {
+	if errs, ok := err.(Errors); ok {
+		s.prepareErrors(errs)
+	}
+	err = &SubscriptionError{Err: err}
+	s.send(ctx, Event{Err: err})
+	return err
+}
+
+// SubscriptionError is the error of a subscription that the server failed
+// as a whole, rather than the errors of one of its results. It's delivered
+// as the last event of the subscription, and reported by Subscription.Err.
+type SubscriptionError struct {
+	// Err is the GraphQL errors that the server failed the subscription with.
+	Err error
+}
+
+func (e *SubscriptionError) Error() string { return e.Err.Error() }
+func (e *SubscriptionError) Unwrap() error { return e.Err }
+
+// errUnexpectedEnd is returned when the stream of a subscription ends before it completes.
+var errUnexpectedEnd = errors.New("graphql: subscription stream ended unexpectedly")
+
+// Result is a single result of a subscription started by Subscribe.
+type Result[T any] struct {
+	// Data is the decoded data of the result.
+	Data T
+
+	// Err is the error that decoding the result failed with,
+	// or the GraphQL errors of the result, if any. A result with
+	// the error that stopped the subscription has no Data,
+	// and is the last result.
+	Err error
+}
+
+// Subscribe starts a GraphQL subscription using client, with a subscription
+// document derived from T, and decodes each of its results into T.
+// T should be a struct type that corresponds to the GraphQL schema,
+// like the types of the structs passed to Client.Query.
+//
+// The returned channel is closed when the subscription stops.
+// Cancel ctx to stop it. Reconnection events aren't delivered.
+func Subscribe[T any](ctx context.Context, client *Client, variables map[string]any, opts ...CallOption) (<-chan Result[T], error) {
+	var v T
+	sub, err := client.SubscribeStruct(ctx, &v, variables, opts...)
+	if err != nil {
+		return nil, err
+	}
+	results := make(chan Result[T])
+	go func() {
+		defer close(results)
+		for ev := range sub.Events() {
+			if ev.Reconnected {
+				continue
+			}
+			if _, ok := ev.Err.(*SubscriptionError); ok {
+				// It's delivered as the error that stopped the subscription.
+				continue
+			}
+			r := Result[T]{Err: ev.Err}
+			if ev.Data != nil {
+				err := ev.Decode(&r.Data)
+				if r.Err == nil {
+					r.Err = err
+				}
+			}
+			select {
+			case results <- r:
+			case <-ctx.Done():
+				sub.Unsubscribe()
+				return
+			}
+		}
+		if err := sub.Err(); err != nil {
+			select {
+			case results <- Result[T]{Err: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return results, nil
+}
@@ -0,0 +1,620 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/isihu/graphql"
+	"github.com/isihu/graphql/internal/websocket"
+)
+
+// transportWSServer returns a test server speaking the graphql-transport-ws protocol.
+// For every subscription, it calls serve with the subscribe payload and a function
+// for sending messages, and reports the messages it received afterwards on received.
+func transportWSServer(t *testing.T, serve func(payload map[string]any, send func(typ, payload string)), received chan<- string) *httptest.Server {
+	return wsServer(t, "graphql-transport-ws", serve, received)
+}
+
+// wsServer returns a test server speaking the WebSocket subscription protocol
+// negotiated as subprotocol, like transportWSServer.
+func wsServer(t *testing.T, subprotocol string, serve func(payload map[string]any, send func(typ, payload string)), received chan<- string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := websocket.Upgrade(w, req, []string{subprotocol}, &websocket.Compression{})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		if got, want := conn.Subprotocol, subprotocol; got != want {
+			t.Errorf("got subprotocol: %q, want: %q", got, want)
+		}
+		read := func() (msg struct {
+			ID      string
+			Type    string
+			Payload map[string]any
+		}) {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return msg
+			}
+			mustUnmarshal(string(data), &msg)
+			return msg
+		}
+		if got, want := read().Type, "connection_init"; got != want {
+			t.Errorf("got message type: %q, want: %q", got, want)
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"connection_ack","payload":{"version":1}}`))
+		sub := read()
+		if got, want := sub.Type, map[string]string{"graphql-transport-ws": "subscribe", "graphql-ws": "start"}[subprotocol]; got != want {
+			t.Errorf("got message type: %q, want: %q", got, want)
+		}
+		serve(sub.Payload, func(typ, payload string) {
+			msg := `{"id":` + mustMarshal(sub.ID) + `,"type":"` + typ + `"`
+			if payload != "" {
+				msg += `,"payload":` + payload
+			}
+			conn.WriteMessage(websocket.TextMessage, []byte(msg+"}"))
+		})
+		for received != nil {
+			msg := read()
+			if msg.Type == "" {
+				close(received)
+				return
+			}
+			received <- msg.Type
+		}
+	}))
+}
+
+func mustMarshal(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+func TestClient_Subscribe(t *testing.T) {
+	srv := transportWSServer(t, func(payload map[string]any, send func(typ, payload string)) {
+		if got, want := payload["query"], "subscription{commentAdded(issue: $issue){body}}"; got != want {
+			t.Errorf("got query: %v, want: %v", got, want)
+		}
+		if got, want := mustMarshal(payload["variables"]), `{"issue":1}`; got != want {
+			t.Errorf("got variables: %v, want: %v", got, want)
+		}
+		send("next", `{"data":{"commentAdded":{"body":"first"}}}`)
+		send("next", `{"data":{"commentAdded":{"body":"second"}}}`)
+		send("complete", "")
+	}, nil)
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil)
+
+	sub, err := client.Subscribe(context.Background(), "subscription{commentAdded(issue: $issue){body}}", map[string]any{"issue": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for ev := range sub.Events() {
+		var s struct {
+			CommentAdded struct {
+				Body string
+			}
+		}
+		err := ev.Decode(&s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, s.CommentAdded.Body)
+	}
+	if err := sub.Err(); err != nil {
+		t.Errorf("got error: %v, want: nil", err)
+	}
+	if want := []string{"first", "second"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got events: %q, want: %q", got, want)
+	}
+}
+
+func TestClient_Subscribe_error(t *testing.T) {
+	srv := transportWSServer(t, func(_ map[string]any, send func(typ, payload string)) {
+		send("error", `[{"message":"field \"nope\" doesn't exist"}]`)
+	}, nil)
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil)
+
+	sub, err := client.Subscribe(context.Background(), "subscription{nope}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []graphql.Event
+	for ev := range sub.Events() {
+		events = append(events, ev)
+	}
+	if len(events) != 1 || events[0].Err != sub.Err() {
+		t.Errorf("got events: %v, want one with the subscription's error", events)
+	}
+	if _, ok := sub.Err().(*graphql.SubscriptionError); !ok {
+		t.Errorf("got error of type %T, want *graphql.SubscriptionError", sub.Err())
+	}
+	if got, want := sub.Err(), `field "nope" doesn't exist`; got == nil || got.Error() != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestClient_Subscribe_resultErrors(t *testing.T) {
+	srv := transportWSServer(t, func(_ map[string]any, send func(typ, payload string)) {
+		send("next", `{"data":{"tick":null},"errors":[{"message":"tick failed","path":["tick"]}]}`)
+		send("next", `{"data":{"tick":2}}`)
+		send("complete", "")
+	}, nil)
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil)
+
+	sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for ev := range sub.Events() {
+		got = append(got, fmt.Sprintf("%s %v", ev.Data, ev.Err))
+	}
+	if err := sub.Err(); err != nil {
+		t.Errorf("got error: %v, want: nil", err)
+	}
+	if want := []string{`{"tick":null} tick failed`, `{"tick":2} <nil>`}; strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got events: %q, want: %q", got, want)
+	}
+}
+
+func TestClient_Subscribe_debugErrors(t *testing.T) {
+	srv := transportWSServer(t, func(_ map[string]any, send func(typ, payload string)) {
+		send("next", `{"data":{"tick":null},"errors":[{"message":"tick failed","extensions":{"stacktrace":["at tick"]}}]}`)
+		send("error", `[{"message":"boom","extensions":{"exception":{"stacktrace":["at boom"]}}}]`)
+	}, nil)
+	defer srv.Close()
+
+	for _, debug := range []bool{false, true} {
+		var opts []graphql.ClientOption
+		if debug {
+			opts = append(opts, graphql.WithDebugErrors())
+		}
+		client := graphql.NewClient(srv.URL, nil, opts...)
+		sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for ev := range sub.Events() {
+			var errs graphql.Errors
+			if !errors.As(ev.Err, &errs) {
+				t.Fatalf("got event error: %v, want graphql.Errors", ev.Err)
+			}
+			got = append(got, strings.Join(errs[0].StackTrace(), ","))
+		}
+		want := []string{"", ""}
+		if debug {
+			want = []string{"at tick", "at boom"}
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("got stack traces with debug %v: %q, want: %q", debug, got, want)
+		}
+	}
+}
+
+func TestClient_Subscribe_unsubscribe(t *testing.T) {
+	received := make(chan string)
+	srv := transportWSServer(t, func(_ map[string]any, send func(typ, payload string)) {
+		send("next", `{"data":{"tick":1}}`)
+	}, received)
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil)
+
+	sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev := <-sub.Events(); string(ev.Data) != `{"tick":1}` {
+		t.Errorf("got event data: %s, want: %s", ev.Data, `{"tick":1}`)
+	}
+	sub.Unsubscribe()
+	if got, want := <-received, "complete"; got != want {
+		t.Errorf("got message type: %q, want: %q", got, want)
+	}
+	if err := sub.Err(); err != nil {
+		t.Errorf("got error: %v, want: nil", err)
+	}
+	if _, ok := <-sub.Events(); ok {
+		t.Error("events channel is open after Unsubscribe")
+	}
+}
+
+func TestClient_Subscribe_contextCanceled(t *testing.T) {
+	srv := transportWSServer(t, func(_ map[string]any, send func(typ, payload string)) {}, nil)
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub, err := client.Subscribe(ctx, "subscription{tick}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	for range sub.Events() {
+		t.Error("got event, want none")
+	}
+	if got, want := sub.Err(), context.Canceled; got != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestClient_Subscribe_subscriptionsTransportWS(t *testing.T) {
+	received := make(chan string)
+	srv := wsServer(t, "graphql-ws", func(_ map[string]any, send func(typ, payload string)) {
+		send("ka", "")
+		send("data", `{"data":{"tick":1}}`)
+		send("ka", "")
+		send("data", `{"data":{"tick":2}}`)
+	}, received)
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil, graphql.WithSubscriptionProtocol(graphql.SubscriptionsTransportWS))
+
+	sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`{"tick":1}`, `{"tick":2}`} {
+		if ev := <-sub.Events(); string(ev.Data) != want {
+			t.Errorf("got event data: %s, want: %s", ev.Data, want)
+		}
+	}
+	sub.Unsubscribe()
+	var got []string
+	for typ := range received {
+		got = append(got, typ)
+	}
+	if want := []string{"stop", "connection_terminate"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got messages: %q, want: %q", got, want)
+	}
+}
+
+func TestClient_Subscribe_subscriptionsTransportWSError(t *testing.T) {
+	srv := wsServer(t, "graphql-ws", func(_ map[string]any, send func(typ, payload string)) {
+		send("error", `{"message":"subscription not allowed"}`)
+	}, nil)
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil, graphql.WithSubscriptionProtocol(graphql.SubscriptionsTransportWS))
+
+	sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ev := range sub.Events() {
+		if ev.Err != sub.Err() {
+			t.Errorf("got event error: %v, want the subscription's error", ev.Err)
+		}
+	}
+	if got, want := sub.Err(), "subscription not allowed"; got == nil || got.Error() != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+}
+
+func TestClient_Subscribe_reconnect(t *testing.T) {
+	var connections atomic.Int32
+	srv := transportWSServer(t, func(_ map[string]any, send func(typ, payload string)) {
+		n := connections.Add(1)
+		send("next", fmt.Sprintf(`{"data":{"tick":%d}}`, n))
+		if n == 2 {
+			send("complete", "")
+		}
+		// The first connection drops without completing.
+	}, nil)
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil, graphql.WithSubscriptionReconnect(graphql.RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+	}))
+
+	sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for ev := range sub.Events() {
+		if ev.Reconnected {
+			got = append(got, "reconnected")
+			continue
+		}
+		got = append(got, string(ev.Data))
+	}
+	if err := sub.Err(); err != nil {
+		t.Errorf("got error: %v, want: nil", err)
+	}
+	if want := []string{`{"tick":1}`, "reconnected", `{"tick":2}`}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got events: %q, want: %q", got, want)
+	}
+}
+
+func TestClient_Subscribe_reconnectExhausted(t *testing.T) {
+	var connections atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if connections.Add(1) > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		conn, err := websocket.Upgrade(w, req, []string{"graphql-transport-ws"}, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.ReadMessage()
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"connection_ack","payload":{"version":1}}`))
+		conn.ReadMessage()
+		conn.CloseWithCode(1011, "going away")
+	}))
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil, graphql.WithSubscriptionReconnect(graphql.RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+	}))
+
+	sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range sub.Events() {
+		t.Error("got event, want none")
+	}
+	if got, want := sub.Err(), `non-200 OK status code: 503 Service Unavailable body: ""`; got == nil || got.Error() != want {
+		t.Errorf("got error: %v, want: %v", got, want)
+	}
+	if got, want := connections.Load(), int32(3); got != want {
+		t.Errorf("got %d connection attempts, want: %d", got, want)
+	}
+}
+
+func TestClient_SubscribeStruct(t *testing.T) {
+	srv := transportWSServer(t, func(payload map[string]any, send func(typ, payload string)) {
+		if got, want := payload["query"], "subscription($issue:Int!){commentAdded(issue: $issue){body}}"; got != want {
+			t.Errorf("got query: %v, want: %v", got, want)
+		}
+		send("next", `{"data":{"commentAdded":{"body":"first"}}}`)
+		send("complete", "")
+	}, nil)
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil)
+
+	var s struct {
+		CommentAdded struct {
+			Body graphql.String
+		} `graphql:"commentAdded(issue: $issue)"`
+	}
+	sub, err := client.SubscribeStruct(context.Background(), &s, map[string]any{"issue": graphql.Int(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ev := range sub.Events() {
+		err := ev.Decode(&s)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := s.CommentAdded.Body, graphql.String("first"); got != want {
+		t.Errorf("got body: %q, want: %q", got, want)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	srv := transportWSServer(t, func(payload map[string]any, send func(typ, payload string)) {
+		if got, want := payload["query"], "subscription($issue:Int!){commentAdded(issue: $issue){body}}"; got != want {
+			t.Errorf("got query: %v, want: %v", got, want)
+		}
+		send("next", `{"data":{"commentAdded":{"body":"first"}}}`)
+		send("next", `{"data":{"commentAdded":{"body":42}}}`)
+		send("next", `{"data":{"commentAdded":{"body":"third"}},"errors":[{"message":"rate limited"}]}`)
+		send("error", `[{"message":"subscription expired"}]`)
+	}, nil)
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil)
+
+	type commentAdded struct {
+		CommentAdded struct {
+			Body graphql.String
+		} `graphql:"commentAdded(issue: $issue)"`
+	}
+	results, err := graphql.Subscribe[commentAdded](context.Background(), client, map[string]any{"issue": graphql.Int(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for r := range results {
+		got = append(got, fmt.Sprintf("%q %v", r.Data.CommentAdded.Body, r.Err))
+	}
+	want := []string{
+		`"first" <nil>`,
+		`"" json: cannot unmarshal number into Go value of type graphql.String`,
+		`"third" rate limited`,
+		`"" subscription expired`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got results:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestClient_Subscribe_multiplexing(t *testing.T) {
+	var connections atomic.Int32
+	closed := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		connections.Add(1)
+		conn, err := websocket.Upgrade(w, req, []string{"graphql-transport-ws"}, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer close(closed)
+		defer conn.Close()
+		conn.ReadMessage() // connection_init.
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"connection_ack","payload":{"version":1}}`))
+		active := make(map[string]string) // Query by ID.
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var msg struct {
+				ID      string
+				Type    string
+				Payload struct{ Query string }
+			}
+			mustUnmarshal(string(data), &msg)
+			switch msg.Type {
+			case "subscribe":
+				active[msg.ID] = msg.Payload.Query
+			case "complete":
+				delete(active, msg.ID)
+			}
+			// Send every active operation an event with its query.
+			for id, query := range active {
+				conn.WriteMessage(websocket.TextMessage, []byte(`{"id":"`+id+`","type":"next","payload":{"data":{"query":`+mustMarshal(query)+`}}}`))
+			}
+		}
+	}))
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil)
+
+	sub1, err := client.Subscribe(context.Background(), "subscription{a}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub2, err := client.Subscribe(context.Background(), "subscription{b}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := func(sub *graphql.Subscription) string {
+		var v struct{ Query string }
+		ev := <-sub.Events()
+		ev.Decode(&v)
+		return v.Query
+	}
+	// sub1 gets an event when each subscription starts, and sub2 when it starts.
+	for _, want := range []string{"subscription{a}", "subscription{a}"} {
+		if got := next(sub1); got != want {
+			t.Errorf("sub1: got event for %q, want %q", got, want)
+		}
+	}
+	if got, want := next(sub2), "subscription{b}"; got != want {
+		t.Errorf("sub2: got event for %q, want %q", got, want)
+	}
+	// Stopping sub1 leaves sub2 running.
+	sub1.Unsubscribe()
+	if got, want := next(sub2), "subscription{b}"; got != want {
+		t.Errorf("sub2: got event for %q, want %q", got, want)
+	}
+	if got, want := connections.Load(), int32(1); got != want {
+		t.Errorf("got %d connections, want %d", got, want)
+	}
+	// Stopping the last subscription closes the connection.
+	sub2.Unsubscribe()
+	<-closed
+	if err := sub2.Err(); err != nil {
+		t.Errorf("got error: %v, want: nil", err)
+	}
+}
+
+func TestClient_Close(t *testing.T) {
+	received := make(chan string, 2)
+	srv := transportWSServer(t, func(_ map[string]any, send func(typ, payload string)) {
+		send("next", `{"data":{"tick":1}}`)
+		send("next", `{"data":{"tick":2}}`)
+		send("next", `{"data":{"tick":3}}`)
+		// The pong tells that the client has queued the results.
+		send("ping", "")
+	}, received)
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil)
+
+	sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := <-received, "pong"; got != want {
+		t.Fatalf("got message type: %q, want: %q", got, want)
+	}
+	closed := make(chan error)
+	go func() { closed <- client.Close(context.Background()) }()
+	if got, want := <-received, "complete"; got != want {
+		t.Errorf("got message type: %q, want: %q", got, want)
+	}
+	var got []string
+	for ev := range sub.Events() {
+		got = append(got, string(ev.Data))
+	}
+	if want := []string{`{"tick":1}`, `{"tick":2}`, `{"tick":3}`}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got events: %q, want: %q", got, want)
+	}
+	if err := <-closed; err != nil {
+		t.Errorf("got Close error: %v, want: nil", err)
+	}
+	if err := sub.Err(); err != nil {
+		t.Errorf("got error: %v, want: nil", err)
+	}
+	if _, err := client.Subscribe(context.Background(), "subscription{tick}", nil); err != graphql.ErrClientClosed {
+		t.Errorf("got Subscribe error after Close: %v, want: %v", err, graphql.ErrClientClosed)
+	}
+}
+
+func TestClient_Close_deadline(t *testing.T) {
+	received := make(chan string, 2)
+	srv := transportWSServer(t, func(_ map[string]any, send func(typ, payload string)) {
+		send("next", `{"data":{"tick":1}}`)
+		send("ping", "")
+	}, received)
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil)
+
+	sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-received
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	// The result isn't received from the subscription until after Close.
+	if got, want := client.Close(ctx), context.DeadlineExceeded; got != want {
+		t.Errorf("got Close error: %v, want: %v", got, want)
+	}
+	for range sub.Events() {
+	}
+}
+
+func TestClient_Subscribe_compression(t *testing.T) {
+	srv := transportWSServer(t, func(_ map[string]any, send func(typ, payload string)) {
+		send("next", `{"data":{"tick":1}}`)
+		send("next", `{"data":{"tick":2}}`)
+		send("complete", "")
+	}, nil)
+	defer srv.Close()
+	client := graphql.NewClient(srv.URL, nil, graphql.WithSubscriptionCompression(graphql.WebSocketCompression{}))
+
+	sub, err := client.Subscribe(context.Background(), "subscription{tick}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for ev := range sub.Events() {
+		got = append(got, string(ev.Data))
+	}
+	if err := sub.Err(); err != nil {
+		t.Errorf("got error: %v, want: nil", err)
+	}
+	if want := []string{`{"tick":1}`, `{"tick":2}`}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got events: %q, want: %q", got, want)
+	}
+}
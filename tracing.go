@@ -0,0 +1,72 @@
+package graphql
+
+import (
+	"context"
+	"net/http"
+)
+
+// Operation identifies a GraphQL operation to tracing and other observability hooks.
+type Operation struct {
+	Type     string // Operation type: "query", "mutation" or "subscription".
+	Name     string // Operation name, or "" for an anonymous operation.
+	Document string // GraphQL document.
+}
+
+// newOperation returns the Operation for the GraphQL document query.
+func newOperation(query string) Operation {
+	return Operation{Type: operationType(query), Name: operationName(query), Document: query}
+}
+
+// SpanName returns the name of a span for op, which is its type followed by
+// its name, if any, as recommended by the OpenTelemetry semantic conventions.
+func (op Operation) SpanName() string {
+	if op.Name == "" {
+		return op.Type
+	}
+	return op.Type + " " + op.Name
+}
+
+// Attributes returns the attributes of a span for op, keyed by the
+// OpenTelemetry semantic convention attribute names for GraphQL.
+// Anonymous operations have no "graphql.operation.name" attribute.
+//
+// Specification: https://opentelemetry.io/docs/specs/semconv/graphql/graphql-spans/.
+func (op Operation) Attributes() map[string]string {
+	attrs := map[string]string{
+		"graphql.operation.type": op.Type,
+		"graphql.document":       op.Document,
+	}
+	if op.Name != "" {
+		attrs["graphql.operation.name"] = op.Name
+	}
+	return attrs
+}
+
+// Tracer creates spans for operations in a tracing system, such as
+// OpenTelemetry, and propagates them to the server. Adapting an
+// OpenTelemetry tracer and propagator to it takes a few lines, which
+// keeps this package free of dependencies on them.
+type Tracer interface {
+	// Start starts a span for op as a child of the span in ctx, if any,
+	// and returns a context containing the new span.
+	Start(ctx context.Context, op Operation) (context.Context, Span)
+
+	// Inject sets the headers that propagate the span in ctx
+	// to the server, such as traceparent, in header.
+	Inject(ctx context.Context, header http.Header)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	// End ends the span. err is the error the operation failed with,
+	// or nil if it succeeded; Classify reports its kind, which is suited
+	// to the "error.type" attribute.
+	End(err error)
+}
+
+// WithTracer makes the client create a span with t for every operation
+// sent by Query, Mutate and Do, and propagate it to the server with the
+// headers of each HTTP request.
+func WithTracer(t Tracer) ClientOption {
+	return func(c *Client) { c.tracer = t }
+}
@@ -0,0 +1,69 @@
+package graphql_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+type spanKey struct{}
+
+// recordTracer is a Tracer that records the spans it starts and ends.
+type recordTracer struct {
+	spans []string
+}
+
+func (t *recordTracer) Start(ctx context.Context, op graphql.Operation) (context.Context, graphql.Span) {
+	attrs := op.Attributes()
+	name := fmt.Sprintf("%s [type=%s name=%s]", op.SpanName(), attrs["graphql.operation.type"], attrs["graphql.operation.name"])
+	return context.WithValue(ctx, spanKey{}, name), recordSpan{t: t, name: name}
+}
+
+func (t *recordTracer) Inject(ctx context.Context, header http.Header) {
+	header.Set("Traceparent", ctx.Value(spanKey{}).(string))
+}
+
+type recordSpan struct {
+	t    *recordTracer
+	name string
+}
+
+func (s recordSpan) End(err error) {
+	s.t.spans = append(s.t.spans, fmt.Sprintf("%s: %v (%v)", s.name, err, graphql.Classify(err)))
+}
+
+func TestWithTracer(t *testing.T) {
+	var traceparents []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, req *http.Request) {
+		traceparents = append(traceparents, req.Header.Get("Traceparent"))
+		w.Header().Set("Content-Type", "application/json")
+		if len(traceparents) == 1 {
+			mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+			return
+		}
+		mustWrite(w, `{"errors": [{"message": "boom"}]}`)
+	})
+	tracer := new(recordTracer)
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{handler: mux}}, graphql.WithTracer(tracer))
+
+	var res struct{ User struct{ Name graphql.String } }
+	if err := client.Do(context.Background(), "query GetUser{user{name}}", &res, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	client.Do(context.Background(), "{user{name}}", &res, false, nil)
+
+	want := []string{
+		"query GetUser [type=query name=GetUser]: <nil> (other)",
+		"query [type=query name=]: boom (graphql)",
+	}
+	if fmt.Sprint(tracer.spans) != fmt.Sprint(want) {
+		t.Errorf("got spans: %q, want: %q", tracer.spans, want)
+	}
+	if got, want := traceparents, []string{"query GetUser [type=query name=GetUser]", "query [type=query name=]"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got traceparent headers: %q, want: %q", got, want)
+	}
+}
@@ -0,0 +1,95 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// WithHTTP2Only restricts the client to HTTP/2. Cleartext http:// URLs
+// are spoken to with HTTP/2 over TCP with prior knowledge (h2c), and
+// https:// URLs must negotiate HTTP/2 during the TLS handshake.
+// It's meant for internal gateways behind service meshes, where TLS is
+// terminated elsewhere and connection multiplexing matters for fan-out.
+//
+// Like other options that configure the transport, it requires the
+// HTTP client's transport to be nil or an *http.Transport.
+func WithHTTP2Only() ClientOption {
+	return func(c *Client) {
+		c.transportOpts = append(c.transportOpts, func(t *http.Transport) {
+			var p http.Protocols
+			p.SetHTTP2(true)
+			p.SetUnencryptedHTTP2(true)
+			t.Protocols = &p
+		})
+	}
+}
+
+// Dialer dials network connections for the client's transport.
+// *net.Dialer satisfies it, as do tunneling and SOCKS dialers, such as
+// those from golang.org/x/net/proxy that implement proxy.ContextDialer.
+type Dialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// WithDialer makes the client's transport use d to dial connections,
+// e.g., to route traffic through an SSH tunnel.
+//
+// Like other options that configure the transport, it requires the
+// HTTP client's transport to be nil or an *http.Transport.
+func WithDialer(d Dialer) ClientOption {
+	return func(c *Client) {
+		c.transportOpts = append(c.transportOpts, func(t *http.Transport) {
+			t.DialContext = d.DialContext
+		})
+	}
+}
+
+// WithProxy makes the client's transport send all requests through
+// the proxy at proxyURL, ignoring proxy environment variables.
+// Supported schemes are "http", "https" and "socks5", the last of which
+// is commonly used to reach staging GraphQL servers.
+//
+// Like other options that configure the transport, it requires the
+// HTTP client's transport to be nil or an *http.Transport.
+func WithProxy(proxyURL *url.URL) ClientOption {
+	return func(c *Client) {
+		c.transportOpts = append(c.transportOpts, func(t *http.Transport) {
+			t.Proxy = http.ProxyURL(proxyURL)
+		})
+	}
+}
+
+// configureTransport applies c.transportOpts, if any, to a copy
+// of the HTTP client's transport. The HTTP client provided to NewClient
+// and its transport are never modified.
+func (c *Client) configureTransport() {
+	if len(c.transportOpts) == 0 && c.balancer == nil {
+		return
+	}
+	var t *http.Transport
+	switch rt := c.httpClient.Transport.(type) {
+	case nil:
+		t = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		t = rt.Clone()
+	default:
+		c.err = fmt.Errorf("graphql: transport options require an *http.Transport, but HTTP client uses %T", rt)
+		return
+	}
+	for _, opt := range c.transportOpts {
+		opt(t)
+	}
+	if c.balancer != nil {
+		// Wrap the dialer last, so it applies regardless of option order.
+		if t.DialContext != nil {
+			c.balancer.dial = t.DialContext
+		}
+		t.DialContext = c.balancer.DialContext
+	}
+	hc := *c.httpClient
+	hc.Transport = t
+	c.httpClient = &hc
+}
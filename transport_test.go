@@ -0,0 +1,223 @@
+package graphql_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/isihu/graphql"
+)
+
+func TestClient_Query_http2Only(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.ProtoMajor != 2 {
+			t.Errorf("got protocol: %v, want: HTTP/2", req.Proto)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	}))
+	ts.Config.Protocols = new(http.Protocols)
+	ts.Config.Protocols.SetUnencryptedHTTP2(true)
+	ts.Start()
+	defer ts.Close()
+	client := graphql.NewClient(ts.URL, nil, graphql.WithHTTP2Only())
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+	err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.User.Name, "Gopher"; got != want {
+		t.Errorf("got q.User.Name: %q, want: %q", got, want)
+	}
+}
+
+// Test that transport options report an error if the transport can't be configured.
+func TestClient_Query_transportOptionUnsupported(t *testing.T) {
+	client := graphql.NewClient("/graphql", &http.Client{Transport: localRoundTripper{}}, graphql.WithHTTP2Only())
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+	err := client.Query(context.Background(), &q, nil)
+	if err == nil {
+		t.Fatal("got error: nil, want: non-nil")
+	}
+	if got, want := err.Error(), "graphql: transport options require an *http.Transport"; !strings.HasPrefix(got, want) {
+		t.Errorf("got error: %v, want prefix: %v", got, want)
+	}
+}
+
+func TestClient_Query_withDialer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	}))
+	defer ts.Close()
+	d := &recordingDialer{}
+	// Use a host name that only the dialer knows how to reach.
+	client := graphql.NewClient("http://graphql.staging.internal/graphql", nil, graphql.WithDialer(d))
+	d.addr = ts.Listener.Addr().String()
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+	err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := d.dialed, []string{"graphql.staging.internal:80"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got dialed: %v, want: %v", got, want)
+	}
+}
+
+func TestClient_Query_withSOCKS5Proxy(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	}))
+	defer ts.Close()
+	var proxied atomic.Int32
+	proxy := newSOCKS5Server(t, &proxied)
+	defer proxy.Close()
+	client := graphql.NewClient(ts.URL, nil, graphql.WithProxy(&url.URL{Scheme: "socks5", Host: proxy.Addr().String()}))
+
+	var q struct {
+		User struct {
+			Name string
+		}
+	}
+	err := client.Query(context.Background(), &q, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.User.Name, "Gopher"; got != want {
+		t.Errorf("got q.User.Name: %q, want: %q", got, want)
+	}
+	if got, want := proxied.Load(), int32(1); got != want {
+		t.Errorf("got %v proxied connections, want: %v", got, want)
+	}
+}
+
+// recordingDialer is a Dialer that records the addresses it's asked to dial,
+// but always connects to addr.
+type recordingDialer struct {
+	addr   string
+	dialed []string
+}
+
+func (d *recordingDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	d.dialed = append(d.dialed, addr)
+	var nd net.Dialer
+	return nd.DialContext(ctx, network, d.addr)
+}
+
+// newSOCKS5Server starts a minimal SOCKS5 proxy server that supports
+// the CONNECT command without authentication. It counts the connections
+// it proxies in proxied.
+func newSOCKS5Server(t *testing.T, proxied *atomic.Int32) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			proxied.Add(1)
+			go serveSOCKS5(t, conn)
+		}
+	}()
+	return l
+}
+
+func serveSOCKS5(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	// Greeting: VER, NMETHODS, METHODS.
+	buf := make([]byte, 262)
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+		t.Error(err)
+		return
+	}
+	conn.Write([]byte{5, 0}) // No authentication required.
+	// Request: VER, CMD, RSV, ATYP, DST.ADDR, DST.PORT.
+	if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+		t.Error(err)
+		return
+	}
+	var host string
+	switch buf[3] {
+	case 1: // IPv4.
+		io.ReadFull(conn, buf[:4])
+		host = net.IP(buf[:4]).String()
+	case 3: // Domain name.
+		io.ReadFull(conn, buf[:1])
+		n := int(buf[0])
+		io.ReadFull(conn, buf[:n])
+		host = string(buf[:n])
+	default:
+		t.Errorf("unsupported SOCKS5 address type %v", buf[3])
+		return
+	}
+	io.ReadFull(conn, buf[:2])
+	port := int(buf[0])<<8 | int(buf[1])
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		conn.Write([]byte{5, 1, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0}) // Succeeded.
+	go io.Copy(target, conn)
+	io.Copy(conn, target)
+}
+
+func TestClient_Query_withDNSLoadBalancing(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		mustWrite(w, `{"data": {"user": {"name": "Gopher"}}}`)
+	}))
+	defer ts.Close()
+	_, port, err := net.SplitHostPort(ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := graphql.NewClient("http://localhost:"+port, nil, graphql.WithDNSLoadBalancing(graphql.LeastPending))
+
+	for range 3 {
+		var q struct {
+			User struct {
+				Name string
+			}
+		}
+		err := client.Query(context.Background(), &q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := q.User.Name, "Gopher"; got != want {
+			t.Errorf("got q.User.Name: %q, want: %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package graphql
+
+import (
+	"reflect"
+
+	"github.com/isihu/graphql/internal/jsonutil"
+)
+
+// Warmup precomputes the documents of the operations derived from the given
+// values by Query, Mutate and Subscribe, and the metadata used to decode
+// results into them, so that the reflection they need is done at startup
+// rather than when they're first sent. Values are typically nil pointers
+// to the query structs, such as (*ViewerQuery)(nil). The precomputed
+// metadata is shared by all clients.
+func (c *Client) Warmup(types ...any) {
+	for _, v := range types {
+		t := reflect.TypeOf(v)
+		if t == nil {
+			continue
+		}
+		if t.Kind() != reflect.Ptr {
+			// Operations are derived from pointers to the values they populate.
+			t = reflect.PointerTo(t)
+		}
+		queryOf(t)
+		jsonutil.Warmup(t)
+	}
+}
@@ -0,0 +1,609 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/isihu/graphql/internal/websocket"
+)
+
+// connectWS starts an operation with in over a WebSocket connection to url,
+// which is shared by all subscriptions of the client to url. It returns
+// a function that receives the operation's results and delivers them to s.
+func (c *Client) connectWS(ctx context.Context, url string, in request, s *Subscription) (recv func() error, err error) {
+	var wc *wsConn
+	var op *wsOperation
+	// A connection may be closed after it's looked up, when its last
+	// subscription stops. Then, dial a new one.
+	for range 2 {
+		wc, err = c.wsConn(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		op, err = wc.start(in)
+		if err != errWSClosed {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.setStopOp(func() { wc.drain(op) })
+	return func() error {
+		defer wc.stop(op)
+		for {
+			msg, err := op.next(ctx)
+			if err != nil {
+				return err
+			}
+			switch msg.Type {
+			case wc.p.next:
+				var out response
+				err := json.Unmarshal(msg.Payload, &out)
+				if err != nil {
+					return err
+				}
+				err = s.deliver(ctx, &out)
+				if err != nil {
+					return err
+				}
+			case "error":
+				return s.fail(ctx, decodeWSErrors(msg.Payload))
+			case "complete":
+				return nil
+			}
+		}
+	}, nil
+}
+
+// WebSocketCompression configures the compression of subscription messages over
+// WebSocket with the permessage-deflate extension. By default, every message is
+// compressed with the context of the previous ones in the same direction, which
+// compresses similar messages best, at the cost of keeping up to 32 KiB of context
+// per direction for the lifetime of the connection.
+type WebSocketCompression struct {
+	// ClientNoContextTakeover makes the client compress every message independently.
+	ClientNoContextTakeover bool
+
+	// ServerNoContextTakeover asks the server to compress every message independently.
+	ServerNoContextTakeover bool
+}
+
+// WithSubscriptionCompression offers the server to compress the messages of
+// WebSocket subscription connections according to c, which cuts the bandwidth
+// of frequent, compressible results. Connections to servers that decline the
+// offer aren't compressed.
+func WithSubscriptionCompression(c WebSocketCompression) ClientOption {
+	return func(cl *Client) {
+		cl.subscriptionCompression = &websocket.Compression{
+			ClientNoContextTakeover: c.ClientNoContextTakeover,
+			ServerNoContextTakeover: c.ServerNoContextTakeover,
+		}
+	}
+}
+
+// errWSClosed is returned when starting an operation on a closed connection.
+var errWSClosed = errors.New("graphql: subscription connection closed")
+
+// errWSDrained is returned when receiving from a drained operation.
+var errWSDrained = errors.New("graphql: subscription stopped")
+
+// wsConn is a WebSocket connection that's shared by
+// all subscriptions of a client to the same URL.
+type wsConn struct {
+	c     *Client
+	p     *wsProtocol
+	url   string
+	ready chan struct{} // Closed when the connection is established, or failed to be.
+	conn  *websocket.Conn
+	codec wsCodec
+	err   error // Why establishing the connection failed, if it did. Set before ready is closed.
+
+	mu     sync.Mutex
+	ops    map[string]*wsOperation // Active operations by ID.
+	lastID int
+	closed bool
+}
+
+// wsConn returns the client's connection to url, establishing it if needed.
+func (c *Client) wsConn(ctx context.Context, url string) (*wsConn, error) {
+	p, ok := wsProtocols[c.subscriptionProtocol]
+	if !ok {
+		return nil, fmt.Errorf("graphql: unknown subscription protocol %d", c.subscriptionProtocol)
+	}
+	c.wsMu.Lock()
+	wc, ok := c.wsConns[url]
+	if !ok {
+		wc = &wsConn{c: c, p: p, url: url, ready: make(chan struct{}), ops: make(map[string]*wsOperation)}
+		if c.wsConns == nil {
+			c.wsConns = make(map[string]*wsConn)
+		}
+		c.wsConns[url] = wc
+	}
+	c.wsMu.Unlock()
+	if !ok {
+		wc.err = wc.dial(ctx)
+		if wc.err != nil {
+			wc.forget()
+			c.subscriptionHooks.transportError(url, wc.err)
+		} else {
+			go wc.read()
+		}
+		close(wc.ready)
+	}
+	select {
+	case <-wc.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if wc.err != nil {
+		return nil, wc.err
+	}
+	return wc, nil
+}
+
+// dial opens the connection and initializes it.
+func (wc *wsConn) dial(ctx context.Context) error {
+	var payload json.RawMessage
+	if wc.c.subscriptionInit != nil {
+		v, err := wc.c.subscriptionInit(ctx)
+		if err != nil {
+			return fmt.Errorf("building connection_init payload: %w", err)
+		}
+		payload, err = json.Marshal(v)
+		if err != nil {
+			return err
+		}
+	}
+	header := make(http.Header)
+	wc.c.setHeaders(header)
+	url := wc.url
+	if wc.p.query != "" {
+		url += querySeparator(url) + wc.p.query
+	}
+	var subprotocols []string
+	if wc.p.subprotocol != "" {
+		subprotocols = []string{wc.p.subprotocol}
+	}
+	conn, resp, err := websocket.Dial(ctx, wc.c.httpClient, url, header, subprotocols, wc.c.subscriptionCompression)
+	if resp != nil && resp.StatusCode != http.StatusSwitchingProtocols {
+		return newHTTPError(resp, resp.Body)
+	} else if err != nil {
+		return err
+	}
+
+	wc.c.subscriptionHooks.connected(wc.url)
+
+	// Abort the handshake by closing the connection if ctx is done.
+	codec := wc.p.newCodec(conn)
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	ack, err := codec.handshake(payload)
+	if !stop() {
+		return ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	wc.c.subscriptionHooks.acknowledged(wc.url, ack)
+	wc.conn = conn
+	wc.codec = codec
+	return nil
+}
+
+// forget removes wc from the client's connections,
+// so that later subscriptions establish a new connection.
+func (wc *wsConn) forget() {
+	wc.c.wsMu.Lock()
+	defer wc.c.wsMu.Unlock()
+	if wc.c.wsConns[wc.url] == wc {
+		delete(wc.c.wsConns, wc.url)
+	}
+}
+
+// start starts an operation with in.
+func (wc *wsConn) start(in request) (*wsOperation, error) {
+	payload, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	if wc.closed {
+		return nil, errWSClosed
+	}
+	wc.lastID++
+	op := newWSOperation(strconv.Itoa(wc.lastID), wc.c.subscriptionOverflow)
+	wc.ops[op.id] = op
+	err = wc.codec.write(wsMessage{ID: op.id, Type: wc.p.subscribe, Payload: payload})
+	if err != nil {
+		delete(wc.ops, op.id)
+		return nil, err
+	}
+	return op, nil
+}
+
+// stop stops the operation op, telling the server to stop it
+// if it's still active. The connection is closed once it has
+// no more active operations.
+func (wc *wsConn) stop(op *wsOperation) {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	if wc.ops[op.id] != op {
+		return
+	}
+	delete(wc.ops, op.id)
+	if wc.closed {
+		return
+	}
+	wc.codec.write(wsMessage{ID: op.id, Type: wc.p.stop})
+	if len(wc.ops) == 0 {
+		wc.closeLocked()
+	}
+}
+
+// drain stops the operation op like stop, but lets its subscription receive
+// the messages that were already queued for it before it fails with errWSDrained.
+func (wc *wsConn) drain(op *wsOperation) {
+	wc.stop(op)
+	op.fail(errWSDrained)
+}
+
+// closeLocked closes the connection. wc.mu must be held.
+func (wc *wsConn) closeLocked() {
+	wc.closed = true
+	wc.forget()
+	if wc.p.terminate != "" {
+		wc.codec.write(wsMessage{Type: wc.p.terminate})
+	}
+	wc.conn.Close()
+}
+
+// read reads messages from the connection and dispatches them to their
+// operations, until the connection fails or is closed. Then it fails all
+// remaining operations. Dispatching doesn't block, so that an operation
+// whose subscriber is slow doesn't hold up the others.
+func (wc *wsConn) read() {
+	ping := func() error { return wc.conn.Ping(nil) }
+	if wc.p.ping {
+		ping = func() error { return wc.codec.write(wsMessage{Type: "ping"}) }
+	}
+	ka := wc.c.subscriptionKeepAlive
+	if ka.PingInterval == 0 && wc.p.pingInterval > 0 {
+		ka.PingInterval = wc.p.pingInterval
+		if ka.PongTimeout == 0 {
+			ka.PongTimeout = ka.PingInterval
+		}
+	}
+	m := startKeepAlive(ka, ping, func() { wc.conn.Close() })
+	wc.conn.SetPongHandler(func([]byte) { m.ponged() })
+
+	err := wc.dispatch(m)
+	err = m.finish(rejection(err))
+
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	if !wc.closed {
+		// The connection failed, rather than being closed by the client.
+		wc.closed = true
+		wc.forget()
+		wc.conn.Close()
+		wc.c.subscriptionHooks.transportError(wc.url, err)
+	}
+	for id, op := range wc.ops {
+		op.fail(err)
+		delete(wc.ops, id)
+	}
+}
+
+// dispatch reads messages and dispatches them to their operations,
+// recording activity on m, until reading fails.
+func (wc *wsConn) dispatch(m *keepAliveMonitor) error {
+	for {
+		msg, err := wc.codec.read()
+		if err != nil {
+			return err
+		}
+		m.activity()
+		switch {
+		case msg.Type == wc.p.next, msg.Type == "error", msg.Type == "complete":
+			wc.mu.Lock()
+			op := wc.ops[msg.ID]
+			if op != nil && msg.Type != wc.p.next {
+				// The server stopped the operation.
+				delete(wc.ops, msg.ID)
+				if len(wc.ops) == 0 {
+					wc.closeLocked()
+				}
+			}
+			wc.mu.Unlock()
+			if op != nil {
+				op.push(msg)
+			}
+		case msg.Type == "ping":
+			err := wc.codec.write(wsMessage{Type: "pong"})
+			if err != nil {
+				return err
+			}
+		case msg.Type == "pong":
+			m.ponged()
+		case wc.p.keepAlive != "" && msg.Type == wc.p.keepAlive:
+			// Ignore keep-alive messages.
+		default:
+			wc.conn.CloseWithCode(websocket.CloseProtocolError, "")
+			return fmt.Errorf("graphql: unexpected %q message", msg.Type)
+		}
+	}
+}
+
+// wsQueueSize is how many messages an operation on a shared connection
+// queues until its subscription receives them.
+const wsQueueSize = 256
+
+// wsQueueWait is how long the connection waits for the subscriber of an
+// operation with a full queue under OverflowBlock, before failing the
+// operation rather than holding up the other operations any longer.
+const wsQueueWait = 100 * time.Millisecond
+
+// wsOperation is an operation on a shared connection.
+// Its messages are queued until its subscription receives them.
+type wsOperation struct {
+	id       string
+	overflow OverflowPolicy // What push does when the queue is full.
+	ready    chan struct{}  // Signaled when a message is queued or the operation fails.
+	space    chan struct{}  // Signaled when a queued message is received.
+
+	mu    sync.Mutex
+	queue []wsMessage
+	err   error // Why the connection failed, if it did. Reported after queued messages.
+}
+
+// newWSOperation returns a new operation with the ID id, whose full queue
+// is handled according to overflow.
+func newWSOperation(id string, overflow OverflowPolicy) *wsOperation {
+	return &wsOperation{
+		id:       id,
+		overflow: overflow,
+		ready:    make(chan struct{}, 1),
+		space:    make(chan struct{}, 1),
+	}
+}
+
+// push queues msg. If the queue is full, it discards the oldest message
+// under OverflowDropOldest. Otherwise, it fails the operation with
+// ErrSubscriptionOverflow, under OverflowBlock only if the subscriber
+// doesn't receive a message within wsQueueWait.
+func (op *wsOperation) push(msg wsMessage) {
+	var wait *time.Timer
+	expired := false
+	for {
+		op.mu.Lock()
+		switch {
+		case op.err == ErrSubscriptionOverflow:
+			op.mu.Unlock()
+			return
+		case len(op.queue) < wsQueueSize:
+			op.queue = append(op.queue, msg)
+		case op.overflow == OverflowDropOldest:
+			op.queue[0] = wsMessage{}
+			op.queue = append(op.queue[1:], msg)
+		case op.overflow == OverflowBlock && !expired:
+			op.mu.Unlock()
+			if wait == nil {
+				wait = time.NewTimer(wsQueueWait)
+				defer wait.Stop()
+			}
+			select {
+			case <-op.space:
+			case <-wait.C:
+				expired = true
+			}
+			continue
+		default:
+			op.queue = nil
+			op.err = ErrSubscriptionOverflow
+		}
+		op.mu.Unlock()
+		op.signal()
+		return
+	}
+}
+
+// fail reports that the connection failed with err.
+func (op *wsOperation) fail(err error) {
+	op.mu.Lock()
+	op.err = err
+	op.mu.Unlock()
+	op.signal()
+}
+
+func (op *wsOperation) signal() {
+	select {
+	case op.ready <- struct{}{}:
+	default:
+	}
+}
+
+// next returns the next queued message, waiting for one if needed.
+func (op *wsOperation) next(ctx context.Context) (wsMessage, error) {
+	for {
+		op.mu.Lock()
+		if len(op.queue) > 0 {
+			msg := op.queue[0]
+			op.queue[0] = wsMessage{}
+			op.queue = op.queue[1:]
+			op.mu.Unlock()
+			select {
+			case op.space <- struct{}{}:
+			default:
+			}
+			return msg, nil
+		}
+		err := op.err
+		op.mu.Unlock()
+		if err != nil {
+			return wsMessage{}, err
+		}
+		select {
+		case <-op.ready:
+		case <-ctx.Done():
+			return wsMessage{}, ctx.Err()
+		}
+	}
+}
+
+// wsProtocol describes the messages of a WebSocket subscription protocol
+// that differ between protocols. The graphql-ws protocols initialize the
+// connection with connection_init and connection_ack, report failed operations
+// with error, and report finished operations with complete. The messages of
+// other protocols are translated to and from those by a codec.
+type wsProtocol struct {
+	subprotocol     string // Subprotocol to negotiate, if any.
+	query           string // Query parameters to add to the URL to dial, if any.
+	subscribe       string // Client message that starts an operation.
+	next            string // Server message with a result of an operation.
+	stop            string // Client message that stops an operation.
+	terminate       string // Client message sent before closing the connection, if any.
+	keepAlive       string // Server keep-alive message, if any.
+	connectionError string // Server message rejecting connection_init, if any.
+	ping            bool   // Whether ping and pong messages are used, rather than WebSocket ping frames.
+
+	// pingInterval is how often to ping if the client's KeepAlive doesn't say,
+	// for servers that close connections that aren't pinged. Zero means never.
+	pingInterval time.Duration
+
+	// codec returns the codec for a connection. Nil means graphqlWSCodec.
+	codec func(conn *websocket.Conn) wsCodec
+}
+
+var wsProtocols = map[SubscriptionProtocol]*wsProtocol{
+	GraphQLTransportWS: {
+		subprotocol: "graphql-transport-ws",
+		subscribe:   "subscribe",
+		next:        "next",
+		stop:        "complete",
+		ping:        true,
+	},
+	SubscriptionsTransportWS: {
+		subprotocol:     "graphql-ws",
+		subscribe:       "start",
+		next:            "data",
+		stop:            "stop",
+		terminate:       "connection_terminate",
+		keepAlive:       "ka",
+		connectionError: "connection_error",
+	},
+	AbsinthePhoenix: {
+		query:        "vsn=2.0.0",
+		subscribe:    "doc",
+		next:         "subscription:data",
+		stop:         "unsubscribe",
+		ping:         true,
+		pingInterval: 30 * time.Second,
+		codec:        newPhoenixCodec,
+	},
+}
+
+// wsMessage is a message of a WebSocket subscription protocol.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsCodec reads and writes the messages of a protocol on a connection.
+type wsCodec interface {
+	// handshake initializes the connection and waits for the server to acknowledge it.
+	// It returns the payload of the acknowledgement, if any.
+	// payload is the payload to initialize it with, if any.
+	handshake(payload json.RawMessage) (json.RawMessage, error)
+
+	read() (wsMessage, error)
+	write(msg wsMessage) error
+}
+
+// newCodec returns the codec of p for conn.
+func (p *wsProtocol) newCodec(conn *websocket.Conn) wsCodec {
+	if p.codec != nil {
+		return p.codec(conn)
+	}
+	return graphqlWSCodec{p, conn}
+}
+
+// graphqlWSCodec is the codec of the graphql-ws protocols,
+// whose messages are JSON encoded wsMessages.
+type graphqlWSCodec struct {
+	p    *wsProtocol
+	conn *websocket.Conn
+}
+
+func (c graphqlWSCodec) handshake(payload json.RawMessage) (json.RawMessage, error) {
+	p := c.p
+	err := c.write(wsMessage{Type: "connection_init", Payload: payload})
+	if err != nil {
+		return nil, err
+	}
+	for {
+		msg, err := c.read()
+		if err != nil {
+			return nil, rejection(err)
+		}
+		switch {
+		case msg.Type == "connection_ack":
+			return msg.Payload, nil
+		case msg.Type == "ping":
+			err = c.write(wsMessage{Type: "pong"})
+			if err != nil {
+				return nil, err
+			}
+		case p.keepAlive != "" && msg.Type == p.keepAlive:
+			// Ignore keep-alive messages.
+		case p.connectionError != "" && msg.Type == p.connectionError:
+			return nil, &ConnectionRejectedError{Payload: msg.Payload}
+		default:
+			return nil, fmt.Errorf("graphql: unexpected %q message before connection_ack", msg.Type)
+		}
+	}
+}
+
+// decodeWSErrors decodes the payload of an error message, which is a list
+// of GraphQL errors, or a single one in some implementations of the legacy protocol.
+func decodeWSErrors(payload json.RawMessage) error {
+	var errs Errors
+	if bytes.HasPrefix(bytes.TrimSpace(payload), []byte("{")) {
+		payload = append(append(json.RawMessage("["), payload...), ']')
+	}
+	err := json.Unmarshal(payload, &errs)
+	if err != nil {
+		return err
+	}
+	if len(errs) == 0 {
+		return errors.New("graphql: subscription failed without errors")
+	}
+	return errs
+}
+
+func (c graphqlWSCodec) read() (wsMessage, error) {
+	var msg wsMessage
+	_, data, err := c.conn.ReadMessage()
+	if err != nil {
+		return msg, err
+	}
+	err = json.Unmarshal(data, &msg)
+	return msg, err
+}
+
+func (c graphqlWSCodec) write(msg wsMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
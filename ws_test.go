@@ -0,0 +1,60 @@
+package graphql
+
+import (
+	"context"
+	"strconv"
+	"testing"
+)
+
+func TestWSOperation_push(t *testing.T) {
+	tests := []struct {
+		overflow OverflowPolicy
+		wantID   string // ID of the first message received.
+		wantErr  error
+	}{
+		{overflow: OverflowBlock, wantErr: ErrSubscriptionOverflow},
+		{overflow: OverflowDropOldest, wantID: strconv.Itoa(wsQueueSize)},
+		{overflow: OverflowError, wantErr: ErrSubscriptionOverflow},
+	}
+	for _, tc := range tests {
+		op := newWSOperation("1", tc.overflow)
+		for i := range 2 * wsQueueSize {
+			op.push(wsMessage{ID: strconv.Itoa(i), Type: "next"})
+			if got := len(op.queue); got > wsQueueSize {
+				t.Fatalf("overflow %d: got queue length: %d, want at most: %d", tc.overflow, got, wsQueueSize)
+			}
+		}
+		msg, err := op.next(context.Background())
+		if err != tc.wantErr {
+			t.Errorf("overflow %d: got error: %v, want: %v", tc.overflow, err, tc.wantErr)
+		}
+		if got, want := msg.ID, tc.wantID; got != want {
+			t.Errorf("overflow %d: got message: %q, want: %q", tc.overflow, got, want)
+		}
+	}
+}
+
+func TestWSOperation_pushBlock(t *testing.T) {
+	op := newWSOperation("1", OverflowBlock)
+	const n = 4 * wsQueueSize
+	received := make(chan error)
+	go func() {
+		for i := range n {
+			msg, err := op.next(context.Background())
+			if err == nil && msg.ID != strconv.Itoa(i) {
+				t.Errorf("got message: %q, want: %d", msg.ID, i)
+			}
+			if err != nil {
+				received <- err
+				return
+			}
+		}
+		received <- nil
+	}()
+	for i := range n {
+		op.push(wsMessage{ID: strconv.Itoa(i), Type: "next"})
+	}
+	if err := <-received; err != nil {
+		t.Errorf("got error: %v, want: nil", err)
+	}
+}